@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"dbx/internal/cloud"
+	"dbx/internal/db"
+	"dbx/internal/secrets"
+	"dbx/internal/tunnel"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -21,12 +23,53 @@ var (
 	s3Bucket, s3Prefix string
 	gcsBucket, gcsPrefix string
 	azureAccount, azureContainer, azureBlob string
+	// Multi-database flags
+	allDatabases     bool
+	includeDatabases []string
+	excludeDatabases []string
+	// Table and collection filters
+	includeTables, excludeTables           []string
+	includeCollections, excludeCollections []string
+	backupContent                          string
+	maskRulesFile                          string
+	parallelJobs                           int
+	backupDumper                           string
+	sqliteFormat                           string
+	// MySQL/MariaDB: logical or physical; --physical is MariaDB's deprecated alias for --method physical
+	backupMethod    string
+	mariadbPhysical bool
+	// Connection options beyond host/port
+	socket, tlsMode, tlsCA, tlsCert, tlsKey string
+	// SSH jump host
+	sshHost, sshUser, sshKey, sshKnownHosts string
+	// Host the database's certificate is checked against once openTunnel has rewritten host
+	tlsServerName string
+	// Backup file name prefix of whole-server engines (Redis, etcd, Consul)
+	serverName string
 )
 
 var backupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Backup a database",
-	Long:  "Backup a database. Use subcommands (mysql, postgres, mongo, sqlite) for specific database types.",
+	Long:  "Backup a database. Use subcommands (mysql, postgres, mongo, sqlite) for specific database types, or --profile to back up a profile from the config file.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if profileName == "" {
+			return cmd.Help()
+		}
+		p, err := loadProfile(profileName)
+		if err != nil {
+			return err
+		}
+		for _, sub := range cmd.Commands() {
+			if commandEngines[sub.Name()] == p.Engine {
+				if err := applyProfile(sub, args); err != nil {
+					return err
+				}
+				return sub.RunE(sub, args)
+			}
+		}
+		return fmt.Errorf("profile %q: no backup command for engine %s", p.Name, p.Engine)
+	},
 }
 
 func init() {
@@ -34,18 +77,174 @@ func init() {
 	// Subcommands are added in their respective files
 }
 
-// handleCloudUpload handles cloud upload for backup files
-func handleCloudUpload(dbName, outDir, dbType string) error {
-	// Find the most recent backup file
-	pattern := filepath.Join(outDir, dbName+"*")
-	matches, _ := filepath.Glob(pattern)
-	if len(matches) == 0 {
-		return fmt.Errorf("no backup file found in %s", outDir)
+// resolveCredentials replaces secret references (env:, file:, store:) given on the command line
+func resolveCredentials() error {
+	var err error
+	if password, err = secrets.Resolve(password); err != nil {
+		return fmt.Errorf("failed to resolve password: %w", err)
+	}
+	if uri, err = secrets.Resolve(uri); err != nil {
+		return fmt.Errorf("failed to resolve uri: %w", err)
+	}
+	return nil
+}
+
+// multiDatabaseRequested reports whether --all-databases or --include selects databases by discovery
+func multiDatabaseRequested() bool {
+	return allDatabases || len(includeDatabases) > 0
+}
+
+// addMultiDatabaseFlags registers --all-databases, --include and --exclude on a backup command
+func addMultiDatabaseFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&allDatabases, "all-databases", false, "Back up every database on the server (one artifact per database)")
+	cmd.Flags().StringSliceVar(&includeDatabases, "include", nil, "Back up databases matching these glob patterns (e.g. 'shop_*'); implies discovery")
+	cmd.Flags().StringSliceVar(&excludeDatabases, "exclude", nil, "Skip databases matching these glob patterns")
+}
+
+// addTableFilterFlags registers --tables and --exclude-tables on a MySQL/PostgreSQL command
+func addTableFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&includeTables, "tables", nil, "Back up only these tables (comma-separated or repeated)")
+	cmd.Flags().StringSliceVar(&excludeTables, "exclude-tables", nil, "Skip these tables, e.g. large audit or log tables")
+}
+
+// addCollectionFilterFlags registers --collections and --exclude-collections on a MongoDB command
+func addCollectionFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&includeCollections, "collections", nil, "Back up only these collections")
+	cmd.Flags().StringSliceVar(&excludeCollections, "exclude-collections", nil, "Skip these collections")
+}
+
+// addContentFlag registers --content on a MySQL/PostgreSQL/SQLite command
+func addContentFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&backupContent, "content", "all", "What to back up: schema, data, or all")
+}
+
+// addMaskFlag registers --mask-rules on a MySQL/PostgreSQL/SQLite command
+func addMaskFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&maskRulesFile, "mask-rules", "", "Masking rules file (YAML/JSON) applied while dumping, for sanitized copies")
+}
+
+// addJobsFlag registers --jobs on a backup or restore command
+func addJobsFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&parallelJobs, "jobs", 0, "Parallel jobs for large databases (pg_dump/pg_restore -j, per-table MySQL dumps, parallel MongoDB collections)")
+}
+
+// addDumperFlag registers --dumper on a MySQL, MariaDB, PostgreSQL or MongoDB command
+func addDumperFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&backupDumper, "dumper", "auto", "Logical dump engine: auto (the client tool when installed, else built-in), tool, or builtin")
+}
+
+// addConnFlags registers --socket and the TLS flags on a MySQL, PostgreSQL or MongoDB command
+func addConnFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&socket, "socket", "", "Unix socket file (MySQL, MongoDB, Redis, etcd, Consul) or socket directory (PostgreSQL), used instead of the host")
+	cmd.Flags().StringVar(&tlsMode, "tls-mode", "", "TLS mode: disable, prefer, require, verify-ca, or verify-full (default: the client's)")
+	cmd.Flags().StringVar(&tlsCA, "tls-ca", "", "CA certificate file for verifying the server")
+	cmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Client certificate file")
+	cmd.Flags().StringVar(&tlsKey, "tls-key", "", "Client key file")
+}
+
+// connOptions collects the connection flags into db.ConnOptions
+func connOptions() db.ConnOptions {
+	return db.ConnOptions{Port: port, Socket: socket, TLSMode: tlsMode, TLSCA: tlsCA, TLSCert: tlsCert, TLSKey: tlsKey, TLSServerName: tlsServerName}
+}
+
+// addSSHFlags registers the SSH jump host flags on a MySQL, PostgreSQL or MongoDB command
+func addSSHFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sshHost, "ssh-host", "", "Reach the database through this SSH jump host (host or host:port)")
+	cmd.Flags().StringVar(&sshUser, "ssh-user", "", "SSH user on the jump host (default: the local user)")
+	cmd.Flags().StringVar(&sshKey, "ssh-key", "", "SSH private key (default: keys from ssh-agent)")
+	cmd.Flags().StringVar(&sshKnownHosts, "ssh-known-hosts", "", "known_hosts file for the jump host's key (default: ~/.ssh/known_hosts)")
+}
+
+// openTunnel starts an SSH port forward when --ssh-host is set and points host/port (or --uri for
+// MongoDB) at it. The returned function closes the tunnel.
+func openTunnel(engine string) (func(), error) {
+	cfg := tunnel.Config{Host: sshHost, User: sshUser, KeyFile: sshKey, KnownHosts: sshKnownHosts}
+	params := map[string]string{"host": host, "port": port, "uri": uri, "socket": socket}
+	rewritten, closeTunnel, err := tunnel.Rewrite(engine, params, cfg)
+	if err != nil {
+		return nil, err
+	}
+	host, port, uri = rewritten["host"], rewritten["port"], rewritten["uri"]
+	tlsServerName = rewritten["tls_server_name"]
+	return closeTunnel, nil
+}
+
+// backupOptions collects the filter flags into db.BackupOptions
+func backupOptions() db.BackupOptions {
+	return db.BackupOptions{
+		Content:            db.BackupContent(backupContent),
+		Tables:             includeTables,
+		ExcludeTables:      excludeTables,
+		Collections:        includeCollections,
+		ExcludeCollections: excludeCollections,
+		MaskRules:          maskRulesFile,
+		Jobs:               parallelJobs,
+		Dumper:             backupDumper,
+		Format:             sqliteFormat,
+		Conn:               connOptions(),
+	}
+}
+
+// physicalMethod reads --method, taking the deprecated --physical as --method physical, and reports
+// whether it asks for a physical backup
+func physicalMethod() (bool, error) {
+	method := backupMethod
+	if mariadbPhysical {
+		method = "physical"
+	}
+	switch method {
+	case "logical":
+		return false, nil
+	case "physical":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid method %q (use logical or physical)", method)
+}
+
+// backupAllDatabases discovers the server's databases, applies --include/--exclude and backs up each one.
+// Failures don't stop the run; the combined result is reported once at the end.
+func backupAllDatabases(engine, label string, opts db.BackupOptions, discover func() ([]string, error), backup func(database string, opts db.BackupOptions) error) error {
+	names, err := discover()
+	if err != nil {
+		return err
+	}
+	names, err = db.FilterDatabases(engine, names, includeDatabases, excludeDatabases)
+	if err != nil {
+		return err
 	}
-	
-	// Use the most recent file
-	backupFile := matches[len(matches)-1]
-	
+	if len(names) == 0 {
+		return fmt.Errorf("no databases matched on the server")
+	}
+
+	summary := db.BackupDatabases(label, names, opts, func(name string, opts db.BackupOptions) error {
+		upload := recordArtifact(&opts)
+		if err := backup(name, opts); err != nil {
+			return err
+		}
+		if uploadCloud {
+			if err := upload(); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+		return nil
+	})
+	return summary.Err()
+}
+
+// recordArtifact makes opts remember the backup file they write; the returned function uploads it
+func recordArtifact(opts *db.BackupOptions) func() error {
+	var artifact string
+	opts.OnArtifact = func(path string) { artifact = path }
+	return func() error {
+		if artifact == "" {
+			return fmt.Errorf("the backup did not report a file to upload")
+		}
+		return uploadToCloud(artifact)
+	}
+}
+
+// uploadToCloud uploads a file to the provider selected with --cloud
+func uploadToCloud(file string) error {
 	switch strings.ToLower(cloudProvider) {
 	case "s3":
 		bucket := s3Bucket
@@ -62,17 +261,17 @@ func handleCloudUpload(dbName, outDir, dbType string) error {
 				prefix = "dbx/"
 			}
 		}
-		return cloud.UploadToS3(backupFile, bucket, prefix)
+		return cloud.UploadToS3(file, bucket, prefix)
 	case "gcs":
 		if gcsBucket == "" {
 			return fmt.Errorf("GCS bucket name required (use --gcs-bucket)")
 		}
-		return cloud.UploadToGCS(backupFile, gcsBucket, gcsPrefix)
+		return cloud.UploadToGCS(file, gcsBucket, gcsPrefix)
 	case "azure":
 		if azureAccount == "" || azureContainer == "" {
 			return fmt.Errorf("Azure account and container required (use --azure-account and --azure-container)")
 		}
-		return cloud.UploadToAzure(backupFile, azureAccount, azureContainer, azureBlob)
+		return cloud.UploadToAzure(file, azureAccount, azureContainer, azureBlob)
 	default:
 		return fmt.Errorf("unsupported cloud provider: %s (use s3, gcs, or azure)", cloudProvider)
 	}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Variables are declared in backup.go
+
+var clickhouseCmd = &cobra.Command{
+	Use:   "clickhouse",
+	Short: "Backup a ClickHouse database",
+	Long: `Export every table of a ClickHouse database with clickhouse-client: its CREATE statement and
+its rows in Native format, --jobs tables at a time. Views, materialized views and dictionaries
+are saved as their CREATE statements. The exports are bundled into one zip, which restores
+with dbx restore clickhouse.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("clickhouse")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		opts := backupOptions()
+		upload := recordArtifact(&opts)
+		err = db.BackupClickHouseWithOptions(host, port, user, password, database, out, opts)
+		if err != nil {
+			fmt.Println("Backup failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Backup complete")
+
+		// Handle cloud upload if requested
+		if uploadCloud {
+			if err := upload(); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(clickhouseCmd)
+
+	clickhouseCmd.Flags().StringVar(&host, "host", "localhost", "ClickHouse host")
+	clickhouseCmd.Flags().StringVar(&port, "port", "", "ClickHouse native protocol port (default 9000, 9440 with TLS)")
+	clickhouseCmd.Flags().StringVar(&user, "user", "default", "ClickHouse user")
+	clickhouseCmd.Flags().StringVar(&password, "password", "", "ClickHouse password")
+	clickhouseCmd.Flags().StringVar(&database, "database", "", "Database name")
+	clickhouseCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+	clickhouseCmd.MarkFlagRequired("database")
+
+	// Cloud upload flags
+	clickhouseCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
+	clickhouseCmd.Flags().StringVar(&cloudProvider, "cloud", "s3", "Cloud provider: s3, gcs, or azure")
+	clickhouseCmd.Flags().StringVar(&s3Bucket, "s3-bucket", "", "S3 bucket name (or set DBX_S3_BUCKET env var)")
+	clickhouseCmd.Flags().StringVar(&s3Prefix, "s3-prefix", "dbx/", "S3 prefix/folder path")
+	clickhouseCmd.Flags().StringVar(&gcsBucket, "gcs-bucket", "", "GCS bucket name")
+	clickhouseCmd.Flags().StringVar(&gcsPrefix, "gcs-prefix", "dbx/", "GCS prefix/folder path")
+	clickhouseCmd.Flags().StringVar(&azureAccount, "azure-account", "", "Azure storage account name")
+	clickhouseCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
+	clickhouseCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
+
+	addTableFilterFlags(clickhouseCmd)
+	addContentFlag(clickhouseCmd)
+	addJobsFlag(clickhouseCmd)
+	addConnFlags(clickhouseCmd)
+	addSSHFlags(clickhouseCmd)
+}
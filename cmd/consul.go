@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Variables are declared in backup.go
+
+var consulCmd = &cobra.Command{
+	Use:   "consul",
+	Short: "Backup a Consul cluster",
+	Long: `Save a snapshot of a Consul cluster's state (KV store, catalog, sessions, prepared queries
+and ACLs) through the agent's snapshot API. Consul snapshots are already gzipped archives, so
+they are kept as .snap files; every file in the archive is checked against its SHA256SUMS
+before the snapshot is kept. With ACLs enabled --password is a management token (default:
+$CONSUL_HTTP_TOKEN).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		// Named before the tunnel replaces host and port
+		name := serverName
+		if name == "" {
+			name = db.ConsulBackupName(host, port, connOptions())
+		}
+		closeTunnel, err := openTunnel("consul")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		opts := backupOptions()
+		upload := recordArtifact(&opts)
+		err = db.BackupConsulWithOptions(host, port, password, name, out, opts)
+		if err != nil {
+			fmt.Println("Backup failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Backup complete")
+
+		// Handle cloud upload if requested
+		if uploadCloud {
+			if err := upload(); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(consulCmd)
+
+	consulCmd.Flags().StringVar(&host, "host", "localhost", "Consul agent host")
+	consulCmd.Flags().StringVar(&port, "port", "8500", "Consul HTTP API port")
+	consulCmd.Flags().StringVar(&password, "password", "", "Consul ACL token (or set CONSUL_HTTP_TOKEN)")
+	consulCmd.Flags().StringVar(&serverName, "name", "", "Backup file name prefix (default: consul_<host>_<port>)")
+	consulCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+
+	// Cloud upload flags
+	consulCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
+	consulCmd.Flags().StringVar(&cloudProvider, "cloud", "s3", "Cloud provider: s3, gcs, or azure")
+	consulCmd.Flags().StringVar(&s3Bucket, "s3-bucket", "", "S3 bucket name (or set DBX_S3_BUCKET env var)")
+	consulCmd.Flags().StringVar(&s3Prefix, "s3-prefix", "dbx/", "S3 prefix/folder path")
+	consulCmd.Flags().StringVar(&gcsBucket, "gcs-bucket", "", "GCS bucket name")
+	consulCmd.Flags().StringVar(&gcsPrefix, "gcs-prefix", "dbx/", "GCS prefix/folder path")
+	consulCmd.Flags().StringVar(&azureAccount, "azure-account", "", "Azure storage account name")
+	consulCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
+	consulCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
+
+	addConnFlags(consulCmd)
+	addSSHFlags(consulCmd)
+}
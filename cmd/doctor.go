@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"dbx/internal/config"
+	"dbx/internal/doctor"
+	"dbx/internal/scheduler"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorJSON        bool
+	doctorOutDirs     []string
+	doctorMinFree     string
+	doctorSkipConnect bool
+	doctorTimeout     time.Duration
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment backups run in",
+	Long: `Check everything dbx relies on outside itself: dump and restore tools on PATH and
+their versions against each configured server, writability and free space of the
+backup and log directories, cloud CLIs and credentials for configured destinations,
+scheduled jobs, and the clock and timezone.
+
+Each check passes, warns or fails; the command exits non-zero when any check fails.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		minFree, err := doctor.ParseSize(doctorMinFree)
+		if err != nil {
+			return err
+		}
+
+		opts := doctor.Options{
+			Jobs:    scheduler.ListJobs(),
+			OutDirs: doctorOutDirs,
+			MinFree: minFree,
+			Connect: !doctorSkipConnect,
+			Timeout: doctorTimeout,
+		}
+		cfg, err := config.LoadDefault()
+		if err == nil {
+			opts.Config = cfg
+		} else if _, statErr := os.Stat(config.DefaultPath()); statErr == nil {
+			opts.ConfigErr = err
+		}
+
+		report := doctor.Run(opts)
+		if doctorJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			printReport(report)
+		}
+		if !report.OK() {
+			return fmt.Errorf("%d check(s) failed", report.Failures)
+		}
+		return nil
+	},
+}
+
+// printReport prints the checks grouped by category
+func printReport(r *doctor.Report) {
+	icons := map[doctor.Status]string{doctor.Pass: "✅", doctor.Warn: "⚠️ ", doctor.Fail: "❌"}
+	category := ""
+	for _, c := range r.Checks {
+		if c.Category != category {
+			category = c.Category
+			fmt.Printf("\n%s\n", strings.ToUpper(category[:1])+category[1:])
+		}
+		fmt.Printf("  %s %s: %s\n", icons[c.Status], c.Name, c.Detail)
+	}
+	fmt.Printf("\n%d passed, %d warnings, %d failed\n", r.Passed, r.Warnings, r.Failures)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+	doctorCmd.Flags().StringSliceVar(&doctorOutDirs, "out", nil, "Extra backup directories to check (./backups, profile and job directories are always checked)")
+	doctorCmd.Flags().StringVar(&doctorMinFree, "min-free", "1GB", "Free space below which a directory fails (warns below twice this)")
+	doctorCmd.Flags().BoolVar(&doctorSkipConnect, "skip-connect", false, "Don't connect to profiles and jobs to compare server and client versions")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 5*time.Second, "Give up on each connection after this long")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Variables are declared in backup.go
+
+var (
+	esRepository, esRepositoryPath string
+	esIndices                      []string
+	esGlobalState                  bool
+)
+
+var elasticsearchCmd = &cobra.Command{
+	Use:     "elasticsearch",
+	Aliases: []string{"opensearch"},
+	Short:   "Snapshot an Elasticsearch or OpenSearch cluster",
+	Long: `Have an Elasticsearch or OpenSearch cluster snapshot its indices into a snapshot repository,
+and wait until the snapshot has finished. The snapshot is written by the cluster's nodes, so
+it stays in the repository rather than in a local backup file. --repository-path registers
+a shared filesystem repository first; the path must be listed in path.repo on every node.
+A --password without --user is sent as an API key.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		// Named before the tunnel replaces host and port
+		name := serverName
+		if name == "" {
+			name = db.ElasticsearchBackupName(host, port, connOptions())
+		}
+		closeTunnel, err := openTunnel("elasticsearch")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		if err := db.BackupElasticsearch(host, port, user, password, name, elasticsearchOptions()); err != nil {
+			fmt.Println("Backup failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Backup complete")
+		return nil
+	},
+}
+
+// elasticsearchOptions collects the snapshot flags of the Elasticsearch commands
+func elasticsearchOptions() db.ElasticsearchOptions {
+	return db.ElasticsearchOptions{
+		Repository:     esRepository,
+		RepositoryPath: esRepositoryPath,
+		Indices:        esIndices,
+		GlobalState:    esGlobalState,
+		Conn:           connOptions(),
+	}
+}
+
+func init() {
+	backupCmd.AddCommand(elasticsearchCmd)
+
+	elasticsearchCmd.Flags().StringVar(&host, "host", "localhost", "Elasticsearch host")
+	elasticsearchCmd.Flags().StringVar(&port, "port", "9200", "Elasticsearch HTTP port")
+	elasticsearchCmd.Flags().StringVar(&user, "user", "", "Elasticsearch user (basic authentication)")
+	elasticsearchCmd.Flags().StringVar(&password, "password", "", "Elasticsearch password, or an API key without --user")
+	elasticsearchCmd.Flags().StringVar(&serverName, "name", "", "Snapshot name prefix (default: elasticsearch_<host>_<port>)")
+	elasticsearchCmd.Flags().StringVar(&esRepository, "repository", "dbx", "Snapshot repository")
+	elasticsearchCmd.Flags().StringVar(&esRepositoryPath, "repository-path", "", "Register --repository as a filesystem repository at this path (must be in path.repo)")
+	elasticsearchCmd.Flags().StringSliceVar(&esIndices, "indices", nil, "Indices, aliases or patterns to snapshot (default: all)")
+	elasticsearchCmd.Flags().BoolVar(&esGlobalState, "global-state", false, "Include the cluster state (templates, persistent settings, pipelines)")
+
+	addConnFlags(elasticsearchCmd)
+	addSSHFlags(elasticsearchCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Variables are declared in backup.go
+
+var etcdCmd = &cobra.Command{
+	Use:   "etcd",
+	Short: "Backup an etcd cluster",
+	Long: `Save a snapshot of an etcd cluster's keyspace with etcdctl snapshot save, taken from the
+member at --host. The snapshot's checksum is verified and etcdutl snapshot status reads its
+revision and key count before it is kept.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		// Named before the tunnel replaces host and port
+		name := serverName
+		if name == "" {
+			name = db.EtcdBackupName(host, port, connOptions())
+		}
+		closeTunnel, err := openTunnel("etcd")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		opts := backupOptions()
+		upload := recordArtifact(&opts)
+		err = db.BackupEtcdWithOptions(host, port, user, password, name, out, opts)
+		if err != nil {
+			fmt.Println("Backup failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Backup complete")
+
+		// Handle cloud upload if requested
+		if uploadCloud {
+			if err := upload(); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(etcdCmd)
+
+	etcdCmd.Flags().StringVar(&host, "host", "localhost", "etcd member host")
+	etcdCmd.Flags().StringVar(&port, "port", "2379", "etcd client port")
+	etcdCmd.Flags().StringVar(&user, "user", "", "etcd user (when authentication is enabled)")
+	etcdCmd.Flags().StringVar(&password, "password", "", "etcd password")
+	etcdCmd.Flags().StringVar(&serverName, "name", "", "Backup file name prefix (default: etcd_<host>_<port>)")
+	etcdCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+
+	// Cloud upload flags
+	etcdCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
+	etcdCmd.Flags().StringVar(&cloudProvider, "cloud", "s3", "Cloud provider: s3, gcs, or azure")
+	etcdCmd.Flags().StringVar(&s3Bucket, "s3-bucket", "", "S3 bucket name (or set DBX_S3_BUCKET env var)")
+	etcdCmd.Flags().StringVar(&s3Prefix, "s3-prefix", "dbx/", "S3 prefix/folder path")
+	etcdCmd.Flags().StringVar(&gcsBucket, "gcs-bucket", "", "GCS bucket name")
+	etcdCmd.Flags().StringVar(&gcsPrefix, "gcs-prefix", "dbx/", "GCS prefix/folder path")
+	etcdCmd.Flags().StringVar(&azureAccount, "azure-account", "", "Azure storage account name")
+	etcdCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
+	etcdCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
+
+	addConnFlags(etcdCmd)
+	addSSHFlags(etcdCmd)
+}
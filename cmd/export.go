@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"dbx/internal/export"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Export flags; the connection and table filter variables are declared in backup.go
+var (
+	exportFileFormat string
+	exportColumns    []string
+	exportWhere      string
+	exportCompress   bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export tables as CSV, JSON Lines or Parquet",
+	Long: `Read tables (or MongoDB collections) from a live database, or a dbx backup restored to a
+scratch database, and write one CSV, JSON Lines or Parquet file per table into
+<database>-export_<timestamp> under --out. --columns and --where select the columns and rows;
+--compress (implied by --upload) bundles the files into a zip.`,
+}
+
+// runExport runs an export and uploads the result when --upload is set
+func runExport(engine string, run func() (string, error)) error {
+	if err := resolveCredentials(); err != nil {
+		return err
+	}
+	closeTunnel, err := openTunnel(engine)
+	if err != nil {
+		return err
+	}
+	defer closeTunnel()
+
+	artifact, err := run()
+	if err != nil {
+		fmt.Println("Export failed:", err)
+		os.Exit(1)
+	}
+
+	if uploadCloud {
+		if err := uploadToCloud(artifact); err != nil {
+			fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// exportOptions collects the export flags into db.ExportOptions
+func exportOptions(tables, exclude []string) db.ExportOptions {
+	return db.ExportOptions{
+		Format:        exportFileFormat,
+		Tables:        tables,
+		ExcludeTables: exclude,
+		Columns:       exportColumns,
+		Where:         exportWhere,
+		Compress:      exportCompress || uploadCloud,
+		Conn:          connOptions(),
+	}
+}
+
+var exportMySQLCmd = &cobra.Command{
+	Use:   "mysql",
+	Short: "Export tables of a MySQL database",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport("mysql", func() (string, error) {
+			return db.ExportMySQL(host, user, password, database, out, exportOptions(includeTables, excludeTables))
+		})
+	},
+}
+
+var exportMariaDBCmd = &cobra.Command{
+	Use:   "mariadb",
+	Short: "Export tables of a MariaDB database",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport("mariadb", func() (string, error) {
+			opts := exportOptions(includeTables, excludeTables)
+			opts.Conn.MariaDB = true
+			return db.ExportMySQL(host, user, password, database, out, opts)
+		})
+	},
+}
+
+var exportPostgresCmd = &cobra.Command{
+	Use:   "postgres",
+	Short: "Export tables of a PostgreSQL database",
+	Long: `Export tables of a PostgreSQL database. Tables outside the public schema are named
+schema.table, in --tables and in the output file names.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport("postgres", func() (string, error) {
+			return db.ExportPostgres(host, port, user, password, database, out, exportOptions(includeTables, excludeTables))
+		})
+	},
+}
+
+var exportMongoCmd = &cobra.Command{
+	Use:   "mongo",
+	Short: "Export collections of a MongoDB database",
+	Long: `Export collections of a MongoDB database. --where takes a query document in extended JSON,
+e.g. '{"status": "active"}', and --columns field paths such as address.city. Without --columns
+the fields are those of the first 1000 matching documents; nested documents and arrays are
+written as JSON.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport("mongodb", func() (string, error) {
+			return db.ExportMongo(uri, database, out, exportOptions(includeCollections, excludeCollections))
+		})
+	},
+}
+
+var exportSQLiteCmd = &cobra.Command{
+	Use:   "sqlite",
+	Short: "Export tables of a SQLite database",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport("sqlite", func() (string, error) {
+			return db.ExportSQLite(sqlitePath, out, exportOptions(includeTables, excludeTables))
+		})
+	},
+}
+
+var exportClickHouseCmd = &cobra.Command{
+	Use:   "clickhouse",
+	Short: "Export tables of a ClickHouse database",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport("clickhouse", func() (string, error) {
+			return db.ExportClickHouse(host, port, user, password, database, out, exportOptions(includeTables, excludeTables))
+		})
+	},
+}
+
+// addExportFlags registers the format, selection, output and cloud upload flags on an export command
+func addExportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&exportFileFormat, "format", "csv", "Output format: "+strings.Join(export.Formats, ", "))
+	cmd.Flags().StringSliceVar(&exportColumns, "columns", nil, "Export only these columns, in this order")
+	cmd.Flags().StringVar(&exportWhere, "where", "", "Export only rows matching this SQL condition (MongoDB: a JSON query document)")
+	cmd.Flags().StringVar(&out, "out", "./exports", "Output directory")
+	cmd.Flags().BoolVar(&exportCompress, "compress", false, "Bundle the exported files into a zip")
+
+	// Cloud upload flags
+	cmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload the export (zipped) to cloud storage")
+	cmd.Flags().StringVar(&cloudProvider, "cloud", "s3", "Cloud provider: s3, gcs, or azure")
+	cmd.Flags().StringVar(&s3Bucket, "s3-bucket", "", "S3 bucket name (or set DBX_S3_BUCKET env var)")
+	cmd.Flags().StringVar(&s3Prefix, "s3-prefix", "dbx/", "S3 prefix/folder path")
+	cmd.Flags().StringVar(&gcsBucket, "gcs-bucket", "", "GCS bucket name")
+	cmd.Flags().StringVar(&gcsPrefix, "gcs-prefix", "dbx/", "GCS prefix/folder path")
+	cmd.Flags().StringVar(&azureAccount, "azure-account", "", "Azure storage account name")
+	cmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
+	cmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportMySQLCmd, exportMariaDBCmd, exportPostgresCmd, exportMongoCmd, exportSQLiteCmd, exportClickHouseCmd)
+
+	exportMySQLCmd.Flags().StringVar(&host, "host", "localhost", "MySQL host")
+	exportMySQLCmd.Flags().StringVar(&port, "port", "", "MySQL port (default 3306)")
+	exportMySQLCmd.Flags().StringVar(&user, "user", "root", "MySQL user")
+	exportMySQLCmd.Flags().StringVar(&password, "password", "", "MySQL password")
+	exportMySQLCmd.Flags().StringVar(&database, "database", "", "Database name")
+	exportMySQLCmd.MarkFlagRequired("database")
+
+	exportMariaDBCmd.Flags().StringVar(&host, "host", "localhost", "MariaDB host")
+	exportMariaDBCmd.Flags().StringVar(&port, "port", "", "MariaDB port (default 3306)")
+	exportMariaDBCmd.Flags().StringVar(&user, "user", "root", "MariaDB user")
+	exportMariaDBCmd.Flags().StringVar(&password, "password", "", "MariaDB password")
+	exportMariaDBCmd.Flags().StringVar(&database, "database", "", "Database name")
+	exportMariaDBCmd.MarkFlagRequired("database")
+
+	exportPostgresCmd.Flags().StringVar(&host, "host", "localhost", "PostgreSQL host")
+	exportPostgresCmd.Flags().StringVar(&port, "port", "5432", "PostgreSQL port")
+	exportPostgresCmd.Flags().StringVar(&user, "user", "postgres", "PostgreSQL user")
+	exportPostgresCmd.Flags().StringVar(&password, "password", "", "PostgreSQL password")
+	exportPostgresCmd.Flags().StringVar(&database, "database", "", "Database name")
+	exportPostgresCmd.MarkFlagRequired("database")
+
+	exportMongoCmd.Flags().StringVar(&uri, "uri", "mongodb://localhost:27017", "MongoDB URI")
+	exportMongoCmd.Flags().StringVar(&port, "port", "", "MongoDB port, replacing the one in --uri")
+	exportMongoCmd.Flags().StringVar(&database, "database", "", "Database name")
+	exportMongoCmd.MarkFlagRequired("database")
+
+	exportSQLiteCmd.Flags().StringVar(&sqlitePath, "path", "", "Path to SQLite database file")
+	exportSQLiteCmd.MarkFlagRequired("path")
+
+	exportClickHouseCmd.Flags().StringVar(&host, "host", "localhost", "ClickHouse host")
+	exportClickHouseCmd.Flags().StringVar(&port, "port", "", "ClickHouse native protocol port (default 9000, 9440 with TLS)")
+	exportClickHouseCmd.Flags().StringVar(&user, "user", "default", "ClickHouse user")
+	exportClickHouseCmd.Flags().StringVar(&password, "password", "", "ClickHouse password")
+	exportClickHouseCmd.Flags().StringVar(&database, "database", "", "Database name")
+	exportClickHouseCmd.MarkFlagRequired("database")
+
+	for _, cmd := range []*cobra.Command{exportMySQLCmd, exportMariaDBCmd, exportPostgresCmd, exportClickHouseCmd, exportSQLiteCmd} {
+		addTableFilterFlags(cmd)
+	}
+	addCollectionFilterFlags(exportMongoCmd)
+	for _, cmd := range exportCmd.Commands() {
+		addExportFlags(cmd)
+		if cmd != exportSQLiteCmd {
+			addConnFlags(cmd)
+			addSSHFlags(cmd)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Variables are declared in backup.go
+
+var mariadbCmd = &cobra.Command{
+	Use:   "mariadb",
+	Short: "Backup a MariaDB database",
+	Long: `Back up a MariaDB database with mariadb-dump (falling back to mysqldump), taking the
+same options as dbx backup mysql. --method physical instead copies the whole server's data
+files with mariabackup while it runs, and prepares the copy so it can be restored directly;
+mariabackup reads the data directory, so dbx must run on the database host. --physical is a
+deprecated alias for --method physical.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		physical, err := physicalMethod()
+		if err != nil {
+			return err
+		}
+		if physical && sshHost != "" {
+			return fmt.Errorf("--method physical can't run through --ssh-host: mariabackup must run on the database host")
+		}
+		closeTunnel, err := openTunnel("mariadb")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		bt := db.BackupTypeFull
+		if backupType == "incremental" {
+			bt = db.BackupTypeIncremental
+		} else if backupType == "differential" {
+			bt = db.BackupTypeDifferential
+		}
+		opts := backupOptions()
+		opts.Physical = physical
+		upload := recordArtifact(&opts)
+
+		if multiDatabaseRequested() {
+			if physical {
+				return fmt.Errorf("--method physical always copies every database (drop --all-databases / --include)")
+			}
+			err = backupAllDatabases("mariadb", "MariaDB", opts,
+				func() ([]string, error) {
+					conn := connOptions()
+					conn.MariaDB = true
+					return db.ListMySQLDatabases(host, user, password, conn)
+				},
+				func(name string, opts db.BackupOptions) error {
+					return db.BackupMariaDBWithOptions(host, user, password, name, out, bt, opts)
+				})
+			if err != nil {
+				fmt.Println("Backup failed:", err)
+				os.Exit(1)
+			}
+			return nil
+		}
+		if !physical && database == "" {
+			return fmt.Errorf("--database is required (or use --all-databases / --include, or --method physical)")
+		}
+
+		err = db.BackupMariaDBWithOptions(host, user, password, database, out, bt, opts)
+		if err != nil {
+			fmt.Println("Backup failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Backup complete")
+
+		// Handle cloud upload if requested
+		if uploadCloud {
+			if err := upload(); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(mariadbCmd)
+
+	mariadbCmd.Flags().StringVar(&host, "host", "localhost", "MariaDB host")
+	mariadbCmd.Flags().StringVar(&port, "port", "", "MariaDB port (default: the client's, 3306)")
+	mariadbCmd.Flags().StringVar(&user, "user", "root", "MariaDB user")
+	mariadbCmd.Flags().StringVar(&password, "password", "", "MariaDB password")
+	mariadbCmd.Flags().StringVar(&database, "database", "", "MariaDB database name")
+	mariadbCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+	mariadbCmd.Flags().StringVar(&backupType, "type", "full", "Backup type: full, incremental, or differential")
+	mariadbCmd.Flags().StringVar(&backupMethod, "method", "logical", "Backup method: logical (mariadb-dump) or physical (mariabackup hot copy of the whole server, run on the database host)")
+	mariadbCmd.Flags().BoolVar(&mariadbPhysical, "physical", false, "Deprecated alias for --method physical")
+	mariadbCmd.Flags().MarkDeprecated("physical", "use --method physical")
+
+	// Cloud upload flags
+	mariadbCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
+	mariadbCmd.Flags().StringVar(&cloudProvider, "cloud", "s3", "Cloud provider: s3, gcs, or azure")
+	mariadbCmd.Flags().StringVar(&s3Bucket, "s3-bucket", "", "S3 bucket name (or set DBX_S3_BUCKET env var)")
+	mariadbCmd.Flags().StringVar(&s3Prefix, "s3-prefix", "dbx/", "S3 prefix/folder path")
+	mariadbCmd.Flags().StringVar(&gcsBucket, "gcs-bucket", "", "GCS bucket name")
+	mariadbCmd.Flags().StringVar(&gcsPrefix, "gcs-prefix", "dbx/", "GCS prefix/folder path")
+	mariadbCmd.Flags().StringVar(&azureAccount, "azure-account", "", "Azure storage account name")
+	mariadbCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
+	mariadbCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
+
+	addMultiDatabaseFlags(mariadbCmd)
+	addTableFilterFlags(mariadbCmd)
+	addContentFlag(mariadbCmd)
+	addMaskFlag(mariadbCmd)
+	addJobsFlag(mariadbCmd)
+	addDumperFlag(mariadbCmd)
+	addConnFlags(mariadbCmd)
+	addSSHFlags(mariadbCmd)
+}
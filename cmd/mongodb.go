@@ -1,12 +1,9 @@
 package cmd
 
 import (
-	"dbx/internal/cloud"
 	"dbx/internal/db"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -15,7 +12,32 @@ var mongodbCmd = &cobra.Command{
 	Use:   "mongo",
 	Short: "Backup a MongoDB database",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		err := db.BackupMongo(uri, database, out)
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("mongodb")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		if multiDatabaseRequested() {
+			err = backupAllDatabases("mongodb", "MongoDB", backupOptions(),
+				func() ([]string, error) { return db.ListMongoDatabases(uri, connOptions()) },
+				func(name string, opts db.BackupOptions) error { return db.BackupMongoWithOptions(uri, name, out, opts) })
+			if err != nil {
+				fmt.Println("Backup failed:", err)
+				os.Exit(1)
+			}
+			return nil
+		}
+		if database == "" {
+			return fmt.Errorf("--database is required (or use --all-databases / --include)")
+		}
+
+		opts := backupOptions()
+		upload := recordArtifact(&opts)
+		err = db.BackupMongoWithOptions(uri, database, out, opts)
 		if err != nil {
 			fmt.Println("Backup failed:", err)
 			os.Exit(1)
@@ -24,7 +46,7 @@ var mongodbCmd = &cobra.Command{
 
 		// Handle cloud upload if requested
 		if uploadCloud {
-			if err := handleCloudUpload(database, out, "mongodb"); err != nil {
+			if err := upload(); err != nil {
 				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
 			}
 		}
@@ -37,6 +59,7 @@ func init() {
 	backupCmd.AddCommand(mongodbCmd)
 
 	mongodbCmd.Flags().StringVar(&uri, "uri", "mongodb://localhost:27017", "MongoDB URI")
+	mongodbCmd.Flags().StringVar(&port, "port", "", "MongoDB port, replacing the one in --uri")
 	mongodbCmd.Flags().StringVar(&database, "database", "", "Database name")
 	mongodbCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
 	
@@ -51,5 +74,10 @@ func init() {
 	mongodbCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
 	mongodbCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
 
-	mongodbCmd.MarkFlagRequired("database")
+	addMultiDatabaseFlags(mongodbCmd)
+	addCollectionFilterFlags(mongodbCmd)
+	addJobsFlag(mongodbCmd)
+	addDumperFlag(mongodbCmd)
+	addConnFlags(mongodbCmd)
+	addSSHFlags(mongodbCmd)
 }
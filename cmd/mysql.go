@@ -1,12 +1,9 @@
 package cmd
 
 import (
-	"dbx/internal/cloud"
 	"dbx/internal/db"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -16,7 +13,27 @@ import (
 var mysqlCmd = &cobra.Command{
 	Use:   "mysql",
 	Short: "Backup a MySQL database",
+	Long: `Back up a MySQL database with mysqldump (falling back to mariadb-dump). --method physical
+instead copies the whole server's data files with Percona XtraBackup while it runs; --type
+incremental and differential then copy only the pages changed since the last backup or the last
+full backup in --out. xtrabackup reads the data directory, so dbx must run on the database host.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		physical, err := physicalMethod()
+		if err != nil {
+			return err
+		}
+		if physical && sshHost != "" {
+			return fmt.Errorf("--method physical can't run through --ssh-host: xtrabackup must run on the database host")
+		}
+		closeTunnel, err := openTunnel("mysql")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
 		bt := db.BackupTypeFull
 		if backupType == "incremental" {
 			bt = db.BackupTypeIncremental
@@ -24,7 +41,30 @@ var mysqlCmd = &cobra.Command{
 			bt = db.BackupTypeDifferential
 		}
 
-		err := db.BackupMySQLWithType(host, user, password, database, out, bt)
+		opts := backupOptions()
+		opts.Physical = physical
+		upload := recordArtifact(&opts)
+
+		if multiDatabaseRequested() {
+			if physical {
+				return fmt.Errorf("--method physical always copies every database (drop --all-databases / --include)")
+			}
+			err = backupAllDatabases("mysql", "MySQL", backupOptions(),
+				func() ([]string, error) { return db.ListMySQLDatabases(host, user, password, connOptions()) },
+				func(name string, opts db.BackupOptions) error {
+					return db.BackupMySQLWithOptions(host, user, password, name, out, bt, opts)
+				})
+			if err != nil {
+				fmt.Println("Backup failed:", err)
+				os.Exit(1)
+			}
+			return nil
+		}
+		if !physical && database == "" {
+			return fmt.Errorf("--database is required (or use --all-databases / --include, or --method physical)")
+		}
+
+		err = db.BackupMySQLWithOptions(host, user, password, database, out, bt, opts)
 		if err != nil {
 			fmt.Println("Backup failed:", err)
 			os.Exit(1)
@@ -33,7 +73,7 @@ var mysqlCmd = &cobra.Command{
 
 		// Handle cloud upload if requested
 		if uploadCloud {
-			if err := handleCloudUpload(database, out, "mysql"); err != nil {
+			if err := upload(); err != nil {
 				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
 				// Don't fail the backup if upload fails
 			}
@@ -47,11 +87,13 @@ func init() {
 	backupCmd.AddCommand(mysqlCmd)
 
 	mysqlCmd.Flags().StringVar(&host, "host", "localhost", "MySQL host")
+	mysqlCmd.Flags().StringVar(&port, "port", "", "MySQL port (default: the client's, 3306)")
 	mysqlCmd.Flags().StringVar(&user, "user", "root", "MySQL user")
 	mysqlCmd.Flags().StringVar(&password, "password", "", "MySQL password")
 	mysqlCmd.Flags().StringVar(&database, "database", "", "MySQL database name")
 	mysqlCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
 	mysqlCmd.Flags().StringVar(&backupType, "type", "full", "Backup type: full, incremental, or differential")
+	mysqlCmd.Flags().StringVar(&backupMethod, "method", "logical", "Backup method: logical (mysqldump) or physical (xtrabackup hot copy of the whole server, run on the database host)")
 	
 	// Cloud upload flags
 	mysqlCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
@@ -64,5 +106,12 @@ func init() {
 	mysqlCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
 	mysqlCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
 
-	mysqlCmd.MarkFlagRequired("database")
+	addMultiDatabaseFlags(mysqlCmd)
+	addTableFilterFlags(mysqlCmd)
+	addContentFlag(mysqlCmd)
+	addMaskFlag(mysqlCmd)
+	addJobsFlag(mysqlCmd)
+	addDumperFlag(mysqlCmd)
+	addConnFlags(mysqlCmd)
+	addSSHFlags(mysqlCmd)
 }
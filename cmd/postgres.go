@@ -1,12 +1,9 @@
 package cmd
 
 import (
-	"dbx/internal/cloud"
 	"dbx/internal/db"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -15,6 +12,15 @@ var postgresCmd = &cobra.Command{
 	Use:   "postgres",
 	Short: "Backup a PostgreSQL database",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("postgres")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
 		bt := db.BackupTypeFull
 		if backupType == "incremental" {
 			bt = db.BackupTypeIncremental
@@ -22,7 +28,25 @@ var postgresCmd = &cobra.Command{
 			bt = db.BackupTypeDifferential
 		}
 
-		err := db.BackupPostgresWithType(host, port, user, password, database, out, bt)
+		if multiDatabaseRequested() {
+			err = backupAllDatabases("postgres", "PostgreSQL", backupOptions(),
+				func() ([]string, error) { return db.ListPostgresDatabases(host, port, user, password, connOptions()) },
+				func(name string, opts db.BackupOptions) error {
+					return db.BackupPostgresWithOptions(host, port, user, password, name, out, bt, opts)
+				})
+			if err != nil {
+				fmt.Println("Backup failed:", err)
+				os.Exit(1)
+			}
+			return nil
+		}
+		if database == "" {
+			return fmt.Errorf("--database is required (or use --all-databases / --include)")
+		}
+
+		opts := backupOptions()
+		upload := recordArtifact(&opts)
+		err = db.BackupPostgresWithOptions(host, port, user, password, database, out, bt, opts)
 		if err != nil {
 			fmt.Println("Backup failed:", err)
 			os.Exit(1)
@@ -31,7 +55,7 @@ var postgresCmd = &cobra.Command{
 
 		// Handle cloud upload if requested
 		if uploadCloud {
-			if err := handleCloudUpload(database, out, "postgres"); err != nil {
+			if err := upload(); err != nil {
 				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
 			}
 		}
@@ -62,6 +86,13 @@ func init() {
 	postgresCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
 	postgresCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
 
-	postgresCmd.MarkFlagRequired("database")
+	addMultiDatabaseFlags(postgresCmd)
+	addTableFilterFlags(postgresCmd)
+	addContentFlag(postgresCmd)
+	addMaskFlag(postgresCmd)
+	addJobsFlag(postgresCmd)
+	addDumperFlag(postgresCmd)
+	addConnFlags(postgresCmd)
+	addSSHFlags(postgresCmd)
 }
 
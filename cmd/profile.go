@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"dbx/internal/config"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// profileName selects a named profile from the config file (--profile)
+var profileName string
+
+// profileFlags maps profile params to the flags they fill in
+var profileFlags = map[string]string{
+	"host":                "host",
+	"port":                "port",
+	"user":                "user",
+	"pass":                "password",
+	"dbname":              "database",
+	"uri":                 "uri",
+	"path":                "path",
+	"out":                 "out",
+	"all_databases":       "all-databases",
+	"include":             "include",
+	"exclude":             "exclude",
+	"content":             "content",
+	"mask_rules":          "mask-rules",
+	"jobs":                "jobs",
+	"dumper":              "dumper",
+	"format":              "format",
+	"bgsave":              "bgsave",
+	"method":              "method",
+	"repository":          "repository",
+	"repository_path":     "repository-path",
+	"indices":             "indices",
+	"global_state":        "global-state",
+	"type":                "type",
+	"socket":              "socket",
+	"tls_mode":            "tls-mode",
+	"tls_ca":              "tls-ca",
+	"tls_cert":            "tls-cert",
+	"tls_key":             "tls-key",
+	"ssh_host":            "ssh-host",
+	"ssh_user":            "ssh-user",
+	"ssh_key":             "ssh-key",
+	"ssh_known_hosts":     "ssh-known-hosts",
+	"tables":              "tables",
+	"exclude_tables":      "exclude-tables",
+	"collections":         "collections",
+	"exclude_collections": "exclude-collections",
+	"upload_cloud":        "upload",
+	"cloud_provider":      "cloud",
+	"s3_bucket":           "s3-bucket",
+	"s3_prefix":           "s3-prefix",
+	"gcs_bucket":          "gcs-bucket",
+	"gcs_prefix":          "gcs-prefix",
+	"azure_account":       "azure-account",
+	"azure_container":     "azure-container",
+}
+
+// commandEngines maps backup/restore subcommand names to profile engines
+var commandEngines = map[string]string{
+	"mysql":         "mysql",
+	"postgres":      "postgres",
+	"mongo":         "mongodb",
+	"sqlite":        "sqlite",
+	"redis":         "redis",
+	"mariadb":       "mariadb",
+	"etcd":          "etcd",
+	"consul":        "consul",
+	"elasticsearch": "elasticsearch",
+	"clickhouse":    "clickhouse",
+}
+
+// loadProfile loads a profile from the default config file
+func loadProfile(name string) (config.Profile, error) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return config.Profile{}, err
+	}
+	return cfg.Profile(name)
+}
+
+// applyProfile fills every flag of cmd not given on the command line from the selected profile
+func applyProfile(cmd *cobra.Command, args []string) error {
+	// Subcommands share flag variables, so reset unset flags to this command's own defaults first
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = slice.Replace(nil)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+	})
+	if profileName == "" {
+		return nil
+	}
+	p, err := loadProfile(profileName)
+	if err != nil {
+		return err
+	}
+	if engine, ok := commandEngines[cmd.Name()]; ok && engine != p.Engine {
+		return fmt.Errorf("profile %q is a %s profile, not %s", p.Name, p.Engine, engine)
+	}
+
+	for param, value := range p.Params() {
+		name, ok := profileFlags[param]
+		// A SQLite profile's backup format is not an export format
+		if !ok || name == "format" && cmd.Parent() == exportCmd {
+			continue
+		}
+		if flag := cmd.Flags().Lookup(name); flag != nil && !flag.Changed {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return fmt.Errorf("profile %q: invalid %s: %w", p.Name, param, err)
+			}
+		}
+	}
+	return nil
+}
+
+// changedParams returns the job params for flags explicitly given on the command line
+func changedParams(cmd *cobra.Command) map[string]string {
+	params := make(map[string]string)
+	for param, name := range profileFlags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed {
+			continue
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			params[param] = strings.Join(slice.GetSlice(), ",")
+		} else {
+			params[param] = flag.Value.String()
+		}
+	}
+	return params
+}
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Inspect connection profiles from the config file",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured profiles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return err
+		}
+		names := cfg.ProfileNames()
+		if len(names) == 0 {
+			fmt.Println("No profiles defined in", config.DefaultPath())
+			return nil
+		}
+		for _, name := range names {
+			p, err := cfg.Profile(name)
+			if err != nil {
+				fmt.Printf("%s - ⚠️  %v\n", name, err)
+				continue
+			}
+			target := p.Database
+			switch p.Engine {
+			case "sqlite":
+				target = p.Path
+			case "redis", "etcd", "consul", "elasticsearch":
+				target = p.Host
+			}
+			fmt.Printf("%s - %s %s", name, p.Engine, target)
+			if p.Destination != "" && p.Destination != "none" {
+				fmt.Printf(" → %s", p.Destination)
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+
+	backupCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Named profile from the config file ($DBX_CONFIG or ~/.config/dbx/config.yaml)")
+	restoreCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Named profile from the config file ($DBX_CONFIG or ~/.config/dbx/config.yaml)")
+	testCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Named profile from the config file ($DBX_CONFIG or ~/.config/dbx/config.yaml)")
+	exportCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Named profile from the config file ($DBX_CONFIG or ~/.config/dbx/config.yaml)")
+	backupCmd.PersistentPreRunE = applyProfile
+	restoreCmd.PersistentPreRunE = applyProfile
+	testCmd.PersistentPreRunE = applyProfile
+	exportCmd.PersistentPreRunE = applyProfile
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Variables are declared in backup.go
+
+var redisBGSave bool
+
+var redisCmd = &cobra.Command{
+	Use:   "redis",
+	Short: "Backup a Redis instance",
+	Long: `Save an RDB snapshot of a Redis instance. By default redis-cli --rdb streams the snapshot
+over the replication protocol, which works against remote servers. --bgsave instead has
+the server write a snapshot with BGSAVE and copies it from its data directory, which only
+works when dbx runs on the Redis host.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		// Named before the tunnel replaces host and port
+		name := serverName
+		if name == "" {
+			name = db.RedisBackupName(host, port, connOptions())
+		}
+		closeTunnel, err := openTunnel("redis")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+
+		opts := backupOptions()
+		opts.BGSave = redisBGSave
+		upload := recordArtifact(&opts)
+		err = db.BackupRedisWithOptions(host, port, user, password, name, out, opts)
+		if err != nil {
+			fmt.Println("Backup failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Backup complete")
+
+		// Handle cloud upload if requested
+		if uploadCloud {
+			if err := upload(); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(redisCmd)
+
+	redisCmd.Flags().StringVar(&host, "host", "localhost", "Redis host")
+	redisCmd.Flags().StringVar(&port, "port", "6379", "Redis port")
+	redisCmd.Flags().StringVar(&user, "user", "", "Redis ACL user (default: the default user)")
+	redisCmd.Flags().StringVar(&password, "password", "", "Redis password")
+	redisCmd.Flags().StringVar(&serverName, "name", "", "Backup file name prefix (default: redis_<host>_<port>)")
+	redisCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+	redisCmd.Flags().BoolVar(&redisBGSave, "bgsave", false, "Copy a BGSAVE snapshot from the server's data directory instead of streaming one (dbx must run on the Redis host)")
+
+	// Cloud upload flags
+	redisCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
+	redisCmd.Flags().StringVar(&cloudProvider, "cloud", "s3", "Cloud provider: s3, gcs, or azure")
+	redisCmd.Flags().StringVar(&s3Bucket, "s3-bucket", "", "S3 bucket name (or set DBX_S3_BUCKET env var)")
+	redisCmd.Flags().StringVar(&s3Prefix, "s3-prefix", "dbx/", "S3 prefix/folder path")
+	redisCmd.Flags().StringVar(&gcsBucket, "gcs-bucket", "", "GCS bucket name")
+	redisCmd.Flags().StringVar(&gcsPrefix, "gcs-prefix", "dbx/", "GCS prefix/folder path")
+	redisCmd.Flags().StringVar(&azureAccount, "azure-account", "", "Azure storage account name")
+	redisCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
+	redisCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
+
+	addConnFlags(redisCmd)
+	addSSHFlags(redisCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"dbx/internal/db"
+	"fmt"
 
 	"github.com/spf13/cobra"
 )
@@ -10,6 +11,12 @@ var (
 	restoreFile        string
 	restoreTable       string
 	restoreCollection  string
+	restoreListenHost  string
+	restoreDataDir     string
+	// etcd member settings
+	restoreMemberName, restoreInitialCluster, restorePeerURLs string
+	// Elasticsearch snapshot and index renaming
+	restoreSnapshot, restoreRenamePattern, restoreRenameReplacement string
 )
 
 var restoreCmd = &cobra.Command{
@@ -21,11 +28,60 @@ var restoreCmd = &cobra.Command{
 var restoreMySQLCmd = &cobra.Command{
 	Use:   "mysql",
 	Short: "Restore a MySQL database",
+	Long: `Restore a logical MySQL backup with the mysql client, or a physical backup made with
+--method physical. A physical backup is copied into --datadir with xtrabackup --copy-back after
+preparing it; an incremental or differential backup is prepared together with the full backup
+and increments it builds on, which must be in the same directory. Stop the server and empty the
+directory first, then give the files to the server's user (e.g. chown -R mysql:mysql) before
+starting it again.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if restoreDataDir != "" {
+			return db.RestoreMySQLPhysical(restoreFile, restoreDataDir)
+		}
+		if database == "" {
+			return fmt.Errorf("--database is required (or --datadir for a physical backup)")
+		}
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("mysql")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
 		if restoreTable != "" {
-			return db.RestoreMySQLTable(host, user, password, database, restoreFile, restoreTable)
+			return db.RestoreMySQLTableWithOptions(host, user, password, database, restoreFile, restoreTable, restoreOptions())
 		}
-		return db.RestoreMySQL(host, user, password, database, restoreFile)
+		return db.RestoreMySQLWithOptions(host, user, password, database, restoreFile, restoreOptions())
+	},
+}
+
+var restoreMariaDBCmd = &cobra.Command{
+	Use:   "mariadb",
+	Short: "Restore a MariaDB database",
+	Long: `Restore a logical MariaDB backup with the mariadb client (falling back to mysql), or a
+physical backup made with --method physical. A physical backup is copied into --datadir with
+mariabackup --copy-back: stop the server and empty the directory first, then give the
+files to the server's user (e.g. chown -R mysql:mysql) before starting it again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if restoreDataDir != "" {
+			return db.RestoreMariaDBPhysical(restoreFile, restoreDataDir)
+		}
+		if database == "" {
+			return fmt.Errorf("--database is required (or --datadir for a physical backup)")
+		}
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("mariadb")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+		if restoreTable != "" {
+			return db.RestoreMariaDBTableWithOptions(host, user, password, database, restoreFile, restoreTable, restoreOptions())
+		}
+		return db.RestoreMariaDBWithOptions(host, user, password, database, restoreFile, restoreOptions())
 	},
 }
 
@@ -33,10 +89,18 @@ var restorePostgresCmd = &cobra.Command{
 	Use:   "postgres",
 	Short: "Restore a PostgreSQL database",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("postgres")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
 		if restoreTable != "" {
-			return db.RestorePostgresTable(host, port, user, password, database, restoreFile, restoreTable)
+			return db.RestorePostgresTableWithOptions(host, port, user, password, database, restoreFile, restoreTable, restoreOptions())
 		}
-		return db.RestorePostgres(host, port, user, password, database, restoreFile)
+		return db.RestorePostgresWithOptions(host, port, user, password, database, restoreFile, restoreOptions())
 	},
 }
 
@@ -44,13 +108,26 @@ var restoreMongoCmd = &cobra.Command{
 	Use:   "mongo",
 	Short: "Restore a MongoDB database",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("mongodb")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
 		if restoreCollection != "" {
-			return db.RestoreMongoCollection(uri, database, restoreFile, restoreCollection)
+			return db.RestoreMongoCollectionWithOptions(uri, database, restoreFile, restoreCollection, restoreOptions())
 		}
-		return db.RestoreMongo(uri, database, restoreFile)
+		return db.RestoreMongoWithOptions(uri, database, restoreFile, restoreOptions())
 	},
 }
 
+// restoreOptions collects --jobs and the connection flags into db.RestoreOptions
+func restoreOptions() db.RestoreOptions {
+	return db.RestoreOptions{Jobs: parallelJobs, Conn: connOptions()}
+}
+
 var restoreSQLiteCmd = &cobra.Command{
 	Use:   "sqlite",
 	Short: "Restore a SQLite database",
@@ -60,20 +137,146 @@ var restoreSQLiteCmd = &cobra.Command{
 	},
 }
 
+var restoreRedisCmd = &cobra.Command{
+	Use:   "redis",
+	Short: "Restore a Redis instance",
+	Long: `Load an RDB snapshot into a running Redis instance, replacing all of its data. dbx
+briefly acts as a replication master: the server is made a replica of a listener on
+this host, loads the snapshot in a full resync and is promoted back to master.
+
+The server must be able to connect back to this host; use --listen-host when the
+detected address isn't reachable from it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		if sshHost != "" {
+			return fmt.Errorf("--ssh-host is not supported for Redis restores: the server connects back to this host to load the snapshot")
+		}
+		opts := restoreOptions()
+		opts.ListenHost = restoreListenHost
+		return db.RestoreRedisWithOptions(host, port, user, password, restoreFile, opts)
+	},
+}
+
+var restoreEtcdCmd = &cobra.Command{
+	Use:   "etcd",
+	Short: "Restore an etcd cluster",
+	Long: `Restore an etcd snapshot into a new data directory with etcdutl snapshot restore, after
+verifying the snapshot's checksum and status. etcd is then started on --datadir.
+
+To restore a cluster, stop every member, restore the same snapshot once per member with that
+member's --name, the full --initial-cluster and its --initial-advertise-peer-urls, and start
+the members on their new data directories.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return db.RestoreEtcd(restoreFile, db.RestoreOptions{
+			DataDir:        restoreDataDir,
+			MemberName:     restoreMemberName,
+			InitialCluster: restoreInitialCluster,
+			PeerURLs:       restorePeerURLs,
+		})
+	},
+}
+
+var restoreConsulCmd = &cobra.Command{
+	Use:   "consul",
+	Short: "Restore a Consul cluster",
+	Long: `Verify a Consul snapshot and upload it through the agent's snapshot API. The cluster's
+whole state (KV store, catalog, sessions, prepared queries and ACLs) is replaced with the
+snapshot's; with ACLs enabled --password is a management token (default: $CONSUL_HTTP_TOKEN).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("consul")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+		return db.RestoreConsulWithOptions(host, port, password, restoreFile, restoreOptions())
+	},
+}
+
+var restoreElasticsearchCmd = &cobra.Command{
+	Use:     "elasticsearch",
+	Aliases: []string{"opensearch"},
+	Short:   "Restore an Elasticsearch or OpenSearch snapshot",
+	Long: `Restore indices from a snapshot in the cluster's snapshot repository and wait until the
+restore has finished. An open index can't be restored over, so either close or delete it first
+or restore under new names with --rename-replacement (e.g. "restored_$1", with the default
+--rename-pattern "(.+)"). Without --snapshot the repository's snapshots are listed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("elasticsearch")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+		opts := elasticsearchOptions()
+		opts.RenamePattern = restoreRenamePattern
+		opts.RenameReplacement = restoreRenameReplacement
+		return db.RestoreElasticsearch(host, port, user, password, restoreSnapshot, opts)
+	},
+}
+
+var restoreClickHouseCmd = &cobra.Command{
+	Use:   "clickhouse",
+	Short: "Restore a ClickHouse database",
+	Long: `Restore a bundle written by dbx backup clickhouse: the database is created if it doesn't
+exist, tables are created and filled --jobs at a time, then views and dictionaries are created.
+The tables must not exist yet. Without --database the tables go back into the database they
+were backed up from.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveCredentials(); err != nil {
+			return err
+		}
+		closeTunnel, err := openTunnel("clickhouse")
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+		if restoreTable != "" {
+			return db.RestoreClickHouseTableWithOptions(host, port, user, password, database, restoreFile, restoreTable, restoreOptions())
+		}
+		return db.RestoreClickHouseWithOptions(host, port, user, password, database, restoreFile, restoreOptions())
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(restoreCmd)
-	restoreCmd.AddCommand(restoreMySQLCmd, restorePostgresCmd, restoreMongoCmd, restoreSQLiteCmd)
+	restoreCmd.AddCommand(restoreMySQLCmd, restorePostgresCmd, restoreMongoCmd, restoreSQLiteCmd, restoreRedisCmd, restoreMariaDBCmd,
+		restoreEtcdCmd, restoreConsulCmd, restoreElasticsearchCmd, restoreClickHouseCmd)
 
 	// MySQL restore flags
 	restoreMySQLCmd.Flags().StringVar(&host, "host", "localhost", "MySQL host")
+	restoreMySQLCmd.Flags().StringVar(&port, "port", "", "MySQL port (default: the client's, 3306)")
 	restoreMySQLCmd.Flags().StringVar(&user, "user", "root", "MySQL user")
 	restoreMySQLCmd.Flags().StringVar(&password, "password", "", "MySQL password")
-	restoreMySQLCmd.Flags().StringVar(&database, "database", "", "Database name")
-	restoreMySQLCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file")
+	restoreMySQLCmd.Flags().StringVar(&database, "database", "", "Database name (logical backups)")
+	restoreMySQLCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file (.sql, .zip bundle from a parallel backup, or physical backup .zip/directory)")
 	restoreMySQLCmd.Flags().StringVar(&restoreTable, "table", "", "Restore specific table only (optional)")
-	restoreMySQLCmd.MarkFlagRequired("database")
+	restoreMySQLCmd.Flags().StringVar(&restoreDataDir, "datadir", "", "Data directory of the stopped server to copy a physical backup into")
+	addJobsFlag(restoreMySQLCmd)
+	addConnFlags(restoreMySQLCmd)
+	addSSHFlags(restoreMySQLCmd)
 	restoreMySQLCmd.MarkFlagRequired("file")
 
+	// MariaDB restore flags
+	restoreMariaDBCmd.Flags().StringVar(&host, "host", "localhost", "MariaDB host")
+	restoreMariaDBCmd.Flags().StringVar(&port, "port", "", "MariaDB port (default: the client's, 3306)")
+	restoreMariaDBCmd.Flags().StringVar(&user, "user", "root", "MariaDB user")
+	restoreMariaDBCmd.Flags().StringVar(&password, "password", "", "MariaDB password")
+	restoreMariaDBCmd.Flags().StringVar(&database, "database", "", "Database name (logical backups)")
+	restoreMariaDBCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file (.sql, bundle .zip, or physical backup .zip/directory)")
+	restoreMariaDBCmd.Flags().StringVar(&restoreTable, "table", "", "Restore specific table only (optional)")
+	restoreMariaDBCmd.Flags().StringVar(&restoreDataDir, "datadir", "", "Data directory of the stopped server to copy a physical backup into")
+	addJobsFlag(restoreMariaDBCmd)
+	addConnFlags(restoreMariaDBCmd)
+	addSSHFlags(restoreMariaDBCmd)
+	restoreMariaDBCmd.MarkFlagRequired("file")
+
 	// PostgreSQL restore flags
 	restorePostgresCmd.Flags().StringVar(&host, "host", "localhost", "PostgreSQL host")
 	restorePostgresCmd.Flags().StringVar(&port, "port", "5432", "PostgreSQL port")
@@ -82,14 +285,21 @@ func init() {
 	restorePostgresCmd.Flags().StringVar(&database, "database", "", "Database name")
 	restorePostgresCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file")
 	restorePostgresCmd.Flags().StringVar(&restoreTable, "table", "", "Restore specific table only (optional)")
+	addJobsFlag(restorePostgresCmd)
+	addConnFlags(restorePostgresCmd)
+	addSSHFlags(restorePostgresCmd)
 	restorePostgresCmd.MarkFlagRequired("database")
 	restorePostgresCmd.MarkFlagRequired("file")
 
 	// MongoDB restore flags
 	restoreMongoCmd.Flags().StringVar(&uri, "uri", "mongodb://localhost:27017", "MongoDB URI")
+	restoreMongoCmd.Flags().StringVar(&port, "port", "", "MongoDB port, replacing the one in --uri")
 	restoreMongoCmd.Flags().StringVar(&database, "database", "", "Database name")
 	restoreMongoCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup directory")
 	restoreMongoCmd.Flags().StringVar(&restoreCollection, "collection", "", "Restore specific collection only (optional)")
+	addJobsFlag(restoreMongoCmd)
+	addConnFlags(restoreMongoCmd)
+	addSSHFlags(restoreMongoCmd)
 	restoreMongoCmd.MarkFlagRequired("database")
 	restoreMongoCmd.MarkFlagRequired("file")
 
@@ -97,5 +307,59 @@ func init() {
 	restoreSQLiteCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file")
 	restoreSQLiteCmd.Flags().String("target", "", "Target database path (optional, defaults to restored_<backup_name>)")
 	restoreSQLiteCmd.MarkFlagRequired("file")
-}
 
+	// Redis restore flags
+	restoreRedisCmd.Flags().StringVar(&host, "host", "localhost", "Redis host")
+	restoreRedisCmd.Flags().StringVar(&port, "port", "6379", "Redis port")
+	restoreRedisCmd.Flags().StringVar(&user, "user", "", "Redis ACL user (default: the default user)")
+	restoreRedisCmd.Flags().StringVar(&password, "password", "", "Redis password")
+	restoreRedisCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file (.rdb or .rdb.zip)")
+	restoreRedisCmd.Flags().StringVar(&restoreListenHost, "listen-host", "", "Address of this host the Redis server connects back to (default: detected)")
+	addConnFlags(restoreRedisCmd)
+	restoreRedisCmd.MarkFlagRequired("file")
+
+	// etcd restore flags
+	restoreEtcdCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file (.db or .db.zip)")
+	restoreEtcdCmd.Flags().StringVar(&restoreDataDir, "datadir", "", "New data directory for the restored member (must not exist or be empty)")
+	restoreEtcdCmd.Flags().StringVar(&restoreMemberName, "name", "", "Name of the restored member (default: etcd's, default)")
+	restoreEtcdCmd.Flags().StringVar(&restoreInitialCluster, "initial-cluster", "", "Every member of the restored cluster as name=peer-url,... (default: a single member)")
+	restoreEtcdCmd.Flags().StringVar(&restorePeerURLs, "initial-advertise-peer-urls", "", "Peer URLs of the restored member")
+	restoreEtcdCmd.MarkFlagRequired("file")
+	restoreEtcdCmd.MarkFlagRequired("datadir")
+
+	// Consul restore flags
+	restoreConsulCmd.Flags().StringVar(&host, "host", "localhost", "Consul agent host")
+	restoreConsulCmd.Flags().StringVar(&port, "port", "8500", "Consul HTTP API port")
+	restoreConsulCmd.Flags().StringVar(&password, "password", "", "Consul ACL token (or set CONSUL_HTTP_TOKEN)")
+	restoreConsulCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file (.snap)")
+	addConnFlags(restoreConsulCmd)
+	addSSHFlags(restoreConsulCmd)
+	restoreConsulCmd.MarkFlagRequired("file")
+
+	// Elasticsearch restore flags
+	restoreElasticsearchCmd.Flags().StringVar(&host, "host", "localhost", "Elasticsearch host")
+	restoreElasticsearchCmd.Flags().StringVar(&port, "port", "9200", "Elasticsearch HTTP port")
+	restoreElasticsearchCmd.Flags().StringVar(&user, "user", "", "Elasticsearch user (basic authentication)")
+	restoreElasticsearchCmd.Flags().StringVar(&password, "password", "", "Elasticsearch password, or an API key without --user")
+	restoreElasticsearchCmd.Flags().StringVar(&esRepository, "repository", "dbx", "Snapshot repository")
+	restoreElasticsearchCmd.Flags().StringVar(&restoreSnapshot, "snapshot", "", "Snapshot to restore (omit to list the repository's snapshots)")
+	restoreElasticsearchCmd.Flags().StringSliceVar(&esIndices, "indices", nil, "Indices or patterns to restore (default: all in the snapshot)")
+	restoreElasticsearchCmd.Flags().BoolVar(&esGlobalState, "global-state", false, "Also restore the cluster state saved in the snapshot")
+	restoreElasticsearchCmd.Flags().StringVar(&restoreRenamePattern, "rename-pattern", "", "Regular expression matching index names to rename (default with --rename-replacement: (.+))")
+	restoreElasticsearchCmd.Flags().StringVar(&restoreRenameReplacement, "rename-replacement", "", "New index names, e.g. restored_$1")
+	addConnFlags(restoreElasticsearchCmd)
+	addSSHFlags(restoreElasticsearchCmd)
+
+	// ClickHouse restore flags
+	restoreClickHouseCmd.Flags().StringVar(&host, "host", "localhost", "ClickHouse host")
+	restoreClickHouseCmd.Flags().StringVar(&port, "port", "", "ClickHouse native protocol port (default 9000, 9440 with TLS)")
+	restoreClickHouseCmd.Flags().StringVar(&user, "user", "default", "ClickHouse user")
+	restoreClickHouseCmd.Flags().StringVar(&password, "password", "", "ClickHouse password")
+	restoreClickHouseCmd.Flags().StringVar(&database, "database", "", "Database to restore into (default: the one backed up)")
+	restoreClickHouseCmd.Flags().StringVar(&restoreFile, "file", "", "Path to backup file (.zip bundle)")
+	restoreClickHouseCmd.Flags().StringVar(&restoreTable, "table", "", "Restore specific table only (optional)")
+	addJobsFlag(restoreClickHouseCmd)
+	addConnFlags(restoreClickHouseCmd)
+	addSSHFlags(restoreClickHouseCmd)
+	restoreClickHouseCmd.MarkFlagRequired("file")
+}
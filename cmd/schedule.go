@@ -4,12 +4,31 @@ import (
 	"dbx/internal/scheduler"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/cobra"
 )
 
 var (
-	scheduleCron string
+	scheduleCron      string
+	scheduleTimezone  string
+	scheduleJitter    string
+	scheduleBlackouts []string
+	scheduleCatchUp   bool
+	scheduleCatchUpIn string
+
+	exportFormat    string
+	exportOutDir    string
+	exportBinary    string
+	exportWorkDir   string
+	exportImage     string
+	exportNamespace string
+	exportSecret    string
 )
 
 var scheduleCmd = &cobra.Command{
@@ -22,15 +41,32 @@ var scheduleAddCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new scheduled backup",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if profileName != "" {
+			return addProfileSchedule(cmd)
+		}
+		if dbType == "" {
+			return fmt.Errorf("--db or --profile is required")
+		}
+
 		params := make(map[string]string)
 		
 		switch dbType {
-		case "mysql":
+		case "mysql", "mariadb":
 			params["host"] = host
 			params["user"] = user
 			params["pass"] = password
 			params["dbname"] = database
 			params["out"] = out
+			if mariadbPhysical && dbType != "mariadb" {
+				return fmt.Errorf("--physical is a MariaDB alias; use --method physical")
+			}
+			physical, err := physicalMethod()
+			if err != nil {
+				return err
+			}
+			if physical {
+				params["method"] = "physical"
+			}
 		case "postgres":
 			params["host"] = host
 			params["port"] = port
@@ -38,6 +74,12 @@ var scheduleAddCmd = &cobra.Command{
 			params["pass"] = password
 			params["dbname"] = database
 			params["out"] = out
+		case "clickhouse":
+			params["host"] = host
+			params["user"] = user
+			params["pass"] = password
+			params["dbname"] = database
+			params["out"] = out
 		case "mongodb":
 			params["uri"] = uri
 			params["dbname"] = database
@@ -45,10 +87,129 @@ var scheduleAddCmd = &cobra.Command{
 		case "sqlite":
 			params["path"] = sqlitePath
 			params["out"] = out
+		case "redis", "etcd", "consul":
+			params["host"] = host
+			params["pass"] = password
+			if dbType != "consul" {
+				// Consul authenticates with the ACL token alone
+				params["user"] = user
+			}
+			params["out"] = out
+			if serverName != "" {
+				params["name"] = serverName
+			}
+			if redisBGSave {
+				if dbType != "redis" {
+					return fmt.Errorf("--bgsave is supported for redis only")
+				}
+				params["bgsave"] = "true"
+			}
+		case "elasticsearch":
+			params["host"] = host
+			params["user"] = user
+			params["pass"] = password
+			if serverName != "" {
+				params["name"] = serverName
+			}
+			params["repository"] = esRepository
+			if esRepositoryPath != "" {
+				params["repository_path"] = esRepositoryPath
+			}
+			if len(esIndices) > 0 {
+				params["indices"] = strings.Join(esIndices, ",")
+			}
+			if esGlobalState {
+				params["global_state"] = "true"
+			}
+			if uploadCloud {
+				return fmt.Errorf("--upload is not supported for elasticsearch; snapshots stay in the cluster's repository")
+			}
 		default:
 			return fmt.Errorf("unsupported database type: %s", dbType)
 		}
 
+		if dbType == "clickhouse" && (allDatabases || len(includeDatabases) > 0) {
+			return fmt.Errorf("clickhouse does not support multi-database backups")
+		}
+		if !scheduler.WholeServer(dbType) {
+			if allDatabases {
+				params["all_databases"] = "true"
+			}
+			if len(includeDatabases) > 0 {
+				params["include"] = strings.Join(includeDatabases, ",")
+			}
+			if len(excludeDatabases) > 0 {
+				params["exclude"] = strings.Join(excludeDatabases, ",")
+			}
+		}
+		if err := backupOptions().Validate(); err != nil {
+			return err
+		}
+		if backupContent != "" && backupContent != "all" {
+			params["content"] = backupContent
+		}
+		if maskRulesFile != "" {
+			if dbType == "mongodb" || dbType == "clickhouse" || scheduler.WholeServer(dbType) && dbType != "sqlite" {
+				return fmt.Errorf("--mask-rules is supported for mysql, postgres and sqlite")
+			}
+			params["mask_rules"] = maskRulesFile
+		}
+		if backupType != "full" {
+			switch {
+			case dbType != "mysql" && dbType != "mariadb" && dbType != "postgres":
+				return fmt.Errorf("--type is supported for mysql, mariadb and postgres")
+			case backupType != "incremental" && backupType != "differential":
+				return fmt.Errorf("invalid backup type %q (use full, incremental or differential)", backupType)
+			}
+			params["type"] = backupType
+		}
+		if parallelJobs > 0 && !scheduler.WholeServer(dbType) {
+			params["jobs"] = strconv.Itoa(parallelJobs)
+		}
+		if sqliteFormat != "" && sqliteFormat != "binary" {
+			if dbType != "sqlite" {
+				return fmt.Errorf("--format is supported for sqlite")
+			}
+			params["format"] = sqliteFormat
+		}
+		if backupDumper != "" && backupDumper != "auto" {
+			if dbType != "mysql" && dbType != "mariadb" && dbType != "postgres" && dbType != "mongodb" {
+				return fmt.Errorf("--dumper is supported for mysql, mariadb, postgres and mongodb")
+			}
+			params["dumper"] = backupDumper
+		}
+		if dbType != "sqlite" {
+			// PostgreSQL always stores its port above; the others only when one is given
+			if dbType != "postgres" && cmd.Flags().Changed("port") {
+				params["port"] = port
+			}
+			for param, value := range map[string]string{
+				"socket":          socket,
+				"tls_mode":        tlsMode,
+				"tls_ca":          tlsCA,
+				"tls_cert":        tlsCert,
+				"tls_key":         tlsKey,
+				"ssh_host":        sshHost,
+				"ssh_user":        sshUser,
+				"ssh_key":         sshKey,
+				"ssh_known_hosts": sshKnownHosts,
+			} {
+				if value != "" {
+					params[param] = value
+				}
+			}
+		}
+		for param, values := range map[string][]string{
+			"tables":              includeTables,
+			"exclude_tables":      excludeTables,
+			"collections":         includeCollections,
+			"exclude_collections": excludeCollections,
+		} {
+			if len(values) > 0 {
+				params[param] = strings.Join(values, ",")
+			}
+		}
+
 		// Add cloud upload parameters if requested
 		if uploadCloud {
 			params["upload_cloud"] = "true"
@@ -76,7 +237,7 @@ var scheduleAddCmd = &cobra.Command{
 			}
 		}
 
-		if err := scheduler.AddJob(dbType, scheduleCron, params); err != nil {
+		if err := scheduler.AddJobWithOptions(dbType, scheduleCron, params, scheduleOptions()); err != nil {
 			fmt.Println("Failed to schedule backup:", err)
 			os.Exit(1)
 		}
@@ -88,6 +249,38 @@ var scheduleAddCmd = &cobra.Command{
 	},
 }
 
+// scheduleOptions collects the timing flags of schedule add
+func scheduleOptions() scheduler.JobOptions {
+	return scheduler.JobOptions{
+		Timezone:      scheduleTimezone,
+		Jitter:        scheduleJitter,
+		Blackouts:     scheduleBlackouts,
+		CatchUp:       scheduleCatchUp,
+		CatchUpWindow: scheduleCatchUpIn,
+	}
+}
+
+// addProfileSchedule schedules a backup that reads its connection settings from a profile at run time.
+// Only flags given explicitly are stored, as overrides of the profile.
+func addProfileSchedule(cmd *cobra.Command) error {
+	p, err := loadProfile(profileName)
+	if err != nil {
+		return err
+	}
+	if dbType != "" && dbType != p.Engine {
+		return fmt.Errorf("profile %q is a %s profile, not %s", p.Name, p.Engine, dbType)
+	}
+
+	params := changedParams(cmd)
+	params["profile"] = p.Name
+	if err := scheduler.AddJobWithOptions(p.Engine, scheduleCron, params, scheduleOptions()); err != nil {
+		fmt.Println("Failed to schedule backup:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Backup of profile %s scheduled successfully\n", p.Name)
+	return nil
+}
+
 var scheduleListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all scheduled backups",
@@ -98,16 +291,145 @@ var scheduleListCmd = &cobra.Command{
 			return nil
 		}
 		
+		states := scheduler.JobStates()
 		fmt.Println("Scheduled Backups:")
-		for i, job := range jobs {
+		for _, job := range jobs {
 			dbName := job.Params["dbname"]
 			if dbName == "" {
 				dbName = job.Params["database"]
 			}
+			if dbName == "" && job.Params["all_databases"] == "true" {
+				dbName = "all databases"
+			}
+			if dbName == "" && job.Params["include"] != "" {
+				dbName = "databases " + job.Params["include"]
+			}
+			if dbName == "" && job.Params["profile"] != "" {
+				dbName = "profile " + job.Params["profile"]
+			}
 			if dbName == "" {
 				dbName = "N/A"
 			}
-			fmt.Printf("%d. %s - %s @ %s\n", i+1, job.DBType, dbName, job.Schedule)
+			fmt.Printf("#%d %s - %s @ %s", job.ID, job.DBType, dbName, job.Schedule)
+			if job.Timezone != "" {
+				fmt.Printf(" (%s)", job.Timezone)
+			}
+			if job.Jitter != "" {
+				fmt.Printf(" jitter %s", job.Jitter)
+			}
+			for _, b := range job.Blackouts {
+				fmt.Printf(" [blackout %s]", b)
+			}
+			if job.CatchUp {
+				fmt.Print(" catch-up")
+			}
+			if state, ok := states[job.ID]; ok {
+				fmt.Printf(" — last run %s %s", state.LastRun.Format("2006-01-02 15:04"), state.LastStatus)
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+var scheduleTriggerCmd = &cobra.Command{
+	Use:   "trigger <id>",
+	Short: "Run a scheduled backup immediately",
+	Long:  "Run a scheduled backup once with its stored params and upload settings. If a scheduler daemon is running, the run is handed to it instead of racing it.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid job id %q (see 'dbx schedule list')", args[0])
+		}
+
+		result, err := scheduler.Trigger(cron.EntryID(id))
+		if err != nil {
+			return err
+		}
+
+		if result.Status == "SUCCESS" {
+			fmt.Printf("✅ Job #%d completed in %s (run by %s)\n", result.JobID, result.Duration, result.ExecutedBy)
+			return nil
+		}
+		fmt.Printf("❌ Job #%d failed after %s (run by %s): %s\n", result.JobID, result.Duration, result.ExecutedBy, result.Error)
+		os.Exit(1)
+		return nil
+	},
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the scheduler daemon in the foreground",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			close(stop)
+		}()
+		return scheduler.RunDaemon(stop)
+	},
+}
+
+var scheduleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export schedules as systemd timers, a crontab, or Kubernetes CronJobs",
+	Long:  "Turn each scheduled backup into native scheduler definitions that invoke the matching 'dbx backup' command. Secrets stay as references (env:, file:, store:) and are never inlined.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobs := scheduler.ListJobs()
+		if len(jobs) == 0 {
+			return fmt.Errorf("no scheduled backups to export")
+		}
+
+		opts := scheduler.ExportOptions{
+			Binary:     exportBinary,
+			WorkDir:    exportWorkDir,
+			Image:      exportImage,
+			Namespace:  exportNamespace,
+			SecretName: exportSecret,
+		}
+		// Host schedulers run dbx outside this shell, so default to absolute paths
+		if exportFormat != scheduler.ExportK8sCronJob {
+			if opts.Binary == "" {
+				if exe, err := os.Executable(); err == nil {
+					opts.Binary = exe
+				}
+			}
+			if opts.WorkDir == "" {
+				if wd, err := os.Getwd(); err == nil {
+					opts.WorkDir = wd
+				}
+			}
+		}
+
+		files, err := scheduler.Export(jobs, exportFormat, opts)
+		if err != nil {
+			return err
+		}
+
+		if exportOutDir == "" {
+			for i, f := range files {
+				if exportFormat == scheduler.ExportK8sCronJob && i > 0 {
+					fmt.Println("---")
+				} else if exportFormat == scheduler.ExportSystemd {
+					fmt.Printf("# ===== %s =====\n", f.Name)
+				}
+				fmt.Print(f.Content)
+			}
+			return nil
+		}
+
+		if err := os.MkdirAll(exportOutDir, 0755); err != nil {
+			return err
+		}
+		for _, f := range files {
+			path := filepath.Join(exportOutDir, f.Name)
+			if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+				return err
+			}
+			fmt.Println("✅ Wrote", path)
 		}
 		return nil
 	},
@@ -115,18 +437,53 @@ var scheduleListCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(scheduleCmd)
-	scheduleCmd.AddCommand(scheduleAddCmd, scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd, scheduleListCmd, scheduleTriggerCmd, scheduleRunCmd, scheduleExportCmd)
 
-	scheduleAddCmd.Flags().StringVar(&dbType, "db", "", "Database type (mysql, postgres, mongodb, sqlite)")
+	scheduleExportCmd.Flags().StringVar(&exportFormat, "format", "", "Export format: systemd, crontab, or k8s-cronjob")
+	scheduleExportCmd.Flags().StringVar(&exportOutDir, "out", "", "Directory to write files to (default: print to stdout)")
+	scheduleExportCmd.Flags().StringVar(&exportBinary, "binary", "", "dbx executable to invoke (default: this executable, or 'dbx' for k8s-cronjob)")
+	scheduleExportCmd.Flags().StringVar(&exportWorkDir, "workdir", "", "Working directory for systemd/crontab (default: current directory)")
+	scheduleExportCmd.Flags().StringVar(&exportImage, "image", "dbx:latest", "Container image for k8s-cronjob")
+	scheduleExportCmd.Flags().StringVar(&exportNamespace, "namespace", "", "Kubernetes namespace for k8s-cronjob")
+	scheduleExportCmd.Flags().StringVar(&exportSecret, "k8s-secret", "dbx-secrets", "Kubernetes Secret providing env: references")
+	scheduleExportCmd.MarkFlagRequired("format")
+
+	scheduleAddCmd.Flags().StringVar(&dbType, "db", "", "Database type (mysql, mariadb, postgres, mongodb, sqlite, redis, etcd, consul, elasticsearch, clickhouse); optional with --profile")
+	scheduleAddCmd.Flags().StringVar(&profileName, "profile", "", "Named profile from the config file, read at run time")
 	scheduleAddCmd.Flags().StringVar(&host, "host", "localhost", "Database host")
-	scheduleAddCmd.Flags().StringVar(&port, "port", "5432", "Database port (PostgreSQL)")
+	scheduleAddCmd.Flags().StringVar(&port, "port", "5432", "Database port (default 5432 for PostgreSQL; the other engines use theirs unless set)")
 	scheduleAddCmd.Flags().StringVar(&user, "user", "", "Database user")
 	scheduleAddCmd.Flags().StringVar(&password, "password", "", "Database password")
 	scheduleAddCmd.Flags().StringVar(&database, "database", "", "Database name")
 	scheduleAddCmd.Flags().StringVar(&uri, "uri", "mongodb://localhost:27017", "MongoDB URI")
 	scheduleAddCmd.Flags().StringVar(&sqlitePath, "path", "", "SQLite database path")
 	scheduleAddCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+	scheduleAddCmd.Flags().StringVar(&serverName, "name", "", "Redis, etcd or Consul backup file or Elasticsearch snapshot name prefix (default: <engine>_<host>_<port>)")
+	scheduleAddCmd.Flags().StringVar(&backupMethod, "method", "logical", "MySQL/MariaDB: logical (dump) or physical (xtrabackup/mariabackup hot copy of the whole server)")
+	scheduleAddCmd.Flags().BoolVar(&mariadbPhysical, "physical", false, "Deprecated alias for --method physical (MariaDB)")
+	scheduleAddCmd.Flags().MarkDeprecated("physical", "use --method physical")
+	scheduleAddCmd.Flags().StringVar(&backupType, "type", "full", "Backup type for MySQL, MariaDB and PostgreSQL: full, incremental, or differential")
+	scheduleAddCmd.Flags().BoolVar(&redisBGSave, "bgsave", false, "Redis: copy a BGSAVE snapshot from the server's data directory instead of streaming one")
+	scheduleAddCmd.Flags().StringVar(&esRepository, "repository", "dbx", "Elasticsearch: snapshot repository")
+	scheduleAddCmd.Flags().StringVar(&esRepositoryPath, "repository-path", "", "Elasticsearch: register --repository as a filesystem repository at this path")
+	scheduleAddCmd.Flags().StringSliceVar(&esIndices, "indices", nil, "Elasticsearch: indices, aliases or patterns to snapshot (default: all)")
+	scheduleAddCmd.Flags().BoolVar(&esGlobalState, "global-state", false, "Elasticsearch: include the cluster state")
+	addMultiDatabaseFlags(scheduleAddCmd)
+	addTableFilterFlags(scheduleAddCmd)
+	addCollectionFilterFlags(scheduleAddCmd)
+	addContentFlag(scheduleAddCmd)
+	addMaskFlag(scheduleAddCmd)
+	addJobsFlag(scheduleAddCmd)
+	addDumperFlag(scheduleAddCmd)
+	scheduleAddCmd.Flags().StringVar(&sqliteFormat, "format", "binary", "SQLite: binary (copy of the database file) or sql (SQL text dump)")
+	addConnFlags(scheduleAddCmd)
+	addSSHFlags(scheduleAddCmd)
 	scheduleAddCmd.Flags().StringVar(&scheduleCron, "cron", "", "Cron schedule (e.g., '0 2 * * *' for daily at 2 AM)")
+	scheduleAddCmd.Flags().StringVar(&scheduleTimezone, "timezone", "", "IANA timezone for the schedule (e.g., Europe/Berlin); defaults to host local time")
+	scheduleAddCmd.Flags().StringVar(&scheduleJitter, "jitter", "", "Maximum random start delay (e.g., 10m)")
+	scheduleAddCmd.Flags().BoolVar(&scheduleCatchUp, "catch-up", false, "Run a missed backup once when the scheduler daemon starts after downtime")
+	scheduleAddCmd.Flags().StringVar(&scheduleCatchUpIn, "catch-up-window", "24h", "Only catch up runs missed within this window")
+	scheduleAddCmd.Flags().StringArrayVar(&scheduleBlackouts, "blackout", nil, "Window in which the job must not start, runs are deferred (e.g., 'Mon-Fri 09:00-18:00'); repeatable")
 	
 	// Cloud upload flags for scheduled backups
 	scheduleAddCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backups to cloud storage automatically")
@@ -139,6 +496,5 @@ func init() {
 	scheduleAddCmd.Flags().StringVar(&azureContainer, "azure-container", "", "Azure container name")
 	scheduleAddCmd.Flags().StringVar(&azureBlob, "azure-blob", "", "Azure blob name (optional)")
 
-	scheduleAddCmd.MarkFlagRequired("db")
 	scheduleAddCmd.MarkFlagRequired("cron")
-
+}
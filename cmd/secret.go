@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"dbx/internal/secrets"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage the local encrypted secret store",
+	Long: `Manage the local encrypted secret store (./config/secrets.enc).
+
+Secrets are referenced as store:<name> anywhere a password is accepted, next to
+env:<VAR> and file:<path> references. The store is encrypted with DBX_SECRET_KEY
+if set, otherwise with a random key kept in ./config/secret.key (mode 0600).`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Add or replace a secret (value is read from the terminal or stdin)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := readSecretValue()
+		if err != nil {
+			return err
+		}
+		if err := secrets.Set(args[0], value); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Secret stored. Reference it as %s%s\n", secrets.PrefixStore, args[0])
+		return nil
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List secret names",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := secrets.List()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Println("No secrets stored")
+			return nil
+		}
+		for _, name := range names {
+			fmt.Println(secrets.PrefixStore + name)
+		}
+		return nil
+	},
+}
+
+var secretRemoveCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Remove a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := secrets.Delete(args[0]); err != nil {
+			return err
+		}
+		fmt.Println("✅ Secret removed")
+		return nil
+	},
+}
+
+// readSecretValue reads a secret without echo when attached to a terminal, otherwise one line from stdin
+func readSecretValue() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Print("Secret value: ")
+		value, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return string(value), nil
+	}
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		return "", fmt.Errorf("failed to read secret from stdin: %w", err)
+	}
+	return strings.TrimRight(value, "\r\n"), nil
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretSetCmd, secretListCmd, secretRemoveCmd)
+}
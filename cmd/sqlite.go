@@ -1,12 +1,9 @@
 package cmd
 
 import (
-	"dbx/internal/cloud"
 	"dbx/internal/db"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -16,8 +13,14 @@ import (
 var sqliteCmd = &cobra.Command{
 	Use:   "sqlite",
 	Short: "Backup a SQLite database",
+	Long: `Back up a SQLite database as a copy of the database file, or with --format sql as a SQL text
+dump like sqlite3 .dump (tables with their rows, indexes, triggers and views), which is diffable
+and loads into any SQLite version. The dump is read from the file directly, so the sqlite3 CLI
+isn't needed; restoring it with dbx restore sqlite uses sqlite3.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		err := db.BackupSQLite(sqlitePath, out)
+		opts := backupOptions()
+		upload := recordArtifact(&opts)
+		err := db.BackupSQLiteWithOptions(sqlitePath, out, opts)
 		if err != nil {
 			fmt.Println("Backup failed:", err)
 			os.Exit(1)
@@ -26,8 +29,7 @@ var sqliteCmd = &cobra.Command{
 
 		// Handle cloud upload if requested
 		if uploadCloud {
-			dbName := filepath.Base(sqlitePath)
-			if err := handleCloudUpload(dbName, out, "sqlite"); err != nil {
+			if err := upload(); err != nil {
 				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
 			}
 		}
@@ -41,6 +43,9 @@ func init() {
 
 	sqliteCmd.Flags().StringVar(&sqlitePath, "path", "", "Path to SQLite database file")
 	sqliteCmd.Flags().StringVar(&out, "out", "./backups", "Output directory")
+	sqliteCmd.Flags().StringVar(&sqliteFormat, "format", "binary", "Backup format: binary (copy of the database file) or sql (SQL text dump, no sqlite3 needed)")
+	addContentFlag(sqliteCmd)
+	addMaskFlag(sqliteCmd)
 	
 	// Cloud upload flags
 	sqliteCmd.Flags().BoolVar(&uploadCloud, "upload", false, "Upload backup to cloud storage")
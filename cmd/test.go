@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"dbx/internal/db"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	testTimeout time.Duration
+	testJSON    bool
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test a database connection and the account's dump privileges",
+	Long: `Connect with the engine's Go driver and report the server version, latency,
+TLS status and the account's privileges. Privileges a dump needs but the account
+lacks (e.g. LOCK TABLES, REPLICATION CLIENT, pg_read_all_data) are reported as warnings.`,
+}
+
+// runConnectionTest runs the check for engine and prints the report
+func runConnectionTest(engine string) error {
+	if err := resolveCredentials(); err != nil {
+		return err
+	}
+	closeTunnel, err := openTunnel(engine)
+	if err != nil {
+		return err
+	}
+	defer closeTunnel()
+
+	params := map[string]string{
+		"host":     host,
+		"port":     port,
+		"user":     user,
+		"pass":     password,
+		"dbname":   database,
+		"uri":      uri,
+		"path":     sqlitePath,
+		"socket":   socket,
+		"tls_mode": tlsMode,
+		"tls_ca":   tlsCA,
+		"tls_cert": tlsCert,
+		"tls_key":  tlsKey,
+		"timeout":  testTimeout.String(),
+		// set by openTunnel, so verify-full checks the database's own host through --ssh-host
+		"tls_server_name": tlsServerName,
+	}
+	diag, err := db.DiagnoseConnection(engine, params)
+	if err != nil {
+		return err
+	}
+
+	if testJSON {
+		data, err := json.MarshalIndent(diag, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printDiagnosis(diag)
+	return nil
+}
+
+// printDiagnosis prints a connection check as a short report
+func printDiagnosis(d *db.Diagnosis) {
+	version := ""
+	if d.ServerVersion != "" {
+		version = " " + d.ServerVersion
+	}
+	fmt.Printf("✅ Connected to %s%s (latency %s)\n", d.Engine, version, d.Latency.Round(time.Microsecond))
+	if d.User != "" {
+		fmt.Println("   User:", d.User)
+	}
+	if d.TLS != "" {
+		fmt.Println("   TLS:", d.TLS)
+	}
+	if len(d.Privileges) > 0 {
+		fmt.Println("   Privileges:")
+		for _, p := range d.Privileges {
+			fmt.Println("     -", p)
+		}
+	}
+	if len(d.Missing) > 0 {
+		fmt.Println("⚠️  Missing for backups:", strings.Join(d.Missing, ", "))
+	}
+}
+
+var testMySQLCmd = &cobra.Command{
+	Use:   "mysql",
+	Short: "Test a MySQL connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("mysql")
+	},
+}
+
+var testPostgresCmd = &cobra.Command{
+	Use:   "postgres",
+	Short: "Test a PostgreSQL connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("postgres")
+	},
+}
+
+var testMongoCmd = &cobra.Command{
+	Use:   "mongo",
+	Short: "Test a MongoDB connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("mongodb")
+	},
+}
+
+var testSQLiteCmd = &cobra.Command{
+	Use:   "sqlite",
+	Short: "Check a SQLite database file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("sqlite")
+	},
+}
+
+var testMariaDBCmd = &cobra.Command{
+	Use:   "mariadb",
+	Short: "Test a MariaDB connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("mariadb")
+	},
+}
+
+var testRedisCmd = &cobra.Command{
+	Use:   "redis",
+	Short: "Test a Redis connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("redis")
+	},
+}
+
+var testEtcdCmd = &cobra.Command{
+	Use:   "etcd",
+	Short: "Test an etcd connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("etcd")
+	},
+}
+
+var testConsulCmd = &cobra.Command{
+	Use:   "consul",
+	Short: "Test a Consul connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("consul")
+	},
+}
+
+var testElasticsearchCmd = &cobra.Command{
+	Use:     "elasticsearch",
+	Aliases: []string{"opensearch"},
+	Short:   "Test an Elasticsearch or OpenSearch connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("elasticsearch")
+	},
+}
+
+var testClickHouseCmd = &cobra.Command{
+	Use:   "clickhouse",
+	Short: "Test a ClickHouse connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConnectionTest("clickhouse")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+	testCmd.AddCommand(testMySQLCmd, testPostgresCmd, testMongoCmd, testSQLiteCmd, testRedisCmd, testMariaDBCmd, testEtcdCmd, testConsulCmd, testElasticsearchCmd,
+		testClickHouseCmd)
+	testCmd.PersistentFlags().DurationVar(&testTimeout, "timeout", 5*time.Second, "Give up on the connection after this long")
+	testCmd.PersistentFlags().BoolVar(&testJSON, "json", false, "Print the report as JSON")
+
+	testMySQLCmd.Flags().StringVar(&host, "host", "localhost", "MySQL host")
+	testMySQLCmd.Flags().StringVar(&port, "port", "", "MySQL port (default 3306)")
+	testMySQLCmd.Flags().StringVar(&user, "user", "root", "MySQL user")
+	testMySQLCmd.Flags().StringVar(&password, "password", "", "MySQL password")
+	testMySQLCmd.Flags().StringVar(&database, "database", "", "Database the backups will read (checks database-level grants)")
+	addConnFlags(testMySQLCmd)
+	addSSHFlags(testMySQLCmd)
+
+	testMariaDBCmd.Flags().StringVar(&host, "host", "localhost", "MariaDB host")
+	testMariaDBCmd.Flags().StringVar(&port, "port", "", "MariaDB port (default 3306)")
+	testMariaDBCmd.Flags().StringVar(&user, "user", "root", "MariaDB user")
+	testMariaDBCmd.Flags().StringVar(&password, "password", "", "MariaDB password")
+	testMariaDBCmd.Flags().StringVar(&database, "database", "", "Database the backups will read (checks database-level grants)")
+	addConnFlags(testMariaDBCmd)
+	addSSHFlags(testMariaDBCmd)
+
+	testPostgresCmd.Flags().StringVar(&host, "host", "localhost", "PostgreSQL host")
+	testPostgresCmd.Flags().StringVar(&port, "port", "5432", "PostgreSQL port")
+	testPostgresCmd.Flags().StringVar(&user, "user", "postgres", "PostgreSQL user")
+	testPostgresCmd.Flags().StringVar(&password, "password", "", "PostgreSQL password")
+	testPostgresCmd.Flags().StringVar(&database, "database", "postgres", "Database to connect to")
+	addConnFlags(testPostgresCmd)
+	addSSHFlags(testPostgresCmd)
+
+	testMongoCmd.Flags().StringVar(&uri, "uri", "mongodb://localhost:27017", "MongoDB URI")
+	testMongoCmd.Flags().StringVar(&port, "port", "", "MongoDB port, replacing the one in --uri")
+	testMongoCmd.Flags().StringVar(&database, "database", "", "Database the backups will read (checks its roles)")
+	addConnFlags(testMongoCmd)
+	addSSHFlags(testMongoCmd)
+
+	testSQLiteCmd.Flags().StringVar(&sqlitePath, "path", "", "SQLite database path")
+	testSQLiteCmd.MarkFlagRequired("path")
+
+	testRedisCmd.Flags().StringVar(&host, "host", "localhost", "Redis host")
+	testRedisCmd.Flags().StringVar(&port, "port", "6379", "Redis port")
+	testRedisCmd.Flags().StringVar(&user, "user", "", "Redis ACL user (default: the default user)")
+	testRedisCmd.Flags().StringVar(&password, "password", "", "Redis password")
+	addConnFlags(testRedisCmd)
+	addSSHFlags(testRedisCmd)
+
+	testEtcdCmd.Flags().StringVar(&host, "host", "localhost", "etcd member host")
+	testEtcdCmd.Flags().StringVar(&port, "port", "2379", "etcd client port")
+	testEtcdCmd.Flags().StringVar(&user, "user", "", "etcd user (when authentication is enabled)")
+	testEtcdCmd.Flags().StringVar(&password, "password", "", "etcd password")
+	addConnFlags(testEtcdCmd)
+	addSSHFlags(testEtcdCmd)
+
+	testConsulCmd.Flags().StringVar(&host, "host", "localhost", "Consul agent host")
+	testConsulCmd.Flags().StringVar(&port, "port", "8500", "Consul HTTP API port")
+	testConsulCmd.Flags().StringVar(&password, "password", "", "Consul ACL token (or set CONSUL_HTTP_TOKEN)")
+	addConnFlags(testConsulCmd)
+	addSSHFlags(testConsulCmd)
+
+	testElasticsearchCmd.Flags().StringVar(&host, "host", "localhost", "Elasticsearch host")
+	testElasticsearchCmd.Flags().StringVar(&port, "port", "9200", "Elasticsearch HTTP port")
+	testElasticsearchCmd.Flags().StringVar(&user, "user", "", "Elasticsearch user (basic authentication)")
+	testElasticsearchCmd.Flags().StringVar(&password, "password", "", "Elasticsearch password, or an API key without --user")
+	addConnFlags(testElasticsearchCmd)
+	addSSHFlags(testElasticsearchCmd)
+
+	testClickHouseCmd.Flags().StringVar(&host, "host", "localhost", "ClickHouse host")
+	testClickHouseCmd.Flags().StringVar(&port, "port", "", "ClickHouse native protocol port (default 9000, 9440 with TLS)")
+	testClickHouseCmd.Flags().StringVar(&user, "user", "default", "ClickHouse user")
+	testClickHouseCmd.Flags().StringVar(&password, "password", "", "ClickHouse password")
+	addConnFlags(testClickHouseCmd)
+	addSSHFlags(testClickHouseCmd)
+}
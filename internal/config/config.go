@@ -0,0 +1,302 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative dbx configuration: named connection profiles,
+// named upload destinations and defaults shared by all profiles
+type Config struct {
+	Defaults     Defaults               `yaml:"defaults" toml:"defaults"`
+	Destinations map[string]Destination `yaml:"destinations" toml:"destinations"`
+	Profiles     map[string]Profile     `yaml:"profiles" toml:"profiles"`
+}
+
+// Defaults apply to every profile that doesn't set the value itself
+type Defaults struct {
+	Out         string `yaml:"out" toml:"out"`
+	Destination string `yaml:"destination" toml:"destination"`
+}
+
+// Destination is a named cloud upload target
+type Destination struct {
+	Provider  string `yaml:"provider" toml:"provider"` // s3, gcs, or azure
+	Bucket    string `yaml:"bucket" toml:"bucket"`
+	Prefix    string `yaml:"prefix" toml:"prefix"`
+	Account   string `yaml:"account" toml:"account"`
+	Container string `yaml:"container" toml:"container"`
+}
+
+// Profile is a named database target. Password and URI may be secret references (env:, file:, store:).
+type Profile struct {
+	Name     string `yaml:"-" toml:"-"`
+	Engine   string `yaml:"engine" toml:"engine"` // mysql, mariadb, postgres, mongodb, sqlite, redis, etcd, consul, elasticsearch, or clickhouse
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Database string `yaml:"database" toml:"database"`
+	URI      string `yaml:"uri" toml:"uri"`
+	Path     string `yaml:"path" toml:"path"`
+	// AllDatabases, Include and Exclude select databases by discovery instead of Database
+	AllDatabases bool     `yaml:"all_databases" toml:"all_databases"`
+	Include      []string `yaml:"include" toml:"include"`
+	Exclude      []string `yaml:"exclude" toml:"exclude"`
+	// Content is schema, data or all (default), as --content
+	Content string `yaml:"content" toml:"content"`
+	// MaskRules is a masking rules file applied while dumping, as --mask-rules
+	MaskRules string `yaml:"mask_rules" toml:"mask_rules"`
+	// Jobs is the dump parallelism, as --jobs
+	Jobs int `yaml:"jobs" toml:"jobs"`
+	// Dumper is auto, tool or builtin, as --dumper
+	Dumper string `yaml:"dumper" toml:"dumper"`
+	// Format is binary or sql for SQLite, as --format
+	Format string `yaml:"format" toml:"format"`
+	// BGSave copies a Redis server's own BGSAVE snapshot, as --bgsave
+	BGSave bool `yaml:"bgsave" toml:"bgsave"`
+	// Method is logical or physical (xtrabackup for MySQL, mariabackup for MariaDB), as --method
+	Method string `yaml:"method" toml:"method"`
+	// Physical is the deprecated spelling of method: physical for MariaDB
+	Physical bool `yaml:"physical" toml:"physical"`
+	// Elasticsearch snapshot settings, as --repository/--repository-path/--indices/--global-state
+	Repository     string   `yaml:"repository" toml:"repository"`
+	RepositoryPath string   `yaml:"repository_path" toml:"repository_path"`
+	Indices        []string `yaml:"indices" toml:"indices"`
+	GlobalState    bool     `yaml:"global_state" toml:"global_state"`
+	// Socket and the TLS settings, as --socket/--tls-mode/--tls-ca/--tls-cert/--tls-key
+	Socket  string `yaml:"socket" toml:"socket"`
+	TLSMode string `yaml:"tls_mode" toml:"tls_mode"`
+	TLSCA   string `yaml:"tls_ca" toml:"tls_ca"`
+	TLSCert string `yaml:"tls_cert" toml:"tls_cert"`
+	TLSKey  string `yaml:"tls_key" toml:"tls_key"`
+	// SSH jump host, as --ssh-host/--ssh-user/--ssh-key/--ssh-known-hosts
+	SSHHost       string `yaml:"ssh_host" toml:"ssh_host"`
+	SSHUser       string `yaml:"ssh_user" toml:"ssh_user"`
+	SSHKey        string `yaml:"ssh_key" toml:"ssh_key"`
+	SSHKnownHosts string `yaml:"ssh_known_hosts" toml:"ssh_known_hosts"`
+	// Table and collection filters, as --tables/--exclude-tables/--collections/--exclude-collections
+	Tables             []string `yaml:"tables" toml:"tables"`
+	ExcludeTables      []string `yaml:"exclude_tables" toml:"exclude_tables"`
+	Collections        []string `yaml:"collections" toml:"collections"`
+	ExcludeCollections []string `yaml:"exclude_collections" toml:"exclude_collections"`
+	Out                string   `yaml:"out" toml:"out"`
+	Destination        string   `yaml:"destination" toml:"destination"`
+
+	dest *Destination
+}
+
+// engines maps accepted engine names to the names used by the scheduler
+var engines = map[string]string{
+	"mysql":         "mysql",
+	"mariadb":       "mariadb",
+	"postgres":      "postgres",
+	"postgresql":    "postgres",
+	"mongodb":       "mongodb",
+	"mongo":         "mongodb",
+	"sqlite":        "sqlite",
+	"redis":         "redis",
+	"etcd":          "etcd",
+	"consul":        "consul",
+	"elasticsearch": "elasticsearch",
+	"opensearch":    "elasticsearch",
+	"clickhouse":    "clickhouse",
+}
+
+// DefaultPath returns the config file location: $DBX_CONFIG, or config.yaml, config.yml or
+// config.toml in the user's config directory (e.g. ~/.config/dbx on Linux)
+func DefaultPath() string {
+	if path := os.Getenv("DBX_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".", "config", "config.yaml")
+	}
+	dir = filepath.Join(dir, "dbx")
+	for _, name := range []string{"config.yaml", "config.yml", "config.toml"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, "config.yaml")
+}
+
+// LoadDefault loads the config file from DefaultPath
+func LoadDefault() (*Config, error) {
+	return Load(DefaultPath())
+}
+
+// Load reads a YAML or TOML config file, chosen by extension
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("config file not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		if _, err := toml.Decode(string(data), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	return &cfg, nil
+}
+
+// ProfileNames returns the profile names in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile with defaults applied and its destination looked up
+func (c *Config) Profile(name string) (Profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	p.Name = name
+
+	engine, ok := engines[strings.ToLower(p.Engine)]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q: unsupported engine %q (use mysql, mariadb, postgres, mongodb, sqlite, redis, etcd, consul, elasticsearch or clickhouse)", name, p.Engine)
+	}
+	p.Engine = engine
+
+	if p.Out == "" {
+		p.Out = c.Defaults.Out
+	}
+	if p.Destination == "" {
+		p.Destination = c.Defaults.Destination
+	}
+	// "none" lets a profile opt out of the default destination
+	if p.Destination != "" && p.Destination != "none" {
+		dest, ok := c.Destinations[p.Destination]
+		if !ok {
+			return Profile{}, fmt.Errorf("profile %q: destination %q not found", name, p.Destination)
+		}
+		switch strings.ToLower(dest.Provider) {
+		case "s3", "gcs", "azure":
+		default:
+			return Profile{}, fmt.Errorf("destination %q: unsupported provider %q (use s3, gcs or azure)", p.Destination, dest.Provider)
+		}
+		p.dest = &dest
+	}
+	return p, nil
+}
+
+// Params returns the profile as scheduler job params; empty values are left out
+func (p Profile) Params() map[string]string {
+	params := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	set("host", p.Host)
+	if p.Port != 0 {
+		params["port"] = strconv.Itoa(p.Port)
+	}
+	set("user", p.User)
+	set("pass", p.Password)
+	set("dbname", p.Database)
+	set("uri", p.URI)
+	set("path", p.Path)
+	set("out", p.Out)
+	if p.AllDatabases {
+		params["all_databases"] = "true"
+	}
+	set("include", strings.Join(p.Include, ","))
+	set("exclude", strings.Join(p.Exclude, ","))
+	set("content", p.Content)
+	set("mask_rules", p.MaskRules)
+	if p.Jobs > 0 {
+		params["jobs"] = strconv.Itoa(p.Jobs)
+	}
+	set("dumper", p.Dumper)
+	set("format", p.Format)
+	if p.BGSave {
+		params["bgsave"] = "true"
+	}
+	set("method", p.Method)
+	if p.Physical && p.Method == "" {
+		params["method"] = "physical"
+	}
+	set("repository", p.Repository)
+	set("repository_path", p.RepositoryPath)
+	set("indices", strings.Join(p.Indices, ","))
+	if p.GlobalState {
+		params["global_state"] = "true"
+	}
+	set("socket", p.Socket)
+	set("tls_mode", p.TLSMode)
+	set("tls_ca", p.TLSCA)
+	set("tls_cert", p.TLSCert)
+	set("tls_key", p.TLSKey)
+	set("ssh_host", p.SSHHost)
+	set("ssh_user", p.SSHUser)
+	set("ssh_key", p.SSHKey)
+	set("ssh_known_hosts", p.SSHKnownHosts)
+	set("tables", strings.Join(p.Tables, ","))
+	set("exclude_tables", strings.Join(p.ExcludeTables, ","))
+	set("collections", strings.Join(p.Collections, ","))
+	set("exclude_collections", strings.Join(p.ExcludeCollections, ","))
+
+	if p.dest != nil {
+		params["upload_cloud"] = "true"
+		provider := strings.ToLower(p.dest.Provider)
+		params["cloud_provider"] = provider
+		switch provider {
+		case "s3":
+			set("s3_bucket", p.dest.Bucket)
+			set("s3_prefix", p.dest.Prefix)
+		case "gcs":
+			set("gcs_bucket", p.dest.Bucket)
+			set("gcs_prefix", p.dest.Prefix)
+		case "azure":
+			set("azure_account", p.dest.Account)
+			set("azure_container", p.dest.Container)
+		}
+	}
+	return params
+}
+
+// ProfileParams loads the default config file and returns the named profile's params,
+// overlaid with overrides
+func ProfileParams(name string, overrides map[string]string) (map[string]string, error) {
+	cfg, err := LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	p, err := cfg.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	params := p.Params()
+	for key, value := range overrides {
+		params[key] = value
+	}
+	return params, nil
+}
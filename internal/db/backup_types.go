@@ -24,6 +24,7 @@ type BackupMetadata struct {
 	BackupPath         string    `json:"backup_path"`
 	DBType             string    `json:"db_type"`
 	Database           string    `json:"database"`
+	FullBackupPath     string    `json:"full_backup_path,omitempty"` // physical backups: the last full backup, which differentials build on
 }
 
 // GetMetadataPath returns the path to the metadata file for a database
@@ -0,0 +1,1031 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Dumper values choose what writes a logical MySQL, MariaDB, PostgreSQL or MongoDB backup
+const (
+	DumperAuto    = "auto"    // the client tool when it is on PATH, else the built-in dumper
+	DumperTool    = "tool"    // mysqldump/mariadb-dump, pg_dump or mongodump only
+	DumperBuiltin = "builtin" // the built-in dumper, which reads the database with the Go drivers
+)
+
+// mysqlInsertBytes is the size at which the built-in MySQL dumper starts a new INSERT statement,
+// mysqldump's default net_buffer_length
+const mysqlInsertBytes = 1 << 20
+
+// builtinDump reports whether a logical backup is written by the built-in dumper rather than tool:
+// always with DumperBuiltin, never with DumperTool, and otherwise when tool isn't installed
+func (o BackupOptions) builtinDump(tool string, toolFound bool) bool {
+	switch o.Dumper {
+	case DumperBuiltin:
+		return true
+	case DumperTool:
+		return false
+	}
+	if !toolFound {
+		fmt.Printf("⚠️  %s not found in PATH; using the built-in dumper\n", tool)
+	}
+	return !toolFound
+}
+
+// checkBuiltinDump rejects options only the client tools support
+func checkBuiltinDump(tool string, backupType BackupType, opts BackupOptions) error {
+	if backupType != "" && backupType != BackupTypeFull {
+		return fmt.Errorf("the built-in dumper takes full backups only (install %s for %s backups)", tool, backupType)
+	}
+	if opts.Jobs > 1 {
+		return fmt.Errorf("the built-in dumper doesn't run in parallel (install %s for --jobs)", tool)
+	}
+	return nil
+}
+
+// missingTables returns an error naming the first of want not in names
+func missingTables(names, want []string, database string) error {
+	for _, table := range want {
+		if !slices.Contains(names, table) {
+			return fmt.Errorf("table '%s' not found in %s", table, database)
+		}
+	}
+	return nil
+}
+
+// ---------------- MySQL ----------------
+
+// definerClause matches the DEFINER of a view or trigger, which may not exist where the dump is restored
+var definerClause = regexp.MustCompile("DEFINER=(`[^`]*`|[^ @]*)@(`[^`]*`|[^ ]*) ")
+
+// dumpMySQLBuiltin writes a dump of database to w in mysqldump's layout: DROP and CREATE TABLE, then
+// INSERTs with column lists between LOCK TABLES and UNLOCK TABLES for each table, followed by views
+// and triggers. Everything is read in one consistent snapshot with the session time zone at UTC,
+// as mysqldump --single-transaction does, so the dump loads with the mysql client.
+func dumpMySQLBuiltin(w io.Writer, host, user, password, database string, opts BackupOptions) error {
+	pool, err := openMySQL(host, user, password, database, opts.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = pool.Close() }()
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	for _, stmt := range []string{
+		"SET SESSION time_zone = '+00:00'",
+		"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	var names, views []string
+	rows, err := conn.QueryContext(ctx, "SELECT table_name, table_type FROM information_schema.tables WHERE table_schema = DATABASE() ORDER BY table_name")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		names = append(names, name)
+		if kind == "VIEW" {
+			views = append(views, name)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := missingTables(names, opts.Tables, database); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriterSize(w, 1<<20)
+	fmt.Fprintf(bw, "-- MySQL dump of database `%s` written by dbx (built-in dumper)\n\n", database)
+	fmt.Fprint(bw, "SET NAMES utf8mb4;\nSET TIME_ZONE='+00:00';\nSET UNIQUE_CHECKS=0;\nSET FOREIGN_KEY_CHECKS=0;\nSET SQL_MODE='NO_AUTO_VALUE_ON_ZERO';\n\n")
+
+	selected := selectTables(names, opts)
+	var selectedViews []string
+	for _, table := range selected {
+		if slices.Contains(views, table) {
+			selectedViews = append(selectedViews, table)
+			continue
+		}
+		if opts.Content != ContentData {
+			var name, create string
+			if err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+mysqlIdent(table)).Scan(&name, &create); err != nil {
+				return fmt.Errorf("%s: %w", table, err)
+			}
+			fmt.Fprintf(bw, "--\n-- Table structure for table %s\n--\n\nDROP TABLE IF EXISTS %s;\n%s;\n\n", mysqlIdent(table), mysqlIdent(table), create)
+		}
+		if opts.Content != ContentSchema {
+			if err := dumpMySQLRows(ctx, conn, bw, table); err != nil {
+				return fmt.Errorf("%s: %w", table, err)
+			}
+		}
+	}
+
+	if opts.Content != ContentData {
+		if err := dumpMySQLViews(ctx, conn, bw, selectedViews); err != nil {
+			return err
+		}
+		if err := dumpMySQLTriggers(ctx, conn, bw, selected); err != nil {
+			return err
+		}
+	}
+	fmt.Fprint(bw, "SET FOREIGN_KEY_CHECKS=1;\nSET UNIQUE_CHECKS=1;\n")
+	return bw.Flush()
+}
+
+// dumpMySQLRows writes the rows of a table as extended INSERTs of up to mysqlInsertBytes each.
+// Generated columns are left out, since the server computes them.
+func dumpMySQLRows(ctx context.Context, conn *sql.Conn, w io.Writer, table string) error {
+	columns, err := mysqlStoredColumns(ctx, conn, table)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "--\n-- Dumping data for table %s\n--\n\nLOCK TABLES %s WRITE;\n", mysqlIdent(table), mysqlIdent(table))
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = mysqlIdent(col)
+		}
+		list := strings.Join(quoted, ", ")
+		rows, err := conn.QueryContext(ctx, "SELECT "+list+" FROM "+mysqlIdent(table))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		raw := make([]sql.RawBytes, len(types))
+		dest := make([]any, len(raw))
+		for i := range raw {
+			dest[i] = &raw[i]
+		}
+
+		var stmt strings.Builder
+		flush := func() {
+			if stmt.Len() > 0 {
+				fmt.Fprintf(w, "%s;\n", stmt.String())
+				stmt.Reset()
+			}
+		}
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			if stmt.Len() == 0 {
+				fmt.Fprintf(&stmt, "INSERT INTO %s (%s) VALUES ", mysqlIdent(table), list)
+			} else {
+				stmt.WriteByte(',')
+			}
+			stmt.WriteByte('(')
+			for i, value := range raw {
+				if i > 0 {
+					stmt.WriteByte(',')
+				}
+				stmt.WriteString(mysqlDumpValue(value, types[i].DatabaseTypeName()))
+			}
+			stmt.WriteByte(')')
+			if stmt.Len() >= mysqlInsertBytes {
+				flush()
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		flush()
+	}
+	fmt.Fprint(w, "UNLOCK TABLES;\n\n")
+	return nil
+}
+
+// mysqlStoredColumns returns the columns of a table that hold data, in order
+func mysqlStoredColumns(ctx context.Context, conn *sql.Conn, table string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT column_name, extra FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var columns []string
+	for rows.Next() {
+		var name, extra string
+		if err := rows.Scan(&name, &extra); err != nil {
+			return nil, err
+		}
+		// VIRTUAL/STORED GENERATED, but not DEFAULT_GENERATED, which marks expression defaults
+		if !slices.Contains(strings.Fields(extra), "GENERATED") {
+			columns = append(columns, name)
+		}
+	}
+	return columns, rows.Err()
+}
+
+// mysqlDumpValue renders a value read in text form as mysqldump --hex-blob does: numbers bare,
+// binary data in hex and everything else as an escaped string
+func mysqlDumpValue(raw sql.RawBytes, typeName string) string {
+	if raw == nil {
+		return "NULL"
+	}
+	switch strings.TrimPrefix(typeName, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR", "DECIMAL", "FLOAT", "DOUBLE":
+		return string(raw)
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		if len(raw) == 0 {
+			return "''"
+		}
+		return "0x" + strings.ToUpper(hex.EncodeToString(raw))
+	}
+	return mysqlQuote(string(raw))
+}
+
+// mysqlQuote quotes a string literal with the escapes mysqldump uses, which keep every row on one line
+func mysqlQuote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\\', '\'', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case 0x1a:
+			b.WriteString(`\Z`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// dumpMySQLViews writes the views, each after the views it selects from
+func dumpMySQLViews(ctx context.Context, conn *sql.Conn, w io.Writer, views []string) error {
+	defs := make(map[string]string)
+	for _, view := range views {
+		var name, create, charset, collation string
+		if err := conn.QueryRowContext(ctx, "SHOW CREATE VIEW "+mysqlIdent(view)).Scan(&name, &create, &charset, &collation); err != nil {
+			return fmt.Errorf("%s: %w", view, err)
+		}
+		defs[view] = definerClause.ReplaceAllString(create, "")
+	}
+	for len(views) > 0 {
+		// The server writes every name quoted, so a dependency shows as `name` in the definition
+		next := slices.IndexFunc(views, func(view string) bool {
+			return !slices.ContainsFunc(views, func(other string) bool {
+				return other != view && strings.Contains(defs[view], mysqlIdent(other))
+			})
+		})
+		if next < 0 {
+			next = 0
+		}
+		view := views[next]
+		fmt.Fprintf(w, "--\n-- View %s\n--\n\nDROP VIEW IF EXISTS %s;\n%s;\n\n", mysqlIdent(view), mysqlIdent(view), defs[view])
+		views = slices.Delete(views, next, next+1)
+	}
+	return nil
+}
+
+// dumpMySQLTriggers writes the triggers of tables, between DELIMITER lines as mysqldump does
+func dumpMySQLTriggers(ctx context.Context, conn *sql.Conn, w io.Writer, tables []string) error {
+	rows, err := conn.QueryContext(ctx, `SELECT trigger_name, event_object_table FROM information_schema.triggers
+		WHERE trigger_schema = DATABASE() ORDER BY event_object_table, action_order`)
+	if err != nil {
+		return err
+	}
+	var triggers []string
+	for rows.Next() {
+		var name, table string
+		if err := rows.Scan(&name, &table); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if slices.Contains(tables, table) {
+			triggers = append(triggers, name)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, trigger := range triggers {
+		create, err := mysqlTriggerDef(ctx, conn, trigger)
+		if err != nil {
+			return fmt.Errorf("trigger %s: %w", trigger, err)
+		}
+		fmt.Fprintf(w, "DROP TRIGGER IF EXISTS %s;\nDELIMITER ;;\n%s ;;\nDELIMITER ;\n\n", mysqlIdent(trigger), definerClause.ReplaceAllString(create, ""))
+	}
+	return nil
+}
+
+// mysqlTriggerDef returns the CREATE TRIGGER statement of a trigger, the third column of SHOW CREATE
+// TRIGGER, whose column count differs between MySQL and MariaDB versions
+func mysqlTriggerDef(ctx context.Context, conn *sql.Conn, trigger string) (string, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW CREATE TRIGGER "+mysqlIdent(trigger))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("not found")
+	}
+	values := make([]sql.NullString, len(cols))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	if len(values) < 3 {
+		return "", fmt.Errorf("unexpected SHOW CREATE TRIGGER result")
+	}
+	return values[2].String, nil
+}
+
+// ---------------- PostgreSQL ----------------
+
+// pgDumpRelation is a table, view or materialized view the built-in PostgreSQL dumper writes
+type pgDumpRelation struct {
+	oid    uint32
+	schema string
+	name   string // as dbx lists it: schema.table outside the public schema
+	ident  string // quoted and schema-qualified, e.g. public.users
+	kind   string // r (table), p (partitioned table), v (view) or m (materialized view)
+	parent string // the partitioned table a partition belongs to
+	bound  string // a partition's FOR VALUES clause
+	partBy string // a partitioned table's PARTITION BY clause
+}
+
+// pgDumpSequence is a sequence and the column that owns it, if any
+type pgDumpSequence struct {
+	ident                        string
+	dataType                     string
+	start, increment, minV, maxV int64
+	cache                        int64
+	cycle                        bool
+	ownerKind                    string // a (serial column), i (identity column) or empty
+	ownerOID                     uint32
+	ownerColumn                  string // unquoted
+	ownerColumnIdent, ownerIdent string
+}
+
+// pgDumpSchemas excludes the system schemas from the catalog queries below
+const pgDumpSchemas = `n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'`
+
+// pgNotExtension excludes objects that belong to an extension, which CREATE EXTENSION recreates
+func pgNotExtension(catalog, oid string) string {
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.classid = '%s'::regclass AND e.objid = %s AND e.deptype = 'e')", catalog, oid)
+}
+
+// pgDumper writes a plain-format dump from one REPEATABLE READ transaction
+type pgDumper struct {
+	ctx  context.Context
+	tx   pgx.Tx
+	w    *bufio.Writer
+	opts BackupOptions
+}
+
+// dumpPostgresBuiltin writes a plain SQL dump of database to w, laid out as pg_dump --clean
+// --if-exists writes one: drops, then schemas, extensions, enum types, functions, sequences and
+// tables, the rows as COPY blocks and sequence positions, then views, constraints, indexes,
+// foreign keys and triggers. With opts.Tables only those tables and their sequences, constraints, indexes
+// and triggers are written, as with pg_dump -t. Needs PostgreSQL 12 or later; psql restores the file.
+func dumpPostgresBuiltin(w io.Writer, host, port, user, password, database string, opts BackupOptions) error {
+	cfg, err := postgresConfig(host, port, user, password, database, opts.Conn)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = conn.Close(ctx) }()
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	// With an empty search_path the server qualifies every name in the definitions it prints
+	if _, err := tx.Exec(ctx, "SELECT pg_catalog.set_config('search_path', '', true)"); err != nil {
+		return err
+	}
+
+	d := &pgDumper{ctx: ctx, tx: tx, w: bufio.NewWriterSize(w, 1<<20), opts: opts}
+	if err := d.dump(database); err != nil {
+		return err
+	}
+	return d.w.Flush()
+}
+
+func (d *pgDumper) dump(database string) error {
+	relations, err := d.relations()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, rel := range relations {
+		names = append(names, rel.name)
+	}
+	if err := missingTables(names, d.opts.Tables, database); err != nil {
+		return err
+	}
+	selected := make(map[string]bool)
+	for _, name := range selectTables(names, d.opts) {
+		selected[name] = true
+	}
+	relations = slices.DeleteFunc(relations, func(rel pgDumpRelation) bool { return !selected[rel.name] })
+	owners := make(map[uint32]bool)
+	for _, rel := range relations {
+		owners[rel.oid] = true
+	}
+	sequences, err := d.sequences()
+	if err != nil {
+		return err
+	}
+	// Sequences go with their tables; free-standing ones only with the whole database
+	whole := len(d.opts.Tables) == 0
+	sequences = slices.DeleteFunc(sequences, func(seq pgDumpSequence) bool {
+		return seq.ownerKind == "" && !whole || seq.ownerKind != "" && !owners[seq.ownerOID]
+	})
+
+	fmt.Fprintf(d.w, "--\n-- PostgreSQL database dump of %s written by dbx (built-in dumper)\n--\n\n", database)
+	fmt.Fprint(d.w, "SET statement_timeout = 0;\nSET lock_timeout = 0;\nSET client_encoding = 'UTF8';\n"+
+		"SET standard_conforming_strings = on;\nSELECT pg_catalog.set_config('search_path', '', false);\n"+
+		"SET check_function_bodies = false;\nSET client_min_messages = warning;\n\n")
+
+	if d.opts.Content != ContentData {
+		if err := d.preData(relations, sequences, whole); err != nil {
+			return err
+		}
+	}
+	if d.opts.Content != ContentSchema {
+		if err := d.data(relations, sequences); err != nil {
+			return err
+		}
+	}
+	if d.opts.Content != ContentData {
+		if err := d.postData(relations); err != nil {
+			return err
+		}
+	}
+	fmt.Fprint(d.w, "--\n-- PostgreSQL database dump complete\n--\n")
+	return nil
+}
+
+// relations lists the tables, partitions and views in creation order, partitions after their parents
+func (d *pgDumper) relations() ([]pgDumpRelation, error) {
+	rows, err := d.tx.Query(d.ctx, `SELECT c.oid, n.nspname, c.relname, format('%I.%I', n.nspname, c.relname), c.relkind::text,
+			COALESCE((SELECT format('%I.%I', pn.nspname, p.relname) FROM pg_inherits i JOIN pg_class p ON p.oid = i.inhparent
+				JOIN pg_namespace pn ON pn.oid = p.relnamespace WHERE i.inhrelid = c.oid AND c.relispartition), ''),
+			COALESCE(pg_get_expr(c.relpartbound, c.oid), ''),
+			CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) ELSE '' END
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm') AND `+pgDumpSchemas+` AND `+pgNotExtension("pg_class", "c.oid")+`
+		ORDER BY c.relispartition, c.oid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var relations []pgDumpRelation
+	for rows.Next() {
+		var rel pgDumpRelation
+		var table string
+		if err := rows.Scan(&rel.oid, &rel.schema, &table, &rel.ident, &rel.kind, &rel.parent, &rel.bound, &rel.partBy); err != nil {
+			return nil, err
+		}
+		rel.name = table
+		if rel.schema != "public" {
+			rel.name = rel.schema + "." + table
+		}
+		relations = append(relations, rel)
+	}
+	return relations, rows.Err()
+}
+
+func (d *pgDumper) sequences() ([]pgDumpSequence, error) {
+	rows, err := d.tx.Query(d.ctx, `SELECT format('%I.%I', n.nspname, c.relname), format_type(s.seqtypid, NULL),
+			s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache, s.seqcycle,
+			COALESCE(dep.deptype::text, ''), COALESCE(dep.refobjid, 0::oid), COALESCE(a.attname::text, ''),
+			COALESCE(quote_ident(a.attname), ''), COALESCE(format('%I.%I', tn.nspname, t.relname), '')
+		FROM pg_sequence s JOIN pg_class c ON c.oid = s.seqrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_depend dep ON dep.classid = 'pg_class'::regclass AND dep.objid = c.oid
+			AND dep.refclassid = 'pg_class'::regclass AND dep.deptype IN ('a', 'i')
+		LEFT JOIN pg_attribute a ON a.attrelid = dep.refobjid AND a.attnum = dep.refobjsubid
+		LEFT JOIN pg_class t ON t.oid = dep.refobjid LEFT JOIN pg_namespace tn ON tn.oid = t.relnamespace
+		WHERE `+pgDumpSchemas+` AND `+pgNotExtension("pg_class", "c.oid")+`
+		ORDER BY c.oid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sequences []pgDumpSequence
+	for rows.Next() {
+		var s pgDumpSequence
+		if err := rows.Scan(&s.ident, &s.dataType, &s.start, &s.increment, &s.minV, &s.maxV, &s.cache, &s.cycle,
+			&s.ownerKind, &s.ownerOID, &s.ownerColumn, &s.ownerColumnIdent, &s.ownerIdent); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, s)
+	}
+	return sequences, rows.Err()
+}
+
+// list runs a query returning one text column
+func (d *pgDumper) list(query string, args ...any) ([]string, error) {
+	rows, err := d.tx.Query(d.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// preData writes the drops and the definitions the rows are loaded into
+func (d *pgDumper) preData(relations []pgDumpRelation, sequences []pgDumpSequence, whole bool) error {
+	var functions, functionDefs []string
+	if whole {
+		rows, err := d.tx.Query(d.ctx, `SELECT p.oid::regprocedure::text, pg_get_functiondef(p.oid)
+			FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE p.prokind IN ('f', 'p') AND `+pgDumpSchemas+` AND `+pgNotExtension("pg_proc", "p.oid")+` ORDER BY p.oid`)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var signature, def string
+			if err := rows.Scan(&signature, &def); err != nil {
+				rows.Close()
+				return err
+			}
+			functions, functionDefs = append(functions, signature), append(functionDefs, def)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	var enums []string
+	var enumLabels [][]string
+	if whole {
+		rows, err := d.tx.Query(d.ctx, `SELECT format('%I.%I', n.nspname, t.typname), array_agg(e.enumlabel::text ORDER BY e.enumsortorder)
+			FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace JOIN pg_enum e ON e.enumtypid = t.oid
+			WHERE `+pgDumpSchemas+` AND `+pgNotExtension("pg_type", "t.oid")+` GROUP BY t.oid, n.nspname, t.typname ORDER BY t.oid`)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var name string
+			var labels []string
+			if err := rows.Scan(&name, &labels); err != nil {
+				rows.Close()
+				return err
+			}
+			enums, enumLabels = append(enums, name), append(enumLabels, labels)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Drops, dependents first; CASCADE takes along objects that depend on them
+	for i := len(relations) - 1; i >= 0; i-- {
+		if rel := relations[i]; rel.kind == "v" || rel.kind == "m" {
+			fmt.Fprintf(d.w, "DROP %s IF EXISTS %s CASCADE;\n", pgRelationKind(rel.kind), rel.ident)
+		}
+	}
+	for i := len(relations) - 1; i >= 0; i-- {
+		if rel := relations[i]; rel.kind == "r" || rel.kind == "p" {
+			fmt.Fprintf(d.w, "DROP TABLE IF EXISTS %s CASCADE;\n", rel.ident)
+		}
+	}
+	for _, seq := range sequences {
+		if seq.ownerKind != "i" {
+			fmt.Fprintf(d.w, "DROP SEQUENCE IF EXISTS %s CASCADE;\n", seq.ident)
+		}
+	}
+	for _, signature := range functions {
+		fmt.Fprintf(d.w, "DROP ROUTINE IF EXISTS %s CASCADE;\n", signature)
+	}
+	for _, enum := range enums {
+		fmt.Fprintf(d.w, "DROP TYPE IF EXISTS %s CASCADE;\n", enum)
+	}
+	fmt.Fprintln(d.w)
+
+	var schemas []string
+	if whole {
+		var err error
+		schemas, err = d.list(`SELECT quote_ident(n.nspname) FROM pg_namespace n
+			WHERE ` + pgDumpSchemas + ` AND n.nspname <> 'public' AND ` + pgNotExtension("pg_namespace", "n.oid") + ` ORDER BY n.oid`)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, rel := range relations {
+			if ident := pgIdent(rel.schema); rel.schema != "public" && !slices.Contains(schemas, ident) {
+				schemas = append(schemas, ident)
+			}
+		}
+	}
+	for _, schema := range schemas {
+		fmt.Fprintf(d.w, "CREATE SCHEMA IF NOT EXISTS %s;\n", schema)
+	}
+	if whole {
+		extensions, err := d.list(`SELECT format('CREATE EXTENSION IF NOT EXISTS %I WITH SCHEMA %I;', x.extname, n.nspname)
+			FROM pg_extension x JOIN pg_namespace n ON n.oid = x.extnamespace WHERE x.extname <> 'plpgsql' ORDER BY x.oid`)
+		if err != nil {
+			return err
+		}
+		for _, ext := range extensions {
+			fmt.Fprintln(d.w, ext)
+		}
+	}
+	for i, enum := range enums {
+		quoted := make([]string, len(enumLabels[i]))
+		for j, label := range enumLabels[i] {
+			quoted[j] = pgLiteral(label)
+		}
+		fmt.Fprintf(d.w, "CREATE TYPE %s AS ENUM (%s);\n", enum, strings.Join(quoted, ", "))
+	}
+	fmt.Fprintln(d.w)
+	for _, def := range functionDefs {
+		fmt.Fprintf(d.w, "%s;\n\n", strings.TrimRight(def, "\n"))
+	}
+
+	for _, seq := range sequences {
+		if seq.ownerKind == "i" {
+			continue // created with its identity column
+		}
+		cycle := "NO CYCLE"
+		if seq.cycle {
+			cycle = "CYCLE"
+		}
+		fmt.Fprintf(d.w, "CREATE SEQUENCE %s AS %s START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d %s;\n",
+			seq.ident, seq.dataType, seq.start, seq.increment, seq.minV, seq.maxV, seq.cache, cycle)
+	}
+	fmt.Fprintln(d.w)
+
+	for _, rel := range relations {
+		if rel.kind == "r" || rel.kind == "p" {
+			if err := d.createTable(rel); err != nil {
+				return fmt.Errorf("%s: %w", rel.name, err)
+			}
+		}
+	}
+	for _, seq := range sequences {
+		if seq.ownerKind == "a" {
+			fmt.Fprintf(d.w, "ALTER SEQUENCE %s OWNED BY %s.%s;\n", seq.ident, seq.ownerIdent, seq.ownerColumnIdent)
+		}
+	}
+	fmt.Fprintln(d.w)
+	return nil
+}
+
+// createTable writes the CREATE TABLE statement of a table or partition
+func (d *pgDumper) createTable(rel pgDumpRelation) error {
+	fmt.Fprintf(d.w, "CREATE TABLE %s", rel.ident)
+	if rel.parent != "" {
+		// Partitions take their columns from the parent
+		fmt.Fprintf(d.w, " PARTITION OF %s %s", rel.parent, rel.bound)
+	} else {
+		rows, err := d.tx.Query(d.ctx, `SELECT quote_ident(a.attname), format_type(a.atttypid, a.atttypmod), a.attnotnull,
+				COALESCE(pg_get_expr(ad.adbin, ad.adrelid), ''), a.attidentity::text, a.attgenerated::text
+			FROM pg_attribute a LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+			WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum`, rel.oid)
+		if err != nil {
+			return err
+		}
+		var columns []string
+		for rows.Next() {
+			var name, typ, def, identity, generated string
+			var notNull bool
+			if err := rows.Scan(&name, &typ, &notNull, &def, &identity, &generated); err != nil {
+				rows.Close()
+				return err
+			}
+			col := "    " + name + " " + typ
+			switch {
+			case generated == "s":
+				col += " GENERATED ALWAYS AS (" + def + ") STORED"
+			case identity == "a":
+				col += " GENERATED ALWAYS AS IDENTITY"
+			case identity == "d":
+				col += " GENERATED BY DEFAULT AS IDENTITY"
+			case def != "":
+				col += " DEFAULT " + def
+			}
+			if notNull {
+				col += " NOT NULL"
+			}
+			columns = append(columns, col)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(d.w, " (\n%s\n)", strings.Join(columns, ",\n"))
+	}
+	if rel.partBy != "" {
+		fmt.Fprintf(d.w, " PARTITION BY %s", rel.partBy)
+	}
+	fmt.Fprint(d.w, ";\n\n")
+	return nil
+}
+
+// data writes the rows of every table as COPY blocks, then moves the sequences to where they were
+func (d *pgDumper) data(relations []pgDumpRelation, sequences []pgDumpSequence) error {
+	for _, rel := range relations {
+		// A partitioned table holds no rows itself; its partitions are copied instead
+		if rel.kind != "r" {
+			continue
+		}
+		columns, err := d.list(`SELECT quote_ident(attname) FROM pg_attribute
+			WHERE attrelid = $1 AND attnum > 0 AND NOT attisdropped AND attgenerated = '' ORDER BY attnum`, rel.oid)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+		list := strings.Join(columns, ", ")
+		fmt.Fprintf(d.w, "--\n-- Data for %s\n--\n\nCOPY %s (%s) FROM stdin;\n", rel.name, rel.ident, list)
+		if _, err := d.tx.Conn().PgConn().CopyTo(d.ctx, d.w, fmt.Sprintf("COPY %s (%s) TO STDOUT", rel.ident, list)); err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+		fmt.Fprint(d.w, "\\.\n\n")
+	}
+
+	for _, seq := range sequences {
+		var last int64
+		var called bool
+		if err := d.tx.QueryRow(d.ctx, "SELECT last_value, is_called FROM "+seq.ident).Scan(&last, &called); err != nil {
+			return fmt.Errorf("sequence %s: %w", seq.ident, err)
+		}
+		// An identity column's sequence gets a new name when the table is created, so it is looked up
+		name := pgLiteral(seq.ident)
+		if seq.ownerKind == "i" {
+			name = fmt.Sprintf("pg_catalog.pg_get_serial_sequence(%s, %s)", pgLiteral(seq.ownerIdent), pgLiteral(seq.ownerColumn))
+		}
+		fmt.Fprintf(d.w, "SELECT pg_catalog.setval(%s, %d, %t);\n", name, last, called)
+	}
+	fmt.Fprintln(d.w)
+	return nil
+}
+
+// postData writes what is faster to build once the rows are in: views (materialized ones are
+// filled here), constraints, indexes and foreign keys, then triggers
+func (d *pgDumper) postData(relations []pgDumpRelation) error {
+	for _, rel := range relations {
+		if rel.kind != "v" && rel.kind != "m" {
+			continue
+		}
+		var def string
+		if err := d.tx.QueryRow(d.ctx, "SELECT pg_get_viewdef($1::oid, true)", rel.oid).Scan(&def); err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+		fmt.Fprintf(d.w, "CREATE %s %s AS\n%s;\n\n", pgRelationKind(rel.kind), rel.ident, strings.TrimRight(def, "; \n"))
+	}
+
+	var foreignKeys []string
+	for _, rel := range relations {
+		if rel.kind != "r" && rel.kind != "p" && rel.kind != "m" {
+			continue
+		}
+		rows, err := d.tx.Query(d.ctx, `SELECT quote_ident(conname), contype::text, pg_get_constraintdef(oid) FROM pg_constraint
+			WHERE conrelid = $1 AND contype IN ('p', 'u', 'c', 'x', 'f') AND conislocal ORDER BY contype, conname`, rel.oid)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+		for rows.Next() {
+			var name, kind, def string
+			if err := rows.Scan(&name, &kind, &def); err != nil {
+				rows.Close()
+				return err
+			}
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;\n", rel.ident, name, def)
+			if kind == "f" {
+				foreignKeys = append(foreignKeys, stmt)
+			} else {
+				fmt.Fprint(d.w, stmt)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Indexes of constraints come with them, and those of partitions with the parent's index
+		indexes, err := d.list(`SELECT pg_get_indexdef(i.indexrelid) FROM pg_index i WHERE i.indrelid = $1
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid AND c.contype IN ('p', 'u', 'x'))
+			AND NOT EXISTS (SELECT 1 FROM pg_inherits h WHERE h.inhrelid = i.indexrelid)
+			ORDER BY i.indexrelid`, rel.oid)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+		for _, index := range indexes {
+			// ON ONLY would leave the partitions without the index
+			fmt.Fprintf(d.w, "%s;\n", strings.Replace(index, " ON ONLY ", " ON ", 1))
+		}
+	}
+	fmt.Fprintln(d.w)
+	for _, fk := range foreignKeys {
+		fmt.Fprint(d.w, fk)
+	}
+	fmt.Fprintln(d.w)
+
+	for _, rel := range relations {
+		// Triggers cloned onto partitions (tgparentid, PostgreSQL 13+) come with the parent's
+		triggers, err := d.list(`SELECT pg_get_triggerdef(t.oid) FROM pg_trigger t WHERE t.tgrelid = $1 AND NOT t.tgisinternal
+			AND COALESCE((to_jsonb(t) ->> 'tgparentid')::oid, 0::oid) = 0::oid ORDER BY t.tgname`, rel.oid)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel.name, err)
+		}
+		for _, trigger := range triggers {
+			fmt.Fprintf(d.w, "%s;\n", trigger)
+		}
+	}
+	return nil
+}
+
+// pgRelationKind is the SQL keyword for a relation kind
+func pgRelationKind(kind string) string {
+	switch kind {
+	case "v":
+		return "VIEW"
+	case "m":
+		return "MATERIALIZED VIEW"
+	}
+	return "TABLE"
+}
+
+// pgLiteral quotes a PostgreSQL string literal (standard_conforming_strings on)
+func pgLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// ---------------- MongoDB ----------------
+
+// dumpMongoBuiltin writes database to outPath in mongodump's layout, which mongorestore reads:
+// <outPath>/<database>/<collection>.bson holds the documents and <collection>.metadata.json the
+// options and indexes in extended JSON. Views are written as metadata only.
+func dumpMongoBuiltin(uri, database, outPath string, opts BackupOptions) error {
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, opts.Conn.mongoClientOptions(uri))
+	if err == nil {
+		if err = client.Ping(ctx, nil); err != nil {
+			_ = client.Disconnect(ctx)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = client.Disconnect(ctx) }()
+	mdb := client.Database(database)
+
+	specs, err := mdb.ListCollectionSpecifications(ctx, bson.D{})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+	specs = slices.DeleteFunc(specs, func(spec *mongo.CollectionSpecification) bool {
+		return strings.HasPrefix(spec.Name, "system.")
+	})
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	var names []string
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	for _, collection := range opts.Collections {
+		if !slices.Contains(names, collection) {
+			return fmt.Errorf("collection '%s' not found in %s", collection, database)
+		}
+	}
+	selected := selectTables(names, BackupOptions{Tables: opts.Collections, ExcludeTables: opts.ExcludeCollections})
+
+	dir := filepath.Join(outPath, database)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if !slices.Contains(selected, spec.Name) {
+			continue
+		}
+		coll := mdb.Collection(spec.Name)
+		var indexes []bson.Raw
+		if spec.Type != "view" {
+			n, err := dumpMongoDocuments(ctx, coll, filepath.Join(dir, spec.Name+".bson"))
+			if err != nil {
+				return fmt.Errorf("%s: %w", spec.Name, err)
+			}
+			fmt.Printf("📄 %s.%s: %d documents\n", database, spec.Name, n)
+			cursor, err := coll.Indexes().List(ctx)
+			if err != nil {
+				return fmt.Errorf("%s: failed to list indexes: %w", spec.Name, err)
+			}
+			for cursor.Next(ctx) {
+				indexes = append(indexes, slices.Clone(cursor.Current))
+			}
+			err = cursor.Err()
+			_ = cursor.Close(ctx)
+			if err != nil {
+				return fmt.Errorf("%s: failed to list indexes: %w", spec.Name, err)
+			}
+		}
+
+		collOptions := spec.Options
+		if collOptions == nil {
+			collOptions, _ = bson.Marshal(bson.D{})
+		}
+		uuid := ""
+		if spec.UUID != nil {
+			uuid = hex.EncodeToString(spec.UUID.Data)
+		}
+		metadata, err := bson.MarshalExtJSON(bson.D{
+			{Key: "options", Value: collOptions},
+			{Key: "indexes", Value: indexes},
+			{Key: "uuid", Value: uuid},
+			{Key: "collectionName", Value: spec.Name},
+			{Key: "type", Value: spec.Type},
+		}, true, false)
+		if err != nil {
+			return fmt.Errorf("%s: %w", spec.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, spec.Name+".metadata.json"), metadata, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpMongoDocuments writes every document of a collection to file as concatenated BSON
+func dumpMongoDocuments(ctx context.Context, coll *mongo.Collection, file string) (n int, err error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	cursor, err := coll.Find(ctx, bson.D{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+	w := bufio.NewWriterSize(f, 1<<20)
+	for cursor.Next(ctx) {
+		if _, err := w.Write(cursor.Current); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := cursor.Err(); err != nil {
+		return n, err
+	}
+	return n, w.Flush()
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// The escaping helpers are unexported, so they are tested here rather than in tests/internal/db,
+// which covers the built-in dumper end to end.
+
+// TestMySQLQuote tests the escapes of string literals in built-in MySQL dumps
+func TestMySQLQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "shop", `'shop'`},
+		{"empty", "", `''`},
+		{"NUL", "a\x00b", `'a\0b'`},
+		{"Ctrl-Z", "a\x1ab", `'a\Zb'`},
+		{"single quote", "O'Brien", `'O\'Brien'`},
+		{"double quote", `say "hi"`, `'say \"hi\"'`},
+		{"backslash", `C:\temp\`, `'C:\\temp\\'`},
+		{"line breaks", "one\ntwo\r\n", `'one\ntwo\r\n'`},
+		{"tab kept", "a\tb", "'a\tb'"},
+		{"UTF-8 kept", "naïve – ✓", `'naïve – ✓'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mysqlQuote(tt.in); got != tt.want {
+				t.Errorf("mysqlQuote(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMySQLDumpValue tests how values of each column type are written, as mysqldump --hex-blob does
+func TestMySQLDumpValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      sql.RawBytes
+		typeName string
+		want     string
+	}{
+		{"NULL", nil, "VARCHAR", "NULL"},
+		{"NULL binary", nil, "BLOB", "NULL"},
+		{"integer", sql.RawBytes("-42"), "INT", "-42"},
+		{"unsigned", sql.RawBytes("18446744073709551615"), "UNSIGNED BIGINT", "18446744073709551615"},
+		{"decimal", sql.RawBytes("19.99"), "DECIMAL", "19.99"},
+		{"empty string", sql.RawBytes{}, "VARCHAR", "''"},
+		{"string with escapes", sql.RawBytes("it's a\\b\x00"), "VARCHAR", `'it\'s a\\b\0'`},
+		{"datetime quoted", sql.RawBytes("2024-01-02 03:04:05"), "DATETIME", "'2024-01-02 03:04:05'"},
+		{"JSON quoted", sql.RawBytes(`{"path":"C:\\"}`), "JSON", `'{\"path\":\"C:\\\\\"}'`},
+		{"blob in hex", sql.RawBytes{0x00, 0x1a, '\'', '\\', 0xff}, "BLOB", "0x001A275CFF"},
+		{"varbinary in hex", sql.RawBytes("ab"), "VARBINARY", "0x6162"},
+		{"empty blob", sql.RawBytes{}, "LONGBLOB", "''"},
+		{"bit", sql.RawBytes{0x01}, "BIT", "0x01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mysqlDumpValue(tt.raw, tt.typeName); got != tt.want {
+				t.Errorf("mysqlDumpValue(%q, %s) = %s, want %s", tt.raw, tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDefinerClause tests that DEFINER clauses are stripped from view and trigger definitions
+func TestDefinerClause(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"quoted",
+			"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1",
+			"CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v` AS select 1",
+		},
+		{
+			"unquoted",
+			"CREATE DEFINER=app@localhost TRIGGER `t` BEFORE INSERT ON `items` FOR EACH ROW SET NEW.n = 1",
+			"CREATE TRIGGER `t` BEFORE INSERT ON `items` FOR EACH ROW SET NEW.n = 1",
+		},
+		{
+			"host with dots and user with @",
+			"CREATE DEFINER=`ops@corp`@`10.0.%` TRIGGER `t` AFTER DELETE ON `items` FOR EACH ROW DELETE FROM `log`",
+			"CREATE TRIGGER `t` AFTER DELETE ON `items` FOR EACH ROW DELETE FROM `log`",
+		},
+		{
+			"no definer",
+			"CREATE VIEW `v` AS select 'DEFINER' AS `word`",
+			"CREATE VIEW `v` AS select 'DEFINER' AS `word`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := definerClause.ReplaceAllString(tt.in, ""); got != tt.want {
+				t.Errorf("stripped = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPgLiteral tests PostgreSQL string literals with standard_conforming_strings on
+func TestPgLiteral(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"public.users_id_seq", `'public.users_id_seq'`},
+		{"", `''`},
+		{"O'Brien", `'O''Brien'`},
+		{"''", `''''''`},
+		{`C:\temp`, `'C:\temp'`},
+		{`"Mixed Case".seq`, `'"Mixed Case".seq'`},
+	}
+	for _, tt := range tests {
+		if got := pgLiteral(tt.in); got != tt.want {
+			t.Errorf("pgLiteral(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package db
+
+import (
+	"bytes"
+	"dbx/internal/logs"
+	"dbx/internal/notify"
+	"dbx/internal/utils"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	osuser "os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clickhouseIndexFile is the restore plan inside a ClickHouse backup bundle
+const clickhouseIndexFile = "dbx-clickhouse.json"
+
+// clickhouseSchemaOnly are the engines whose tables hold no rows of their own; they are backed up
+// as their CREATE statement and restored after the tables they read from
+var clickhouseSchemaOnly = map[string]bool{
+	"View": true, "MaterializedView": true, "LiveView": true, "WindowView": true, "Dictionary": true,
+}
+
+// ClickHouseTable is one table of a ClickHouse backup bundle
+type ClickHouseTable struct {
+	Name   string `json:"name"`
+	Engine string `json:"engine"`
+	Schema string `json:"schema,omitempty"` // CREATE statement file; empty in data-only backups
+	Data   string `json:"data,omitempty"`   // rows in Native format; empty for views and schema-only backups
+}
+
+// ClickHouseIndex describes a ClickHouse backup: a zip of one CREATE statement and one Native data
+// file per table. Views, materialized views and dictionaries come last, so they are created once
+// the tables they read from exist.
+type ClickHouseIndex struct {
+	Database string            `json:"database"`
+	Tables   []ClickHouseTable `json:"tables"`
+}
+
+// BackupClickHouse creates a backup of a ClickHouse database
+func BackupClickHouse(host, port, user, password, database, outDir string) error {
+	return BackupClickHouseWithOptions(host, port, user, password, database, outDir, BackupOptions{})
+}
+
+// BackupClickHouseWithOptions exports every table of a ClickHouse database with clickhouse-client,
+// opts.Jobs tables at a time: its CREATE statement and its rows in Native format. opts.Tables and
+// opts.ExcludeTables select tables and opts.Content leaves out schema or data. The files are bundled
+// into <database>-full_<timestamp>.zip. Each table is read in its own query, so the backup is
+// consistent per table, not across tables.
+func BackupClickHouseWithOptions(host, port, user, password, database, outDir string, opts BackupOptions) (err error) {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case opts.MaskRules != "":
+		return fmt.Errorf("masking is supported for MySQL, PostgreSQL and SQLite backups only")
+	case len(opts.Collections) > 0 || len(opts.ExcludeCollections) > 0:
+		return fmt.Errorf("ClickHouse backups select tables, not collections")
+	case database == "":
+		return fmt.Errorf("database name cannot be empty")
+	}
+	start := time.Now()
+	ts := time.Now().Format("2006-01-02_15-04")
+	suffix := "full"
+	if label := opts.fileLabel(); label != "" {
+		suffix += "_" + label
+	}
+	bundleDir := filepath.Join(outDir, fmt.Sprintf("%s-%s_%s", database, suffix, ts))
+
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("ClickHouse", "Backup", status, start, err)
+
+		// Send Slack notification if webhook is configured
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
+			duration := time.Since(start).Round(time.Second)
+			hostname, _ := os.Hostname()
+			username := "unknown"
+			if u, e := osuser.Current(); e == nil {
+				username = u.Username
+			}
+
+			message := fmt.Sprintf("ClickHouse Backup %s\nDatabase: %s\nDuration: %s\nHost: %s\nUser: %s",
+				status, database, duration, hostname, username)
+			if err != nil {
+				message += fmt.Sprintf("\nError: %v", err)
+			}
+			_ = notify.SlackNotify(webhook, message)
+		}
+	}()
+
+	client, err := newClickHouseClient(host, port, user, password, opts.Conn)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	tables, engines, err := client.listTables(database)
+	if err != nil {
+		return err
+	}
+	tables = selectTables(tables, opts)
+	if len(tables) == 0 {
+		return fmt.Errorf("no tables to back up in %s", database)
+	}
+
+	if err := os.MkdirAll(bundleDir, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(bundleDir)
+
+	// Numbered file names, since table names may hold characters that aren't safe in paths;
+	// tables first, then the objects that read from them
+	index := ClickHouseIndex{Database: database}
+	entries := make(map[string]*ClickHouseTable)
+	var views []ClickHouseTable
+	for i, table := range tables {
+		entry := ClickHouseTable{Name: table, Engine: engines[table]}
+		if opts.Content != ContentData {
+			entry.Schema = fmt.Sprintf("table-%04d.sql", i+1)
+		}
+		if clickhouseSchemaOnly[entry.Engine] {
+			if entry.Schema != "" {
+				views = append(views, entry)
+			}
+			continue
+		}
+		if opts.Content != ContentSchema {
+			entry.Data = fmt.Sprintf("table-%04d.native", i+1)
+		}
+		index.Tables = append(index.Tables, entry)
+	}
+	index.Tables = append(index.Tables, views...)
+	for i := range index.Tables {
+		entries[index.Tables[i].Name] = &index.Tables[i]
+	}
+
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	fmt.Printf("🔄 Running ClickHouse backup of %d tables...\n", len(index.Tables))
+	var mu sync.Mutex
+	done := 0
+	err = runParallel(jobs, tables, func(table string) error {
+		entry, ok := entries[table]
+		if !ok {
+			return nil // a view in a data-only backup
+		}
+		if err := client.exportTable(database, bundleDir, *entry); err != nil {
+			return err
+		}
+		mu.Lock()
+		done++
+		fmt.Printf("📦 [%d/%d] %s\n", done, len(index.Tables), table)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("clickhouse-client export failed: %w", err)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(bundleDir, clickhouseIndexFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle index: %w", err)
+	}
+
+	zipPath := bundleDir + ".zip"
+	if err := utils.CompressFolder(bundleDir, zipPath); err != nil {
+		return fmt.Errorf("failed to bundle table exports: %w", err)
+	}
+	saveManifest(zipPath, "clickhouse", database, BackupTypeFull, opts)
+	fmt.Println("✅ Backup completed:", zipPath)
+	return nil
+}
+
+// clickhouseClient runs clickhouse-client with fixed connection arguments. The password and TLS
+// settings are kept in a temporary client config file rather than on the command line.
+type clickhouseClient struct {
+	bin    string
+	args   []string
+	config string
+	secure bool
+}
+
+// clickhouseConfig is the part of clickhouse-client's config file dbx writes
+type clickhouseConfig struct {
+	XMLName  xml.Name       `xml:"config"`
+	Password string         `xml:"password,omitempty"`
+	Client   *clickhouseTLS `xml:"openSSL>client"`
+}
+
+// clickhouseTLS are clickhouse-client's openSSL.client settings
+type clickhouseTLS struct {
+	CAConfig         string `xml:"caConfig,omitempty"`
+	CertificateFile  string `xml:"certificateFile,omitempty"`
+	PrivateKeyFile   string `xml:"privateKeyFile,omitempty"`
+	VerificationMode string `xml:"verificationMode"`
+	Handler          string `xml:"invalidCertificateHandler>name"`
+}
+
+// newClickHouseClient finds clickhouse-client (or the clickhouse binary's client mode) and
+// prepares its connection arguments; close removes the config file
+func newClickHouseClient(host, port, user, password string, conn ConnOptions) (*clickhouseClient, error) {
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	if conn.Socket != "" {
+		return nil, fmt.Errorf("clickhouse-client can't connect through a socket (use --host)")
+	}
+	c := &clickhouseClient{bin: "clickhouse-client"}
+	if _, err := exec.LookPath(c.bin); err != nil {
+		if _, err := exec.LookPath("clickhouse"); err != nil {
+			showClickHouseInstallHelp()
+			return nil, fmt.Errorf("clickhouse-client not found in PATH (nor clickhouse)")
+		}
+		c.bin, c.args = "clickhouse", []string{"client"}
+	}
+	c.args = append(c.args, "--host", host)
+	if port != "" {
+		c.args = append(c.args, "--port", port)
+	}
+	if user != "" {
+		c.args = append(c.args, "--user", user)
+	}
+
+	cfg := clickhouseConfig{Password: password}
+	// ClickHouse has no TLS negotiation, so prefer without certificates connects in plain text
+	secure := conn.TLSMode != "disable" && (conn.TLSMode != "" && conn.TLSMode != "prefer" || conn.TLSCA != "" || conn.TLSCert != "")
+	if secure {
+		c.secure = true
+		c.args = append(c.args, "--secure")
+		cfg.Client = &clickhouseTLS{CAConfig: conn.TLSCA, CertificateFile: conn.TLSCert, PrivateKeyFile: conn.TLSKey}
+		if cfg.Client.PrivateKeyFile == "" {
+			cfg.Client.PrivateKeyFile = conn.TLSCert
+		}
+		switch {
+		case conn.TLSMode == "require" && conn.TLSCA == "":
+			cfg.Client.VerificationMode, cfg.Client.Handler = "none", "AcceptCertificateHandler"
+		case conn.TLSMode == "verify-ca":
+			cfg.Client.VerificationMode, cfg.Client.Handler = "relaxed", "RejectCertificateHandler"
+		default:
+			if conn.TLSServerName != "" {
+				return nil, fmt.Errorf("%s can't check the certificate of %s through --ssh-host; use --tls-mode verify-ca", c.bin, conn.TLSServerName)
+			}
+			cfg.Client.VerificationMode, cfg.Client.Handler = "strict", "RejectCertificateHandler"
+		}
+	}
+	if cfg.Password == "" && cfg.Client == nil {
+		return c, nil
+	}
+
+	data, err := xml.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "dbx-clickhouse-*.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write clickhouse-client config: %w", err)
+	}
+	c.config = f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("failed to write clickhouse-client config: %w", err)
+	}
+	c.args = append(c.args, "--config-file", c.config)
+	return c, nil
+}
+
+func (c *clickhouseClient) close() {
+	if c.config != "" {
+		_ = os.Remove(c.config)
+	}
+}
+
+// command builds a clickhouse-client command with the connection arguments followed by args
+func (c *clickhouseClient) command(args ...string) *exec.Cmd {
+	return exec.Command(c.bin, append(append([]string{}, c.args...), args...)...)
+}
+
+// run runs a clickhouse-client command and returns its output, or its stderr as the error
+func (c *clickhouseClient) run(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	if cmd.Stdout == nil {
+		cmd.Stdout = &stdout
+	}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// listTables returns a database's tables with their engines, leaving out the inner tables of
+// materialized views, which are recreated with the views
+func (c *clickhouseClient) listTables(database string) ([]string, map[string]string, error) {
+	out, err := c.run(c.command("--database", database, "--query",
+		"SELECT name, engine FROM system.tables WHERE database = currentDatabase() AND NOT is_temporary AND NOT startsWith(name, '.inner') ORDER BY name FORMAT TSVRaw"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var tables []string
+	engines := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		name, engine, ok := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		if !ok {
+			continue
+		}
+		tables = append(tables, name)
+		engines[name] = engine
+	}
+	return tables, engines, nil
+}
+
+// exportTable writes a table's CREATE statement and its rows into dir, as the entry names them
+func (c *clickhouseClient) exportTable(database, dir string, entry ClickHouseTable) error {
+	if entry.Schema != "" {
+		out, err := c.run(c.command("--database", database, "--query", "SHOW CREATE TABLE "+clickhouseIdent(entry.Name)+" FORMAT TSVRaw"))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Schema), out, 0644); err != nil {
+			return err
+		}
+	}
+	if entry.Data == "" {
+		return nil
+	}
+	f, err := os.Create(filepath.Join(dir, entry.Data))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	cmd := c.command("--database", database, "--query", "SELECT * FROM "+clickhouseIdent(entry.Name)+" FORMAT Native")
+	cmd.Stdout = f
+	_, err = c.run(cmd)
+	return err
+}
+
+// importFile runs an INSERT query with a file as its data
+func (c *clickhouseClient) importFile(database, query, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	cmd := c.command("--database", database, "--query", query)
+	cmd.Stdin = f
+	_, err = c.run(cmd)
+	return err
+}
+
+// clickhouseIdent quotes an identifier for a ClickHouse query
+func clickhouseIdent(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// plainIdent matches identifiers ClickHouse prints without quotes
+var plainIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// retargetCreate points a CREATE statement saved from database from at database to, including the
+// references to other objects of that database in view definitions
+func retargetCreate(stmt, from, to string) string {
+	if from == to {
+		return stmt
+	}
+	quoted := []string{clickhouseIdent(from)}
+	if plainIdent.MatchString(from) {
+		quoted = append(quoted, from)
+	}
+	for _, q := range quoted {
+		for _, sep := range []string{" ", "("} {
+			stmt = strings.ReplaceAll(stmt, sep+q+".", sep+clickhouseIdent(to)+".")
+		}
+	}
+	return stmt
+}
+
+// readClickHouseIndex reads a ClickHouse bundle's restore plan
+func readClickHouseIndex(dir string) (*ClickHouseIndex, error) {
+	data, err := os.ReadFile(filepath.Join(dir, clickhouseIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("not a ClickHouse backup (no %s): %w", clickhouseIndexFile, err)
+	}
+	var index ClickHouseIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle index: %w", err)
+	}
+	return &index, nil
+}
+
+func showClickHouseInstallHelp() {
+	fmt.Println("\n💡 clickhouse-client comes with ClickHouse:")
+	fmt.Println("   Ubuntu/Debian: sudo apt install clickhouse-client")
+	fmt.Println("   macOS:         brew install clickhouse")
+	fmt.Println("   Any platform:  curl https://clickhouse.com/ | sh")
+}
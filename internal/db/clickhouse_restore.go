@@ -0,0 +1,124 @@
+package db
+
+import (
+	"dbx/internal/logs"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RestoreClickHouse restores a ClickHouse database from a backup bundle
+func RestoreClickHouse(host, port, user, pass, dbName, backupFile string) error {
+	return RestoreClickHouseWithOptions(host, port, user, pass, dbName, backupFile, RestoreOptions{})
+}
+
+// RestoreClickHouseWithOptions restores every table of a bundle written by BackupClickHouse into
+// dbName, which is created if it doesn't exist: tables are created and filled opts.Jobs at a time,
+// then views, materialized views and dictionaries are created. dbName empty restores into the
+// database the backup was taken from. The tables must not exist yet.
+func RestoreClickHouseWithOptions(host, port, user, pass, dbName, backupFile string, opts RestoreOptions) error {
+	return restoreClickHouse(host, port, user, pass, dbName, backupFile, "", opts)
+}
+
+// RestoreClickHouseTableWithOptions restores a single table of a ClickHouse backup bundle
+func RestoreClickHouseTableWithOptions(host, port, user, pass, dbName, backupFile, table string, opts RestoreOptions) error {
+	if err := checkIncluded(backupFile, "table", table); err != nil {
+		return err
+	}
+	return restoreClickHouse(host, port, user, pass, dbName, backupFile, table, opts)
+}
+
+func restoreClickHouse(host, port, user, pass, dbName, backupFile, table string, opts RestoreOptions) (err error) {
+	start := time.Now()
+	if backupFile == "" {
+		return fmt.Errorf("backup file path cannot be empty")
+	}
+	if _, err := os.Stat(backupFile); err != nil {
+		return fmt.Errorf("backup file not found: %w", err)
+	}
+
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("ClickHouse", "Restore", status, start, err)
+	}()
+
+	dir, cleanup, err := openBundle(backupFile)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	index, err := readClickHouseIndex(dir)
+	if err != nil {
+		return err
+	}
+	if dbName == "" {
+		dbName = index.Database
+	}
+
+	var tables, views []string
+	entries := make(map[string]ClickHouseTable)
+	for _, entry := range index.Tables {
+		if table != "" && entry.Name != table {
+			continue
+		}
+		entries[entry.Name] = entry
+		if clickhouseSchemaOnly[entry.Engine] {
+			views = append(views, entry.Name)
+		} else {
+			tables = append(tables, entry.Name)
+		}
+	}
+	if table != "" && len(entries) == 0 {
+		return fmt.Errorf("table '%s' not found in backup bundle", table)
+	}
+
+	client, err := newClickHouseClient(host, port, user, pass, opts.Conn)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	if _, err := client.run(client.command("--query", "CREATE DATABASE IF NOT EXISTS "+clickhouseIdent(dbName))); err != nil {
+		return fmt.Errorf("failed to create database %s: %w", dbName, err)
+	}
+
+	restore := func(name string) error {
+		entry := entries[name]
+		if entry.Schema != "" {
+			stmt, err := os.ReadFile(filepath.Join(dir, entry.Schema))
+			if err != nil {
+				return err
+			}
+			query := retargetCreate(string(stmt), index.Database, dbName)
+			if _, err := client.run(client.command("--database", dbName, "--query", query)); err != nil {
+				return fmt.Errorf("create failed: %w", err)
+			}
+		}
+		if entry.Data != "" {
+			query := "INSERT INTO " + clickhouseIdent(name) + " FORMAT Native"
+			if err := client.importFile(dbName, query, filepath.Join(dir, entry.Data)); err != nil {
+				return fmt.Errorf("insert failed: %w", err)
+			}
+		}
+		return nil
+	}
+
+	warnIfPartial(backupFile)
+	jobs := restoreJobs(backupFile, opts.Jobs)
+	fmt.Printf("🔄 Restoring %d ClickHouse tables into %s with %d parallel jobs...\n", len(tables), dbName, jobs)
+	if err := runParallel(jobs, tables, restore); err != nil {
+		return err
+	}
+	for _, view := range views {
+		if err := restore(view); err != nil {
+			return fmt.Errorf("%s: %w", view, err)
+		}
+	}
+
+	fmt.Println("✅ ClickHouse restore completed successfully.")
+	return nil
+}
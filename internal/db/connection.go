@@ -1,152 +1,659 @@
 package db
 
 import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// defaultTestTimeout bounds a connection check unless params["timeout"] says otherwise
+const defaultTestTimeout = 5 * time.Second
+
+// Diagnosis is what a connection check learned about a server and the account used
+type Diagnosis struct {
+	Engine        string        `json:"engine"`
+	ServerVersion string        `json:"server_version,omitempty"`
+	Latency       time.Duration `json:"latency_ns"`    // round trip of a trivial query once connected
+	TLS           string        `json:"tls,omitempty"` // negotiated TLS version, or "off"
+	User          string        `json:"user,omitempty"`
+	Privileges    []string      `json:"privileges,omitempty"`
+	Missing       []string      `json:"missing,omitempty"` // privileges a dump needs that the account lacks
+}
+
 // TestConnection checks database connectivity before running backup.
 func TestConnection(dbType string, params map[string]string) error {
+	_, err := DiagnoseConnection(dbType, params)
+	return err
+}
+
+// DiagnoseConnection connects with the engine's Go driver and reports the server version, latency,
+// TLS status and the account's privileges, including any a dump needs but the account lacks.
+// params use the scheduler's keys (host, port, user, pass, dbname, uri, path and the connection
+// options); params["timeout"] bounds the check (default 5s).
+func DiagnoseConnection(dbType string, params map[string]string) (*Diagnosis, error) {
+	timeout := defaultTestTimeout
+	if value := params["timeout"]; value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", value)
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var diag *Diagnosis
+	var err error
 	switch strings.ToLower(dbType) {
 	case "mysql":
-		return testMySQL(params)
+		diag, err = diagnoseMySQL(ctx, params)
+	case "mariadb":
+		// Same protocol and grants; only the engine label differs
+		if diag, err = diagnoseMySQL(ctx, params); err == nil {
+			diag.Engine = "mariadb"
+		}
 	case "postgres", "postgresql":
-		return testPostgres(params)
+		diag, err = diagnosePostgres(ctx, params)
 	case "mongo", "mongodb":
-		return testMongo(params)
+		diag, err = diagnoseMongo(ctx, params)
 	case "sqlite":
-		return testSQLite(params)
+		diag, err = diagnoseSQLite(params)
+	case "redis":
+		diag, err = diagnoseRedis(ctx, params)
+	case "etcd":
+		diag, err = diagnoseEtcd(ctx, params)
+	case "consul":
+		diag, err = diagnoseConsul(ctx, params)
+	case "elasticsearch", "opensearch":
+		diag, err = diagnoseElasticsearch(ctx, params)
+	case "clickhouse":
+		diag, err = diagnoseClickHouse(ctx, params)
 	default:
-		return fmt.Errorf("unsupported database type: %s", dbType)
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("connection timed out after %s: %w", timeout, err)
+	}
+	return diag, err
+}
+
+// timed runs fn and returns how long it took
+func timed(fn func() error) (time.Duration, error) {
+	start := time.Now()
+	err := fn()
+	return time.Since(start), err
 }
 
 // ---------------- MySQL ----------------
-func testMySQL(params map[string]string) error {
-	host := params["host"]
-	user := params["user"]
-	pass := params["pass"]
-	db := params["dbname"]
 
-	if _, err := exec.LookPath("mysql"); err != nil {
-		return errors.New("mysql client not found in PATH")
-	}
+// mysqlDumpPrivileges are what mysqldump needs: reading rows, views and triggers, locking tables
+// without --single-transaction, PROCESS for tablespace info and REPLICATION CLIENT for binlog positions
+var mysqlDumpPrivileges = []string{"SELECT", "SHOW VIEW", "TRIGGER", "LOCK TABLES", "PROCESS", "REPLICATION CLIENT"}
 
-	args := []string{"-h", host, "-u", user, "-e", "SELECT 1", db}
-	if pass != "" {
-		args = []string{"-h", host, "-u", user, fmt.Sprintf("--password=%s", pass), "-e", "SELECT 1", db}
+// mysqlGlobalPrivileges can only be granted ON *.*
+var mysqlGlobalPrivileges = map[string]bool{"PROCESS": true, "REPLICATION CLIENT": true}
+
+var mysqlGrantPattern = regexp.MustCompile("^GRANT (.+?) ON (\\S+) TO ")
+
+// MissingMySQLPrivileges returns the dump privileges (see mysqlDumpPrivileges) that SHOW GRANTS
+// output doesn't give on database
+func MissingMySQLPrivileges(grants []string, database string) []string {
+	have := make(map[string]bool)
+	for _, grant := range grants {
+		m := mysqlGrantPattern.FindStringSubmatch(grant)
+		if m == nil {
+			continue
+		}
+		scope := strings.ReplaceAll(m[2], "`", "")
+		global := scope == "*.*"
+		if !global && scope != database+".*" {
+			continue
+		}
+		for _, priv := range strings.Split(m[1], ",") {
+			priv = strings.ToUpper(strings.TrimSpace(priv))
+			if priv == "ALL" || priv == "ALL PRIVILEGES" {
+				for _, p := range mysqlDumpPrivileges {
+					if global || !mysqlGlobalPrivileges[p] {
+						have[p] = true
+					}
+				}
+				continue
+			}
+			if global || !mysqlGlobalPrivileges[priv] {
+				have[priv] = true
+			}
+		}
+	}
+	var missing []string
+	for _, p := range mysqlDumpPrivileges {
+		if !have[p] {
+			missing = append(missing, p)
+		}
 	}
+	return missing
+}
 
-	cmd := exec.Command("mysql", args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
+func diagnoseMySQL(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	conn := ConnOptionsFromParams(params)
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	db, err := openMySQL(params["host"], params["user"], params["pass"], params["dbname"], conn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
 
-	// Run with timeout to avoid hang if something goes wrong
-	done := make(chan error, 1)
-	go func() { done <- cmd.Run() }()
+	diag := &Diagnosis{Engine: "mysql", TLS: "off"}
+	if diag.Latency, err = timed(func() error { return db.PingContext(ctx) }); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT VERSION(), CURRENT_USER()").Scan(&diag.ServerVersion, &diag.User); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+	var name, sslVersion string
+	if err := db.QueryRowContext(ctx, "SHOW SESSION STATUS LIKE 'Ssl_version'").Scan(&name, &sslVersion); err == nil && sslVersion != "" {
+		diag.TLS = sslVersion
+	}
 
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("connection failed: %w", err)
+	rows, err := db.QueryContext(ctx, "SHOW GRANTS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grants: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return nil, fmt.Errorf("failed to read grants: %w", err)
 		}
-	case <-time.After(5 * time.Second):
-		cmd.Process.Kill()
-		return errors.New("connection timed out (possibly waiting for password input)")
+		diag.Privileges = append(diag.Privileges, grant)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read grants: %w", err)
+	}
+	diag.Missing = MissingMySQLPrivileges(diag.Privileges, params["dbname"])
+	return diag, nil
+}
 
-	return nil
+// openMySQL opens a MySQL connection pool with the TLS behaviour of the mysql client for conn.TLSMode
+func openMySQL(host, user, password, database string, conn ConnOptions) (*sql.DB, error) {
+	if host == "" {
+		host = "localhost"
+	}
+	port := conn.Port
+	if port == "" {
+		port = "3306"
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = user
+	cfg.Passwd = password
+	cfg.DBName = database
+	cfg.Net, cfg.Addr = "tcp", net.JoinHostPort(host, port)
+	if conn.Socket != "" {
+		cfg.Net, cfg.Addr = "unix", conn.Socket
+	}
+	tlsCfg, err := conn.tlsConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tlsCfg != nil:
+		cfg.TLS = tlsCfg
+	case conn.TLSMode == "disable":
+		cfg.TLSConfig = "false"
+	default:
+		cfg.TLSConfig = "preferred"
+	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
 }
 
 // ---------------- PostgreSQL ----------------
-// ---------------- PostgreSQL ----------------
-func testPostgres(params map[string]string) error {
-	host := params["host"]
-	port := params["port"]
-	user := params["user"]
-	pass := params["pass"]
-	db := params["dbname"]
 
-	if _, err := exec.LookPath("psql"); err != nil {
-		return errors.New("psql not found in PATH")
+// postgresConfig builds a pgx connection config in key/value form, so libpq's sslmode/sslrootcert/
+// sslcert/sslkey apply as they do for pg_dump
+func postgresConfig(host, port, user, password, database string, conn ConnOptions) (*pgx.ConnConfig, error) {
+	if conn.Socket != "" {
+		host = conn.Socket
+	}
+	settings := map[string]string{
+		"host":        host,
+		"port":        port,
+		"user":        user,
+		"password":    password,
+		"dbname":      database,
+		"sslmode":     conn.TLSMode,
+		"sslrootcert": conn.TLSCA,
+		"sslcert":     conn.TLSCert,
+		"sslkey":      conn.TLSKey,
+	}
+	var parts []string
+	for key, value := range settings {
+		if value != "" {
+			quoted := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+			parts = append(parts, fmt.Sprintf("%s='%s'", key, quoted))
+		}
+	}
+	sort.Strings(parts)
+	cfg, err := pgx.ParseConfig(strings.Join(parts, " "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection settings: %w", err)
+	}
+	// Through an SSH tunnel, check the certificate against the database's own host name
+	if conn.TLSServerName != "" {
+		if cfg.TLSConfig != nil {
+			cfg.TLSConfig.ServerName = conn.TLSServerName
+		}
+		for _, fallback := range cfg.Fallbacks {
+			if fallback.TLSConfig != nil {
+				fallback.TLSConfig.ServerName = conn.TLSServerName
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func diagnosePostgres(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	conn := ConnOptionsFromParams(params)
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	database := params["dbname"]
+	if database == "" {
+		database = "postgres"
+	}
+	cfg, err := postgresConfig(params["host"], params["port"], params["user"], params["pass"], database, conn)
+	if err != nil {
+		return nil, err
+	}
+	pg, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = pg.Close(context.Background()) }()
+
+	diag := &Diagnosis{Engine: "postgres", TLS: "off"}
+	if diag.Latency, err = timed(func() error { return pg.Ping(ctx) }); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	if tlsConn, ok := pg.PgConn().Conn().(*tls.Conn); ok {
+		diag.TLS = tls.VersionName(tlsConn.ConnectionState().Version)
 	}
 
-	// Only set PGPASSWORD if a password is provided
-	if pass != "" {
-		os.Setenv("PGPASSWORD", pass)
-	} else {
-		os.Unsetenv("PGPASSWORD")
+	var superuser, replication, readAllData bool
+	var unreadable int
+	err = pg.QueryRow(ctx, `SELECT current_setting('server_version'), current_user, r.rolsuper, r.rolreplication,
+		EXISTS (SELECT 1 FROM pg_roles g WHERE g.rolname = 'pg_read_all_data' AND pg_has_role(current_user, g.oid, 'MEMBER')),
+		(SELECT count(*) FROM pg_tables t WHERE t.schemaname NOT IN ('pg_catalog', 'information_schema')
+			AND NOT has_table_privilege(format('%I.%I', t.schemaname, t.tablename), 'SELECT'))
+		FROM pg_roles r WHERE r.rolname = current_user`).
+		Scan(&diag.ServerVersion, &diag.User, &superuser, &replication, &readAllData, &unreadable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read privileges: %w", err)
 	}
+	for name, has := range map[string]bool{"SUPERUSER": superuser, "REPLICATION": replication, "pg_read_all_data": readAllData} {
+		if has {
+			diag.Privileges = append(diag.Privileges, name)
+		}
+	}
+	sort.Strings(diag.Privileges)
+	if !superuser && !readAllData && unreadable > 0 {
+		diag.Missing = append(diag.Missing, fmt.Sprintf("SELECT on %d tables (or membership in pg_read_all_data)", unreadable))
+	}
+	return diag, nil
+}
 
-	args := []string{"-h", host, "-p", port, "-U", user, "-d", db, "-c", "\\q"}
+// ---------------- MongoDB ----------------
 
-	cmd := exec.Command("psql", args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
+// mongoDumpRoles are roles that can read a whole database for mongodump
+var mongoDumpRoles = map[string]bool{"root": true, "backup": true, "readAnyDatabase": true, "readWriteAnyDatabase": true}
 
-	done := make(chan error, 1)
-	go func() { done <- cmd.Run() }()
+// mongoDatabaseRoles can read the database they are granted on
+var mongoDatabaseRoles = map[string]bool{"read": true, "readWrite": true, "dbOwner": true}
 
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("connection failed: %w", err)
+// MissingMongoRoles reports what a mongodump of database needs when none of roles (as role@db) grant it
+func MissingMongoRoles(roles []string, database string) []string {
+	for _, r := range roles {
+		role, db, _ := strings.Cut(r, "@")
+		if mongoDumpRoles[role] && db == "admin" || mongoDatabaseRoles[role] && db == database {
+			return nil
 		}
-	case <-time.After(5 * time.Second):
-		cmd.Process.Kill()
-		return errors.New("connection timed out (possibly waiting for password input)")
 	}
-	return nil
+	return []string{fmt.Sprintf("read on %s (or backup)", database)}
 }
 
-// ---------------- MongoDB ----------------
-// ---------------- MongoDB ----------------
-func testMongo(params map[string]string) error {
+// mongoClientOptions returns the Go driver's options for a URI built by mongoURI. Through an SSH
+// tunnel the certificate is checked against TLSServerName rather than the tunnel's address.
+func (c ConnOptions) mongoClientOptions(uri string) *options.ClientOptions {
+	clientOpts := options.Client().ApplyURI(uri)
+	if clientOpts.TLSConfig != nil && c.TLSServerName != "" {
+		clientOpts.TLSConfig.ServerName = c.TLSServerName
+	}
+	return clientOpts
+}
+
+func diagnoseMongo(ctx context.Context, params map[string]string) (*Diagnosis, error) {
 	uri := params["uri"]
 	if uri == "" {
 		uri = "mongodb://localhost:27017"
 	}
+	conn := ConnOptionsFromParams(params)
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	uri, cleanup, err := conn.mongoURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	clientOpts := conn.mongoClientOptions(uri)
+	if deadline, ok := ctx.Deadline(); ok {
+		clientOpts.SetServerSelectionTimeout(time.Until(deadline))
+	}
+	client, err := mongo.Connect(ctx, clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
 
-	clientCmd := ""
-	if _, err := exec.LookPath("mongosh"); err == nil {
-		clientCmd = "mongosh"
-	} else if _, err := exec.LookPath("mongo"); err == nil {
-		clientCmd = "mongo"
-	} else {
-		return errors.New("neither mongo nor mongosh client found in PATH")
+	diag := &Diagnosis{Engine: "mongodb", TLS: "off"}
+	if clientOpts.TLSConfig != nil {
+		diag.TLS = "on"
 	}
+	if diag.Latency, err = timed(func() error { return client.Ping(ctx, nil) }); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	admin := client.Database("admin")
 
-	cmd := exec.Command(clientCmd, uri, "--quiet", "--eval", "db.runCommand({ping:1})")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+	diag.ServerVersion = buildInfo.Version
 
-	done := make(chan error, 1)
-	go func() { done <- cmd.Run() }()
+	var status struct {
+		AuthInfo struct {
+			Users []struct {
+				User string `bson:"user"`
+				DB   string `bson:"db"`
+			} `bson:"authenticatedUsers"`
+			Roles []struct {
+				Role string `bson:"role"`
+				DB   string `bson:"db"`
+			} `bson:"authenticatedUserRoles"`
+		} `bson:"authInfo"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "connectionStatus", Value: 1}}).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to read roles: %w", err)
+	}
+	if len(status.AuthInfo.Users) == 0 {
+		// Without authentication every client may read everything
+		return diag, nil
+	}
+	diag.User = status.AuthInfo.Users[0].User + "@" + status.AuthInfo.Users[0].DB
+	for _, role := range status.AuthInfo.Roles {
+		diag.Privileges = append(diag.Privileges, role.Role+"@"+role.DB)
+	}
+	if database := params["dbname"]; database != "" {
+		diag.Missing = MissingMongoRoles(diag.Privileges, database)
+	}
+	return diag, nil
+}
 
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("connection failed: %w", err)
+// ---------------- Redis ----------------
+
+func diagnoseRedis(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	host, port := params["host"], params["port"]
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "6379"
+	}
+	client, err := redisClient(host, port, params["user"], params["pass"], ConnOptionsFromParams(params))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = client.Close() }()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+
+	diag := &Diagnosis{Engine: "redis", TLS: "off"}
+	if client.Options().TLSConfig != nil {
+		diag.TLS = "on"
+	}
+	if diag.Latency, err = timed(func() error { return client.Ping(ctx).Err() }); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	info, err := redisInfo(ctx, client, "server")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+	diag.ServerVersion = info["redis_version"]
+
+	// ACLs arrived in Redis 6 and ACL DRYRUN in 7; older servers only have the password
+	user, err := client.Do(ctx, "ACL", "WHOAMI").Text()
+	if err != nil {
+		return diag, nil
+	}
+	diag.User = user
+	if err := client.Do(ctx, "ACL", "DRYRUN", user, "SYNC").Err(); err != nil && !strings.Contains(strings.ToLower(err.Error()), "unknown") {
+		diag.Missing = append(diag.Missing, "SYNC (redis-cli --rdb)")
+	}
+	return diag, nil
+}
+
+// ---------------- etcd ----------------
+
+func diagnoseEtcd(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	host, port := params["host"], params["port"]
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "2379"
+	}
+	client, baseURL, err := ConnOptionsFromParams(params).httpClient(host, port)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/version", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	diag := &Diagnosis{Engine: "etcd", TLS: "off", User: params["user"]}
+	if diag.Latency, err = timed(func() (err error) { resp, err = client.Do(req); return err }); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /version: %s", resp.Status)
+	}
+	var version struct {
+		Server string `json:"etcdserver"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil || version.Server == "" {
+		return nil, fmt.Errorf("%s:%s did not answer like an etcd member", host, port)
+	}
+	diag.ServerVersion = version.Server
+	if resp.TLS != nil {
+		diag.TLS = tls.VersionName(resp.TLS.Version)
+	}
+	return diag, nil
+}
+
+// ---------------- Consul ----------------
+
+func diagnoseConsul(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	host, port := params["host"], params["port"]
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "8500"
+	}
+	conn := ConnOptionsFromParams(params)
+	var resp *http.Response
+	latency, err := timed(func() (err error) {
+		resp, err = consulRequest(ctx, host, port, params["pass"], conn, http.MethodGet, "/v1/status/leader", nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	diag := &Diagnosis{Engine: "consul", TLS: "off", Latency: latency}
+	if resp.TLS != nil {
+		diag.TLS = tls.VersionName(resp.TLS.Version)
+	}
+
+	// agent:read is enough here; the snapshot API itself needs a management token
+	if resp, err = consulRequest(ctx, host, port, params["pass"], conn, http.MethodGet, "/v1/agent/self", nil); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var self struct {
+		Config struct{ Version string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&self); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+	diag.ServerVersion = self.Config.Version
+	return diag, nil
+}
+
+// ---------------- ClickHouse ----------------
+
+// diagnoseClickHouse connects with clickhouse-client, since backups use it too; the negotiated TLS
+// version isn't reported by the client, so TLS shows as "on"
+func diagnoseClickHouse(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	host := params["host"]
+	if host == "" {
+		host = "localhost"
+	}
+	conn := ConnOptionsFromParams(params)
+	client, err := newClickHouseClient(host, params["port"], params["user"], params["pass"], conn)
+	if err != nil {
+		return nil, err
+	}
+	defer client.close()
+	query := func(q string) ([]byte, error) {
+		args := append(append([]string{}, client.args...), "--query", q)
+		return client.run(exec.CommandContext(ctx, client.bin, args...))
+	}
+
+	var out []byte
+	diag := &Diagnosis{Engine: "clickhouse", TLS: "off"}
+	if diag.Latency, err = timed(func() (err error) { out, err = query("SELECT version(), currentUser() FORMAT TSVRaw"); return err }); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	diag.ServerVersion, diag.User, _ = strings.Cut(strings.TrimSpace(string(out)), "\t")
+	if client.secure {
+		diag.TLS = "on"
+	}
+	if out, err = query("SHOW GRANTS FORMAT TSVRaw"); err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if line != "" {
+				diag.Privileges = append(diag.Privileges, line)
+			}
 		}
-	case <-time.After(5 * time.Second):
-		cmd.Process.Kill()
-		return errors.New("connection timed out (possibly waiting for password input)")
 	}
-	return nil
+	return diag, nil
 }
 
 // ---------------- SQLite ----------------
-func testSQLite(params map[string]string) error {
+
+// ---------------- Elasticsearch ----------------
+
+func diagnoseElasticsearch(ctx context.Context, params map[string]string) (*Diagnosis, error) {
+	host, port := params["host"], params["port"]
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "9200"
+	}
+	client, err := newESClient(host, port, params["user"], params["pass"], ConnOptionsFromParams(params))
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	var resp *http.Response
+	diag := &Diagnosis{Engine: "elasticsearch", TLS: "off", User: params["user"]}
+	if diag.Latency, err = timed(func() (err error) { resp, err = client.request(ctx, http.MethodGet, "/", nil, &info); return err }); err != nil {
+		return nil, err
+	}
+	if info.Version.Number == "" {
+		return nil, fmt.Errorf("%s:%s did not answer like an Elasticsearch node", host, port)
+	}
+	diag.ServerVersion = info.Version.Number
+	if info.Version.Distribution == "opensearch" {
+		diag.Engine = "opensearch"
+	}
+	if resp.TLS != nil {
+		diag.TLS = tls.VersionName(resp.TLS.Version)
+	}
+	return diag, nil
+}
+
+func diagnoseSQLite(params map[string]string) (*Diagnosis, error) {
 	dbPath := params["path"]
 	if dbPath == "" {
-		return errors.New("sqlite database path required")
+		return nil, errors.New("sqlite database path required")
 	}
-	if _, err := os.Stat(dbPath); err != nil {
-		return fmt.Errorf("failed to access sqlite file: %w", err)
+	diag := &Diagnosis{Engine: "sqlite"}
+	var err error
+	diag.Latency, err = timed(func() error {
+		f, err := os.Open(dbPath)
+		if err != nil {
+			return fmt.Errorf("failed to access sqlite file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		header := make([]byte, 100)
+		if _, err := f.Read(header); err != nil || !strings.HasPrefix(string(header), "SQLite format 3") {
+			return fmt.Errorf("%s is not a SQLite database", dbPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return diag, nil
 }
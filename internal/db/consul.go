@@ -0,0 +1,240 @@
+package db
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"dbx/internal/logs"
+	"dbx/internal/notify"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	osuser "os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// consulSnapshotMeta is the part of a Consul snapshot's meta.json dbx reports
+type consulSnapshotMeta struct {
+	ID    string `json:"ID"`
+	Index int64  `json:"Index"`
+	Term  int64  `json:"Term"`
+}
+
+// ConsulBackupName is the default file name prefix of a Consul cluster's backups, e.g. consul_consul.internal_8500
+func ConsulBackupName(host, port string, conn ConnOptions) string {
+	if port == "" {
+		port = "8500"
+	}
+	return serverBackupName("consul", host, port, conn)
+}
+
+// BackupConsul saves a snapshot of a Consul cluster's state through the agent's snapshot API.
+// token is the ACL token (empty uses $CONSUL_HTTP_TOKEN); name prefixes the backup file, empty uses
+// ConsulBackupName.
+func BackupConsul(host, port, token, name, outDir string) error {
+	return BackupConsulWithOptions(host, port, token, name, outDir, BackupOptions{})
+}
+
+// BackupConsulWithOptions saves a snapshot of a Consul cluster from the agent at host:port (or the
+// socket). The snapshot is already a gzipped archive, so it is kept as is; its checksums are verified
+// before it is kept and its Raft index is recorded in the manifest.
+func BackupConsulWithOptions(host, port, token, name, outDir string, opts BackupOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case opts.MaskRules != "":
+		return fmt.Errorf("masking is supported for MySQL, PostgreSQL and SQLite backups only")
+	case opts.Partial():
+		return fmt.Errorf("Consul snapshots always contain the whole cluster state (no content or table filters)")
+	case opts.Jobs > 0:
+		return fmt.Errorf("--jobs is not supported for Consul backups")
+	}
+	if port == "" {
+		port = "8500"
+	}
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if name == "" {
+		name = ConsulBackupName(host, port, opts.Conn)
+	}
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	outFile := filepath.Join(outDir, fmt.Sprintf("%s_%s.snap", name, timestamp))
+
+	start := time.Now()
+	fmt.Println("🔄 Running Consul snapshot...")
+	err := saveConsulSnapshot(host, port, token, outFile, opts.Conn)
+	var meta *consulSnapshotMeta
+	if err == nil {
+		meta, err = verifyConsulSnapshot(outFile)
+	}
+
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("Consul", "Backup", status, start, err)
+
+		// Send Slack notification if webhook is configured
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
+			duration := time.Since(start).Round(time.Second)
+			hostname, _ := os.Hostname()
+			username := "unknown"
+			if u, e := osuser.Current(); e == nil {
+				username = u.Username
+			}
+
+			message := fmt.Sprintf("Consul Backup %s\nCluster: %s\nDuration: %s\nHost: %s\nUser: %s",
+				status, name, duration, hostname, username)
+			if err != nil {
+				message += fmt.Sprintf("\nError: %v", err)
+			}
+			_ = notify.SlackNotify(webhook, message)
+		}
+	}()
+
+	if err != nil {
+		_ = os.Remove(outFile)
+		return err
+	}
+
+	manifest := newManifest("consul", name, BackupTypeFull, opts)
+	manifest.Revision = meta.Index
+	writeManifest(outFile, manifest)
+
+	fmt.Println("✅ Consul backup completed:", outFile)
+	return nil
+}
+
+// saveConsulSnapshot downloads a snapshot from GET /v1/snapshot into outFile
+func saveConsulSnapshot(host, port, token, outFile string, conn ConnOptions) error {
+	resp, err := consulRequest(context.Background(), host, port, token, conn, http.MethodGet, "/v1/snapshot", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	f, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	return f.Close()
+}
+
+// consulRequest calls the Consul HTTP API with the ACL token and turns error statuses into errors
+func consulRequest(ctx context.Context, host, port, token string, conn ConnOptions, method, path string, body io.Reader) (*http.Response, error) {
+	client, baseURL, err := conn.httpClient(host, port)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	if token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// verifyConsulSnapshot checks every file of a snapshot archive against its SHA256SUMS and reads meta.json
+func verifyConsulSnapshot(path string) (*consulSnapshotMeta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a Consul snapshot: %w", filepath.Base(path), err)
+	}
+
+	hashes := make(map[string]hash.Hash)
+	var sums []byte
+	var meta *consulSnapshotMeta
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s is corrupt: %w", filepath.Base(path), err)
+		}
+		switch header.Name {
+		case "SHA256SUMS":
+			if sums, err = io.ReadAll(archive); err != nil {
+				return nil, fmt.Errorf("snapshot %s is corrupt: %w", filepath.Base(path), err)
+			}
+		case "meta.json":
+			h := sha256.New()
+			meta = &consulSnapshotMeta{}
+			if err := json.NewDecoder(io.TeeReader(archive, h)).Decode(meta); err != nil {
+				return nil, fmt.Errorf("failed to parse snapshot metadata: %w", err)
+			}
+			// Hash whatever follows the JSON value too
+			if _, err := io.Copy(h, archive); err != nil {
+				return nil, fmt.Errorf("snapshot %s is corrupt: %w", filepath.Base(path), err)
+			}
+			hashes[header.Name] = h
+		default:
+			h := sha256.New()
+			if _, err := io.Copy(h, archive); err != nil {
+				return nil, fmt.Errorf("snapshot %s is corrupt: %w", filepath.Base(path), err)
+			}
+			hashes[header.Name] = h
+		}
+	}
+	if meta == nil || sums == nil || hashes["state.bin"] == nil {
+		return nil, fmt.Errorf("%s is not a Consul snapshot (meta.json, state.bin or SHA256SUMS missing)", filepath.Base(path))
+	}
+
+	checked := 0
+	scanner := bufio.NewScanner(strings.NewReader(string(sums)))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			continue
+		}
+		h := hashes[name]
+		if h == nil {
+			return nil, fmt.Errorf("snapshot %s is missing %s", filepath.Base(path), name)
+		}
+		if hex.EncodeToString(h.Sum(nil)) != sum {
+			return nil, fmt.Errorf("snapshot %s is corrupt (checksum mismatch for %s)", filepath.Base(path), name)
+		}
+		checked++
+	}
+	if checked != len(hashes) {
+		return nil, fmt.Errorf("snapshot %s has files without checksums", filepath.Base(path))
+	}
+	fmt.Printf("🔍 Snapshot verified: index %d, term %d\n", meta.Index, meta.Term)
+	return meta, nil
+}
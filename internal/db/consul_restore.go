@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"dbx/internal/logs"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RestoreConsul restores a snapshot (.snap, as written by BackupConsul) into a Consul cluster
+func RestoreConsul(host, port, token, backupFile string) error {
+	return RestoreConsulWithOptions(host, port, token, backupFile, RestoreOptions{})
+}
+
+// RestoreConsulWithOptions verifies a snapshot and uploads it with PUT /v1/snapshot. The leader
+// replaces the cluster's whole state (KV, catalog, sessions, ACLs) with it; token needs the
+// management privileges the snapshot API requires.
+func RestoreConsulWithOptions(host, port, token, backupFile string, opts RestoreOptions) (err error) {
+	start := time.Now()
+	if backupFile == "" {
+		return fmt.Errorf("backup file path cannot be empty")
+	}
+	if _, err := os.Stat(backupFile); err != nil {
+		return fmt.Errorf("backup file not found: %w", err)
+	}
+	if port == "" {
+		port = "8500"
+	}
+
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("Consul", "Restore", status, start, err)
+	}()
+
+	if _, err := verifyConsulSnapshot(backupFile); err != nil {
+		return err
+	}
+	f, err := os.Open(backupFile)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	fmt.Println("🔄 Restoring Consul snapshot...")
+	resp, err := consulRequest(context.Background(), host, port, token, opts.Conn, http.MethodPut, "/v1/snapshot", f)
+	if err != nil {
+		return fmt.Errorf("snapshot restore failed: %w", err)
+	}
+	_ = resp.Body.Close()
+
+	fmt.Println("✅ Consul restore completed successfully.")
+	return nil
+}
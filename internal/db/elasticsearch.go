@@ -0,0 +1,287 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"dbx/internal/logs"
+	"dbx/internal/notify"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	osuser "os/user"
+	"strings"
+	"time"
+)
+
+const (
+	// snapshotPollInterval is how often a running Elasticsearch snapshot's state is checked
+	snapshotPollInterval = time.Second
+	// snapshotTimeout bounds how long a backup waits for the cluster to finish a snapshot
+	snapshotTimeout = 12 * time.Hour
+)
+
+// ElasticsearchOptions say where Elasticsearch/OpenSearch snapshots are stored and what they contain.
+// The zero value snapshots every index into the "dbx" repository, which must already exist.
+type ElasticsearchOptions struct {
+	Repository string // snapshot repository (default "dbx")
+	// RepositoryPath registers Repository as a shared filesystem repository at this path first; the
+	// path must be listed in path.repo on every node
+	RepositoryPath string
+	Indices        []string // indices, aliases or patterns; empty means all of them
+	GlobalState    bool     // include the cluster state (templates, persistent settings, pipelines)
+	// Restores only: rename restored indices, e.g. pattern "(.+)" and replacement "restored_$1"
+	RenamePattern, RenameReplacement string
+	Conn                             ConnOptions
+}
+
+// ElasticsearchOptionsFromParams reads snapshot options from scheduler job params
+func ElasticsearchOptionsFromParams(params map[string]string) ElasticsearchOptions {
+	opts := ElasticsearchOptions{
+		Repository:     params["repository"],
+		RepositoryPath: params["repository_path"],
+		GlobalState:    params["global_state"] == "true",
+		Conn:           ConnOptionsFromParams(params),
+	}
+	if params["indices"] != "" {
+		opts.Indices = strings.Split(params["indices"], ",")
+	}
+	return opts
+}
+
+// repository returns the repository name, defaulting to "dbx"
+func (o ElasticsearchOptions) repository() string {
+	if o.Repository == "" {
+		return "dbx"
+	}
+	return o.Repository
+}
+
+// esSnapshot is a snapshot as GET /_snapshot/<repository>/<snapshot> describes it
+type esSnapshot struct {
+	Snapshot string   `json:"snapshot"`
+	State    string   `json:"state"`
+	Indices  []string `json:"indices"`
+	Shards   esShards `json:"shards"`
+	Failures []struct {
+		Index  string `json:"index"`
+		Reason string `json:"reason"`
+	} `json:"failures"`
+}
+
+type esShards struct {
+	Total      int `json:"total"`
+	Failed     int `json:"failed"`
+	Successful int `json:"successful"`
+}
+
+// ElasticsearchBackupName is the default snapshot name prefix of a cluster, e.g. elasticsearch_es.internal_9200.
+// Snapshot names must be lowercase.
+func ElasticsearchBackupName(host, port string, conn ConnOptions) string {
+	if port == "" {
+		port = "9200"
+	}
+	return strings.ToLower(serverBackupName("elasticsearch", host, port, conn))
+}
+
+// BackupElasticsearch has an Elasticsearch or OpenSearch cluster take a snapshot named
+// <name>_<timestamp> of opts.Indices into opts.Repository, and waits for it to finish. The snapshot
+// stays in the repository; nothing is written locally. user and password authenticate with basic
+// auth; a password without a user is sent as an API key. name empty uses ElasticsearchBackupName.
+func BackupElasticsearch(host, port, user, password, name string, opts ElasticsearchOptions) (err error) {
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
+	if port == "" {
+		port = "9200"
+	}
+	if name == "" {
+		name = ElasticsearchBackupName(host, port, opts.Conn)
+	}
+	snapshot := strings.ToLower(name) + "_" + time.Now().Format("2006-01-02_15-04-05")
+	repository := opts.repository()
+
+	start := time.Now()
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("Elasticsearch", "Backup", status, start, err)
+
+		// Send Slack notification if webhook is configured
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" {
+			duration := time.Since(start).Round(time.Second)
+			hostname, _ := os.Hostname()
+			username := "unknown"
+			if u, e := osuser.Current(); e == nil {
+				username = u.Username
+			}
+
+			message := fmt.Sprintf("Elasticsearch Backup %s\nSnapshot: %s/%s\nDuration: %s\nHost: %s\nUser: %s",
+				status, repository, snapshot, duration, hostname, username)
+			if err != nil {
+				message += fmt.Sprintf("\nError: %v", err)
+			}
+			_ = notify.SlackNotify(webhook, message)
+		}
+	}()
+
+	client, err := newESClient(host, port, user, password, opts.Conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	if err := client.ensureRepository(ctx, repository, opts.RepositoryPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("🔄 Creating snapshot %s in repository %s...\n", snapshot, repository)
+	body := map[string]any{
+		"include_global_state": opts.GlobalState,
+		"metadata":             map[string]string{"taken_by": "dbx"},
+	}
+	if len(opts.Indices) > 0 {
+		body["indices"] = strings.Join(opts.Indices, ",")
+	}
+	path := "/_snapshot/" + url.PathEscape(repository) + "/" + url.PathEscape(snapshot)
+	if err := client.do(ctx, http.MethodPut, path+"?wait_for_completion=false", body, nil); err != nil {
+		return fmt.Errorf("failed to start snapshot: %w", err)
+	}
+
+	info, err := client.waitForSnapshot(ctx, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Elasticsearch snapshot completed: %s/%s (%d indices, %d/%d shards)\n",
+		repository, snapshot, len(info.Indices), info.Shards.Successful, info.Shards.Total)
+	return nil
+}
+
+// esClient calls the Elasticsearch/OpenSearch REST API
+type esClient struct {
+	http           *http.Client
+	baseURL        string
+	user, password string
+}
+
+func newESClient(host, port, user, password string, conn ConnOptions) (*esClient, error) {
+	client, baseURL, err := conn.httpClient(host, port)
+	if err != nil {
+		return nil, err
+	}
+	return &esClient{http: client, baseURL: baseURL, user: user, password: password}, nil
+}
+
+// do sends body as JSON and decodes the response into out; error responses are returned with the
+// reason the cluster gave
+func (c *esClient) do(ctx context.Context, method, path string, body, out any) error {
+	_, err := c.request(ctx, method, path, body, out)
+	return err
+}
+
+// request is do, also returning the response (its body already read) for its TLS state
+func (c *esClient) request(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	} else if c.password != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Error.Reason != "" {
+			return nil, fmt.Errorf("%s: %s (%s)", resp.Status, failure.Error.Reason, failure.Error.Type)
+		}
+		return nil, fmt.Errorf("%s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to parse response to %s %s: %w", method, path, err)
+		}
+	}
+	return resp, nil
+}
+
+// ensureRepository registers a shared filesystem repository at location, or checks that the
+// repository exists when no location is given
+func (c *esClient) ensureRepository(ctx context.Context, repository, location string) error {
+	path := "/_snapshot/" + url.PathEscape(repository)
+	if location == "" {
+		if err := c.do(ctx, http.MethodGet, path, nil, nil); err != nil {
+			return fmt.Errorf("snapshot repository %s not found (register it with --repository-path): %w", repository, err)
+		}
+		return nil
+	}
+	body := map[string]any{"type": "fs", "settings": map[string]string{"location": location}}
+	if err := c.do(ctx, http.MethodPut, path, body, nil); err != nil {
+		return fmt.Errorf("failed to register repository %s at %s (it must be listed in path.repo on every node): %w", repository, location, err)
+	}
+	fmt.Printf("📁 Registered repository %s at %s\n", repository, location)
+	return nil
+}
+
+// waitForSnapshot polls a snapshot until it leaves IN_PROGRESS and fails unless every shard was saved
+func (c *esClient) waitForSnapshot(ctx context.Context, path string) (*esSnapshot, error) {
+	for {
+		var status struct {
+			Snapshots []esSnapshot `json:"snapshots"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &status); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot status: %w", err)
+		}
+		if len(status.Snapshots) != 1 {
+			return nil, fmt.Errorf("snapshot status returned %d snapshots", len(status.Snapshots))
+		}
+		info := &status.Snapshots[0]
+		switch info.State {
+		case "SUCCESS":
+			return info, nil
+		case "IN_PROGRESS", "STARTED":
+		default:
+			reasons := make([]string, 0, len(info.Failures))
+			for _, failure := range info.Failures {
+				reasons = append(reasons, failure.Index+": "+failure.Reason)
+			}
+			return nil, fmt.Errorf("snapshot %s finished %s (%d of %d shards failed) %s",
+				info.Snapshot, info.State, info.Shards.Failed, info.Shards.Total, strings.Join(reasons, "; "))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for snapshot after %s", snapshotTimeout)
+		case <-time.After(snapshotPollInterval):
+		}
+	}
+}
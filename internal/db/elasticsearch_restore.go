@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"dbx/internal/logs"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RestoreElasticsearch restores a snapshot from opts.Repository into an Elasticsearch or OpenSearch
+// cluster and waits for the restore to finish. opts.Indices narrows what is restored, and
+// opts.RenameReplacement (with opts.RenamePattern, default "(.+)") restores indices under new names,
+// since an open index can't be restored over. An empty snapshot name fails with the ones available.
+func RestoreElasticsearch(host, port, user, password, snapshot string, opts ElasticsearchOptions) (err error) {
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
+	if opts.RenamePattern != "" && opts.RenameReplacement == "" {
+		return fmt.Errorf("--rename-pattern needs --rename-replacement")
+	}
+	if port == "" {
+		port = "9200"
+	}
+	repository := opts.repository()
+	client, err := newESClient(host, port, user, password, opts.Conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	if snapshot == "" {
+		names, err := client.snapshotNames(ctx, repository)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("--snapshot is required (in %s: %s)", repository, strings.Join(names, ", "))
+	}
+
+	start := time.Now()
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("Elasticsearch", "Restore", status, start, err)
+	}()
+
+	body := map[string]any{"include_global_state": opts.GlobalState}
+	if len(opts.Indices) > 0 {
+		body["indices"] = strings.Join(opts.Indices, ",")
+	}
+	if opts.RenameReplacement != "" {
+		pattern := opts.RenamePattern
+		if pattern == "" {
+			pattern = "(.+)"
+		}
+		body["rename_pattern"] = pattern
+		body["rename_replacement"] = opts.RenameReplacement
+	}
+
+	fmt.Printf("🔄 Restoring snapshot %s from repository %s...\n", snapshot, repository)
+	var result struct {
+		Snapshot struct {
+			Indices []string `json:"indices"`
+			Shards  esShards `json:"shards"`
+		} `json:"snapshot"`
+	}
+	path := "/_snapshot/" + url.PathEscape(repository) + "/" + url.PathEscape(snapshot) + "/_restore?wait_for_completion=true"
+	if err := client.do(ctx, http.MethodPost, path, body, &result); err != nil {
+		return fmt.Errorf("snapshot restore failed: %w", err)
+	}
+	if shards := result.Snapshot.Shards; shards.Failed > 0 {
+		return fmt.Errorf("snapshot restore failed for %d of %d shards", shards.Failed, shards.Total)
+	}
+
+	fmt.Printf("✅ Elasticsearch restore completed: %s\n", strings.Join(result.Snapshot.Indices, ", "))
+	return nil
+}
+
+// snapshotNames lists the snapshots in a repository, oldest first
+func (c *esClient) snapshotNames(ctx context.Context, repository string) ([]string, error) {
+	var list struct {
+		Snapshots []esSnapshot `json:"snapshots"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_snapshot/"+url.PathEscape(repository)+"/_all", nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	names := make([]string, 0, len(list.Snapshots))
+	for _, s := range list.Snapshots {
+		names = append(names, s.Snapshot)
+	}
+	if len(names) == 0 {
+		names = append(names, "no snapshots")
+	}
+	return names, nil
+}
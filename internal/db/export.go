@@ -0,0 +1,986 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"dbx/internal/export"
+	"dbx/internal/logs"
+	"dbx/internal/utils"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoSampleSize is how many documents are read to find a collection's fields when no columns are given
+const mongoSampleSize = 1000
+
+// ExportOptions select the tables, columns and rows dbx export writes, and how
+type ExportOptions struct {
+	Format string // csv (default), jsonl or parquet
+	// Tables and ExcludeTables select tables, or collections for MongoDB; empty exports every one
+	Tables        []string
+	ExcludeTables []string
+	Columns       []string // columns to write, in order (MongoDB: field paths such as address.city); empty writes all
+	Where         string   // row filter: a SQL condition, or a JSON query document for MongoDB
+	Compress      bool     // bundle the files into a zip instead of leaving a directory
+	Conn          ConnOptions
+}
+
+// Validate checks the options before connecting
+func (o ExportOptions) Validate() error {
+	if o.Format != "" && !slices.Contains(export.Formats, o.Format) {
+		return fmt.Errorf("unsupported export format %q (use %s)", o.Format, strings.Join(export.Formats, ", "))
+	}
+	return o.Conn.Validate()
+}
+
+func (o ExportOptions) format() string {
+	if o.Format == "" {
+		return "csv"
+	}
+	return o.Format
+}
+
+// exportSource reads the tables of one database
+type exportSource interface {
+	tables() ([]string, error)
+	query(table string, opts ExportOptions) (exportRows, error)
+	close()
+}
+
+// exportRows iterates over the rows of a query; next returns io.EOF after the last row
+type exportRows interface {
+	columns() []export.Column
+	next() ([]any, error)
+	close() error
+}
+
+// ExportMySQL writes tables of a MySQL or MariaDB database (with opts.Conn.MariaDB) to files, one per
+// table, and returns the directory or zip it wrote
+func ExportMySQL(host, user, password, database, outDir string, opts ExportOptions) (string, error) {
+	_, label := mysqlFlavor(opts.Conn)
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if database == "" {
+		return "", fmt.Errorf("database name cannot be empty")
+	}
+	conn, err := openMySQL(host, user, password, database, opts.Conn)
+	if err != nil {
+		return "", err
+	}
+	if err := conn.Ping(); err != nil {
+		_ = conn.Close()
+		return "", fmt.Errorf("connection failed: %w", err)
+	}
+	return runExport(label, database, outDir, opts, &mysqlExport{db: conn})
+}
+
+// ExportPostgres writes tables of a PostgreSQL database to files, one per table. Tables outside the
+// public schema are named schema.table.
+func ExportPostgres(host, port, user, password, database, outDir string, opts ExportOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if database == "" {
+		return "", fmt.Errorf("database name cannot be empty")
+	}
+	cfg, err := postgresConfig(host, port, user, password, database, opts.Conn)
+	if err != nil {
+		return "", err
+	}
+	conn, err := pgx.ConnectConfig(context.Background(), cfg)
+	if err != nil {
+		return "", fmt.Errorf("connection failed: %w", err)
+	}
+	return runExport("PostgreSQL", database, outDir, opts, &postgresExport{conn: conn})
+}
+
+// ExportMongo writes collections of a MongoDB database to files, one per collection. Without
+// opts.Columns the fields are those of the first documents matched; nested documents and arrays
+// are written as JSON.
+func ExportMongo(uri, database, outDir string, opts ExportOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if database == "" {
+		return "", fmt.Errorf("database name cannot be empty")
+	}
+	uri, cleanup, err := opts.Conn.mongoURI(uri)
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, opts.Conn.mongoClientOptions(uri))
+	if err == nil {
+		if err = client.Ping(ctx, nil); err != nil {
+			_ = client.Disconnect(ctx)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return "", fmt.Errorf("connection failed: %w", err)
+	}
+	src := &mongoExport{client: client, db: client.Database(database), cleanup: cleanup}
+	return runExport("MongoDB", database, outDir, opts, src)
+}
+
+// ExportSQLite writes tables of a SQLite database to files, one per table, reading it with the
+// sqlite3 CLI (3.33 or later)
+func ExportSQLite(dbPath, outDir string, opts ExportOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return "", fmt.Errorf("sqlite database file not found: %w", err)
+	}
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return "", fmt.Errorf("sqlite3 not found in PATH (needed to export)")
+	}
+	name := strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath))
+	return runExport("SQLite", name, outDir, opts, &sqliteExport{path: dbPath})
+}
+
+// ExportClickHouse writes tables of a ClickHouse database to files, one per table, reading them
+// with clickhouse-client
+func ExportClickHouse(host, port, user, password, database, outDir string, opts ExportOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if database == "" {
+		return "", fmt.Errorf("database name cannot be empty")
+	}
+	client, err := newClickHouseClient(host, port, user, password, opts.Conn)
+	if err != nil {
+		return "", err
+	}
+	return runExport("ClickHouse", database, outDir, opts, &clickhouseExport{client: client, database: database})
+}
+
+// runExport writes the selected tables of src into <name>-export_<timestamp>/<table>.<format>, then
+// zips the directory with opts.Compress
+func runExport(label, name, outDir string, opts ExportOptions, src exportSource) (artifact string, err error) {
+	start := time.Now()
+	defer src.close()
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry(label, "Export", status, start, err)
+	}()
+
+	names, err := src.tables()
+	if err != nil {
+		return "", fmt.Errorf("failed to list tables: %w", err)
+	}
+	for _, table := range opts.Tables {
+		if !slices.Contains(names, table) {
+			return "", fmt.Errorf("table '%s' not found in %s", table, name)
+		}
+	}
+	tables := selectTables(names, BackupOptions{Tables: opts.Tables, ExcludeTables: opts.ExcludeTables})
+	if len(tables) == 0 {
+		return "", fmt.Errorf("no tables to export in %s", name)
+	}
+
+	dir, err := exportDir(outDir, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+
+	fmt.Printf("🔄 Exporting %d tables from %s as %s...\n", len(tables), label, opts.format())
+	used := make(map[string]bool)
+	for _, table := range tables {
+		file := exportFileName(table, used) + export.Extension(opts.format())
+		rows, err := exportTable(src, table, filepath.Join(dir, file), opts)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", table, err)
+		}
+		fmt.Printf("📄 %s: %d rows → %s\n", table, rows, file)
+	}
+
+	artifact = dir
+	if opts.Compress {
+		artifact = dir + ".zip"
+		if err := utils.CompressFolder(dir, artifact); err != nil {
+			return "", fmt.Errorf("failed to compress export: %w", err)
+		}
+		_ = os.RemoveAll(dir)
+	}
+	fmt.Println("✅ Export completed:", artifact)
+	return artifact, nil
+}
+
+// exportDir creates a new directory <name>-export_<timestamp> in outDir, numbering it when an
+// export in the same second already took the name
+func exportDir(outDir, name string) (string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(outDir, fmt.Sprintf("%s-export_%s", name, time.Now().Format("2006-01-02_15-04-05")))
+	dir := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(dir + ".zip"); os.IsNotExist(err) {
+			err := os.Mkdir(dir, 0755)
+			if err == nil || !os.IsExist(err) {
+				return dir, err
+			}
+		}
+		dir = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// exportTable writes the rows of one table to path and returns how many there were
+func exportTable(src exportSource, table, path string, opts ExportOptions) (n int, err error) {
+	rows, err := src.query(table, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if cerr := rows.close(); err == nil {
+			err = cerr
+		}
+	}()
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+	w, err := export.NewWriter(opts.format(), f, rows.columns())
+	if err != nil {
+		return 0, err
+	}
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := w.Write(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := w.Close(); err != nil {
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// unsafeFileChars are the characters replaced in export file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// exportFileName turns a table name into a file name not in used
+func exportFileName(table string, used map[string]bool) string {
+	base := unsafeFileChars.ReplaceAllString(table, "_")
+	name := base
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// selectList returns the SELECT list for columns quoted with quote, or * for all columns
+func selectList(columns []string, quote func(string) string) string {
+	if len(columns) == 0 {
+		return "*"
+	}
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quote(col)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// whereClause returns the WHERE clause for a row filter, if there is one
+func whereClause(where string) string {
+	if strings.TrimSpace(where) == "" {
+		return ""
+	}
+	return " WHERE " + where
+}
+
+// exportTimeLayouts are the text forms of dates and timestamps the SQL engines print
+var exportTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseExportTime parses a date or timestamp; values without a time zone are taken as UTC
+func parseExportTime(s string) (time.Time, error) {
+	for _, layout := range exportTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date/time %q", s)
+}
+
+// parseExportText converts the text form of a value to the Go type of a column of type t
+func parseExportText(s string, t export.Type) (any, error) {
+	switch t {
+	case export.Int:
+		return strconv.ParseInt(s, 10, 64)
+	case export.Float:
+		return strconv.ParseFloat(s, 64)
+	case export.Bool:
+		return strconv.ParseBool(s)
+	case export.Time:
+		return parseExportTime(s)
+	}
+	return s, nil
+}
+
+// exportJSONValue converts a value decoded from JSON (with UseNumber) for a column of type t;
+// objects and arrays stay JSON text, and binary columns arrive as hex
+func exportJSONValue(v any, t export.Type) (any, error) {
+	switch v := v.(type) {
+	case nil, bool:
+		return v, nil
+	case json.Number:
+		switch t {
+		case export.Unknown:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+			return v.Float64()
+		case export.Int, export.Float:
+			return parseExportText(v.String(), t)
+		}
+		return v.String(), nil
+	case string:
+		switch t {
+		case export.Unknown:
+			return v, nil
+		case export.Bytes:
+			return hex.DecodeString(v)
+		}
+		return parseExportText(v, t)
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// ---------------- MySQL ----------------
+
+type mysqlExport struct {
+	db *sql.DB
+}
+
+func (m *mysqlExport) tables() ([]string, error) {
+	rows, err := m.db.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() ORDER BY table_name")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (m *mysqlExport) query(table string, opts ExportOptions) (exportRows, error) {
+	rows, err := m.db.Query("SELECT " + selectList(opts.Columns, mysqlIdent) + " FROM " + mysqlIdent(table) + whereClause(opts.Where))
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	r := &mysqlRows{rows: rows, raw: make([]sql.RawBytes, len(types))}
+	for _, t := range types {
+		r.cols = append(r.cols, export.Column{Name: t.Name(), Type: mysqlExportType(t.DatabaseTypeName())})
+	}
+	return r, nil
+}
+
+func (m *mysqlExport) close() {
+	_ = m.db.Close()
+}
+
+// mysqlExportType maps a MySQL column type to an output type; DECIMAL and BIGINT UNSIGNED, which
+// can exceed int64, stay text to keep their precision
+func mysqlExportType(name string) export.Type {
+	if name == "UNSIGNED BIGINT" {
+		return export.String
+	}
+	switch strings.TrimPrefix(name, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		return export.Int
+	case "FLOAT", "DOUBLE":
+		return export.Float
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return export.Time
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		return export.Bytes
+	}
+	return export.String
+}
+
+type mysqlRows struct {
+	rows *sql.Rows
+	cols []export.Column
+	raw  []sql.RawBytes
+}
+
+func (r *mysqlRows) columns() []export.Column { return r.cols }
+
+func (r *mysqlRows) next() ([]any, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	dest := make([]any, len(r.raw))
+	for i := range r.raw {
+		dest[i] = &r.raw[i]
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	row := make([]any, len(r.raw))
+	for i, raw := range r.raw {
+		switch {
+		case raw == nil:
+		case r.cols[i].Type == export.Bytes:
+			row[i] = bytes.Clone(raw)
+		case r.cols[i].Type == export.Time && strings.HasPrefix(string(raw), "0000-00-00"):
+			// MySQL's zero date has no time.Time equivalent
+		default:
+			v, err := parseExportText(string(raw), r.cols[i].Type)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", r.cols[i].Name, err)
+			}
+			row[i] = v
+		}
+	}
+	return row, nil
+}
+
+func (r *mysqlRows) close() error {
+	return r.rows.Close()
+}
+
+// mysqlIdent quotes a MySQL identifier
+func mysqlIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// ---------------- PostgreSQL ----------------
+
+type postgresExport struct {
+	conn *pgx.Conn
+}
+
+func (p *postgresExport) tables() ([]string, error) {
+	rows, err := p.conn.Query(context.Background(), `SELECT table_schema, table_name FROM information_schema.tables
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema') ORDER BY table_schema, table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			return nil, err
+		}
+		if schema != "public" {
+			table = schema + "." + table
+		}
+		names = append(names, table)
+	}
+	return names, rows.Err()
+}
+
+func (p *postgresExport) query(table string, opts ExportOptions) (exportRows, error) {
+	// The simple protocol returns every value as text, parsed by column type below
+	rows, err := p.conn.Query(context.Background(), "SELECT "+selectList(opts.Columns, pgIdent)+" FROM "+pgTable(table)+whereClause(opts.Where),
+		pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return nil, err
+	}
+	r := &postgresRows{rows: rows}
+	for _, field := range rows.FieldDescriptions() {
+		r.cols = append(r.cols, export.Column{Name: field.Name, Type: postgresExportType(field.DataTypeOID)})
+	}
+	return r, nil
+}
+
+func (p *postgresExport) close() {
+	_ = p.conn.Close(context.Background())
+}
+
+// postgresExportType maps a PostgreSQL type OID to an output type; numeric stays text to keep its precision
+func postgresExportType(oid uint32) export.Type {
+	switch oid {
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID:
+		return export.Int
+	case pgtype.Float4OID, pgtype.Float8OID:
+		return export.Float
+	case pgtype.BoolOID:
+		return export.Bool
+	case pgtype.DateOID, pgtype.TimestampOID, pgtype.TimestamptzOID:
+		return export.Time
+	case pgtype.ByteaOID:
+		return export.Bytes
+	}
+	return export.String
+}
+
+type postgresRows struct {
+	rows pgx.Rows
+	cols []export.Column
+}
+
+func (r *postgresRows) columns() []export.Column { return r.cols }
+
+func (r *postgresRows) next() ([]any, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	raw := r.rows.RawValues()
+	row := make([]any, len(raw))
+	for i, value := range raw {
+		if value == nil {
+			continue
+		}
+		var err error
+		switch r.cols[i].Type {
+		case export.Bytes:
+			row[i], err = hex.DecodeString(strings.TrimPrefix(string(value), `\x`))
+		case export.Bool:
+			row[i] = string(value) == "t"
+		default:
+			row[i], err = parseExportText(string(value), r.cols[i].Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", r.cols[i].Name, err)
+		}
+	}
+	return row, nil
+}
+
+func (r *postgresRows) close() error {
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+// pgTable quotes a table name as dbx lists it: schema.table outside the public schema
+func pgTable(table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pgIdent(schema) + "." + pgIdent(name)
+	}
+	return pgIdent(table)
+}
+
+// pgIdent quotes a PostgreSQL identifier
+func pgIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ---------------- MongoDB ----------------
+
+type mongoExport struct {
+	client  *mongo.Client
+	db      *mongo.Database
+	cleanup func()
+}
+
+func (m *mongoExport) tables() ([]string, error) {
+	names, err := m.db.ListCollectionNames(context.Background(), bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	names = slices.DeleteFunc(names, func(name string) bool { return strings.HasPrefix(name, "system.") })
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *mongoExport) query(collection string, opts ExportOptions) (exportRows, error) {
+	ctx := context.Background()
+	filter := bson.D{}
+	if opts.Where != "" {
+		if err := bson.UnmarshalExtJSON([]byte(opts.Where), false, &filter); err != nil {
+			return nil, fmt.Errorf("invalid query document: %w", err)
+		}
+	}
+	coll := m.db.Collection(collection)
+
+	fields := opts.Columns
+	find := options.Find()
+	if len(fields) > 0 {
+		projection := bson.D{}
+		for _, field := range fields {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		find.SetProjection(projection)
+	} else {
+		sample, err := coll.Find(ctx, filter, options.Find().SetLimit(mongoSampleSize))
+		if err != nil {
+			return nil, err
+		}
+		for sample.Next(ctx) {
+			elems, err := sample.Current.Elements()
+			if err != nil {
+				_ = sample.Close(ctx)
+				return nil, err
+			}
+			for _, elem := range elems {
+				if key := elem.Key(); !slices.Contains(fields, key) {
+					fields = append(fields, key)
+				}
+			}
+		}
+		err = sample.Err()
+		_ = sample.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cursor, err := coll.Find(ctx, filter, find)
+	if err != nil {
+		return nil, err
+	}
+	r := &mongoRows{cursor: cursor}
+	for _, field := range fields {
+		r.cols = append(r.cols, export.Column{Name: field})
+		r.paths = append(r.paths, strings.Split(field, "."))
+	}
+	return r, nil
+}
+
+func (m *mongoExport) close() {
+	_ = m.client.Disconnect(context.Background())
+	m.cleanup()
+}
+
+type mongoRows struct {
+	cursor *mongo.Cursor
+	cols   []export.Column
+	paths  [][]string
+}
+
+func (r *mongoRows) columns() []export.Column { return r.cols }
+
+func (r *mongoRows) next() ([]any, error) {
+	if !r.cursor.Next(context.Background()) {
+		if err := r.cursor.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	row := make([]any, len(r.paths))
+	for i, path := range r.paths {
+		if value, err := r.cursor.Current.LookupErr(path...); err == nil {
+			row[i] = mongoExportValue(value)
+		}
+	}
+	return row, nil
+}
+
+func (r *mongoRows) close() error {
+	return r.cursor.Close(context.Background())
+}
+
+// mongoExportValue converts a BSON value for output: ObjectIDs as hex, decimals as text, and
+// documents and arrays as relaxed extended JSON
+func mongoExportValue(v bson.RawValue) any {
+	switch v.Type {
+	case bsontype.Null, bsontype.Undefined:
+		return nil
+	case bsontype.Double:
+		return v.Double()
+	case bsontype.String:
+		return v.StringValue()
+	case bsontype.Boolean:
+		return v.Boolean()
+	case bsontype.Int32:
+		return int64(v.Int32())
+	case bsontype.Int64:
+		return v.Int64()
+	case bsontype.DateTime:
+		return v.Time().UTC()
+	case bsontype.ObjectID:
+		return v.ObjectID().Hex()
+	case bsontype.Decimal128:
+		return v.Decimal128().String()
+	case bsontype.Binary:
+		_, data := v.Binary()
+		return data
+	case bsontype.EmbeddedDocument, bsontype.Array:
+		// Marshalled as the value of a one-field document, which is then cut off
+		data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+		if err == nil {
+			return string(bytes.TrimSuffix(bytes.TrimPrefix(data, []byte(`{"v":`)), []byte("}")))
+		}
+	}
+	return v.String()
+}
+
+// ---------------- SQLite ----------------
+
+type sqliteExport struct {
+	path string
+}
+
+// run runs a query with the sqlite3 CLI and returns its output lines
+func (s *sqliteExport) run(query string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sqlite3", "-readonly", s.path, query)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func (s *sqliteExport) tables() ([]string, error) {
+	return s.run("SELECT name FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name")
+}
+
+func (s *sqliteExport) query(table string, opts ExportOptions) (exportRows, error) {
+	info, err := s.run("SELECT name || char(9) || type FROM pragma_table_info('" + strings.ReplaceAll(table, "'", "''") + "')")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	blobs := make(map[string]bool)
+	for _, line := range info {
+		name, declared, _ := strings.Cut(line, "\t")
+		names = append(names, name)
+		blobs[name] = strings.Contains(strings.ToUpper(declared), "BLOB")
+	}
+	if len(opts.Columns) > 0 {
+		names = opts.Columns
+	}
+
+	// Values keep SQLite's dynamic types; BLOB columns are read as hex, since the JSON output
+	// would mangle binary data
+	r := &jsonRows{}
+	list := make([]string, len(names))
+	for i, name := range names {
+		col := export.Column{Name: name}
+		list[i] = pgIdent(name)
+		if blobs[name] {
+			col.Type = export.Bytes
+			list[i] = fmt.Sprintf("CASE WHEN %s IS NULL THEN NULL ELSE hex(%s) END AS %s", list[i], list[i], list[i])
+		}
+		r.cols = append(r.cols, col)
+	}
+	query := "SELECT " + strings.Join(list, ", ") + " FROM " + pgIdent(table) + whereClause(opts.Where)
+	return r, r.start(exec.Command("sqlite3", "-readonly", "-json", s.path, query), false)
+}
+
+func (s *sqliteExport) close() {}
+
+// jsonRows reads rows a client tool prints as JSON: with objects set, a JSON array of objects keyed
+// by column (sqlite3 -json); otherwise one array of values per row (ClickHouse's JSONCompactEachRow)
+type jsonRows struct {
+	cmd     *exec.Cmd
+	dec     *json.Decoder
+	stderr  bytes.Buffer
+	cols    []export.Column
+	objects bool
+	done    bool
+}
+
+// start runs cmd and reads the opening bracket of its output
+func (r *jsonRows) start(cmd *exec.Cmd, arrays bool) error {
+	r.cmd, r.objects = cmd, !arrays
+	cmd.Stderr = &r.stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	r.dec = json.NewDecoder(stdout)
+	r.dec.UseNumber()
+	if r.objects {
+		// sqlite3 prints nothing at all for an empty result
+		if _, err := r.dec.Token(); err == io.EOF {
+			r.done = true
+		} else if err != nil {
+			_ = r.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *jsonRows) columns() []export.Column { return r.cols }
+
+func (r *jsonRows) next() ([]any, error) {
+	var values []any
+	if r.objects {
+		if r.done || !r.dec.More() {
+			r.done = true
+			return nil, r.wait()
+		}
+		var object map[string]any
+		if err := r.dec.Decode(&object); err != nil {
+			return nil, err
+		}
+		for _, col := range r.cols {
+			values = append(values, object[col.Name])
+		}
+	} else if err := r.dec.Decode(&values); err == io.EOF {
+		return nil, r.wait()
+	} else if err != nil {
+		return nil, err
+	}
+
+	row := make([]any, len(r.cols))
+	for i := range row {
+		if i >= len(values) {
+			break
+		}
+		v, err := exportJSONValue(values[i], r.cols[i].Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", r.cols[i].Name, err)
+		}
+		row[i] = v
+	}
+	return row, nil
+}
+
+// wait waits for the tool to exit after the last row and returns io.EOF, or the tool's error
+func (r *jsonRows) wait() error {
+	cmd := r.cmd
+	r.cmd = nil
+	if cmd == nil {
+		return io.EOF
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed: %v: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(r.stderr.String()))
+	}
+	return io.EOF
+}
+
+func (r *jsonRows) close() error {
+	if r.cmd != nil {
+		_ = r.cmd.Process.Kill()
+		_ = r.cmd.Wait()
+		r.cmd = nil
+	}
+	return nil
+}
+
+// ---------------- ClickHouse ----------------
+
+type clickhouseExport struct {
+	client   *clickhouseClient
+	database string
+}
+
+func (c *clickhouseExport) tables() ([]string, error) {
+	names, _, err := c.client.listTables(c.database)
+	return names, err
+}
+
+func (c *clickhouseExport) query(table string, opts ExportOptions) (exportRows, error) {
+	query := "SELECT " + selectList(opts.Columns, clickhouseIdent) + " FROM " + clickhouseIdent(table) + whereClause(opts.Where) +
+		" FORMAT JSONCompactEachRowWithNamesAndTypes"
+	r := &jsonRows{}
+	if err := r.start(c.client.command("--database", c.database, "--query", query), true); err != nil {
+		return nil, err
+	}
+	var names, types []string
+	if err := r.dec.Decode(&names); err != nil {
+		_ = r.close()
+		if werr := r.wait(); werr != io.EOF {
+			return nil, werr
+		}
+		return nil, fmt.Errorf("failed to read column names: %w", err)
+	}
+	if err := r.dec.Decode(&types); err != nil {
+		_ = r.close()
+		return nil, fmt.Errorf("failed to read column types: %w", err)
+	}
+	for i, name := range names {
+		col := export.Column{Name: name, Type: export.String}
+		if i < len(types) {
+			col.Type = clickhouseExportType(types[i])
+		}
+		r.cols = append(r.cols, col)
+	}
+	return r, nil
+}
+
+func (c *clickhouseExport) close() {
+	c.client.close()
+}
+
+// clickhouseExportType maps a ClickHouse column type to an output type; decimals, UInt64 and
+// 128/256-bit integers stay text
+func clickhouseExportType(t string) export.Type {
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if strings.HasPrefix(t, wrapper) {
+			t = strings.TrimSuffix(strings.TrimPrefix(t, wrapper), ")")
+		}
+	}
+	switch t {
+	case "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32":
+		return export.Int
+	case "Float32", "Float64":
+		return export.Float
+	case "Bool":
+		return export.Bool
+	case "Date", "Date32":
+		return export.Time
+	}
+	if strings.HasPrefix(t, "DateTime") {
+		return export.Time
+	}
+	return export.String
+}
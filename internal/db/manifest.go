@@ -0,0 +1,259 @@
+package db
+
+import (
+	"dbx/internal/mask"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// BackupContent selects whether a backup holds schema, data, or both
+type BackupContent string
+
+const (
+	ContentAll    BackupContent = "all"
+	ContentSchema BackupContent = "schema"
+	ContentData   BackupContent = "data"
+)
+
+// BackupOptions narrows what a backup contains. The zero value backs up everything.
+type BackupOptions struct {
+	Content            BackupContent `json:"content,omitempty"`             // empty means ContentAll
+	Tables             []string      `json:"tables,omitempty"`              // MySQL/PostgreSQL: only these tables (pg_dump accepts patterns)
+	ExcludeTables      []string      `json:"exclude_tables,omitempty"`      // MySQL/PostgreSQL: skip these tables
+	Collections        []string      `json:"collections,omitempty"`         // MongoDB: only these collections
+	ExcludeCollections []string      `json:"exclude_collections,omitempty"` // MongoDB: skip these collections
+	MaskRules          string        `json:"mask_rules,omitempty"`          // MySQL/PostgreSQL/SQLite: masking rules file applied to the dump
+	Jobs               int           `json:"jobs,omitempty"`                // parallel dump jobs; above 1 MySQL and PostgreSQL write a bundle
+	BGSave             bool          `json:"bgsave,omitempty"`              // Redis: copy the server's own BGSAVE snapshot instead of streaming one
+	Physical           bool          `json:"physical,omitempty"`            // MySQL/MariaDB: hot copy of the data files with xtrabackup/mariabackup instead of a dump
+	Dumper             string        `json:"dumper,omitempty"`              // MySQL/MariaDB/PostgreSQL/MongoDB: auto (default), tool or builtin; manifests say builtin when it wrote the backup
+	Format             string        `json:"format,omitempty"`              // SQLite: binary (default) copies the file, sql writes a SQL text dump
+	Conn               ConnOptions   `json:"-"`                             // how to connect; not recorded in manifests
+	SkipNotify         bool          `json:"-"`                             // no Slack notification; set by BackupDatabases, which sends one for the whole run
+	OnArtifact         func(string)  `json:"-"`                             // called with the path of the backup once it is written, e.g. to upload exactly that file
+}
+
+// Validate rejects option combinations the dump tools cannot honour
+func (o BackupOptions) Validate() error {
+	switch o.Content {
+	case "", ContentAll, ContentSchema, ContentData:
+	default:
+		return fmt.Errorf("invalid content %q (use schema, data or all)", o.Content)
+	}
+	if err := o.Conn.Validate(); err != nil {
+		return err
+	}
+	if o.Jobs < 0 {
+		return fmt.Errorf("jobs cannot be negative")
+	}
+	switch o.Dumper {
+	case "", DumperAuto, DumperTool, DumperBuiltin:
+	default:
+		return fmt.Errorf("invalid dumper %q (use auto, tool or builtin)", o.Dumper)
+	}
+	switch o.Format {
+	case "", SQLiteFormatBinary, SQLiteFormatSQL:
+	default:
+		return fmt.Errorf("invalid format %q (use binary or sql)", o.Format)
+	}
+	if len(o.Collections) > 0 && len(o.ExcludeCollections) > 0 {
+		return fmt.Errorf("--collections and --exclude-collections cannot be combined (mongodump limitation)")
+	}
+	if _, err := o.maskRules(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// maskRules loads the masking rules file, or returns nil when no masking was requested
+func (o BackupOptions) maskRules() (mask.Rules, error) {
+	if o.MaskRules == "" {
+		return nil, nil
+	}
+	return mask.Load(o.MaskRules)
+}
+
+// Partial reports whether the options leave anything out of the backup
+func (o BackupOptions) Partial() bool {
+	return o.contentLabel() != "" || len(o.Tables) > 0 || len(o.ExcludeTables) > 0 || len(o.Collections) > 0 || len(o.ExcludeCollections) > 0
+}
+
+// BackupManifest describes one backup artifact. It is written next to the artifact as <artifact>.meta.json
+// so restores can tell what the artifact contains.
+type BackupManifest struct {
+	DBType     string     `json:"db_type"`
+	Database   string     `json:"database"`
+	BackupType BackupType `json:"backup_type"`
+	CreatedAt  time.Time  `json:"created_at"`
+	BackupOptions
+	// Masked lists the masking rules applied, as table.column (strategy)
+	Masked []string `json:"masked,omitempty"`
+	// Base is the file name of the backup an incremental or differential physical backup applies on top of
+	Base string `json:"base,omitempty"`
+	// ToLSN is the log sequence number a physical backup copied up to; the next incremental starts there
+	ToLSN string `json:"to_lsn,omitempty"`
+	// Revision is the etcd revision, or the Consul Raft index, a snapshot was taken at
+	Revision int64 `json:"revision,omitempty"`
+	// Keys is the number of keys in an etcd snapshot
+	Keys int64 `json:"keys,omitempty"`
+}
+
+// contentLabel is the file name label for schema-only and data-only backups
+func (o BackupOptions) contentLabel() string {
+	if o.Content == ContentSchema || o.Content == ContentData {
+		return string(o.Content)
+	}
+	return ""
+}
+
+// fileLabel is the file name label for content and masking, e.g. "schema", "masked" or "data_masked"
+func (o BackupOptions) fileLabel() string {
+	label := o.contentLabel()
+	if o.MaskRules != "" {
+		if label != "" {
+			label += "_"
+		}
+		label += "masked"
+	}
+	return label
+}
+
+// ManifestPath returns the manifest location for an artifact
+func ManifestPath(artifact string) string {
+	return artifact + ".meta.json"
+}
+
+// SaveManifest writes the manifest for an artifact
+func SaveManifest(artifact string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(ManifestPath(artifact), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest for an artifact. Artifacts made before manifests existed return nil, nil.
+func LoadManifest(artifact string) (*BackupManifest, error) {
+	data, err := os.ReadFile(ManifestPath(artifact))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Describe summarizes what a partial backup contains, or returns "" for a complete one
+func (m *BackupManifest) Describe() string {
+	var parts []string
+	if label := m.contentLabel(); label != "" {
+		parts = append(parts, label+" only")
+	}
+	if len(m.Tables) > 0 {
+		parts = append(parts, "tables "+strings.Join(m.Tables, ", "))
+	}
+	if len(m.ExcludeTables) > 0 {
+		parts = append(parts, "excluding tables "+strings.Join(m.ExcludeTables, ", "))
+	}
+	if len(m.Collections) > 0 {
+		parts = append(parts, "collections "+strings.Join(m.Collections, ", "))
+	}
+	if len(m.ExcludeCollections) > 0 {
+		parts = append(parts, "excluding collections "+strings.Join(m.ExcludeCollections, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// saveManifest records an artifact's manifest; a failure is reported but doesn't fail the backup
+func saveManifest(artifact, dbType, database string, backupType BackupType, opts BackupOptions) {
+	writeManifest(artifact, newManifest(dbType, database, backupType, opts))
+}
+
+// newManifest describes a backup made now with opts
+func newManifest(dbType, database string, backupType BackupType, opts BackupOptions) *BackupManifest {
+	manifest := &BackupManifest{
+		DBType:        dbType,
+		Database:      database,
+		BackupType:    backupType,
+		CreatedAt:     time.Now(),
+		BackupOptions: opts,
+	}
+	if rules, err := opts.maskRules(); err == nil && rules != nil {
+		manifest.Masked = rules.Describe()
+	}
+	return manifest
+}
+
+// writeManifest saves a manifest, reporting a failure without failing the backup, and hands the
+// artifact to OnArtifact. Every backup ends here once its artifact is complete.
+func writeManifest(artifact string, manifest *BackupManifest) {
+	if err := SaveManifest(artifact, manifest); err != nil {
+		fmt.Println("⚠️ Failed to write backup manifest:", err)
+	}
+	if manifest.OnArtifact != nil {
+		manifest.OnArtifact(artifact)
+	}
+}
+
+// findManifest loads the manifest for a restore source, which may be the artifact itself
+// or (for MongoDB) the directory extracted from a zipped artifact
+func findManifest(artifact string) *BackupManifest {
+	for _, candidate := range []string{artifact, artifact + ".zip", strings.TrimSuffix(artifact, ".zip")} {
+		if manifest, err := LoadManifest(candidate); err == nil && manifest != nil {
+			return manifest
+		}
+	}
+	return nil
+}
+
+// warnIfPartial tells the user before a restore that the artifact doesn't hold the whole database
+func warnIfPartial(artifact string) {
+	manifest := findManifest(artifact)
+	if manifest == nil {
+		return
+	}
+	if desc := manifest.Describe(); desc != "" {
+		fmt.Printf("⚠️  Partial backup: contains %s. Objects outside this selection are not restored.\n", desc)
+	}
+}
+
+// checkIncluded returns an error if the artifact's manifest shows the table or collection was left out
+func checkIncluded(artifact, kind, name string) error {
+	manifest := findManifest(artifact)
+	if manifest == nil {
+		return nil
+	}
+	included, excluded := manifest.Tables, manifest.ExcludeTables
+	if kind == "collection" {
+		included, excluded = manifest.Collections, manifest.ExcludeCollections
+	}
+
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			// pg_dump table selections may be patterns; plain names match themselves
+			if ok, _ := path.Match(pattern, name); ok || pattern == name {
+				return true
+			}
+		}
+		return false
+	}
+	if matches(excluded) {
+		return fmt.Errorf("%s '%s' was excluded from this backup", kind, name)
+	}
+	if len(included) > 0 && !matches(included) {
+		return fmt.Errorf("%s '%s' is not in this partial backup (contains %s)", kind, name, strings.Join(included, ", "))
+	}
+	return nil
+}
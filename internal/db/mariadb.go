@@ -0,0 +1,71 @@
+package db
+
+import (
+	"fmt"
+)
+
+// mysqlFlavor returns the manifest engine and display name of a MySQL-protocol backup or restore
+func mysqlFlavor(conn ConnOptions) (engine, name string) {
+	if conn.MariaDB {
+		return "mariadb", "MariaDB"
+	}
+	return "mysql", "MySQL"
+}
+
+// MariaDBBackupName is the file name prefix of a MariaDB server's physical backups, e.g. mariadb_db1_3306.
+// A physical backup covers every database, so the server stands in for a database name.
+func MariaDBBackupName(host string, conn ConnOptions) string {
+	return physicalBackupName(mariabackup.engine, host, conn)
+}
+
+// BackupMariaDB creates a logical backup of a MariaDB database with mariadb-dump
+func BackupMariaDB(host, user, password, database, outDir string) error {
+	return BackupMariaDBWithOptions(host, user, password, database, outDir, BackupTypeFull, BackupOptions{})
+}
+
+// BackupMariaDBWithOptions backs up a MariaDB server. Logical backups take the MySQL path with the
+// MariaDB clients (mariadb-dump, falling back to mysqldump), so every MySQL option applies; with
+// opts.Physical the whole server is copied with mariabackup instead and database is ignored.
+func BackupMariaDBWithOptions(host, user, password, database, outDir string, backupType BackupType, opts BackupOptions) error {
+	opts.Conn.MariaDB = true
+	if opts.Physical {
+		return backupPhysical(mariabackup, host, user, password, outDir, backupType, opts)
+	}
+	return BackupMySQLWithOptions(host, user, password, database, outDir, backupType, opts)
+}
+
+// RestoreMariaDB restores a MariaDB database from a logical backup
+func RestoreMariaDB(host, user, pass, dbName, backupFile string) error {
+	return RestoreMariaDBWithOptions(host, user, pass, dbName, backupFile, RestoreOptions{})
+}
+
+// RestoreMariaDBWithOptions restores a logical backup with the MariaDB clients, or copies a physical
+// backup into opts.DataDir (see RestoreMariaDBPhysical)
+func RestoreMariaDBWithOptions(host, user, pass, dbName, backupFile string, opts RestoreOptions) error {
+	if isPhysicalBackup(backupFile) {
+		return RestoreMariaDBPhysical(backupFile, opts.DataDir)
+	}
+	opts.Conn.MariaDB = true
+	return RestoreMySQLWithOptions(host, user, pass, dbName, backupFile, opts)
+}
+
+// RestoreMariaDBTableWithOptions restores one table from a logical MariaDB backup
+func RestoreMariaDBTableWithOptions(host, user, pass, dbName, backupFile, tableName string, opts RestoreOptions) error {
+	opts.Conn.MariaDB = true
+	return RestoreMySQLTableWithOptions(host, user, pass, dbName, backupFile, tableName, opts)
+}
+
+// RestoreMariaDBPhysical copies a physical backup (the zip written by a physical backup, or a
+// mariabackup target directory) into dataDir with mariabackup --copy-back, preparing it first if it
+// hasn't been. The server must be stopped and dataDir empty; afterwards the files need to be owned
+// by the server's user before it is started.
+func RestoreMariaDBPhysical(backupFile, dataDir string) error {
+	return restorePhysical(physicalToolFor(backupFile, mariabackup), backupFile, dataDir)
+}
+
+func showMariaDBInstallHelp() {
+	fmt.Println("\n💡 mariadb-backup comes with the MariaDB server packages:")
+	fmt.Println("   Ubuntu/Debian: sudo apt install mariadb-backup")
+	fmt.Println("   RHEL/Fedora:   sudo dnf install MariaDB-backup")
+	fmt.Println("   macOS:         brew install mariadb")
+}
@@ -11,18 +11,39 @@ import (
 	osuser "os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // BackupMongo runs mongodump to create a backup
 func BackupMongo(uri, dbName, outDir string) error {
+	return BackupMongoWithOptions(uri, dbName, outDir, BackupOptions{})
+}
+
+// BackupMongoWithOptions runs mongodump limited by opts. mongodump takes a single --collection,
+// so selected collections are dumped one run each into the same backup. Without mongodump, or with
+// opts.Dumper builtin, the built-in dumper writes the same directory layout.
+func BackupMongoWithOptions(uri, dbName, outDir string, opts BackupOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.MaskRules != "" {
+		return fmt.Errorf("masking is supported for MySQL, PostgreSQL and SQLite backups only")
+	}
 	if dbName == "" {
 		return fmt.Errorf("database name cannot be empty")
 	}
 
-	// Check if mongodump is available
-	if _, err := exec.LookPath("mongodump"); err != nil {
+	// Without mongodump the built-in dumper is used, unless the tool was asked for explicitly
+	_, lookErr := exec.LookPath("mongodump")
+	builtin := opts.builtinDump("mongodump", lookErr == nil)
+	if builtin {
+		if err := checkBuiltinDump("mongodump", BackupTypeFull, opts); err != nil {
+			return err
+		}
+		opts.Dumper = DumperBuiltin
+	} else if lookErr != nil {
 		fmt.Println("\n❌ 'mongodump' not found in PATH.")
 		reader := bufio.NewReader(os.Stdin)
 		fmt.Print("Would you like DBX to install MongoDB Tools for you? (y/N): ")
@@ -43,6 +64,13 @@ func BackupMongo(uri, dbName, outDir string) error {
 		if _, err := exec.LookPath("mongodump"); err != nil {
 			return fmt.Errorf("mongodump still not found, aborting backup")
 		}
+	} else {
+		opts.Dumper = ""
+	}
+	if !builtin {
+		if err := opts.Conn.checkToolTLS("mongodump"); err != nil {
+			return err
+		}
 	}
 
 	// Ensure output directory exists
@@ -54,19 +82,43 @@ func BackupMongo(uri, dbName, outDir string) error {
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
 	outPath := filepath.Join(outDir, fmt.Sprintf("%s_%s", dbName, timestamp))
 
-	cmd := exec.Command("mongodump",
-		"--uri="+uri,
-		"--db="+dbName,
-		"--out="+outPath,
-	)
+	connURI, cleanup, err := opts.Conn.mongoURI(uri)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	baseArgs := []string{"--uri=" + connURI, "--db=" + dbName, "--out=" + outPath}
+	for _, collection := range opts.ExcludeCollections {
+		baseArgs = append(baseArgs, "--excludeCollection="+collection)
+	}
+	if opts.Jobs > 0 {
+		baseArgs = append(baseArgs, "--numParallelCollections="+strconv.Itoa(opts.Jobs))
+	}
+	runs := [][]string{baseArgs}
+	if len(opts.Collections) > 0 {
+		runs = nil
+		for _, collection := range opts.Collections {
+			runs = append(runs, append(append([]string{}, baseArgs...), "--collection="+collection))
+		}
+	}
 
 	start := time.Now()
-	fmt.Println("🔄 Running MongoDB backup...")
-	err := cmd.Run()
-	
+	if builtin {
+		fmt.Println("🔄 Running MongoDB backup with the built-in dumper...")
+		err = dumpMongoBuiltin(connURI, dbName, outPath, opts)
+	} else {
+		fmt.Println("🔄 Running MongoDB backup...")
+		for _, args := range runs {
+			cmd := exec.Command("mongodump", args...)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err = cmd.Run(); err != nil {
+				break
+			}
+		}
+	}
+
 	defer func() {
 		status := "SUCCESS"
 		if err != nil {
@@ -75,7 +127,7 @@ func BackupMongo(uri, dbName, outDir string) error {
 		logs.LogEntry("MongoDB", "Backup", status, start, err)
 		
 		// Send Slack notification if webhook is configured
-		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" {
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
 			duration := time.Since(start).Round(time.Second)
 			hostname, _ := os.Hostname()
 			username := "unknown"
@@ -92,7 +144,9 @@ func BackupMongo(uri, dbName, outDir string) error {
 		}
 	}()
 	
-	if err != nil {
+	if err != nil && builtin {
+		return fmt.Errorf("built-in dump failed: %w", err)
+	} else if err != nil {
 		return fmt.Errorf("mongodump failed: %w", err)
 	}
 
@@ -102,9 +156,11 @@ func BackupMongo(uri, dbName, outDir string) error {
 		// Remove uncompressed directory after successful compression
 		defer func() { _ = os.RemoveAll(outPath) }()
 		fmt.Println("🗜 Compressed to:", zipPath)
+		saveManifest(zipPath, "mongodb", dbName, BackupTypeFull, opts)
 	} else {
 		// Compression failed - keep uncompressed backup directory
 		fmt.Println("⚠️ Compression failed:", err)
+		saveManifest(outPath, "mongodb", dbName, BackupTypeFull, opts)
 	}
 
 	fmt.Println("✅ Backup completed:", outPath)
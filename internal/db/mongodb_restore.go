@@ -7,31 +7,50 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"time"
 )
 
 // RestoreMongo restores a MongoDB database using mongorestore.
 func RestoreMongo(uri, dbName, backupDir string) error {
+	return RestoreMongoWithOptions(uri, dbName, backupDir, RestoreOptions{})
+}
+
+// RestoreMongoWithOptions restores a MongoDB database with the given connection options, restoring
+// opts.Jobs collections at a time (0 uses the parallelism the backup was made with, else mongorestore's default)
+func RestoreMongoWithOptions(uri, dbName, backupDir string, opts RestoreOptions) error {
 	start := time.Now()
 	if dbName == "" {
 		return fmt.Errorf("database name cannot be empty")
 	}
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
 
 	if _, err := exec.LookPath("mongorestore"); err != nil {
 		showMongoRestoreHelp()
 		return fmt.Errorf("mongorestore not found in PATH")
 	}
 
-	cmd := exec.Command("mongorestore",
-		"--uri="+uri,
-		"--db="+dbName,
-		"--drop",
-		backupDir,
-	)
+	if err := opts.Conn.checkToolTLS("mongorestore"); err != nil {
+		return err
+	}
+	uri, cleanup, err := opts.Conn.mongoURI(uri)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	args := []string{"--uri=" + uri, "--db=" + dbName, "--drop"}
+	if jobs := restoreJobs(backupDir, opts.Jobs); jobs > 1 {
+		args = append(args, "--numParallelCollections="+strconv.Itoa(jobs))
+	}
+	cmd := exec.Command("mongorestore", append(args, backupDir)...)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 
+	warnIfPartial(backupDir)
 	fmt.Println("🔄 Restoring MongoDB database...")
-	err := cmd.Run()
+	err = cmd.Run()
 	defer func() {
 		status := "SUCCESS"
 		if err != nil {
@@ -50,7 +69,15 @@ func RestoreMongo(uri, dbName, backupDir string) error {
 
 // RestoreMongoCollection restores a specific collection from a MongoDB backup
 func RestoreMongoCollection(uri, dbName, backupDir, collectionName string) error {
+	return RestoreMongoCollectionWithOptions(uri, dbName, backupDir, collectionName, RestoreOptions{})
+}
+
+// RestoreMongoCollectionWithOptions restores a specific collection using the given connection options
+func RestoreMongoCollectionWithOptions(uri, dbName, backupDir, collectionName string, opts RestoreOptions) error {
 	start := time.Now()
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
 	if dbName == "" {
 		return fmt.Errorf("database name cannot be empty")
 	}
@@ -64,6 +91,10 @@ func RestoreMongoCollection(uri, dbName, backupDir, collectionName string) error
 		return fmt.Errorf("mongorestore not found in PATH")
 	}
 
+	if err := checkIncluded(backupDir, "collection", collectionName); err != nil {
+		return err
+	}
+
 	// Find the collection directory in the backup
 	collectionPath := filepath.Join(backupDir, dbName, collectionName+".bson")
 	if _, err := os.Stat(collectionPath); err != nil {
@@ -74,6 +105,15 @@ func RestoreMongoCollection(uri, dbName, backupDir, collectionName string) error
 		}
 	}
 
+	if err := opts.Conn.checkToolTLS("mongorestore"); err != nil {
+		return err
+	}
+	uri, cleanup, err := opts.Conn.mongoURI(uri)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	cmd := exec.Command("mongorestore",
 		"--uri="+uri,
 		"--db="+dbName,
@@ -84,7 +124,7 @@ func RestoreMongoCollection(uri, dbName, backupDir, collectionName string) error
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 
 	fmt.Printf("🔄 Restoring MongoDB collection '%s'...\n", collectionName)
-	err := cmd.Run()
+	err = cmd.Run()
 	defer func() {
 		status := "SUCCESS"
 		if err != nil {
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"bytes"
+	"dbx/internal/logs"
+	"dbx/internal/notify"
+	"fmt"
+	"os"
+	"os/exec"
+	osuser "os/user"
+	"path"
+	"strings"
+	"time"
+)
+
+// systemDatabases are skipped by discovery unless an include pattern names them
+var systemDatabases = map[string][]string{
+	"mysql":    {"information_schema", "performance_schema", "mysql", "sys"},
+	"mariadb":  {"information_schema", "performance_schema", "mysql", "sys"},
+	"postgres": {"postgres"},
+	"mongodb":  {"admin", "config", "local"},
+}
+
+// DatabaseResult is the outcome of backing up one database in a multi-database run
+type DatabaseResult struct {
+	Database string
+	Duration time.Duration
+	Err      error
+}
+
+// MultiBackupSummary collects the results of a multi-database run
+type MultiBackupSummary struct {
+	Engine  string
+	Start   time.Time
+	Results []DatabaseResult
+}
+
+// Failed returns the number of databases whose backup failed
+func (s *MultiBackupSummary) Failed() int {
+	failed := 0
+	for _, r := range s.Results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Err returns an error naming the failed databases, or nil if all succeeded
+func (s *MultiBackupSummary) Err() error {
+	var names []string
+	for _, r := range s.Results {
+		if r.Err != nil {
+			names = append(names, r.Database)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d database backups failed: %s", len(names), len(s.Results), strings.Join(names, ", "))
+}
+
+// String renders the summary as shown on the terminal and in the notification
+func (s *MultiBackupSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s backup of %d databases: %d succeeded, %d failed (%s)\n",
+		s.Engine, len(s.Results), len(s.Results)-s.Failed(), s.Failed(), time.Since(s.Start).Round(time.Second))
+	for _, r := range s.Results {
+		if r.Err != nil {
+			fmt.Fprintf(&b, "❌ %s (%s): %v\n", r.Database, r.Duration.Round(time.Second), r.Err)
+		} else {
+			fmt.Fprintf(&b, "✅ %s (%s)\n", r.Database, r.Duration.Round(time.Second))
+		}
+	}
+	return b.String()
+}
+
+// FilterDatabases applies include and exclude glob patterns (path.Match syntax) to discovered databases.
+// With no include patterns every database is included, except the engine's system databases.
+func FilterDatabases(engine string, names, include, exclude []string) ([]string, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid database pattern %q: %w", pattern, err)
+		}
+	}
+	system := make(map[string]bool)
+	for _, name := range systemDatabases[engine] {
+		system[name] = true
+	}
+
+	var selected []string
+	for _, name := range names {
+		included := len(include) == 0 && !system[name]
+		for _, pattern := range include {
+			if ok, _ := path.Match(pattern, name); ok {
+				included = true
+				break
+			}
+		}
+		for _, pattern := range exclude {
+			if ok, _ := path.Match(pattern, name); ok {
+				included = false
+				break
+			}
+		}
+		if included {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// ListMySQLDatabases discovers the databases on a MySQL server (SHOW DATABASES)
+func ListMySQLDatabases(host, user, password string, conn ConnOptions) ([]string, error) {
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	cmd, err := conn.mysqlCommand("mysql", host, user, "-N", "-B", "-e", "SHOW DATABASES")
+	if err != nil {
+		return nil, fmt.Errorf("%w (needed to discover databases)", err)
+	}
+	cmd.Env = mysqlEnv(password)
+	return listDatabases(cmd.Args[0], cmd)
+}
+
+// ListPostgresDatabases discovers the databases on a PostgreSQL server (pg_database)
+func ListPostgresDatabases(host, port, user, pass string, conn ConnOptions) ([]string, error) {
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("psql", "-h", conn.postgresHost(host), "-p", port, "-U", user, "-d", "postgres", "-At",
+		"-c", "SELECT datname FROM pg_database WHERE NOT datistemplate AND datallowconn ORDER BY datname")
+	cmd.Env = conn.postgresEnv(host)
+	if pass != "" {
+		cmd.Env = append(cmd.Env, "PGPASSWORD="+pass)
+	}
+	return listDatabases("psql", cmd)
+}
+
+// ListMongoDatabases discovers the databases on a MongoDB server (listDatabases)
+func ListMongoDatabases(uri string, conn ConnOptions) ([]string, error) {
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	if err := conn.checkToolTLS("mongosh"); err != nil {
+		return nil, err
+	}
+	uri, cleanup, err := conn.mongoURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	cmd := exec.Command("mongosh", uri, "--quiet", "--eval",
+		"db.adminCommand({listDatabases: 1, nameOnly: true}).databases.forEach(d => print(d.name))")
+	return listDatabases("mongosh", cmd)
+}
+
+func listDatabases(tool string, cmd *exec.Cmd) ([]string, error) {
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, fmt.Errorf("%s not found in PATH (needed to discover databases)", tool)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %v\n%s", err, stderr.String())
+	}
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// BackupDatabases runs backup for each database in turn, continuing past failures, and reports
+// the run as a whole: one log entry, one printed summary and one Slack notification. Each backup
+// gets opts with SkipNotify set, so it doesn't send a notification of its own.
+func BackupDatabases(engine string, names []string, opts BackupOptions, backup func(database string, opts BackupOptions) error) *MultiBackupSummary {
+	summary := &MultiBackupSummary{Engine: engine, Start: time.Now()}
+
+	opts.SkipNotify = true
+	for _, name := range names {
+		fmt.Printf("\n📦 [%d/%d] %s\n", len(summary.Results)+1, len(names), name)
+		start := time.Now()
+		err := backup(name, opts)
+		summary.Results = append(summary.Results, DatabaseResult{Database: name, Duration: time.Since(start), Err: err})
+	}
+
+	err := summary.Err()
+	status := "SUCCESS"
+	if err != nil {
+		status = "FAILED"
+	}
+	logs.LogEntry(engine, fmt.Sprintf("Backup (%d databases)", len(names)), status, summary.Start, err)
+	fmt.Println("\n" + summary.String())
+
+	if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" {
+		hostname, _ := os.Hostname()
+		username := "unknown"
+		if u, e := osuser.Current(); e == nil {
+			username = u.Username
+		}
+		message := fmt.Sprintf("%s\nHost: %s\nUser: %s", summary.String(), hostname, username)
+		_ = notify.SlackNotify(webhook, message)
+	}
+	return summary
+}
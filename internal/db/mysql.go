@@ -3,13 +3,14 @@ package db
 import (
 	"bytes"
 	"dbx/internal/logs"
+	"dbx/internal/mask"
 	"dbx/internal/notify"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	osuser "os/user"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -20,75 +21,78 @@ func BackupMySQL(host, user, password, database, outDir string) error {
 
 // BackupMySQLWithType creates a backup of a MySQL database with specified backup type
 func BackupMySQLWithType(host, user, password, database, outDir string, backupType BackupType) error {
+	return BackupMySQLWithOptions(host, user, password, database, outDir, backupType, BackupOptions{})
+}
+
+// BackupMySQLWithOptions creates a backup of a MySQL database limited by opts
+// (--tables are dumped alone, --exclude-tables map to --ignore-table, content to --no-data/--no-create-info).
+// With opts.Physical the whole server is copied with xtrabackup instead and database is ignored.
+// Without mysqldump, or with opts.Dumper builtin, the built-in dumper writes the same kind of dump.
+func BackupMySQLWithOptions(host, user, password, database, outDir string, backupType BackupType, opts BackupOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.Physical {
+		return backupPhysical(xtrabackup, host, user, password, outDir, backupType, opts)
+	}
+	rules, err := opts.maskRules()
+	if err != nil {
+		return err
+	}
 	start := time.Now()
+	engine, engineName := mysqlFlavor(opts.Conn)
+	_, toolErr := opts.Conn.mysqlClient("mysqldump")
+	builtin := opts.builtinDump("mysqldump", toolErr == nil)
+	if builtin {
+		if err := checkBuiltinDump("mysqldump", backupType, opts); err != nil {
+			return err
+		}
+		opts.Dumper = DumperBuiltin
+	} else {
+		opts.Dumper = ""
+	}
 
 	ts := time.Now().Format("2006-01-02_15-04")
 	backupSuffix := string(backupType)
 	if backupType != BackupTypeFull {
 		backupSuffix = string(backupType) + "_" + ts
 	}
+	if label := opts.fileLabel(); label != "" {
+		backupSuffix += "_" + label
+	}
 	outFile := filepath.Join(outDir, fmt.Sprintf("%s-%s_%s.sql", database, backupSuffix, ts))
 
 	if err := os.MkdirAll(outDir, 0755); err != nil {
 		return err
 	}
 
-	args := []string{"-h", host, "-u", user}
-	// Use MYSQL_PWD environment variable for security (password not visible in process list)
-	if password != "" {
-		// Set environment variable before command execution
-		// Note: This is set per-command, not globally
-	}
-	
-	// For incremental backups, use --master-data and --flush-logs
-	if backupType == BackupTypeIncremental {
-		args = append(args, "--master-data=2", "--flush-logs", "--single-transaction")
-	} else if backupType == BackupTypeDifferential {
-		// Differential backup: backup since last full backup
-		args = append(args, "--master-data=2", "--single-transaction")
-	}
-	
-	args = append(args, database)
-
-	cmd := exec.Command("mysqldump", args...)
-	env := os.Environ()
-	// Set MYSQL_PWD environment variable for secure password passing
-	if password != "" {
-		env = append(env, "MYSQL_PWD="+password)
-	}
-	cmd.Env = env
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return err
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return err
-	}
-
-	var stderrBuf bytes.Buffer
-	go func() {
-		io.Copy(&stderrBuf, stderr)
-	}()
-
-	// Start mysqldump process
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("mysqldump failed to start: %v", err)
+	if opts.Jobs > 1 {
+		if backupType != BackupTypeFull {
+			return fmt.Errorf("parallel %s backups (--jobs) support full backups only", engineName)
+		}
+		return backupMySQLParallel(host, user, password, database, strings.TrimSuffix(outFile, ".sql"), opts, rules)
 	}
 
-	fmt.Println("🔄 Running MySQL backup...")
-
 	// Buffer the dump output in memory
 	var outputBuf bytes.Buffer
-	if _, err := io.Copy(&outputBuf, stdout); err != nil {
+	if builtin {
+		fmt.Printf("🔄 Running %s backup with the built-in dumper...\n", engineName)
+		if err := dumpMySQLBuiltin(&outputBuf, host, user, password, database, opts); err != nil {
+			return fmt.Errorf("built-in dump failed: %w", err)
+		}
+	} else if err := runMysqldump(&outputBuf, host, user, password, database, backupType, opts, rules != nil); err != nil {
 		return err
 	}
 
-	// Wait for mysqldump to finish and check for error
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("mysqldump failed: %v\n%s", err, stderrBuf.String())
+	// Mask in memory, so unmasked data never reaches the disk
+	if rules != nil {
+		var masked bytes.Buffer
+		stats, err := mask.Dump(&outputBuf, &masked, mask.MySQL, rules, nil)
+		if err != nil {
+			return fmt.Errorf("masking failed: %w", err)
+		}
+		reportMaskStats(stats)
+		outputBuf = masked
 	}
 
 	// Write to .sql file only after successful dump
@@ -103,10 +107,10 @@ func BackupMySQLWithType(host, user, password, database, outDir string, backupTy
 		if err != nil {
 			status = "FAILED"
 		}
-		logs.LogEntry("MySQL", "Backup", status, start, err)
+		logs.LogEntry(engineName, "Backup", status, start, err)
 		
 		// Send Slack notification if webhook is configured
-		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" {
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
 			duration := time.Since(start).Round(time.Second)
 			hostname, _ := os.Hostname()
 			username := "unknown"
@@ -114,8 +118,8 @@ func BackupMySQLWithType(host, user, password, database, outDir string, backupTy
 				username = u.Username
 			}
 			
-			message := fmt.Sprintf("MySQL Backup %s\nDatabase: %s\nDuration: %s\nHost: %s\nUser: %s", 
-				status, database, duration, hostname, username)
+			message := fmt.Sprintf("%s Backup %s\nDatabase: %s\nDuration: %s\nHost: %s\nUser: %s", 
+				engineName, status, database, duration, hostname, username)
 			if err != nil {
 				message += fmt.Sprintf("\nError: %v", err)
 			}
@@ -136,6 +140,87 @@ func BackupMySQLWithType(host, user, password, database, outDir string, backupTy
 		return fmt.Errorf("backup file is empty")
 	}
 
+	saveManifest(outFile, engine, database, backupType, opts)
 	fmt.Printf("✅ Backup verified: %s (%.2f MB)\n", outFile, float64(info.Size())/1024/1024)
 	return nil
 }
+
+// runMysqldump runs mysqldump (or mariadb-dump) for database into w. completeInsert adds column
+// lists to every INSERT, which masking needs.
+func runMysqldump(w io.Writer, host, user, password, database string, backupType BackupType, opts BackupOptions, completeInsert bool) error {
+	_, engineName := mysqlFlavor(opts.Conn)
+	var args []string
+	// Use MYSQL_PWD environment variable for security (password not visible in process list)
+	if password != "" {
+		// Set environment variable before command execution
+		// Note: This is set per-command, not globally
+	}
+	
+	// For incremental backups, use --master-data and --flush-logs
+	if backupType == BackupTypeIncremental {
+		args = append(args, "--master-data=2", "--flush-logs", "--single-transaction")
+	} else if backupType == BackupTypeDifferential {
+		// Differential backup: backup since last full backup
+		args = append(args, "--master-data=2", "--single-transaction")
+	}
+	
+	switch opts.Content {
+	case ContentSchema:
+		args = append(args, "--no-data")
+	case ContentData:
+		args = append(args, "--no-create-info")
+	}
+	for _, table := range opts.ExcludeTables {
+		args = append(args, "--ignore-table="+database+"."+table)
+	}
+	if completeInsert {
+		// Column lists on every INSERT let masking work on data-only dumps too
+		args = append(args, "--complete-insert")
+	}
+	args = append(args, database)
+	args = append(args, opts.Tables...)
+
+	cmd, err := opts.Conn.mysqlCommand("mysqldump", host, user, args...)
+	if err != nil {
+		return err
+	}
+	env := os.Environ()
+	// Set MYSQL_PWD environment variable for secure password passing
+	if password != "" {
+		env = append(env, "MYSQL_PWD="+password)
+	}
+	cmd.Env = env
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	var stderrBuf bytes.Buffer
+	go func() {
+		io.Copy(&stderrBuf, stderr)
+	}()
+
+	// Start mysqldump process
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("mysqldump failed to start: %v", err)
+	}
+
+	fmt.Printf("🔄 Running %s backup...\n", engineName)
+
+	if _, err := io.Copy(w, stdout); err != nil {
+		return err
+	}
+
+	// Wait for mysqldump to finish and check for error
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("mysqldump failed: %v\n%s", err, stderrBuf.String())
+	}
+	return nil
+}
+
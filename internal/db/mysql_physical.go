@@ -0,0 +1,23 @@
+package db
+
+import "fmt"
+
+// MySQLBackupName is the file name prefix of a MySQL server's physical backups, e.g. mysql_db1_3306
+func MySQLBackupName(host string, conn ConnOptions) string {
+	return physicalBackupName(xtrabackup.engine, host, conn)
+}
+
+// RestoreMySQLPhysical copies a physical backup into dataDir with xtrabackup --copy-back. For an
+// incremental or differential backup, the full backup and increments it builds on must be in the
+// same directory; they are prepared together first. The server must be stopped and dataDir empty;
+// afterwards the files need to be owned by the server's user before it is started.
+func RestoreMySQLPhysical(backupFile, dataDir string) error {
+	return restorePhysical(physicalToolFor(backupFile, xtrabackup), backupFile, dataDir)
+}
+
+func showXtrabackupInstallHelp() {
+	fmt.Println("\n💡 Install Percona XtraBackup matching your MySQL version (8.0 needs XtraBackup 8.0):")
+	fmt.Println("   Ubuntu/Debian: sudo apt install percona-xtrabackup-80 (from the Percona repository)")
+	fmt.Println("   RHEL/Fedora:   sudo dnf install percona-xtrabackup-80")
+	fmt.Println("   Docs:          https://docs.percona.com/percona-xtrabackup/")
+}
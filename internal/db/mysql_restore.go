@@ -5,28 +5,55 @@ import (
 	"dbx/internal/logs"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 )
 
-// RestoreMySQL restores a MySQL database from a .sql dump file
+// RestoreMySQL restores a MySQL database from a .sql dump file or a parallel backup bundle
 func RestoreMySQL(host, user, pass, dbName, backupFile string) error {
+	return RestoreMySQLWithOptions(host, user, pass, dbName, backupFile, RestoreOptions{})
+}
+
+// RestoreMySQLWithOptions restores a MySQL database with the given connection options, loading the
+// tables of a parallel backup bundle opts.Jobs at a time. Physical backups are copied into opts.DataDir
+// instead (see RestoreMySQLPhysical).
+func RestoreMySQLWithOptions(host, user, pass, dbName, backupFile string, opts RestoreOptions) error {
+	if isPhysicalBackup(backupFile) {
+		return RestoreMySQLPhysical(backupFile, opts.DataDir)
+	}
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
 	start := time.Now()
-	if _, err := exec.LookPath("mysql"); err != nil {
+	_, engineName := mysqlFlavor(opts.Conn)
+	if _, err := opts.Conn.mysqlClient("mysql"); err != nil {
 		fmt.Println("❌ 'mysql' command not found in PATH.")
 		fmt.Println("👉 Install MySQL client tools:")
-		fmt.Println("   sudo apt install -y mysql-client")
-		return fmt.Errorf("mysql not found in PATH")
+		fmt.Println("   sudo apt install -y mysql-client (or mariadb-client)")
+		return err
 	}
 
-	fmt.Println("🔄 Restoring MySQL database...")
+	warnIfPartial(backupFile)
+	if isMySQLBundle(backupFile) {
+		opts.Jobs = restoreJobs(backupFile, opts.Jobs)
+		err := restoreMySQLBundle(host, user, pass, dbName, backupFile, "", opts)
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry(engineName, "Restore", status, start, err)
+		if err != nil {
+			return fmt.Errorf("mysql restore failed: %w", err)
+		}
+		fmt.Printf("✅ %s restore completed successfully.\n", engineName)
+		return nil
+	}
+	fmt.Printf("🔄 Restoring %s database...\n", engineName)
 
-	cmd := exec.Command("mysql",
-		"-h", host,
-		"-u", user,
-		dbName,
-	)
+	cmd, err := opts.Conn.mysqlCommand("mysql", host, user, dbName)
+	if err != nil {
+		return err
+	}
 	// Use MYSQL_PWD environment variable for security
 	env := os.Environ()
 	if pass != "" {
@@ -50,31 +77,62 @@ func RestoreMySQL(host, user, pass, dbName, backupFile string) error {
 		if err != nil {
 			status = "FAILED"
 		}
-		logs.LogEntry("MySQL", "Restore", status, start, err)
+		logs.LogEntry(engineName, "Restore", status, start, err)
 	}()
 
 	if err != nil {
 		return fmt.Errorf("mysql restore failed: %w", err)
 	}
 
-	fmt.Println("✅ MySQL restore completed successfully.")
+	fmt.Printf("✅ %s restore completed successfully.\n", engineName)
 	return nil
 }
 
 // RestoreMySQLTable restores a specific table from a MySQL dump file
 func RestoreMySQLTable(host, user, pass, dbName, backupFile, tableName string) error {
+	return RestoreMySQLTableWithOptions(host, user, pass, dbName, backupFile, tableName, RestoreOptions{})
+}
+
+// RestoreMySQLTableWithOptions restores a specific table using the given connection options
+func RestoreMySQLTableWithOptions(host, user, pass, dbName, backupFile, tableName string, opts RestoreOptions) error {
+	if isPhysicalBackup(backupFile) {
+		return fmt.Errorf("physical backups can only be restored whole (omit --table)")
+	}
 	start := time.Now()
-	if _, err := exec.LookPath("mysql"); err != nil {
-		return fmt.Errorf("mysql not found in PATH")
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
+	_, engineName := mysqlFlavor(opts.Conn)
+	if _, err := opts.Conn.mysqlClient("mysql"); err != nil {
+		return err
+	}
+
+	if err := checkIncluded(backupFile, "table", tableName); err != nil {
+		return err
 	}
 
-	fmt.Printf("🔄 Restoring MySQL table '%s'...\n", tableName)
+	fmt.Printf("🔄 Restoring %s table '%s'...\n", engineName, tableName)
 
 	// Verify backup file exists before attempting restore
 	if _, err := os.Stat(backupFile); err != nil {
 		return fmt.Errorf("backup file not found: %w", err)
 	}
 
+	// A parallel bundle holds each table in its own file, so restore just that file
+	if isMySQLBundle(backupFile) {
+		err := restoreMySQLBundle(host, user, pass, dbName, backupFile, tableName, opts)
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry(engineName, fmt.Sprintf("RestoreTable(%s)", tableName), status, start, err)
+		if err != nil {
+			return fmt.Errorf("mysql table restore failed: %w", err)
+		}
+		fmt.Printf("✅ %s table '%s' restore completed successfully.\n", engineName, tableName)
+		return nil
+	}
+
 	// Extract table data from dump file
 	file, err := os.Open(backupFile)
 	if err != nil {
@@ -113,11 +171,10 @@ func RestoreMySQLTable(host, user, pass, dbName, backupFile, tableName string) e
 		return fmt.Errorf("table '%s' not found in backup file. Please verify the table name and backup file contents", tableName)
 	}
 
-	cmd := exec.Command("mysql",
-		"-h", host,
-		"-u", user,
-		dbName,
-	)
+	cmd, err := opts.Conn.mysqlCommand("mysql", host, user, dbName)
+	if err != nil {
+		return err
+	}
 	// Use MYSQL_PWD environment variable for security
 	env := os.Environ()
 	if pass != "" {
@@ -133,14 +190,14 @@ func RestoreMySQLTable(host, user, pass, dbName, backupFile, tableName string) e
 		if err != nil {
 			status = "FAILED"
 		}
-		logs.LogEntry("MySQL", fmt.Sprintf("RestoreTable(%s)", tableName), status, start, err)
+		logs.LogEntry(engineName, fmt.Sprintf("RestoreTable(%s)", tableName), status, start, err)
 	}()
 
 	if err != nil {
 		return fmt.Errorf("mysql table restore failed: %w", err)
 	}
 
-	fmt.Printf("✅ MySQL table '%s' restore completed successfully.\n", tableName)
+	fmt.Printf("✅ %s table '%s' restore completed successfully.\n", engineName, tableName)
 	return nil
 }
 
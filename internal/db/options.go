@@ -0,0 +1,469 @@
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// mysqlSSLModes maps --tls-mode values (named as libpq names them) to MySQL's --ssl-mode
+var mysqlSSLModes = map[string]string{
+	"disable":     "DISABLED",
+	"prefer":      "PREFERRED",
+	"require":     "REQUIRED",
+	"verify-ca":   "VERIFY_CA",
+	"verify-full": "VERIFY_IDENTITY",
+}
+
+// mariadbSSLArgs maps --tls-mode values to the MariaDB clients' flags, which have no --ssl-mode
+var mariadbSSLArgs = map[string][]string{
+	"disable":     {"--skip-ssl"},
+	"require":     {"--ssl"},
+	"verify-ca":   {"--ssl", "--ssl-verify-server-cert"},
+	"verify-full": {"--ssl", "--ssl-verify-server-cert"},
+}
+
+// mariadbClients are the MariaDB names of the MySQL client tools. Recent MariaDB packages may
+// ship only these, without the mysqldump and mysql symlinks.
+var mariadbClients = map[string]string{
+	"mysqldump": "mariadb-dump",
+	"mysql":     "mariadb",
+}
+
+// ConnOptions are the connection settings beyond host, user and password.
+// The zero value connects over TCP with each client's default TLS behaviour.
+type ConnOptions struct {
+	Port    string // MySQL and MongoDB; PostgreSQL, Redis, etcd and Consul functions take the port as an argument
+	Socket  string // MySQL/MongoDB/Redis/etcd/Consul socket file, or PostgreSQL socket directory; used instead of host
+	TLSMode string // disable, prefer, require, verify-ca or verify-full
+	TLSCA   string // CA certificate file
+	TLSCert string // client certificate file
+	TLSKey  string // client key file
+	MariaDB bool   // prefer the MariaDB clients (mariadb-dump, mariadb) and their TLS flags
+	// TLSServerName is the name the server certificate is checked against when the host is an SSH
+	// tunnel's local address; tunnel.Rewrite sets it to the database's own host
+	TLSServerName string
+}
+
+// RestoreOptions tune a restore. The zero value restores with the tools' defaults.
+type RestoreOptions struct {
+	Jobs int // parallel jobs; 0 uses the parallelism the backup was made with
+	Conn ConnOptions
+	// ListenHost is the address Redis connects back to during a restore (default: detected)
+	ListenHost string
+	// DataDir is the stopped server's data directory a physical MySQL or MariaDB backup, or an etcd
+	// snapshot, is restored into
+	DataDir string
+	// etcd: the member the snapshot is restored as, and the cluster it joins; empty restores a
+	// single-member cluster with etcd's defaults
+	MemberName, InitialCluster, PeerURLs string
+}
+
+// ConnOptionsFromParams reads connection options from scheduler job params
+func ConnOptionsFromParams(params map[string]string) ConnOptions {
+	return ConnOptions{
+		Port:    params["port"],
+		Socket:  params["socket"],
+		TLSMode: params["tls_mode"],
+		TLSCA:   params["tls_ca"],
+		TLSCert: params["tls_cert"],
+		TLSKey:  params["tls_key"],
+
+		TLSServerName: params["tls_server_name"],
+	}
+}
+
+// BackupOptionsFromParams reads backup options from scheduler job or profile params: filters,
+// content, masking, parallelism, dumper, format, method and connection options
+func BackupOptionsFromParams(params map[string]string) BackupOptions {
+	jobs, _ := strconv.Atoi(params["jobs"])
+	return BackupOptions{
+		Content:            BackupContent(params["content"]),
+		Tables:             SplitParam(params["tables"]),
+		ExcludeTables:      SplitParam(params["exclude_tables"]),
+		Collections:        SplitParam(params["collections"]),
+		ExcludeCollections: SplitParam(params["exclude_collections"]),
+		MaskRules:          params["mask_rules"],
+		Jobs:               jobs,
+		Dumper:             params["dumper"],
+		Format:             params["format"],
+		Physical:           BackupMethod(params) == "physical",
+		BGSave:             params["bgsave"] == "true",
+		Conn:               ConnOptionsFromParams(params),
+	}
+}
+
+// BackupTypeFromParams reads the backup type from job or profile params; without one backups are full
+func BackupTypeFromParams(params map[string]string) BackupType {
+	if params["type"] == "" {
+		return BackupTypeFull
+	}
+	return BackupType(params["type"])
+}
+
+// SplitParam splits a comma-separated job or profile param into its non-empty items
+func SplitParam(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// BackupMethod reads a MySQL or MariaDB backup method from scheduler job or profile params.
+// Params saved with physical=true, the old MariaDB spelling, take physical backups too.
+func BackupMethod(params map[string]string) string {
+	if params["method"] == "" && params["physical"] == "true" {
+		return "physical"
+	}
+	return params["method"]
+}
+
+// Validate checks the TLS mode and that client keys come with a certificate
+func (c ConnOptions) Validate() error {
+	if _, ok := mysqlSSLModes[c.TLSMode]; c.TLSMode != "" && !ok {
+		return fmt.Errorf("invalid TLS mode %q (use disable, prefer, require, verify-ca or verify-full)", c.TLSMode)
+	}
+	if c.TLSKey != "" && c.TLSCert == "" {
+		return fmt.Errorf("--tls-key needs --tls-cert")
+	}
+	if c.TLSMode == "disable" && (c.TLSCA != "" || c.TLSCert != "") {
+		return fmt.Errorf("TLS certificates can't be combined with --tls-mode disable")
+	}
+	return nil
+}
+
+// checkToolTLS rejects verify-full through an SSH tunnel for client tools that can only check the
+// certificate against the host they connect to, which is then the tunnel's local address
+func (c ConnOptions) checkToolTLS(tool string) error {
+	if c.TLSMode == "verify-full" && c.TLSServerName != "" {
+		return fmt.Errorf("%s can't check the certificate of %s through --ssh-host with --tls-mode verify-full; use verify-ca, or --dumper builtin for backups",
+			tool, c.TLSServerName)
+	}
+	return nil
+}
+
+// mysqlClient finds tool ("mysqldump" or "mysql") on PATH, falling back to its MariaDB name;
+// with MariaDB set the MariaDB name is tried first
+func (c ConnOptions) mysqlClient(tool string) (string, error) {
+	candidates := []string{tool, mariadbClients[tool]}
+	if c.MariaDB {
+		candidates[0], candidates[1] = candidates[1], candidates[0]
+	}
+	for _, name := range candidates {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in PATH (nor %s)", candidates[0], candidates[1])
+}
+
+// mysqlCommand builds a mysqldump or mysql command (see mysqlClient) with the connection arguments
+// followed by args; the caller sets the environment
+func (c ConnOptions) mysqlCommand(tool, host, user string, args ...string) (*exec.Cmd, error) {
+	bin, err := c.mysqlClient(tool)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkToolTLS(bin); err != nil {
+		return nil, err
+	}
+	mariadb := c.MariaDB || strings.HasPrefix(bin, "mariadb")
+	return exec.Command(bin, append(c.mysqlArgs(host, user, mariadb), args...)...), nil
+}
+
+// mysqlArgs returns the connection arguments for the mysql and mysqldump clients, or their
+// MariaDB counterparts
+func (c ConnOptions) mysqlArgs(host, user string, mariadb bool) []string {
+	if c.Socket != "" {
+		// The MySQL clients only use the socket when connecting to localhost
+		host = "localhost"
+	}
+	args := []string{"-h", host, "-u", user}
+	if c.Port != "" {
+		args = append(args, "--port="+c.Port)
+	}
+	if c.Socket != "" {
+		args = append(args, "--socket="+c.Socket)
+	}
+	if mariadb {
+		args = append(args, mariadbSSLArgs[c.TLSMode]...)
+	} else if mode := mysqlSSLModes[c.TLSMode]; mode != "" {
+		args = append(args, "--ssl-mode="+mode)
+	}
+	if c.TLSCA != "" {
+		args = append(args, "--ssl-ca="+c.TLSCA)
+	}
+	if c.TLSCert != "" {
+		args = append(args, "--ssl-cert="+c.TLSCert)
+	}
+	if c.TLSKey != "" {
+		args = append(args, "--ssl-key="+c.TLSKey)
+	}
+	return args
+}
+
+// redisArgs returns the connection arguments for redis-cli; the password is passed in REDISCLI_AUTH.
+// Redis has no TLS negotiation, so prefer without certificates connects in plain text.
+func (c ConnOptions) redisArgs(host, port, user string) []string {
+	var args []string
+	if c.Socket != "" {
+		args = append(args, "-s", c.Socket)
+	} else {
+		args = append(args, "-h", host, "-p", port)
+	}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	if c.TLSMode == "disable" || (c.TLSMode == "" || c.TLSMode == "prefer") && c.TLSCA == "" && c.TLSCert == "" {
+		return args
+	}
+	args = append(args, "--tls")
+	if c.TLSServerName != "" {
+		args = append(args, "--sni", c.TLSServerName)
+	}
+	if c.TLSMode == "require" && c.TLSCA == "" {
+		args = append(args, "--insecure")
+	}
+	if c.TLSCA != "" {
+		args = append(args, "--cacert", c.TLSCA)
+	}
+	if c.TLSCert != "" {
+		key := c.TLSKey
+		if key == "" {
+			key = c.TLSCert
+		}
+		args = append(args, "--cert", c.TLSCert, "--key", key)
+	}
+	return args
+}
+
+// tlsConfig builds the TLS settings for a Go driver connecting to serverName (or TLSServerName,
+// through a tunnel); nil means the options ask for no particular TLS behaviour (no mode and no
+// certificates), or disable it
+func (c ConnOptions) tlsConfig(serverName string) (*tls.Config, error) {
+	mode := c.TLSMode
+	if mode == "disable" || (mode == "" || mode == "prefer") && c.TLSCA == "" && c.TLSCert == "" {
+		return nil, nil
+	}
+	if c.TLSServerName != "" {
+		serverName = c.TLSServerName
+	}
+	cfg := &tls.Config{ServerName: serverName}
+	if c.TLSCA != "" {
+		pem, err := os.ReadFile(c.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.TLSCA)
+		}
+	}
+	if c.TLSCert != "" {
+		keyFile := c.TLSKey
+		if keyFile == "" {
+			keyFile = c.TLSCert
+		}
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// As in libpq, require with a CA file verifies the chain like verify-ca
+	if mode == "verify-ca" || mode != "verify-full" && c.TLSCA != "" {
+		// Check the chain against the CA but not the host name
+		roots := cfg.RootCAs
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("server sent no certificate")
+			}
+			certs := make([]*x509.Certificate, len(rawCerts))
+			for i, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					return err
+				}
+				certs[i] = cert
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range certs[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+			return err
+		}
+	} else if mode != "verify-full" {
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg, nil
+}
+
+// httpClient returns a client for the HTTP API of etcd or Consul at host:port, or at the socket,
+// and the base URL to address it by; https is used when the options ask for TLS
+func (c ConnOptions) httpClient(host, port string) (*http.Client, string, error) {
+	if err := c.Validate(); err != nil {
+		return nil, "", err
+	}
+	tlsConfig, err := c.tlsConfig(host)
+	if err != nil {
+		return nil, "", err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	baseURL := scheme + "://" + net.JoinHostPort(host, port)
+	if c.Socket != "" {
+		socket := c.Socket
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		baseURL = scheme + "://localhost"
+	}
+	return &http.Client{Transport: transport}, baseURL, nil
+}
+
+// postgresHost returns the -h of a libpq client: the socket directory when one is set, since libpq
+// treats a directory as the host, or the TLS server name through a tunnel, which libpq checks the
+// certificate against while it connects to PGHOSTADDR (see postgresEnv)
+func (c ConnOptions) postgresHost(host string) string {
+	if c.Socket != "" {
+		return c.Socket
+	}
+	if c.TLSServerName != "" {
+		return c.TLSServerName
+	}
+	return host
+}
+
+// postgresEnv returns the environment for libpq clients connecting to host, with the TLS settings
+// as PGSSL* variables
+func (c ConnOptions) postgresEnv(host string) []string {
+	env := os.Environ()
+	if c.TLSServerName != "" && c.Socket == "" {
+		env = append(env, "PGHOSTADDR="+host)
+	}
+	for name, value := range map[string]string{
+		"PGSSLMODE":     c.TLSMode,
+		"PGSSLROOTCERT": c.TLSCA,
+		"PGSSLCERT":     c.TLSCert,
+		"PGSSLKEY":      c.TLSKey,
+	} {
+		if value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// mongoURI applies the options to a MongoDB connection string, which every MongoDB tool accepts:
+// port and socket replace the host, TLS settings become URI options. MongoDB wants the client
+// certificate and key in one PEM file, so separate files are combined into a temporary file that
+// cleanup removes.
+func (c ConnOptions) mongoURI(uri string) (string, func(), error) {
+	cleanup := func() {}
+	if c == (ConnOptions{}) {
+		return uri, cleanup, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid MongoDB URI: %w", err)
+	}
+
+	host := u.Host
+	if c.Socket != "" || c.Port != "" {
+		if u.Scheme == "mongodb+srv" || strings.Contains(u.Host, ",") {
+			return "", nil, fmt.Errorf("--port and --socket need a single-host mongodb:// URI")
+		}
+		switch {
+		case c.Socket != "":
+			host = strings.ReplaceAll(url.PathEscape(c.Socket), "/", "%2F")
+		default:
+			host = u.Hostname() + ":" + c.Port
+		}
+	}
+
+	query := u.Query()
+	switch c.TLSMode {
+	case "disable":
+		query.Set("tls", "false")
+	case "require":
+		query.Set("tls", "true")
+		query.Set("tlsInsecure", "true")
+	case "verify-ca":
+		query.Set("tls", "true")
+		query.Set("tlsAllowInvalidHostnames", "true")
+	case "verify-full":
+		query.Set("tls", "true")
+	}
+	if c.TLSCA != "" {
+		query.Set("tls", "true")
+		query.Set("tlsCAFile", c.TLSCA)
+	}
+	if c.TLSCert != "" {
+		pem := c.TLSCert
+		if c.TLSKey != "" && c.TLSKey != c.TLSCert {
+			if pem, err = combinePEM(c.TLSCert, c.TLSKey); err != nil {
+				return "", nil, err
+			}
+			cleanup = func() { _ = os.Remove(pem) }
+		}
+		query.Set("tls", "true")
+		query.Set("tlsCertificateKeyFile", pem)
+	}
+
+	// Built by hand, since url.URL would escape the %2F of a socket path again
+	var b strings.Builder
+	b.WriteString(u.Scheme + "://")
+	if u.User != nil {
+		b.WriteString(u.User.String() + "@")
+	}
+	b.WriteString(host)
+	b.WriteString(u.EscapedPath())
+	if encoded := query.Encode(); encoded != "" {
+		if u.EscapedPath() == "" {
+			b.WriteString("/")
+		}
+		b.WriteString("?" + encoded)
+	}
+	return b.String(), cleanup, nil
+}
+
+// combinePEM writes a certificate and its key into one private temp file
+func combinePEM(certFile, keyFile string) (string, error) {
+	cert, err := os.ReadFile(certFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read TLS key: %w", err)
+	}
+	f, err := os.CreateTemp("", "dbx-mongo-*.pem")
+	if err != nil {
+		return "", fmt.Errorf("failed to create certificate file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(append(cert, '\n'), key...)); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write certificate file: %w", err)
+	}
+	return f.Name(), nil
+}
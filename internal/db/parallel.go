@@ -0,0 +1,409 @@
+package db
+
+import (
+	"archive/zip"
+	"bytes"
+	"dbx/internal/logs"
+	"dbx/internal/mask"
+	"dbx/internal/notify"
+	"dbx/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	osuser "os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bundleIndexFile is the restore plan inside a parallel MySQL backup bundle
+const bundleIndexFile = "dbx-bundle.json"
+
+// BundleEntry is one table's dump file in a parallel MySQL backup bundle
+type BundleEntry struct {
+	Table string `json:"table"`
+	File  string `json:"file"`
+}
+
+// BundleIndex describes a parallel MySQL backup: a zip of one dump file per table, restored in
+// parallel, plus the views, restored once every table exists
+type BundleIndex struct {
+	Database  string        `json:"database"`
+	Tables    []BundleEntry `json:"tables"`
+	ViewsFile string        `json:"views_file,omitempty"`
+}
+
+// runParallel calls fn for every item with at most jobs calls running at once, and reports every failure
+func runParallel(jobs int, items []string, fn func(item string) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	queue := make(chan string)
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if err := fn(item); err != nil {
+					mu.Lock()
+					failed = append(failed, fmt.Sprintf("%s: %v", item, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d failed:\n%s", len(failed), len(items), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// mysqlEnv returns the environment for a MySQL client, passing the password as MYSQL_PWD
+func mysqlEnv(password string) []string {
+	env := os.Environ()
+	if password != "" {
+		env = append(env, "MYSQL_PWD="+password)
+	}
+	return env
+}
+
+// listMySQLTables returns the base tables and views of a MySQL database (SHOW FULL TABLES)
+func listMySQLTables(host, user, password, database string, conn ConnOptions) (tables, views []string, err error) {
+	cmd, err := conn.mysqlCommand("mysql", host, user, "-N", "-B", "-e", "SHOW FULL TABLES", database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w (needed to list tables for a parallel backup)", err)
+	}
+	cmd.Env = mysqlEnv(password)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tables: %v\n%s", err, stderr.String())
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		name, kind, ok := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		if !ok {
+			continue
+		}
+		if kind == "VIEW" {
+			views = append(views, name)
+		} else {
+			tables = append(tables, name)
+		}
+	}
+	return tables, views, nil
+}
+
+// selectTables applies --tables and --exclude-tables to a table list
+func selectTables(names []string, opts BackupOptions) []string {
+	include := make(map[string]bool)
+	for _, t := range opts.Tables {
+		include[t] = true
+	}
+	exclude := make(map[string]bool)
+	for _, t := range opts.ExcludeTables {
+		exclude[t] = true
+	}
+	var selected []string
+	for _, name := range names {
+		if (len(include) == 0 || include[name]) && !exclude[name] {
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
+
+// backupMySQLParallel dumps each table of a MySQL database with its own mysqldump, opts.Jobs at a time,
+// and bundles the files into one zip with a restore plan. Each table is dumped in its own transaction,
+// so the bundle is consistent per table, not across tables.
+func backupMySQLParallel(host, user, password, database, bundleDir string, opts BackupOptions, rules mask.Rules) (err error) {
+	start := time.Now()
+	engine, engineName := mysqlFlavor(opts.Conn)
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry(engineName, fmt.Sprintf("Backup (%d jobs)", opts.Jobs), status, start, err)
+
+		// Send Slack notification if webhook is configured
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
+			duration := time.Since(start).Round(time.Second)
+			hostname, _ := os.Hostname()
+			username := "unknown"
+			if u, e := osuser.Current(); e == nil {
+				username = u.Username
+			}
+
+			message := fmt.Sprintf("%s Backup %s\nDatabase: %s\nParallel jobs: %d\nDuration: %s\nHost: %s\nUser: %s",
+				engineName, status, database, opts.Jobs, duration, hostname, username)
+			if err != nil {
+				message += fmt.Sprintf("\nError: %v", err)
+			}
+			_ = notify.SlackNotify(webhook, message)
+		}
+	}()
+
+	allTables, allViews, err := listMySQLTables(host, user, password, database, opts.Conn)
+	if err != nil {
+		return err
+	}
+	tables := selectTables(allTables, opts)
+	views := selectTables(allViews, opts)
+	if opts.Content == ContentData {
+		views = nil // views hold no rows
+	}
+	if len(tables) == 0 && len(views) == 0 {
+		return fmt.Errorf("no tables to back up in %s", database)
+	}
+
+	if err := os.MkdirAll(bundleDir, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(bundleDir)
+
+	index := BundleIndex{Database: database}
+	files := make(map[string]string)
+	for i, table := range tables {
+		// Numbered file names, since table names may hold characters that aren't safe in paths
+		entry := BundleEntry{Table: table, File: fmt.Sprintf("table-%04d.sql", i+1)}
+		index.Tables = append(index.Tables, entry)
+		files[table] = entry.File
+	}
+
+	baseArgs := []string{"--single-transaction"}
+	switch opts.Content {
+	case ContentSchema:
+		baseArgs = append(baseArgs, "--no-data")
+	case ContentData:
+		baseArgs = append(baseArgs, "--no-create-info")
+	}
+	if rules != nil {
+		baseArgs = append(baseArgs, "--complete-insert")
+	}
+
+	fmt.Printf("🔄 Running %s backup of %d tables with %d parallel jobs...\n", engineName, len(tables), opts.Jobs)
+	var mu sync.Mutex
+	done := 0
+	err = runParallel(opts.Jobs, tables, func(table string) error {
+		args := append(append([]string{}, baseArgs...), database, table)
+		if err := dumpMySQLTo(filepath.Join(bundleDir, files[table]), host, user, password, args, opts.Conn, rules); err != nil {
+			return err
+		}
+		mu.Lock()
+		done++
+		fmt.Printf("📦 [%d/%d] %s\n", done, len(tables), table)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("mysqldump failed: %w", err)
+	}
+
+	if len(views) > 0 {
+		index.ViewsFile = "views.sql"
+		args := append(append([]string{}, baseArgs...), database)
+		if err := dumpMySQLTo(filepath.Join(bundleDir, index.ViewsFile), host, user, password, append(args, views...), opts.Conn, rules); err != nil {
+			return fmt.Errorf("mysqldump of views failed: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(bundleDir, bundleIndexFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle index: %w", err)
+	}
+
+	zipPath := bundleDir + ".zip"
+	if err := utils.CompressFolder(bundleDir, zipPath); err != nil {
+		return fmt.Errorf("failed to bundle table dumps: %w", err)
+	}
+	saveManifest(zipPath, engine, database, BackupTypeFull, opts)
+	fmt.Println("✅ Backup completed:", zipPath)
+	return nil
+}
+
+// dumpMySQLTo runs mysqldump with args into outFile, masking the output when rules are given
+func dumpMySQLTo(outFile, host, user, password string, args []string, conn ConnOptions, rules mask.Rules) error {
+	cmd, err := conn.mysqlCommand("mysqldump", host, user, args...)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	cmd.Env = mysqlEnv(password)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if rules == nil {
+		cmd.Stdout = file
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%v\n%s", err, stderr.String())
+		}
+		return nil
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	_, maskErr := mask.Dump(stdout, file, mask.MySQL, rules, nil)
+	if maskErr != nil {
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%v\n%s", err, stderr.String())
+	}
+	if maskErr != nil {
+		return fmt.Errorf("masking failed: %w", maskErr)
+	}
+	return nil
+}
+
+// isMySQLBundle reports whether a backup is a parallel MySQL bundle (the zip or its extracted directory)
+func isMySQLBundle(backupFile string) bool {
+	return containsFile(backupFile, bundleIndexFile)
+}
+
+// containsFile reports whether a directory or zip backup holds a file called name at its top level
+func containsFile(backupFile, name string) bool {
+	if info, err := os.Stat(backupFile); err == nil && info.IsDir() {
+		_, err := os.Stat(filepath.Join(backupFile, name))
+		return err == nil
+	}
+	archive, err := zip.OpenReader(backupFile)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = archive.Close() }()
+	for _, entry := range archive.File {
+		if entry.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// openBundle returns the directory holding a bundle's files, extracting a zip to a temp
+// directory that cleanup removes
+func openBundle(backupFile string) (dir string, cleanup func(), err error) {
+	if info, err := os.Stat(backupFile); err == nil && info.IsDir() {
+		return backupFile, func() {}, nil
+	}
+	dir, err = os.MkdirTemp("", "dbx-restore-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup = func() { _ = os.RemoveAll(dir) }
+	if err := utils.ExtractFolder(backupFile, dir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dir, cleanup, nil
+}
+
+func readBundleIndex(dir string) (*BundleIndex, error) {
+	data, err := os.ReadFile(filepath.Join(dir, bundleIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle index: %w", err)
+	}
+	var index BundleIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle index: %w", err)
+	}
+	return &index, nil
+}
+
+// restoreJobs picks the restore parallelism: the requested jobs, else the jobs the backup was made with
+func restoreJobs(backupFile string, jobs int) int {
+	if jobs > 0 {
+		return jobs
+	}
+	if manifest := findManifest(backupFile); manifest != nil && manifest.Jobs > 0 {
+		return manifest.Jobs
+	}
+	return 1
+}
+
+// restoreMySQLFile loads one dump file with the mysql client
+func restoreMySQLFile(host, user, pass, dbName, file string, conn ConnOptions) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	cmd, err := conn.mysqlCommand("mysql", host, user, dbName)
+	if err != nil {
+		return err
+	}
+	cmd.Env = mysqlEnv(pass)
+	cmd.Stdin = src
+	var stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = os.Stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v\n%s", err, stderr.String())
+	}
+	return nil
+}
+
+// restoreMySQLBundle restores every table of a parallel bundle, opts.Jobs at a time, then its views.
+// With table set, only that table is restored.
+func restoreMySQLBundle(host, user, pass, dbName, backupFile, table string, opts RestoreOptions) error {
+	dir, cleanup, err := openBundle(backupFile)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	index, err := readBundleIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]string)
+	var tables []string
+	for _, entry := range index.Tables {
+		files[entry.Table] = filepath.Join(dir, entry.File)
+		tables = append(tables, entry.Table)
+	}
+	if table != "" {
+		file, ok := files[table]
+		if !ok {
+			return fmt.Errorf("table '%s' not found in backup bundle", table)
+		}
+		return restoreMySQLFile(host, user, pass, dbName, file, opts.Conn)
+	}
+
+	fmt.Printf("🔄 Restoring %d tables with %d parallel jobs...\n", len(tables), opts.Jobs)
+	err = runParallel(opts.Jobs, tables, func(t string) error {
+		return restoreMySQLFile(host, user, pass, dbName, files[t], opts.Conn)
+	})
+	if err != nil {
+		return err
+	}
+	if index.ViewsFile != "" {
+		return restoreMySQLFile(host, user, pass, dbName, filepath.Join(dir, index.ViewsFile), opts.Conn)
+	}
+	return nil
+}
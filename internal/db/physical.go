@@ -0,0 +1,391 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"dbx/internal/logs"
+	"dbx/internal/notify"
+	"dbx/internal/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	osuser "os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// physicalCheckpoints is the file xtrabackup and mariabackup write into every physical backup; it
+// records the backup_type (full-backuped, incremental, full-prepared...) and the LSN range copied
+const physicalCheckpoints = "xtrabackup_checkpoints"
+
+// physicalTool is a hot physical backup tool for a MySQL flavour
+type physicalTool struct {
+	engine      string   // manifest and metadata engine
+	name        string   // display name of the engine
+	binaries    []string // executable names, newest first
+	group       string   // option file group the tool reads the password from
+	incremental bool     // supports incremental and differential backups (--incremental-lsn)
+	prepare     bool     // prepare full backups right after taking them instead of at restore time
+	installHelp func()
+}
+
+var (
+	// xtrabackup is Percona XtraBackup. Backups are left unprepared so incrementals can be applied on
+	// top of them when restoring.
+	xtrabackup = physicalTool{
+		engine: "mysql", name: "MySQL", binaries: []string{"xtrabackup"}, group: "xtrabackup",
+		incremental: true, installHelp: showXtrabackupInstallHelp,
+	}
+	// mariabackup is MariaDB's fork of XtraBackup, renamed mariadb-backup in MariaDB 10.5
+	mariabackup = physicalTool{
+		engine: "mariadb", name: "MariaDB", binaries: []string{"mariadb-backup", "mariabackup"}, group: "mariabackup",
+		prepare: true, installHelp: showMariaDBInstallHelp,
+	}
+)
+
+// physicalBackupName is the file name prefix of a server's physical backups, e.g. mysql_db1_3306.
+// A physical backup covers every database, so the server stands in for a database name.
+func physicalBackupName(engine, host string, conn ConnOptions) string {
+	port := conn.Port
+	if port == "" {
+		port = "3306"
+	}
+	return serverBackupName(engine, host, port, conn)
+}
+
+// backupPhysical copies a running server's data files with the tool and zips the copy. Incremental
+// backups copy the pages changed since the last backup of the server in outDir, differential ones
+// those changed since its last full backup; both are tracked in the server's backup metadata and
+// their manifests name the backup they apply on top of. The tool reads the data directory itself,
+// so it has to run on the database host.
+func backupPhysical(tool physicalTool, host, user, password, outDir string, backupType BackupType, opts BackupOptions) (err error) {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case backupType != BackupTypeFull && !tool.incremental:
+		return fmt.Errorf("physical %s backups support full backups only", tool.name)
+	case opts.Partial() || opts.MaskRules != "":
+		return fmt.Errorf("physical backups copy the whole server (no content, table filters or masking)")
+	}
+	bin, err := tool.find()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	name := physicalBackupName(tool.engine, host, opts.Conn)
+	metadataPath := GetMetadataPath(outDir, tool.engine, name)
+	metadata, err := LoadMetadata(metadataPath)
+	if err != nil {
+		return err
+	}
+	var baseFile string
+	var base *BackupManifest
+	if backupType != BackupTypeFull {
+		baseFile = metadata.BackupPath
+		if backupType == BackupTypeDifferential {
+			baseFile = metadata.FullBackupPath
+		}
+		if baseFile == "" {
+			return fmt.Errorf("no physical full backup of %s recorded in %s; take a full backup first", name, outDir)
+		}
+		if base, err = LoadManifest(baseFile); err != nil {
+			return err
+		}
+		if base == nil || base.ToLSN == "" {
+			return fmt.Errorf("%s has no recorded LSN to continue from; take a full backup first", baseFile)
+		}
+	}
+
+	targetDir := filepath.Join(outDir, fmt.Sprintf("%s-physical_%s_%s", name, backupType, time.Now().Format("2006-01-02_15-04-05")))
+	defer os.RemoveAll(targetDir)
+
+	start := time.Now()
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry(tool.name, "Backup (physical)", status, start, err)
+
+		// Send Slack notification if webhook is configured
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
+			duration := time.Since(start).Round(time.Second)
+			hostname, _ := os.Hostname()
+			username := "unknown"
+			if u, e := osuser.Current(); e == nil {
+				username = u.Username
+			}
+
+			message := fmt.Sprintf("%s Physical Backup %s\nServer: %s\nType: %s\nDuration: %s\nHost: %s\nUser: %s",
+				tool.name, status, name, backupType, duration, hostname, username)
+			if err != nil {
+				message += fmt.Sprintf("\nError: %v", err)
+			}
+			_ = notify.SlackNotify(webhook, message)
+		}
+	}()
+
+	defaultsFile, err := tool.defaults(password)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(defaultsFile)
+
+	// --defaults-extra-file has to come first
+	args := []string{"--defaults-extra-file=" + defaultsFile, "--backup", "--target-dir=" + targetDir, "--user=" + user}
+	if opts.Conn.Socket != "" {
+		args = append(args, "--socket="+opts.Conn.Socket)
+	} else {
+		args = append(args, "--host="+host)
+	}
+	if opts.Conn.Port != "" {
+		args = append(args, "--port="+opts.Conn.Port)
+	}
+	if opts.Jobs > 1 {
+		args = append(args, fmt.Sprintf("--parallel=%d", opts.Jobs))
+	}
+	if base != nil {
+		args = append(args, "--incremental-lsn="+base.ToLSN)
+	}
+
+	fmt.Printf("🔄 Running %s physical %s backup...\n", tool.name, backupType)
+	if err := runPhysicalTool(bin, args...); err != nil {
+		return fmt.Errorf("%s --backup failed: %w", bin, err)
+	}
+	if tool.prepare {
+		fmt.Println("🔄 Preparing backup...")
+		if err := runPhysicalTool(bin, "--prepare", "--target-dir="+targetDir); err != nil {
+			return fmt.Errorf("%s --prepare failed: %w", bin, err)
+		}
+	}
+	toLSN := checkpoint(targetDir, "to_lsn")
+
+	zipPath := targetDir + ".zip"
+	if err := utils.CompressFolder(targetDir, zipPath); err != nil {
+		return fmt.Errorf("failed to compress backup: %w", err)
+	}
+	manifest := newManifest(tool.engine, name, backupType, opts)
+	manifest.ToLSN = toLSN
+	if base != nil {
+		manifest.Base = filepath.Base(baseFile)
+	}
+	writeManifest(zipPath, manifest)
+
+	if abs, err := filepath.Abs(zipPath); err == nil {
+		metadata.BackupPath = abs
+	} else {
+		metadata.BackupPath = zipPath
+	}
+	metadata.DBType, metadata.Database = tool.engine, name
+	if backupType == BackupTypeFull {
+		metadata.LastFullBackup = start
+		metadata.FullBackupPath = metadata.BackupPath
+	} else {
+		metadata.LastIncrementalBackup = start
+	}
+	if err := SaveMetadata(metadataPath, metadata); err != nil {
+		fmt.Println("⚠️ Failed to update backup metadata:", err)
+	}
+	fmt.Println("✅ Backup completed:", zipPath)
+	return nil
+}
+
+// restorePhysical copies a physical backup into dataDir with --copy-back. The backup may be a zip
+// written by backupPhysical or a target directory; an incremental or differential backup brings its
+// chain back to the full backup (found next to it through the manifests), which is prepared with
+// each increment applied in turn. The server must be stopped and dataDir empty; afterwards the files
+// need to be owned by the server's user before it is started.
+func restorePhysical(tool physicalTool, backupFile, dataDir string) (err error) {
+	start := time.Now()
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry(tool.name, "Restore (physical)", status, start, err)
+	}()
+
+	if dataDir == "" {
+		return fmt.Errorf("restoring a physical backup needs the server's data directory (--datadir)")
+	}
+	if entries, err := os.ReadDir(dataDir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("data directory %s is not empty; stop the server and move its contents aside first", dataDir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+	chain, err := physicalChain(backupFile)
+	if err != nil {
+		return err
+	}
+	bin, err := tool.find()
+	if err != nil {
+		return err
+	}
+
+	dirs := make([]string, len(chain))
+	for i, file := range chain {
+		dir, cleanup, err := openBundle(file)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		dirs[i] = dir
+	}
+	full := dirs[0]
+	switch {
+	case len(dirs) > 1:
+		if physicalPrepared(full) {
+			return fmt.Errorf("%s has already been prepared, so later backups can't be applied to it", chain[0])
+		}
+		fmt.Printf("🔄 Preparing backup chain of %d backups...\n", len(dirs))
+		if err := runPhysicalTool(bin, "--prepare", "--apply-log-only", "--target-dir="+full); err != nil {
+			return fmt.Errorf("%s --prepare failed: %w", bin, err)
+		}
+		for i, dir := range dirs[1:] {
+			args := []string{"--prepare"}
+			// The last increment also rolls back uncommitted transactions
+			if i < len(dirs)-2 {
+				args = append(args, "--apply-log-only")
+			}
+			args = append(args, "--target-dir="+full, "--incremental-dir="+dir)
+			if err := runPhysicalTool(bin, args...); err != nil {
+				return fmt.Errorf("%s --prepare of %s failed: %w", bin, filepath.Base(chain[i+1]), err)
+			}
+		}
+	case checkpoint(full, "backup_type") == "incremental":
+		return fmt.Errorf("%s is an incremental backup, but its manifest naming the backup it builds on is missing", backupFile)
+	case !physicalPrepared(full):
+		fmt.Println("🔄 Preparing backup...")
+		if err := runPhysicalTool(bin, "--prepare", "--target-dir="+full); err != nil {
+			return fmt.Errorf("%s --prepare failed: %w", bin, err)
+		}
+	}
+
+	fmt.Printf("🔄 Copying backup into %s...\n", dataDir)
+	if err := runPhysicalTool(bin, "--copy-back", "--target-dir="+full, "--datadir="+dataDir); err != nil {
+		return fmt.Errorf("%s --copy-back failed: %w", bin, err)
+	}
+	fmt.Printf("✅ %s physical restore completed successfully.\n", tool.name)
+	fmt.Printf("👉 Give the files to the server's user before starting it, e.g. chown -R mysql:mysql %s\n", dataDir)
+	return nil
+}
+
+// physicalChain returns the backups to apply, from the full backup to backupFile, by following each
+// manifest's base
+func physicalChain(backupFile string) ([]string, error) {
+	chain := []string{backupFile}
+	seen := map[string]bool{backupFile: true}
+	for current := backupFile; ; {
+		manifest := findManifest(current)
+		if manifest == nil || manifest.Base == "" {
+			return chain, nil
+		}
+		base := filepath.Join(filepath.Dir(current), manifest.Base)
+		if _, err := os.Stat(base); err != nil {
+			return nil, fmt.Errorf("backup chain is incomplete: %s builds on %s, which is missing", filepath.Base(current), manifest.Base)
+		}
+		if seen[base] {
+			return nil, fmt.Errorf("backup chain of %s loops at %s", backupFile, manifest.Base)
+		}
+		seen[base] = true
+		chain = append([]string{base}, chain...)
+		current = base
+	}
+}
+
+// physicalToolFor picks the tool that restores a physical backup from its manifest, or fallback
+// for backups without one
+func physicalToolFor(backupFile string, fallback physicalTool) physicalTool {
+	if manifest := findManifest(backupFile); manifest != nil {
+		switch manifest.DBType {
+		case xtrabackup.engine:
+			return xtrabackup
+		case mariabackup.engine:
+			return mariabackup
+		}
+	}
+	return fallback
+}
+
+// isPhysicalBackup reports whether a backup (a zip or directory) was taken by xtrabackup or mariabackup
+func isPhysicalBackup(backupFile string) bool {
+	return containsFile(backupFile, physicalCheckpoints)
+}
+
+// physicalPrepared reports whether the backup in dir has been prepared (backup_type = full-prepared)
+func physicalPrepared(dir string) bool {
+	return checkpoint(dir, "backup_type") == "full-prepared"
+}
+
+// checkpoint returns a value from the xtrabackup_checkpoints file in dir, or "" if it is missing
+func checkpoint(dir, key string) string {
+	f, err := os.Open(filepath.Join(dir, physicalCheckpoints))
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// find looks the tool up on PATH under each of its names
+func (t physicalTool) find() (string, error) {
+	for _, name := range t.binaries {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	t.installHelp()
+	missing := t.binaries[0]
+	if len(t.binaries) > 1 {
+		missing += " (or " + strings.Join(t.binaries[1:], ", ") + ")"
+	}
+	return "", fmt.Errorf("%s not found in PATH", missing)
+}
+
+// defaults writes the password to a private option file, so it isn't visible in the process list.
+// The caller removes the file.
+func (t physicalTool) defaults(password string) (string, error) {
+	f, err := os.CreateTemp("", "dbx-"+t.group+"-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create option file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := f.Chmod(0600); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(password)
+	if _, err := fmt.Fprintf(f, "[%s]\npassword=\"%s\"\n", t.group, escaped); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write option file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// runPhysicalTool runs xtrabackup or mariabackup, which log their progress to stderr; the end of
+// the log is returned on failure
+func runPhysicalTool(bin string, args ...string) error {
+	cmd := exec.Command(bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if len(lines) > 10 {
+			lines = lines[len(lines)-10:]
+		}
+		return fmt.Errorf("%v\n%s", err, strings.Join(lines, "\n"))
+	}
+	return nil
+}
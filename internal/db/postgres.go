@@ -2,13 +2,17 @@ package db
 
 import (
 	"dbx/internal/logs"
+	"dbx/internal/mask"
 	"dbx/internal/notify"
 	"dbx/internal/utils"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	osuser "os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,13 +23,36 @@ func BackupPostgres(host, port, user, pass, dbName, outDir string) error {
 
 // BackupPostgresWithType runs pg_dump to create a backup with specified type
 func BackupPostgresWithType(host, port, user, pass, dbName, outDir string, backupType BackupType) error {
+	return BackupPostgresWithOptions(host, port, user, pass, dbName, outDir, backupType, BackupOptions{})
+}
+
+// BackupPostgresWithOptions runs pg_dump limited by opts (tables map to -t, excluded tables to -T,
+// content to --schema-only/--data-only). Without pg_dump, or with opts.Dumper builtin, the built-in
+// dumper writes a plain SQL dump instead.
+func BackupPostgresWithOptions(host, port, user, pass, dbName, outDir string, backupType BackupType, opts BackupOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	rules, err := opts.maskRules()
+	if err != nil {
+		return err
+	}
 	if dbName == "" {
 		return fmt.Errorf("database name cannot be empty")
 	}
 
-	if _, err := exec.LookPath("pg_dump"); err != nil {
+	_, lookErr := exec.LookPath("pg_dump")
+	builtin := opts.builtinDump("pg_dump", lookErr == nil)
+	if builtin {
+		if err := checkBuiltinDump("pg_dump", backupType, opts); err != nil {
+			return err
+		}
+		opts.Dumper = DumperBuiltin
+	} else if lookErr != nil {
 		showPostgresInstallHelp()
 		return fmt.Errorf("pg_dump not found in PATH")
+	} else {
+		opts.Dumper = ""
 	}
 
 	// Prepare directory
@@ -38,6 +65,9 @@ func BackupPostgresWithType(host, port, user, pass, dbName, outDir string, backu
 	if backupType != BackupTypeFull {
 		backupSuffix = string(backupType) + "_" + timestamp
 	}
+	if label := opts.fileLabel(); label != "" {
+		backupSuffix += "_" + label
+	}
 	outFile := filepath.Join(outDir, fmt.Sprintf("%s_%s_%s.sql", dbName, backupSuffix, timestamp))
 
 	// Set env for passwordless execution - must be set BEFORE cmd.Run()
@@ -47,12 +77,37 @@ func BackupPostgresWithType(host, port, user, pass, dbName, outDir string, backu
 		os.Unsetenv("PGPASSWORD")
 	}
 
+	// Masking rewrites the dump as text, so masked backups are plain SQL restored with psql
+	// The built-in dumper writes plain SQL too. Plain dumps are written to outFile here, masked on
+	// the way, so unmasked data never reaches the disk
+	format := "c" // custom format (compressed binary)
+	plain := builtin || rules != nil
+	if plain {
+		format = "p"
+	}
+	if opts.Jobs > 1 {
+		if rules != nil {
+			return fmt.Errorf("--jobs can't be combined with --mask-rules for PostgreSQL (masking needs a plain SQL dump)")
+		}
+		// Directory format is the only one pg_dump writes in parallel; it is zipped into one artifact below
+		format, outFile = "d", strings.TrimSuffix(outFile, ".sql")
+	}
+
 	args := []string{
-		"-h", host,
+		"-h", opts.Conn.postgresHost(host),
 		"-p", port,
 		"-U", user,
-		"-F", "c", // custom format (compressed binary)
-		"-f", outFile,
+		"-F", format,
+	}
+	if !plain {
+		args = append(args, "-f", outFile)
+	}
+	if opts.Jobs > 1 {
+		args = append(args, "-j", strconv.Itoa(opts.Jobs))
+	}
+	if rules != nil {
+		// Plain dumps drop objects themselves, as pg_restore -c does for custom ones
+		args = append(args, "--clean", "--if-exists")
 	}
 
 	// For incremental/differential backups in PostgreSQL:
@@ -72,13 +127,49 @@ func BackupPostgresWithType(host, port, user, pass, dbName, outDir string, backu
 		args = append(args, "--verbose")
 	}
 
+	switch opts.Content {
+	case ContentSchema:
+		args = append(args, "--schema-only")
+	case ContentData:
+		args = append(args, "--data-only")
+	}
+	for _, table := range opts.Tables {
+		args = append(args, "-t", table)
+	}
+	for _, table := range opts.ExcludeTables {
+		args = append(args, "-T", table)
+	}
+
 	args = append(args, dbName)
 	cmd := exec.Command("pg_dump", args...)
+	cmd.Env = opts.Conn.postgresEnv(host)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 
 	start := time.Now()
-	fmt.Println("🔄 Running PostgreSQL backup...")
-	err := cmd.Run()
+	switch {
+	case builtin:
+		fmt.Println("🔄 Running PostgreSQL backup with the built-in dumper...")
+		err = writePlainDump(outFile, mask.Postgres, rules, func(w io.Writer) error {
+			if err := dumpPostgresBuiltin(w, host, port, user, pass, dbName, opts); err != nil {
+				return fmt.Errorf("built-in dump failed: %w", err)
+			}
+			return nil
+		})
+	case plain:
+		fmt.Println("🔄 Running PostgreSQL backup...")
+		err = writePlainDump(outFile, mask.Postgres, rules, func(w io.Writer) error {
+			cmd.Stdout = w
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("pg_dump failed: %w", err)
+			}
+			return nil
+		})
+	default:
+		fmt.Println("🔄 Running PostgreSQL backup...")
+		if err = cmd.Run(); err != nil {
+			err = fmt.Errorf("pg_dump failed: %w", err)
+		}
+	}
 
 	defer func() {
 		status := "SUCCESS"
@@ -88,7 +179,7 @@ func BackupPostgresWithType(host, port, user, pass, dbName, outDir string, backu
 		logs.LogEntry("PostgreSQL", "Backup", status, start, err)
 
 		// Send Slack notification if webhook is configured
-		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" {
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
 			duration := time.Since(start).Round(time.Second)
 			hostname, _ := os.Hostname()
 			username := "unknown"
@@ -106,15 +197,29 @@ func BackupPostgresWithType(host, port, user, pass, dbName, outDir string, backu
 	}()
 
 	if err != nil {
-		return fmt.Errorf("pg_dump failed: %w", err)
+		return err
+	}
+
+	if format == "d" {
+		zipPath := outFile + ".zip"
+		if err = utils.CompressFolder(outFile, zipPath); err != nil {
+			err = fmt.Errorf("failed to bundle directory dump: %w", err)
+			return err
+		}
+		_ = os.RemoveAll(outFile)
+		saveManifest(zipPath, "postgres", dbName, backupType, opts)
+		fmt.Println("✅ Backup completed:", zipPath)
+		return nil
 	}
 
 	fmt.Println("✅ Backup completed:", outFile)
+	saveManifest(outFile, "postgres", dbName, backupType, opts)
 
 	// Optional: compress final .sql/.dump
 	// Compression failure is non-critical - backup file already exists
+	// Compress only this dump, so each database keeps its own artifact when outDir is shared
 	zipPath := outFile + ".zip"
-	if err := utils.CompressFolder(outDir, zipPath); err == nil {
+	if err := utils.CompressFile(outFile, zipPath); err == nil {
 		fmt.Println("🗜 Compressed to:", zipPath)
 	}
 	// Note: Compression errors are silently ignored - uncompressed backup is still valid
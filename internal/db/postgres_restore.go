@@ -3,19 +3,33 @@ package db
 import (
 	"dbx/internal/logs"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // RestorePostgres restores a PostgreSQL database from a backup file
 func RestorePostgres(host, port, user, pass, dbName, backupFile string) error {
+	return RestorePostgresWithOptions(host, port, user, pass, dbName, backupFile, RestoreOptions{})
+}
+
+// RestorePostgresWithOptions restores a PostgreSQL database with pg_restore -j opts.Jobs and the given
+// connection options. Zipped directory-format backups from parallel dumps are extracted first.
+func RestorePostgresWithOptions(host, port, user, pass, dbName, backupFile string, opts RestoreOptions) error {
 	start := time.Now()
-	if _, err := exec.LookPath("pg_restore"); err != nil {
-		showPostgresInstallHelp()
-		return fmt.Errorf("pg_restore not found in PATH")
+	if err := opts.Conn.Validate(); err != nil {
+		return err
 	}
+	source, cleanup, err := postgresRestoreSource(backupFile)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	jobs := restoreJobs(backupFile, opts.Jobs)
 
 	// Set env for passwordless execution - must be set BEFORE cmd.Run()
 	if pass != "" {
@@ -24,18 +38,45 @@ func RestorePostgres(host, port, user, pass, dbName, backupFile string) error {
 		os.Unsetenv("PGPASSWORD")
 	}
 
-	cmd := exec.Command("pg_restore",
-		"-h", host,
-		"-p", port,
-		"-U", user,
-		"-d", dbName,
-		"-c", // clean before restore
-		backupFile,
-	)
+	// Masked and built-in backups are plain SQL scripts, which pg_restore can't read; only custom and
+	// directory-format backups need it
+	tool := "pg_restore"
+	var cmd *exec.Cmd
+	if isPlainSQLDump(source) {
+		tool = "psql"
+		if _, err := exec.LookPath("psql"); err != nil {
+			showPostgresInstallHelp()
+			return fmt.Errorf("psql not found in PATH (needed to restore plain SQL dumps)")
+		}
+		cmd = exec.Command("psql",
+			"-h", opts.Conn.postgresHost(host),
+			"-p", port,
+			"-U", user,
+			"-d", dbName,
+			"-v", "ON_ERROR_STOP=1",
+			"-f", source,
+		)
+	} else {
+		if _, err := exec.LookPath("pg_restore"); err != nil {
+			showPostgresInstallHelp()
+			return fmt.Errorf("pg_restore not found in PATH")
+		}
+		cmd = exec.Command("pg_restore",
+			"-h", opts.Conn.postgresHost(host),
+			"-p", port,
+			"-U", user,
+			"-d", dbName,
+			"-c", // clean before restore
+			"-j", strconv.Itoa(jobs),
+			source,
+		)
+	}
+	cmd.Env = opts.Conn.postgresEnv(host)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 
+	warnIfPartial(backupFile)
 	fmt.Println("🔄 Restoring PostgreSQL database...")
-	err := cmd.Run()
+	err = cmd.Run()
 	defer func() {
 		status := "SUCCESS"
 		if err != nil {
@@ -45,16 +86,62 @@ func RestorePostgres(host, port, user, pass, dbName, backupFile string) error {
 	}()
 
 	if err != nil {
-		return fmt.Errorf("pg_restore failed: %w", err)
+		return fmt.Errorf("%s failed: %w", tool, err)
 	}
 
 	fmt.Println("✅ Restore completed successfully.")
 	return nil
 }
 
+// postgresRestoreSource returns what pg_restore should read: zipped backups are extracted, giving
+// the directory of a parallel (directory-format) dump or the single dump file
+func postgresRestoreSource(backupFile string) (string, func(), error) {
+	if !strings.HasSuffix(backupFile, ".zip") {
+		return backupFile, func() {}, nil
+	}
+	dir, cleanup, err := openBundle(backupFile)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "toc.dat")); err == nil {
+		return dir, cleanup, nil
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && !entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), cleanup, nil
+	}
+	cleanup()
+	return "", nil, fmt.Errorf("%s is not a PostgreSQL backup (no toc.dat and not a single dump file)", backupFile)
+}
+
+// isPlainSQLDump reports whether a PostgreSQL backup is a plain SQL script rather than a
+// custom-format archive, which starts with the PGDMP signature
+func isPlainSQLDump(backupFile string) bool {
+	if info, err := os.Stat(backupFile); err != nil || info.IsDir() {
+		return false
+	}
+	f, err := os.Open(backupFile)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	signature := make([]byte, 5)
+	if _, err := io.ReadFull(f, signature); err != nil {
+		return false
+	}
+	return string(signature) != "PGDMP"
+}
+
 // RestorePostgresTable restores a specific table from a PostgreSQL backup
 func RestorePostgresTable(host, port, user, pass, dbName, backupFile, tableName string) error {
+	return RestorePostgresTableWithOptions(host, port, user, pass, dbName, backupFile, tableName, RestoreOptions{})
+}
+
+// RestorePostgresTableWithOptions restores a specific table using the given connection options
+func RestorePostgresTableWithOptions(host, port, user, pass, dbName, backupFile, tableName string, opts RestoreOptions) error {
 	start := time.Now()
+	if err := opts.Conn.Validate(); err != nil {
+		return err
+	}
 	if _, err := exec.LookPath("pg_restore"); err != nil {
 		showPostgresInstallHelp()
 		return fmt.Errorf("pg_restore not found in PATH")
@@ -64,6 +151,17 @@ func RestorePostgresTable(host, port, user, pass, dbName, backupFile, tableName
 	if _, err := os.Stat(backupFile); err != nil {
 		return fmt.Errorf("backup file not found: %w", err)
 	}
+	if err := checkIncluded(backupFile, "table", tableName); err != nil {
+		return err
+	}
+	source, cleanup, err := postgresRestoreSource(backupFile)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if isPlainSQLDump(source) {
+		return fmt.Errorf("%s is a plain SQL dump; table restore needs a custom-format backup (restore the whole dump instead)", backupFile)
+	}
 
 	// Verify table exists in backup using pg_restore --list
 	// Set env for passwordless execution - must be set BEFORE cmd.Run()
@@ -74,7 +172,7 @@ func RestorePostgresTable(host, port, user, pass, dbName, backupFile, tableName
 	}
 
 	// List contents of backup to verify table exists
-	listCmd := exec.Command("pg_restore", "--list", backupFile)
+	listCmd := exec.Command("pg_restore", "--list", source)
 	listOutput, listErr := listCmd.Output()
 	if listErr == nil {
 		// Check if table name appears in the backup contents
@@ -85,18 +183,19 @@ func RestorePostgresTable(host, port, user, pass, dbName, backupFile, tableName
 	// If list command fails, continue anyway (backup might be in different format)
 
 	cmd := exec.Command("pg_restore",
-		"-h", host,
+		"-h", opts.Conn.postgresHost(host),
 		"-p", port,
 		"-U", user,
 		"-d", dbName,
 		"-t", tableName, // restore specific table
 		"-c",            // clean before restore
-		backupFile,
+		source,
 	)
+	cmd.Env = opts.Conn.postgresEnv(host)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 
 	fmt.Printf("🔄 Restoring PostgreSQL table '%s'...\n", tableName)
-	err := cmd.Run()
+	err = cmd.Run()
 	defer func() {
 		status := "SUCCESS"
 		if err != nil {
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"dbx/internal/logs"
+	"dbx/internal/notify"
+	"dbx/internal/utils"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	osuser "os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bgsaveTimeout bounds how long a --bgsave backup waits for the server's snapshot
+const bgsaveTimeout = 30 * time.Minute
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RedisBackupName is the default file name prefix of a Redis instance's backups, e.g. redis_cache.internal_6379.
+// A Redis snapshot covers every logical database, so the instance stands in for a database name.
+func RedisBackupName(host, port string, conn ConnOptions) string {
+	if port == "" {
+		port = "6379"
+	}
+	return serverBackupName("redis", host, port, conn)
+}
+
+// serverBackupName names the backups of a whole server: engine_host_port, or engine_socket
+func serverBackupName(engine, host, port string, conn ConnOptions) string {
+	if conn.Socket != "" {
+		return engine + "_" + unsafeNameChars.ReplaceAllString(filepath.Base(conn.Socket), "_")
+	}
+	return engine + "_" + unsafeNameChars.ReplaceAllString(host, "_") + "_" + port
+}
+
+// BackupRedis saves an RDB snapshot of a Redis instance by streaming it with redis-cli --rdb.
+// name prefixes the backup file; empty uses RedisBackupName.
+func BackupRedis(host, port, user, password, name, outDir string) error {
+	return BackupRedisWithOptions(host, port, user, password, name, outDir, BackupOptions{})
+}
+
+// BackupRedisWithOptions saves an RDB snapshot of a Redis instance. redis-cli --rdb streams one over
+// the replication protocol, which works remotely; with opts.BGSave the server writes a snapshot with
+// BGSAVE and it is copied from the server's data directory, which must be readable from this host.
+func BackupRedisWithOptions(host, port, user, password, name, outDir string, opts BackupOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case opts.MaskRules != "":
+		return fmt.Errorf("masking is supported for MySQL, PostgreSQL and SQLite backups only")
+	case opts.Partial():
+		return fmt.Errorf("Redis backups always contain the whole instance (no content, table or collection filters)")
+	case opts.Jobs > 0:
+		return fmt.Errorf("--jobs is not supported for Redis backups")
+	}
+	if port == "" {
+		port = "6379"
+	}
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if name == "" {
+		name = RedisBackupName(host, port, opts.Conn)
+	}
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	outFile := filepath.Join(outDir, fmt.Sprintf("%s_%s.rdb", name, timestamp))
+
+	start := time.Now()
+	fmt.Println("🔄 Running Redis backup...")
+	var err error
+	if opts.BGSave {
+		err = copyRedisSnapshot(host, port, user, password, outFile, opts.Conn)
+	} else {
+		err = streamRedisSnapshot(host, port, user, password, outFile, opts.Conn)
+	}
+	if err == nil {
+		err = checkRDBHeader(outFile)
+	}
+
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("Redis", "Backup", status, start, err)
+
+		// Send Slack notification if webhook is configured
+		if webhook := os.Getenv("SLACK_WEBHOOK"); webhook != "" && !opts.SkipNotify {
+			duration := time.Since(start).Round(time.Second)
+			hostname, _ := os.Hostname()
+			username := "unknown"
+			if u, e := osuser.Current(); e == nil {
+				username = u.Username
+			}
+
+			message := fmt.Sprintf("Redis Backup %s\nInstance: %s\nDuration: %s\nHost: %s\nUser: %s",
+				status, name, duration, hostname, username)
+			if err != nil {
+				message += fmt.Sprintf("\nError: %v", err)
+			}
+			_ = notify.SlackNotify(webhook, message)
+		}
+	}()
+
+	if err != nil {
+		_ = os.Remove(outFile)
+		return err
+	}
+
+	// Compression is optional - the RDB file is a valid backup on its own
+	zipPath := outFile + ".zip"
+	if cerr := utils.CompressFile(outFile, zipPath); cerr == nil {
+		_ = os.Remove(outFile)
+		fmt.Println("🗜 Compressed to:", zipPath)
+		saveManifest(zipPath, "redis", name, BackupTypeFull, opts)
+	} else {
+		fmt.Println("⚠️ Compression failed, keeping uncompressed backup:", cerr)
+		saveManifest(outFile, "redis", name, BackupTypeFull, opts)
+	}
+
+	fmt.Println("✅ Redis backup completed:", outFile)
+	return nil
+}
+
+// streamRedisSnapshot has redis-cli fetch an RDB over the replication protocol
+func streamRedisSnapshot(host, port, user, password, outFile string, conn ConnOptions) error {
+	if _, err := exec.LookPath("redis-cli"); err != nil {
+		showRedisInstallHelp()
+		return fmt.Errorf("redis-cli not found in PATH")
+	}
+	args := append(conn.redisArgs(host, port, user), "--rdb", outFile)
+	cmd := exec.Command("redis-cli", args...)
+	cmd.Env = os.Environ()
+	if password != "" {
+		cmd.Env = append(cmd.Env, "REDISCLI_AUTH="+password)
+	}
+	var stderr bytes.Buffer
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("redis-cli --rdb failed: %v\n%s", err, stderr.String())
+	}
+	return nil
+}
+
+// copyRedisSnapshot runs BGSAVE, waits for it to finish and copies the snapshot from the server's data directory
+func copyRedisSnapshot(host, port, user, password, outFile string, conn ConnOptions) error {
+	client, err := redisClient(host, port, user, password, conn)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), bgsaveTimeout)
+	defer cancel()
+
+	dir, err := client.ConfigGet(ctx, "dir").Result()
+	if err != nil {
+		return fmt.Errorf("failed to read the data directory (CONFIG GET needs to be allowed for --bgsave): %w", err)
+	}
+	dbfilename, err := client.ConfigGet(ctx, "dbfilename").Result()
+	if err != nil {
+		return fmt.Errorf("failed to read dbfilename: %w", err)
+	}
+	snapshot := filepath.Join(dir["dir"], dbfilename["dbfilename"])
+
+	// LASTSAVE has second resolution, so start in a fresh second to tell the new snapshot apart
+	lastSave, err := client.LastSave(ctx).Result()
+	if err != nil {
+		return fmt.Errorf("LASTSAVE failed: %w", err)
+	}
+	if lastSave >= time.Now().Unix() {
+		time.Sleep(time.Second)
+	}
+	// SCHEDULE waits for a running AOF rewrite instead of failing
+	if err := client.Do(ctx, "BGSAVE", "SCHEDULE").Err(); err != nil && !strings.Contains(err.Error(), "already in progress") {
+		return fmt.Errorf("BGSAVE failed: %w", err)
+	}
+
+	for {
+		info, err := redisInfo(ctx, client, "persistence")
+		if err != nil {
+			return err
+		}
+		if info["rdb_bgsave_in_progress"] == "0" {
+			saved, err := client.LastSave(ctx).Result()
+			if err != nil {
+				return fmt.Errorf("LASTSAVE failed: %w", err)
+			}
+			if saved > lastSave {
+				if info["rdb_last_bgsave_status"] != "ok" {
+					return fmt.Errorf("BGSAVE failed on the server (see its log)")
+				}
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for BGSAVE after %s", bgsaveTimeout)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	if _, err := os.Stat(snapshot); err != nil {
+		return fmt.Errorf("snapshot %s is not readable from this host; --bgsave needs dbx to run on the Redis host (omit it to stream the snapshot): %w", snapshot, err)
+	}
+	return copyFile(snapshot, outFile)
+}
+
+// redisClient connects with the Go driver, for the commands redis-cli can't script reliably
+func redisClient(host, port, user, password string, conn ConnOptions) (*redis.Client, error) {
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	opts := &redis.Options{
+		Network:         "tcp",
+		Addr:            net.JoinHostPort(host, port),
+		Username:        user,
+		Password:        password,
+		DisableIdentity: true,
+	}
+	if conn.Socket != "" {
+		opts.Network, opts.Addr = "unix", conn.Socket
+	}
+	tlsConfig, err := conn.tlsConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	opts.TLSConfig = tlsConfig
+	return redis.NewClient(opts), nil
+}
+
+// redisInfo returns the fields of an INFO section
+func redisInfo(ctx context.Context, client *redis.Client, section string) (map[string]string, error) {
+	text, err := client.Info(ctx, section).Result()
+	if err != nil {
+		return nil, fmt.Errorf("INFO %s failed: %w", section, err)
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+			fields[key] = value
+		}
+	}
+	return fields, nil
+}
+
+// checkRDBHeader checks that a file starts with the RDB magic "REDIS" and a four-digit version
+func checkRDBHeader(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	header := make([]byte, 9)
+	if n, _ := f.Read(header); n < 9 || string(header[:5]) != "REDIS" {
+		return fmt.Errorf("%s is not a Redis RDB snapshot", filepath.Base(path))
+	}
+	return nil
+}
+
+func showRedisInstallHelp() {
+	fmt.Println("\n💡 redis-cli comes with Redis:")
+	fmt.Println("   Ubuntu/Debian: sudo apt install redis-tools")
+	fmt.Println("   macOS:         brew install redis")
+	fmt.Println("   Windows:       use WSL or the Redis for Windows builds")
+}
@@ -0,0 +1,313 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"dbx/internal/logs"
+	"dbx/internal/utils"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRestoreTimeout bounds how long a restore waits for Redis to load the snapshot
+const redisRestoreTimeout = 30 * time.Minute
+
+// RestoreRedis loads an RDB snapshot (.rdb, or .rdb.zip as written by BackupRedis) into a Redis instance
+func RestoreRedis(host, port, user, password, backupFile string) error {
+	return RestoreRedisWithOptions(host, port, user, password, backupFile, RestoreOptions{})
+}
+
+// RestoreRedisWithOptions loads an RDB snapshot into a running Redis instance, replacing its whole dataset.
+// Redis can only load a snapshot at startup or from a master, so dbx briefly acts as a master: the server is
+// made a replica of a listener on this host (opts.ListenHost, as the server reaches it), receives the snapshot
+// in a full resync, and is promoted back with REPLICAOF NO ONE.
+func RestoreRedisWithOptions(host, port, user, password, backupFile string, opts RestoreOptions) error {
+	start := time.Now()
+	if backupFile == "" {
+		return fmt.Errorf("backup file path cannot be empty")
+	}
+	if _, err := os.Stat(backupFile); err != nil {
+		return fmt.Errorf("backup file not found: %w", err)
+	}
+	if port == "" {
+		port = "6379"
+	}
+
+	err := restoreRedis(host, port, user, password, backupFile, opts)
+	defer func() {
+		status := "SUCCESS"
+		if err != nil {
+			status = "FAILED"
+		}
+		logs.LogEntry("Redis", "Restore", status, start, err)
+	}()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Redis restore completed successfully.")
+	return nil
+}
+
+func restoreRedis(host, port, user, password, backupFile string, opts RestoreOptions) error {
+	snapshot := backupFile
+	if strings.HasSuffix(backupFile, ".zip") {
+		tmpDir, err := os.MkdirTemp("", "dbx-redis-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmpDir) }()
+		if snapshot, err = utils.ExtractFile(backupFile, tmpDir); err != nil {
+			return err
+		}
+	}
+	if err := checkRDBHeader(snapshot); err != nil {
+		return err
+	}
+
+	client, err := redisClient(host, port, user, password, opts.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), redisRestoreTimeout)
+	defer cancel()
+
+	info, err := redisInfo(ctx, client, "replication")
+	if err != nil {
+		return err
+	}
+	if info["role"] != "master" {
+		return fmt.Errorf("%s is a replica; restore into its master instead", net.JoinHostPort(host, port))
+	}
+
+	listenHost := opts.ListenHost
+	if listenHost == "" {
+		if listenHost, err = redisListenHost(host, port, opts.Conn); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenHost, "0"))
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for the server to connect back (set --listen-host): %w", listenHost, err)
+	}
+	master := &snapshotMaster{listener: listener, snapshot: snapshot, errc: make(chan error, 1), done: make(chan struct{})}
+	go master.serve()
+	defer func() { _ = listener.Close() }()
+
+	_, listenPort, _ := net.SplitHostPort(listener.Addr().String())
+	fmt.Printf("🔄 Restoring Redis snapshot via %s...\n", net.JoinHostPort(listenHost, listenPort))
+	if err := replicaOf(ctx, client, listenHost, listenPort); err != nil {
+		return err
+	}
+	// Whatever happens next, the server must not stay a replica of a listener that is about to close
+	defer func() {
+		if err := replicaOf(context.Background(), client, "NO", "ONE"); err != nil {
+			fmt.Printf("⚠️  Failed to promote %s back to master, run REPLICAOF NO ONE: %v\n", host, err)
+		}
+	}()
+
+	return waitForRedisLoad(ctx, client, master)
+}
+
+// waitForRedisLoad polls the server until it has loaded the snapshot from master
+func waitForRedisLoad(ctx context.Context, client *redis.Client, master *snapshotMaster) error {
+	for {
+		select {
+		case err := <-master.errc:
+			return err
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for Redis to load the snapshot", redisRestoreTimeout)
+		case <-time.After(200 * time.Millisecond):
+		}
+		if !master.sent() {
+			continue
+		}
+		repl, err := redisInfo(ctx, client, "replication")
+		if err != nil {
+			// The server doesn't answer while it loads
+			if strings.Contains(err.Error(), "LOADING") {
+				continue
+			}
+			return err
+		}
+		persistence, err := redisInfo(ctx, client, "persistence")
+		if err != nil {
+			continue
+		}
+		if repl["master_link_status"] == "up" && repl["master_sync_in_progress"] == "0" && persistence["loading"] == "0" {
+			return nil
+		}
+	}
+}
+
+// replicaOf runs REPLICAOF, falling back to SLAVEOF for Redis before 5.0
+func replicaOf(ctx context.Context, client *redis.Client, host, port string) error {
+	err := client.Do(ctx, "REPLICAOF", host, port).Err()
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown command") {
+		err = client.Do(ctx, "SLAVEOF", host, port).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("REPLICAOF %s %s failed: %w", host, port, err)
+	}
+	return nil
+}
+
+// redisListenHost picks the local address the Redis server can reach this host on: loopback for a
+// local server or socket, else the address this host uses to reach the server
+func redisListenHost(host, port string, conn ConnOptions) (string, error) {
+	if conn.Socket != "" || host == "localhost" {
+		return "127.0.0.1", nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return ip.String(), nil
+	}
+	// A UDP "connection" sends nothing but makes the OS pick the outgoing address
+	probe, err := net.Dial("udp", net.JoinHostPort(host, port))
+	if err != nil {
+		return "", fmt.Errorf("failed to find a local address the server can reach (set --listen-host): %w", err)
+	}
+	defer func() { _ = probe.Close() }()
+	return probe.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// snapshotMaster speaks just enough of the master side of Redis replication to hand one
+// snapshot to a replica in a full resync
+type snapshotMaster struct {
+	listener net.Listener
+	snapshot string
+	errc     chan error    // receives the error that ends the restore early
+	done     chan struct{} // closed once the snapshot is sent
+}
+
+// sent reports whether the whole snapshot has been sent
+func (m *snapshotMaster) sent() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *snapshotMaster) serve() {
+	conn, err := m.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	if err := m.handshake(conn); err != nil {
+		m.errc <- fmt.Errorf("replication handshake failed: %w", err)
+		return
+	}
+
+	// Redis reconnects when loading fails, e.g. for a snapshot from a newer Redis version
+	go func() {
+		if again, err := m.listener.Accept(); err == nil {
+			_ = again.Close()
+			m.errc <- fmt.Errorf("the server rejected the snapshot and reconnected (check its log; a snapshot from a newer Redis version can't be loaded)")
+		}
+	}()
+	// Keep the link open, discarding the replica's REPLCONF ACKs, until the restore ends
+	_, _ = io.Copy(io.Discard, conn)
+}
+
+// handshake answers the replica's PING/AUTH/REPLCONF commands and sends the snapshot on PSYNC or SYNC
+func (m *snapshotMaster) handshake(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			_, err = io.WriteString(conn, "+PONG\r\n")
+		case "PSYNC", "SYNC":
+			return m.sendSnapshot(conn, strings.ToUpper(args[0]) == "PSYNC")
+		default:
+			// AUTH and REPLCONF only need an OK from a master that has nothing else to offer
+			_, err = io.WriteString(conn, "+OK\r\n")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendSnapshot sends the RDB as the payload of a full resync
+func (m *snapshotMaster) sendSnapshot(conn net.Conn, psync bool) error {
+	f, err := os.Open(m.snapshot)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if psync {
+		replID := make([]byte, 20)
+		_, _ = rand.Read(replID)
+		if _, err := fmt.Fprintf(conn, "+FULLRESYNC %s 0\r\n", hex.EncodeToString(replID)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(conn, "$%d\r\n", info.Size()); err != nil {
+		return err
+	}
+	if _, err := io.Copy(conn, f); err != nil {
+		return err
+	}
+	close(m.done)
+	return nil
+}
+
+// readRESPCommand reads one command, sent either as a RESP array of bulk strings or inline
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("malformed command %q", line)
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("malformed argument %q", header)
+		}
+		size, err := strconv.Atoi(strings.TrimRight(header[1:], "\r\n"))
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("malformed argument %q", header)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
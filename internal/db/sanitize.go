@@ -0,0 +1,373 @@
+package db
+
+import (
+	"bytes"
+	"dbx/internal/logs"
+	"dbx/internal/mask"
+	"dbx/internal/utils"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SanitizeBackup writes a masked copy of a MySQL, MariaDB, PostgreSQL or SQLite backup to outDir (default:
+// next to the backup) and returns its path. engine may be empty to detect it from the manifest or the file
+// itself. Parallel MySQL bundles are masked table file by table file into a new bundle; PostgreSQL
+// custom-format and directory-format backups come out as plain SQL, restored with psql.
+func SanitizeBackup(backupFile, rulesFile, engine, outDir string) (string, error) {
+	start := time.Now()
+	if _, err := os.Stat(backupFile); err != nil {
+		return "", fmt.Errorf("backup file not found: %w", err)
+	}
+	rules, err := mask.Load(rulesFile)
+	if err != nil {
+		return "", err
+	}
+
+	bundle := isMySQLBundle(backupFile)
+	source := backupFile
+	if strings.HasSuffix(backupFile, ".zip") && !bundle {
+		var cleanup func()
+		if source, cleanup, err = sanitizeSource(backupFile); err != nil {
+			return "", err
+		}
+		defer cleanup()
+	}
+
+	manifest := findManifest(backupFile)
+	if engine == "" && manifest != nil {
+		engine = manifest.DBType
+	}
+	if engine == "" {
+		if engine, err = detectEngine(source); err != nil {
+			return "", err
+		}
+	}
+
+	if outDir == "" {
+		outDir = filepath.Dir(backupFile)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	base := filepath.Base(source)
+	ext := filepath.Ext(base)
+	if info, err := os.Stat(source); err == nil && info.IsDir() && !bundle {
+		// A directory-format dump, which comes out as one SQL script
+		base, ext = strings.TrimSuffix(filepath.Base(backupFile), ".zip"), ".sql"
+	}
+	outFile := filepath.Join(outDir, strings.TrimSuffix(base, ext)+"_masked"+ext)
+
+	fmt.Printf("🔒 Sanitizing %s backup %s...\n", engine, filepath.Base(backupFile))
+	switch engine {
+	case "mysql", "mariadb":
+		if bundle {
+			err = sanitizeMySQLBundle(source, outFile, rules)
+		} else {
+			err = maskDumpFile(source, outFile, mask.MySQL, rules)
+		}
+	case "postgres":
+		err = sanitizePostgres(source, outFile, rules)
+	case "sqlite":
+		if isSQLiteSQLDump(source) {
+			err = sanitizeSQLiteDump(source, outFile, rules)
+		} else {
+			err = sanitizeSQLite(source, outFile, rules)
+		}
+	default:
+		err = fmt.Errorf("sanitize supports mysql, mariadb, postgres and sqlite backups, not %s", engine)
+	}
+	logEngine := map[string]string{"mysql": "MySQL", "mariadb": "MariaDB", "postgres": "PostgreSQL", "sqlite": "SQLite"}[engine]
+	status := "SUCCESS"
+	if err != nil {
+		status = "FAILED"
+		_ = os.Remove(outFile)
+	}
+	logs.LogEntry(logEngine, "Sanitize", status, start, err)
+	if err != nil {
+		return "", err
+	}
+
+	// Carry the original manifest over, so restores still see what the backup holds
+	if manifest == nil {
+		manifest = &BackupManifest{DBType: engine, Database: base, BackupType: BackupTypeFull, CreatedAt: time.Now()}
+	}
+	manifest.MaskRules = rulesFile
+	manifest.Masked = rules.Describe()
+	if err := SaveManifest(outFile, manifest); err != nil {
+		fmt.Println("⚠️ Failed to write backup manifest:", err)
+	}
+
+	fmt.Println("✅ Sanitized backup written:", outFile)
+	return outFile, nil
+}
+
+// sanitizeSource extracts a zipped backup to a temp directory that cleanup removes, and returns the
+// directory of a PostgreSQL directory-format dump or the single file the zip holds
+func sanitizeSource(backupFile string) (string, func(), error) {
+	dir, cleanup, err := openBundle(backupFile)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "toc.dat")); err == nil {
+		return dir, cleanup, nil
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && !entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), cleanup, nil
+	}
+	cleanup()
+	return "", nil, fmt.Errorf("%s holds neither a single dump file, a parallel MySQL bundle nor a PostgreSQL directory-format dump", filepath.Base(backupFile))
+}
+
+// detectEngine tells which engine made a backup from its first bytes, or a directory-format
+// PostgreSQL dump from its toc.dat
+func detectEngine(backupFile string) (string, error) {
+	if isMySQLBundle(backupFile) {
+		return "mysql", nil
+	}
+	if _, err := os.Stat(filepath.Join(backupFile, "toc.dat")); err == nil {
+		return "postgres", nil
+	}
+	f, err := os.Open(backupFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("SQLite format 3\x00")), bytes.HasPrefix(head, []byte("-- SQLite database dump")):
+		return "sqlite", nil
+	case bytes.HasPrefix(head, []byte("PGDMP")), bytes.Contains(head, []byte("-- PostgreSQL database dump")):
+		return "postgres", nil
+	case bytes.Contains(head, []byte("-- MySQL dump")), bytes.Contains(head, []byte("-- MariaDB dump")):
+		return "mysql", nil
+	}
+	return "", fmt.Errorf("cannot tell which database made %s; pass the engine explicitly", filepath.Base(backupFile))
+}
+
+// maskDumpFile masks a SQL dump file into outFile
+func maskDumpFile(dumpFile, outFile string, dialect mask.Dialect, rules mask.Rules) error {
+	stats, err := maskFile(dumpFile, outFile, dialect, rules)
+	if err != nil {
+		return err
+	}
+	reportMaskStats(stats)
+	return nil
+}
+
+// maskFile masks a SQL dump file into outFile and returns what it changed
+func maskFile(dumpFile, outFile string, dialect mask.Dialect, rules mask.Rules) (*mask.Stats, error) {
+	src, err := os.Open(dumpFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.Create(outFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create masked dump: %w", err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	stats, err := mask.Dump(src, dst, dialect, rules, nil)
+	if err != nil {
+		return nil, fmt.Errorf("masking failed: %w", err)
+	}
+	return stats, nil
+}
+
+// writePlainDump writes the plain SQL dump that dump produces to outFile. With rules the dump is
+// masked as it streams to the file, so unmasked data never reaches the disk. outFile is removed if
+// anything fails.
+func writePlainDump(outFile string, dialect mask.Dialect, rules mask.Rules, dump func(w io.Writer) error) (err error) {
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(outFile)
+		}
+	}()
+	if rules == nil {
+		return dump(f)
+	}
+
+	// A failed masker closes the pipe, which stops the dump writing into it
+	r, w := io.Pipe()
+	masked := make(chan error, 1)
+	go func() {
+		stats, err := mask.Dump(r, f, dialect, rules, nil)
+		if err != nil {
+			err = fmt.Errorf("masking failed: %w", err)
+		} else {
+			reportMaskStats(stats)
+		}
+		_ = r.CloseWithError(err)
+		masked <- err
+	}()
+	err = dump(w)
+	_ = w.CloseWithError(err)
+	if maskErr := <-masked; err == nil {
+		err = maskErr
+	}
+	return err
+}
+
+// sanitizeMySQLBundle masks every table file of a parallel MySQL bundle and zips the masked files,
+// with the same restore plan, into outFile
+func sanitizeMySQLBundle(backupFile, outFile string, rules mask.Rules) error {
+	dir, cleanup, err := openBundle(backupFile)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	index, err := readBundleIndex(dir)
+	if err != nil {
+		return err
+	}
+	maskedDir, err := os.MkdirTemp("", "dbx-sanitize-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(maskedDir)
+
+	var files []string
+	for _, entry := range index.Tables {
+		files = append(files, entry.File)
+	}
+	if index.ViewsFile != "" {
+		files = append(files, index.ViewsFile)
+	}
+	total := &mask.Stats{}
+	for i, file := range files {
+		stats, err := maskFile(filepath.Join(dir, file), filepath.Join(maskedDir, file), mask.MySQL, rules)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		total.Rows += stats.Rows
+		total.Values += stats.Values
+		// A rule is unused only if no file had a column for it
+		if i == 0 {
+			total.Unused = stats.Unused
+			continue
+		}
+		var unused mask.Rules
+		for _, rule := range total.Unused {
+			for _, other := range stats.Unused {
+				if rule == other {
+					unused = append(unused, rule)
+					break
+				}
+			}
+		}
+		total.Unused = unused
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, bundleIndexFile))
+	if err != nil {
+		return fmt.Errorf("failed to read bundle index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(maskedDir, bundleIndexFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle index: %w", err)
+	}
+	if err := utils.CompressFolder(maskedDir, outFile); err != nil {
+		return fmt.Errorf("failed to bundle masked table dumps: %w", err)
+	}
+	reportMaskStats(total)
+	return nil
+}
+
+// sanitizePostgres masks a plain SQL dump, converting a custom-format archive or a directory-format
+// dump to SQL with pg_restore first
+func sanitizePostgres(backupFile, outFile string, rules mask.Rules) error {
+	if isPlainSQLDump(backupFile) {
+		return maskDumpFile(backupFile, outFile, mask.Postgres, rules)
+	}
+	if _, err := exec.LookPath("pg_restore"); err != nil {
+		showPostgresInstallHelp()
+		return fmt.Errorf("pg_restore not found in PATH (needed to read custom-format backups)")
+	}
+
+	dst, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("failed to create masked dump: %w", err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	// Without -d, pg_restore writes the archive as a SQL script; --clean matches pg_restore -c on restore
+	cmd := exec.Command("pg_restore", "--clean", "--if-exists", "-f", "-", backupFile)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("pg_restore failed to start: %w", err)
+	}
+	stats, maskErr := mask.Dump(stdout, dst, mask.Postgres, rules, nil)
+	if maskErr != nil {
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("pg_restore failed: %v\n%s", err, stderr.String())
+	}
+	if maskErr != nil {
+		return fmt.Errorf("masking failed: %w", maskErr)
+	}
+	reportMaskStats(stats)
+	return nil
+}
+
+// sanitizeSQLite writes a masked copy of a SQLite database: the source is dumped as SQL through
+// the masking rules, and loaded into a new database at outFile
+func sanitizeSQLite(dbPath, outFile string, rules mask.Rules) error {
+	maskedSQL, err := os.CreateTemp(filepath.Dir(outFile), ".dbx-masked-*.sql")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_ = maskedSQL.Close()
+	defer os.Remove(maskedSQL.Name())
+
+	if err := writeSQLiteDump(dbPath, maskedSQL.Name(), ContentAll, rules); err != nil {
+		return err
+	}
+	if err := loadSQLiteDump(maskedSQL.Name(), outFile); err != nil {
+		return fmt.Errorf("failed to build masked database: %w", err)
+	}
+	return nil
+}
+
+// sanitizeSQLiteDump writes a masked copy of a SQLite SQL dump: the dump is loaded into a scratch
+// database, which is dumped again through the masking rules
+func sanitizeSQLiteDump(dumpFile, outFile string, rules mask.Rules) error {
+	tmpDir, err := os.MkdirTemp("", "dbx-sanitize-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	scratch := filepath.Join(tmpDir, "scratch.db")
+	if err := loadSQLiteDump(dumpFile, scratch); err != nil {
+		return err
+	}
+	return writeSQLiteDump(scratch, outFile, ContentAll, rules)
+}
+
+// reportMaskStats prints what a masking pass changed and warns about rules that matched nothing
+func reportMaskStats(stats *mask.Stats) {
+	fmt.Printf("🔒 Masked %d values in %d rows\n", stats.Values, stats.Rows)
+	for _, rule := range stats.Unused {
+		fmt.Printf("⚠️  Mask rule %s matched no column\n", rule)
+	}
+}
@@ -2,8 +2,10 @@ package db
 
 import (
 	"dbx/internal/logs"
+	"dbx/internal/mask"
 	"dbx/internal/notify"
 	"dbx/internal/utils"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,6 +16,25 @@ import (
 
 // BackupSQLite creates a backup of a SQLite database
 func BackupSQLite(dbPath, outDir string) error {
+	return BackupSQLiteWithOptions(dbPath, outDir, BackupOptions{})
+}
+
+// BackupSQLiteWithOptions creates a backup of a SQLite database. With Format set to sql it writes
+// a SQL text dump, like sqlite3 .dump, instead of copying the database; with Content set to schema
+// the dump holds only the CREATE statements.
+//
+// SQL dumps, schema-only and masked backups are read in a single read transaction, so they hold
+// the database as of one committed transaction even while other processes write to it, and
+// include commits still in the write-ahead log. The binary format copies the file as it is on
+// disk: it is only consistent when nothing writes to the database during the copy, and a
+// database in WAL mode should be checkpointed first.
+func BackupSQLiteWithOptions(dbPath, outDir string, opts BackupOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.Content == ContentData {
+		return fmt.Errorf("data-only backups are not supported for SQLite (use --content schema or all)")
+	}
 	start := time.Now()
 
 	if dbPath == "" {
@@ -35,34 +56,52 @@ func BackupSQLite(dbPath, outDir string) error {
 	dbNameWithoutExt := dbName[:len(dbName)-len(filepath.Ext(dbName))]
 	outFile := filepath.Join(outDir, fmt.Sprintf("%s_%s.db", dbNameWithoutExt, timestamp))
 
-	// Open source database
-	src, err := os.Open(dbPath)
-	if err != nil {
-		return fmt.Errorf("failed to open source database: %w", err)
-	}
-	defer func() { _ = src.Close() }()
-
-	// Create destination database
-	dst, err := os.Create(outFile)
-	if err != nil {
-		return fmt.Errorf("failed to create backup file: %w", err)
+	if opts.Format == SQLiteFormatBinary {
+		opts.Format = ""
 	}
-	defer func() { _ = dst.Close() }()
-
-	// Copy database file
-	if _, err := io.Copy(dst, src); err != nil {
-		return fmt.Errorf("failed to copy database: %w", err)
+	if opts.Content == ContentSchema {
+		// A schema holds no values, so there is nothing to mask
+		outFile = filepath.Join(outDir, fmt.Sprintf("%s_schema_%s.sql", dbNameWithoutExt, timestamp))
+		if err := writeSQLiteDump(dbPath, outFile, opts.Content, nil); err != nil {
+			return err
+		}
+	} else if opts.Format == SQLiteFormatSQL {
+		rules, err := opts.maskRules()
+		if err != nil {
+			return err
+		}
+		outFile = filepath.Join(outDir, fmt.Sprintf("%s_%s.sql", dbNameWithoutExt, timestamp))
+		if rules != nil {
+			outFile = filepath.Join(outDir, fmt.Sprintf("%s_masked_%s.sql", dbNameWithoutExt, timestamp))
+		}
+		if err := writeSQLiteDump(dbPath, outFile, opts.Content, rules); err != nil {
+			return err
+		}
+	} else if opts.MaskRules != "" {
+		rules, err := opts.maskRules()
+		if err != nil {
+			return err
+		}
+		outFile = filepath.Join(outDir, fmt.Sprintf("%s_masked_%s.db", dbNameWithoutExt, timestamp))
+		if err := sanitizeSQLite(dbPath, outFile, rules); err != nil {
+			return err
+		}
+	} else if err := copyFile(dbPath, outFile); err != nil {
+		return err
 	}
 
 	// Compress the backup (optional - uncompressed backup is still valid)
+	var err error
 	zipPath := outFile + ".zip"
 	if err := utils.CompressFile(outFile, zipPath); err == nil {
 		// Remove uncompressed file after successful compression
 		_ = os.Remove(outFile)
 		fmt.Println("🗜 Compressed to:", zipPath)
+		saveManifest(zipPath, "sqlite", dbName, BackupTypeFull, opts)
 	} else {
 		// Compression failed - keep uncompressed backup file
 		fmt.Println("⚠️ Compression failed, keeping uncompressed backup:", err)
+		saveManifest(outFile, "sqlite", dbName, BackupTypeFull, opts)
 	}
 
 	defer func() {
@@ -94,3 +133,71 @@ func BackupSQLite(dbPath, outDir string) error {
 	return nil
 }
 
+
+// copyFile copies a file byte for byte
+func copyFile(srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(srcPath), err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", filepath.Base(srcPath), err)
+	}
+	return nil
+}
+
+// writeSQLiteDump writes the database at dbPath to outFile as SQL, masking it when rules are given.
+// The database is read with the Go SQLite driver, so the sqlite3 CLI isn't needed.
+func writeSQLiteDump(dbPath, outFile string, content BackupContent, rules mask.Rules) (err error) {
+	src, err := openSQLiteDump(dbPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	file, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write backup file: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(outFile)
+		}
+	}()
+
+	if rules == nil {
+		if err := src.dump(file, content); err != nil {
+			return fmt.Errorf("failed to dump database: %w", err)
+		}
+		return nil
+	}
+	pr, pw := io.Pipe()
+	dumped := make(chan error, 1)
+	go func() {
+		err := src.dump(pw, content)
+		_ = pw.CloseWithError(err)
+		dumped <- err
+	}()
+	stats, err := mask.Dump(pr, file, mask.SQLite, rules, src.columns())
+	// Unblocks the dump if masking stopped early
+	_ = pr.Close()
+	if dumpErr := <-dumped; dumpErr != nil && !errors.Is(dumpErr, io.ErrClosedPipe) {
+		return fmt.Errorf("failed to dump database: %w", dumpErr)
+	}
+	if err != nil {
+		return fmt.Errorf("masking failed: %w", err)
+	}
+	reportMaskStats(stats)
+	return nil
+}
@@ -0,0 +1,342 @@
+package db
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	// SQLite compiled to Go, so backups and restores need neither cgo nor the sqlite3 CLI
+	_ "modernc.org/sqlite"
+)
+
+// SQLite backup formats
+const (
+	SQLiteFormatBinary = "binary" // a copy of the database file
+	SQLiteFormatSQL    = "sql"    // SQL text like sqlite3 .dump, written without the sqlite3 CLI
+)
+
+// sqliteMagic starts every SQLite database file
+const sqliteMagic = "SQLite format 3\x00"
+
+// sqliteObject is one row of sqlite_master
+type sqliteObject struct {
+	kind, name, sql string
+	columns         []string // tables only: the columns an INSERT sets, leaving out generated ones
+	generated       bool     // the table has generated columns, so its INSERTs list their columns
+}
+
+// openSQLite opens the database at path with the Go SQLite driver on a single connection, waiting
+// for locks held by other processes rather than failing at once
+func openSQLite(path string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	conn.SetMaxOpenConns(1)
+	if _, err := conn.Exec("PRAGMA busy_timeout = 10000"); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return conn, nil
+}
+
+// checkSQLiteFile fails for files that aren't SQLite databases; SQLite treats an empty file as an
+// empty database
+func checkSQLiteFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	header := make([]byte, len(sqliteMagic))
+	n, _ := io.ReadFull(f, header)
+	if n > 0 && string(header[:n]) != sqliteMagic {
+		return fmt.Errorf("%s is not a SQLite database", path)
+	}
+	return nil
+}
+
+// sqliteDump reads a database for dumping. Everything is read in one read transaction, which
+// holds SQLite's shared lock (or its WAL read mark) from the first read to the end, so the dump is
+// a consistent snapshot of the last committed state while other processes keep writing.
+type sqliteDump struct {
+	conn    *sql.DB
+	tx      *sql.Tx
+	objects []sqliteObject
+}
+
+// openSQLiteDump starts the read transaction and reads the schema in creation order
+func openSQLiteDump(path string) (*sqliteDump, error) {
+	if err := checkSQLiteFile(path); err != nil {
+		return nil, err
+	}
+	conn, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &sqliteDump{conn: conn}
+	if d.tx, err = conn.Begin(); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to start read transaction: %w", err)
+	}
+	if err := d.readSchema(); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return d, nil
+}
+
+// Close ends the read transaction and closes the database
+func (d *sqliteDump) Close() {
+	if d.tx != nil {
+		_ = d.tx.Rollback()
+	}
+	_ = d.conn.Close()
+}
+
+func (d *sqliteDump) readSchema() error {
+	rows, err := d.tx.Query("SELECT type, name, sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY rowid")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var obj sqliteObject
+		if err := rows.Scan(&obj.kind, &obj.name, &obj.sql); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		d.objects = append(d.objects, obj)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range d.objects {
+		obj := &d.objects[i]
+		if obj.kind != "table" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(obj.sql), "CREATE VIRTUAL TABLE") {
+			return fmt.Errorf("virtual table %s can't be dumped as SQL (use the binary format)", obj.name)
+		}
+		// hidden is 2 for VIRTUAL and 3 for STORED generated columns
+		rows, err := d.tx.Query("SELECT name, hidden FROM pragma_table_xinfo(?) ORDER BY cid", obj.name)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", obj.name, err)
+		}
+		for rows.Next() {
+			var name string
+			var hidden int
+			if err := rows.Scan(&name, &hidden); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("table %s: %w", obj.name, err)
+			}
+			if hidden == 0 {
+				obj.columns = append(obj.columns, name)
+			} else {
+				obj.generated = true
+			}
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("table %s: %w", obj.name, err)
+		}
+	}
+	return nil
+}
+
+// dump writes the database as SQL in the layout of sqlite3 .dump: tables with their rows, the
+// AUTOINCREMENT counters, then indexes, triggers and views in creation order, all in one
+// transaction. Generated columns are left to be computed again.
+func (d *sqliteDump) dump(w io.Writer, content BackupContent) error {
+	out := bufio.NewWriterSize(w, 1<<20)
+	out.WriteString("-- SQLite database dump\nPRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n")
+	// sqlite3 .dump leaves out the header's version numbers, which applications use for migrations
+	for _, pragma := range []string{"user_version", "application_id"} {
+		var v int64
+		if err := d.tx.QueryRow("PRAGMA " + pragma).Scan(&v); err != nil {
+			return err
+		}
+		if v != 0 {
+			fmt.Fprintf(out, "PRAGMA %s=%d;\n", pragma, v)
+		}
+	}
+
+	var sequence *sqliteObject
+	for i, obj := range d.objects {
+		if obj.kind != "table" {
+			continue
+		}
+		if obj.name == "sqlite_sequence" {
+			sequence = &d.objects[i]
+			continue
+		}
+		// The statistics tables are rebuilt by ANALYZE
+		if strings.HasPrefix(strings.ToLower(obj.name), "sqlite_") {
+			continue
+		}
+		fmt.Fprintf(out, "%s;\n", obj.sql)
+		if content == ContentSchema {
+			continue
+		}
+		if err := d.dumpRows(out, obj); err != nil {
+			return err
+		}
+	}
+	if sequence != nil && content != ContentSchema {
+		out.WriteString("DELETE FROM sqlite_sequence;\n")
+		if err := d.dumpRows(out, *sequence); err != nil {
+			return err
+		}
+	}
+	for _, obj := range d.objects {
+		if obj.kind == "index" || obj.kind == "trigger" || obj.kind == "view" {
+			fmt.Fprintf(out, "%s;\n", obj.sql)
+		}
+	}
+	out.WriteString("COMMIT;\n")
+	return out.Flush()
+}
+
+// dumpRows writes an INSERT statement for every row of a table. SQLite's quote() formats each
+// value as a literal that reads back the same; text holding NUL characters, which quote() would
+// cut short, is written as a blob cast back to text.
+func (d *sqliteDump) dumpRows(out *bufio.Writer, obj sqliteObject) error {
+	if len(obj.columns) == 0 {
+		return nil
+	}
+	values := make([]string, len(obj.columns))
+	for i, column := range obj.columns {
+		c := sqliteIdent(column)
+		values[i] = fmt.Sprintf("CASE WHEN typeof(%s) = 'text' AND instr(%s, char(0)) > 0 "+
+			"THEN 'CAST(' || quote(CAST(%s AS BLOB)) || ' AS TEXT)' ELSE quote(%s) END", c, c, c, c)
+	}
+	rows, err := d.tx.Query("SELECT " + strings.Join(values, ", ") + " FROM " + sqliteIdent(obj.name))
+	if err != nil {
+		return fmt.Errorf("failed to read table %s: %w", obj.name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	prefix := "INSERT INTO " + sqliteIdent(obj.name)
+	if obj.generated {
+		quoted := make([]string, len(obj.columns))
+		for i, column := range obj.columns {
+			quoted[i] = sqliteIdent(column)
+		}
+		prefix += "(" + strings.Join(quoted, ",") + ")"
+	}
+	prefix += " VALUES("
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to read table %s: %w", obj.name, err)
+		}
+		out.WriteString(prefix)
+		out.WriteString(strings.Join(values, ","))
+		if _, err := out.WriteString(");\n"); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table %s: %w", obj.name, err)
+	}
+	return nil
+}
+
+// columns returns the columns of each table, for masking INSERTs without column lists
+func (d *sqliteDump) columns() map[string][]string {
+	columns := make(map[string][]string)
+	for _, obj := range d.objects {
+		if obj.kind == "table" {
+			columns[obj.name] = obj.columns
+		}
+	}
+	return columns
+}
+
+// sqliteIdent double-quotes an identifier
+func sqliteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqliteToken is one token of a SQL statement: a word (keyword or bare name), a quoted name, a
+// string, a number or a single punctuation character
+type sqliteToken struct {
+	text   string // the unquoted value
+	quoted bool
+	pos    int // byte offset in the SQL
+}
+
+func (t sqliteToken) is(word string) bool {
+	return !t.quoted && strings.EqualFold(t.text, word)
+}
+
+// tokenizeSQLite splits SQL into tokens, skipping whitespace and comments
+func tokenizeSQLite(sql string) ([]sqliteToken, error) {
+	var tokens []sqliteToken
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || c == '`' || c == '\'' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == closing {
+					if closing != ']' && j+1 < len(sql) && sql[j+1] == closing {
+						b.WriteByte(closing)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(sql[j])
+			}
+			if j >= len(sql) {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			tokens = append(tokens, sqliteToken{text: b.String(), quoted: true, pos: i})
+			i = j + 1
+		case c == '_' || c == '$' || c >= 0x80 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(sql) {
+				d := sql[j]
+				if d == '_' || d == '$' || d >= 0x80 || d >= '0' && d <= '9' || d >= 'a' && d <= 'z' || d >= 'A' && d <= 'Z' || d == '.' && c >= '0' && c <= '9' {
+					j++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, sqliteToken{text: sql[i:j], pos: i})
+			i = j
+		default:
+			tokens = append(tokens, sqliteToken{text: sql[i : i+1], pos: i})
+			i++
+		}
+	}
+	return tokens, nil
+}
@@ -1,13 +1,18 @@
 package db
 
 import (
+	"bufio"
+	"dbx/internal/utils"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// RestoreSQLite restores a SQLite database from a backup file
+// RestoreSQLite restores a SQLite database from a backup file: a copy of the database is copied to
+// targetPath, a SQL dump (--format sql) is loaded into a new database there.
+// Zipped backups are extracted first.
 func RestoreSQLite(backupFile, targetPath string) error {
 	if backupFile == "" {
 		return fmt.Errorf("backup file path cannot be empty")
@@ -18,9 +23,28 @@ func RestoreSQLite(backupFile, targetPath string) error {
 		return fmt.Errorf("backup file not found: %w", err)
 	}
 
+	warnIfPartial(backupFile)
+
+	source := backupFile
+	if strings.HasSuffix(backupFile, ".zip") {
+		tmpDir, err := os.MkdirTemp("", "dbx-sqlite-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmpDir) }()
+		if source, err = utils.ExtractFile(backupFile, tmpDir); err != nil {
+			return err
+		}
+	}
+	sqlDump := isSQLiteSQLDump(source)
+
 	// If target path is not provided, use the backup file name
 	if targetPath == "" {
-		targetPath = filepath.Join(filepath.Dir(backupFile), "restored_"+filepath.Base(backupFile))
+		name := filepath.Base(backupFile)
+		if sqlDump {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, ".zip"), ".sql") + ".db"
+		}
+		targetPath = filepath.Join(filepath.Dir(backupFile), "restored_"+name)
 	}
 
 	// Ensure target directory exists
@@ -28,8 +52,16 @@ func RestoreSQLite(backupFile, targetPath string) error {
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
+	if sqlDump {
+		if err := loadSQLiteDump(source, targetPath); err != nil {
+			return err
+		}
+		fmt.Println("✅ SQLite restore completed successfully:", targetPath)
+		return nil
+	}
+
 	// Open backup file
-	src, err := os.Open(backupFile)
+	src, err := os.Open(source)
 	if err != nil {
 		return fmt.Errorf("failed to open backup file: %w", err)
 	}
@@ -51,3 +83,143 @@ func RestoreSQLite(backupFile, targetPath string) error {
 	return nil
 }
 
+// isSQLiteSQLDump reports whether a SQLite backup is SQL text rather than a database file
+func isSQLiteSQLDump(backupFile string) bool {
+	f, err := os.Open(backupFile)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	head := make([]byte, 64)
+	n, _ := io.ReadFull(f, head)
+	text := strings.ToUpper(strings.TrimLeft(string(head[:n]), " \t\r\n"))
+	for _, prefix := range []string{"PRAGMA ", "BEGIN", "CREATE ", "INSERT ", "--", "/*"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSQLiteDump builds a database from a SQL dump next to targetPath and moves it into place,
+// so a failed load leaves an existing target untouched. The statements are run one at a time
+// with the Go SQLite driver, so the sqlite3 CLI isn't needed.
+func loadSQLiteDump(dumpFile, targetPath string) error {
+	dump, err := os.Open(dumpFile)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer func() { _ = dump.Close() }()
+
+	tmpPath := targetPath + ".dbx-restore"
+	_ = os.Remove(tmpPath)
+	conn, err := openSQLite(tmpPath)
+	if err != nil {
+		return err
+	}
+	fmt.Println("🔄 Loading SQL dump...")
+	err = splitSQLiteStatements(dump, func(stmt string, line int) error {
+		if _, err := conn.Exec(stmt); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		return nil
+	})
+	if closeErr := conn.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to load SQL dump: %w", err)
+	}
+
+	// A WAL left by the old database would be applied to the new one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		_ = os.Remove(targetPath + suffix)
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+	return nil
+}
+
+// splitSQLiteStatements reads SQL from r and calls fn with each statement and the line it starts
+// on. A statement ends at a semicolon outside quotes and comments, except in CREATE TRIGGER, whose
+// body holds statements of its own: it ends at the semicolon after the body's END.
+func splitSQLiteStatements(r io.Reader, fn func(stmt string, line int) error) error {
+	in := bufio.NewReaderSize(r, 1<<20)
+	var pending []byte
+	line := 1
+	for {
+		chunk, readErr := in.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		eof := readErr == io.EOF
+		pending = append(pending, chunk...)
+		// Statements end at the end of a line in dumps, so only look for one there
+		if !eof && !strings.HasSuffix(strings.TrimRight(chunk, " \t\r\n"), ";") {
+			continue
+		}
+		text := string(pending)
+		tokens, err := tokenizeSQLite(text)
+		if err != nil {
+			if eof {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			// A quoted value or comment runs on past this line
+			continue
+		}
+
+		done, start := 0, 0
+		for start < len(tokens) {
+			end := sqliteStatementEnd(tokens[start:])
+			if end < 0 {
+				break
+			}
+			end += start
+			stmtLine := line + strings.Count(text[done:tokens[start].pos], "\n")
+			if end > start {
+				if err := fn(text[tokens[start].pos:tokens[end].pos+1], stmtLine); err != nil {
+					return err
+				}
+			}
+			line += strings.Count(text[done:tokens[end].pos+1], "\n")
+			done, start = tokens[end].pos+1, end+1
+		}
+		if eof {
+			if start < len(tokens) {
+				stmtLine := line + strings.Count(text[done:tokens[start].pos], "\n")
+				return fn(text[tokens[start].pos:], stmtLine)
+			}
+			return nil
+		}
+		pending = append(pending[:0], text[done:]...)
+	}
+}
+
+// sqliteStatementEnd returns the index of the semicolon that ends the statement starting at
+// tokens[0], or -1 if the statement goes on past the tokens
+func sqliteStatementEnd(tokens []sqliteToken) int {
+	trigger := len(tokens) > 2 && tokens[0].is("CREATE") &&
+		(tokens[1].is("TRIGGER") || (tokens[1].is("TEMP") || tokens[1].is("TEMPORARY")) && tokens[2].is("TRIGGER"))
+	inBody, ended, cases := false, false, 0
+	for i, tok := range tokens {
+		switch {
+		case tok.is(";"):
+			if !trigger || ended {
+				return i
+			}
+		case !trigger:
+		case tok.is("BEGIN"):
+			inBody = true
+		case tok.is("CASE"):
+			cases++
+		case tok.is("END") && cases > 0:
+			cases--
+		case tok.is("END") && inBody:
+			ended = true
+		}
+	}
+	return -1
+}
@@ -0,0 +1,307 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbx/internal/config"
+	"dbx/internal/scheduler"
+	"dbx/internal/secrets"
+)
+
+// Status is the outcome of a single check
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Check is one line of the report
+type Check struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Report collects the checks of a doctor run
+type Report struct {
+	Checks   []Check `json:"checks"`
+	Passed   int     `json:"passed"`
+	Warnings int     `json:"warnings"`
+	Failures int     `json:"failures"`
+}
+
+// Options controls what Run looks at
+type Options struct {
+	// Config is the loaded config file, nil when there is none
+	Config *config.Config
+	// ConfigErr is the error loading the config file, other than it not existing
+	ConfigErr error
+	// Jobs are the scheduled jobs to validate
+	Jobs []scheduler.JobConfig
+	// OutDirs are backup directories checked in addition to those of profiles and jobs
+	OutDirs []string
+	// LogDir is the log directory (default $DBX_LOG_DIR or ./logs)
+	LogDir string
+	// MinFree is the free space below which a directory fails (default 1 GiB)
+	MinFree uint64
+	// Connect checks server versions against the client tools by connecting to each profile and job
+	Connect bool
+	// Timeout bounds each connection and tool invocation (default 5s)
+	Timeout time.Duration
+}
+
+// DefaultMinFree is the free space a backup or log directory should have
+const DefaultMinFree = 1 << 30
+
+// Run performs every check and returns the report
+func Run(opts Options) *Report {
+	if opts.MinFree == 0 {
+		opts.MinFree = DefaultMinFree
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.LogDir == "" {
+		opts.LogDir = os.Getenv("DBX_LOG_DIR")
+		if opts.LogDir == "" {
+			opts.LogDir = "./logs"
+		}
+	}
+
+	r := &Report{}
+	r.checkConfig(opts)
+	versions := r.checkTools(opts)
+	if opts.Connect {
+		r.checkServers(opts, versions)
+	}
+	r.checkDirs(opts)
+	r.checkCloud(opts)
+	r.checkSchedules(opts)
+	r.checkClock()
+	return r
+}
+
+// OK reports whether no check failed
+func (r *Report) OK() bool {
+	return r.Failures == 0
+}
+
+func (r *Report) add(category, name string, status Status, detail string) {
+	r.Checks = append(r.Checks, Check{Category: category, Name: name, Status: status, Detail: detail})
+	switch status {
+	case Pass:
+		r.Passed++
+	case Warn:
+		r.Warnings++
+	case Fail:
+		r.Failures++
+	}
+}
+
+// checkConfig reports whether the config file loaded
+func (r *Report) checkConfig(opts Options) {
+	switch {
+	case opts.ConfigErr != nil:
+		r.add("config", "config file", Fail, opts.ConfigErr.Error())
+	case opts.Config == nil:
+		r.add("config", "config file", Pass, "none ("+config.DefaultPath()+" not found); profiles and destinations not checked")
+	default:
+		r.add("config", "config file", Pass, fmt.Sprintf("%s (%d profiles, %d destinations)",
+			config.DefaultPath(), len(opts.Config.Profiles), len(opts.Config.Destinations)))
+	}
+}
+
+// target is a database a profile or scheduled job backs up
+type target struct {
+	name   string
+	engine string
+	params map[string]string
+}
+
+// targets returns the profiles and jobs with their params resolved; errors are reported as failures
+func (r *Report) targets(opts Options) []target {
+	var out []target
+	if opts.Config != nil {
+		for _, name := range opts.Config.ProfileNames() {
+			p, err := opts.Config.Profile(name)
+			if err != nil {
+				r.add("config", "profile "+name, Fail, err.Error())
+				continue
+			}
+			out = append(out, target{name: "profile " + name, engine: p.Engine, params: p.Params()})
+		}
+	}
+	for _, job := range opts.Jobs {
+		// Profile-based jobs are covered by their profile
+		if job.Params["profile"] != "" {
+			continue
+		}
+		out = append(out, target{name: fmt.Sprintf("job #%d", job.ID), engine: job.DBType, params: job.Params})
+	}
+	return out
+}
+
+// engines returns the engines used by profiles and jobs
+func (opts Options) engines() map[string]bool {
+	used := make(map[string]bool)
+	if opts.Config != nil {
+		for _, name := range opts.Config.ProfileNames() {
+			if p, err := opts.Config.Profile(name); err == nil {
+				used[p.Engine] = true
+			}
+		}
+	}
+	for _, job := range opts.Jobs {
+		used[job.DBType] = true
+	}
+	return used
+}
+
+// checkDirs checks that the backup and log directories are writable and have room
+func (r *Report) checkDirs(opts Options) {
+	dirs := map[string]string{filepath.Clean(opts.LogDir): "log directory"}
+	addOut := func(dir string) {
+		if dir == "" {
+			dir = "./backups"
+		}
+		if _, ok := dirs[filepath.Clean(dir)]; !ok {
+			dirs[filepath.Clean(dir)] = "backup directory"
+		}
+	}
+	addOut("")
+	for _, dir := range opts.OutDirs {
+		addOut(dir)
+	}
+	if opts.Config != nil {
+		for _, name := range opts.Config.ProfileNames() {
+			if p, err := opts.Config.Profile(name); err == nil {
+				addOut(p.Out)
+			}
+		}
+	}
+	for _, job := range opts.Jobs {
+		addOut(job.Params["out"])
+	}
+
+	paths := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+	for _, dir := range paths {
+		status, detail := CheckDir(dir, opts.MinFree)
+		r.add("storage", dirs[dir]+" "+dir, status, detail)
+	}
+}
+
+// CheckDir checks that dir (or, if it doesn't exist yet, the parent it would be created in)
+// is writable and has at least minFree bytes available
+func CheckDir(dir string, minFree uint64) (Status, string) {
+	existing := dir
+	created := false
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return Fail, existing + " is not a directory"
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return Fail, err.Error()
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return Fail, "no existing parent directory"
+		}
+		existing, created = parent, true
+	}
+
+	probe, err := os.CreateTemp(existing, ".dbx-doctor-*")
+	if err != nil {
+		return Fail, "not writable: " + err.Error()
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	note := ""
+	if created {
+		note = " (will be created)"
+	}
+	free, err := freeSpace(existing)
+	if err != nil {
+		return Warn, "writable" + note + "; free space unknown: " + err.Error()
+	}
+	detail := fmt.Sprintf("writable%s, %s free", note, FormatBytes(free))
+	if free < minFree {
+		return Fail, detail + ", below " + FormatBytes(minFree)
+	}
+	if free < 2*minFree {
+		return Warn, detail + ", getting low"
+	}
+	return Pass, detail
+}
+
+// checkSchedules validates every scheduled job and its secret references
+func (r *Report) checkSchedules(opts Options) {
+	if len(opts.Jobs) == 0 {
+		r.add("schedules", "scheduled jobs", Pass, "none")
+		return
+	}
+	for _, job := range opts.Jobs {
+		name := fmt.Sprintf("job #%d", job.ID)
+		if err := scheduler.ValidateJob(job); err != nil {
+			r.add("schedules", name, Fail, err.Error())
+			continue
+		}
+		if _, err := secrets.ResolveParams(job.Params); err != nil {
+			r.add("schedules", name, Fail, err.Error())
+			continue
+		}
+		r.add("schedules", name, Pass, fmt.Sprintf("%s %q", job.DBType, job.Schedule))
+	}
+}
+
+// ParseSize parses a byte size such as "500MB", "2GB" or "1073741824" (binary units)
+func ParseSize(s string) (uint64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		factor uint64
+	}{{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+	factor := uint64(1)
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			value, factor = strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500MB or 2GB)", s)
+	}
+	return uint64(n * float64(factor)), nil
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "1.5 GB"
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
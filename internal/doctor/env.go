@@ -0,0 +1,159 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cloudCLIs are the programs uploads shell out to, by provider
+var cloudCLIs = map[string]string{
+	"s3":    "aws",
+	"gcs":   "gsutil",
+	"azure": "az",
+}
+
+// checkCloud checks the CLI and credentials of every provider a destination or job uploads to
+func (r *Report) checkCloud(opts Options) {
+	providers := make(map[string]string) // provider -> who uses it
+	if opts.Config != nil {
+		names := make([]string, 0, len(opts.Config.Destinations))
+		for name := range opts.Config.Destinations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			provider := strings.ToLower(opts.Config.Destinations[name].Provider)
+			if _, ok := providers[provider]; !ok {
+				providers[provider] = "destination " + name
+			}
+		}
+	}
+	autoUpload := os.Getenv("DBX_AUTO_UPLOAD") == "true"
+	for _, job := range opts.Jobs {
+		if job.Params["upload_cloud"] != "true" && !autoUpload {
+			continue
+		}
+		provider := job.Params["cloud_provider"]
+		if provider == "" {
+			provider = os.Getenv("DBX_CLOUD_PROVIDER")
+		}
+		if provider == "" {
+			provider = "s3"
+		}
+		provider = strings.ToLower(provider)
+		if _, ok := providers[provider]; !ok {
+			providers[provider] = fmt.Sprintf("job #%d", job.ID)
+		}
+	}
+
+	if len(providers) == 0 {
+		r.add("cloud", "destinations", Pass, "none configured")
+		return
+	}
+	names := make([]string, 0, len(providers))
+	for provider := range providers {
+		names = append(names, provider)
+	}
+	sort.Strings(names)
+	for _, provider := range names {
+		cli, ok := cloudCLIs[provider]
+		if !ok {
+			r.add("cloud", provider, Fail, fmt.Sprintf("unsupported provider (used by %s; use s3, gcs or azure)", providers[provider]))
+			continue
+		}
+		if _, err := exec.LookPath(cli); err != nil {
+			r.add("cloud", provider, Fail, fmt.Sprintf("%s CLI not found on PATH (used by %s)", cli, providers[provider]))
+			continue
+		}
+		status, detail := CloudCredentials(provider)
+		r.add("cloud", provider, status, detail)
+	}
+}
+
+// CloudCredentials reports where the provider's CLI would find credentials, from the
+// environment and the CLI's usual credential files. Nothing found is a warning, since
+// instance roles and workload identity don't leave a trace locally.
+func CloudCredentials(provider string) (Status, string) {
+	home, _ := os.UserHomeDir()
+	var envVars, files []string
+	switch provider {
+	case "s3":
+		if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+			return Fail, "AWS_ACCESS_KEY_ID is set without AWS_SECRET_ACCESS_KEY"
+		}
+		envVars = []string{"AWS_ACCESS_KEY_ID", "AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"}
+		files = []string{os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), filepath.Join(home, ".aws", "credentials"), filepath.Join(home, ".aws", "config")}
+	case "gcs":
+		if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+			if _, err := os.Stat(path); err != nil {
+				return Fail, "GOOGLE_APPLICATION_CREDENTIALS points to a missing file: " + path
+			}
+		}
+		envVars = []string{"GOOGLE_APPLICATION_CREDENTIALS"}
+		files = []string{filepath.Join(home, ".config", "gcloud", "application_default_credentials.json"),
+			filepath.Join(home, ".config", "gcloud", "credentials.db"), filepath.Join(home, ".boto")}
+	case "azure":
+		envVars = []string{"AZURE_STORAGE_CONNECTION_STRING", "AZURE_STORAGE_KEY", "AZURE_STORAGE_SAS_TOKEN"}
+		files = []string{filepath.Join(home, ".azure", "msal_token_cache.json"), filepath.Join(home, ".azure", "accessTokens.json")}
+	default:
+		return Fail, "unsupported provider " + provider
+	}
+
+	for _, name := range envVars {
+		if os.Getenv(name) != "" {
+			return Pass, "credentials from $" + name
+		}
+	}
+	for _, path := range files {
+		if path == "" || !filepath.IsAbs(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return Pass, "credentials from " + path
+		}
+	}
+	return Warn, "no credentials found (fine only with an instance role or workload identity)"
+}
+
+// checkClock reports the timezone and whether the clock looks right and is synchronised
+func (r *Report) checkClock() {
+	now := time.Now()
+	zone, _ := now.Zone()
+	if tz := os.Getenv("TZ"); tz != "" {
+		if _, err := time.LoadLocation(strings.TrimPrefix(tz, ":")); err != nil {
+			r.add("clock", "timezone", Fail, fmt.Sprintf("TZ=%q is not a valid zone; schedules fall back to UTC", tz))
+		} else {
+			r.add("clock", "timezone", Pass, fmt.Sprintf("%s (%s, UTC%s); schedules without --timezone use it", tz, zone, now.Format("-07:00")))
+		}
+	} else {
+		r.add("clock", "timezone", Pass, fmt.Sprintf("%s, UTC%s; schedules without --timezone use it", zone, now.Format("-07:00")))
+	}
+
+	if now.Year() < 2024 {
+		r.add("clock", "system time", Fail, "clock reads "+now.Format(time.RFC3339)+"; backup names and retention depend on it")
+	} else {
+		r.add("clock", "system time", Pass, now.Format(time.RFC3339))
+	}
+
+	// NTP status is only known where systemd reports it
+	if _, err := exec.LookPath("timedatectl"); err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "timedatectl", "show", "--property=NTPSynchronized", "--value").Output()
+	if err != nil {
+		return
+	}
+	if strings.TrimSpace(string(out)) == "yes" {
+		r.add("clock", "time sync", Pass, "synchronised via NTP")
+	} else {
+		r.add("clock", "time sync", Warn, "not synchronised via NTP; scheduled runs may drift")
+	}
+}
@@ -0,0 +1,196 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dbx/internal/db"
+	"dbx/internal/secrets"
+	"dbx/internal/tunnel"
+)
+
+// tool is a client program dbx shells out to
+type tool struct {
+	name     string
+	engine   string
+	optional string // why the tool is only sometimes needed; empty if every backup or restore needs it
+	alt      string // the other MySQL/MariaDB flavour's name for the tool, which dbx uses instead
+}
+
+var tools = []tool{
+	{"mysqldump", "mysql", "", "mariadb-dump"},
+	{"mysql", "mysql", "", "mariadb"},
+	{"xtrabackup", "mysql", "only needed for --method physical", ""},
+	{"mariadb-dump", "mariadb", "", "mysqldump"},
+	{"mariadb", "mariadb", "", "mysql"},
+	{"mariadb-backup", "mariadb", "only needed for --physical", "mariabackup"},
+	{"pg_dump", "postgres", "", ""},
+	{"pg_restore", "postgres", "", ""},
+	{"psql", "postgres", "", ""},
+	{"mongodump", "mongodb", "", ""},
+	{"mongorestore", "mongodb", "", ""},
+	{"mongosh", "mongodb", "only needed for --all-databases and --include", ""},
+	{"sqlite3", "sqlite", "", ""},
+	{"redis-cli", "redis", "", ""},
+	{"etcdctl", "etcd", "", ""},
+	{"etcdutl", "etcd", "", "etcdctl"},
+	{"clickhouse-client", "clickhouse", "", "clickhouse"},
+}
+
+// versionCommands are the tools that print their version with a subcommand instead of --version
+var versionCommands = map[string]bool{"etcdctl": true, "etcdutl": true}
+
+// dumpTools are the tools whose version is compared with the server's
+var dumpTools = map[string]string{
+	"mysql":    "mysqldump",
+	"mariadb":  "mariadb-dump",
+	"postgres": "pg_dump",
+}
+
+// checkTools looks for each client tool on PATH and returns the first line of `tool --version` by name
+func (r *Report) checkTools(opts Options) map[string]string {
+	used := opts.engines()
+	versions := make(map[string]string)
+	for _, t := range tools {
+		path, err := exec.LookPath(t.name)
+		if err != nil && t.alt != "" {
+			if altPath, altErr := exec.LookPath(t.alt); altErr == nil {
+				versions[t.name] = toolVersion(opts, altPath)
+				r.add("tools", t.name, Pass, fmt.Sprintf("not found on PATH; %s is used instead", t.alt))
+				continue
+			}
+		}
+		if err != nil {
+			status, detail := Warn, "not found on PATH"
+			if used[t.engine] && t.optional == "" {
+				status, detail = Fail, fmt.Sprintf("not found on PATH (needed for %s backups)", t.engine)
+			} else if t.optional != "" {
+				detail += " (" + t.optional + ")"
+			}
+			r.add("tools", t.name, status, detail)
+			continue
+		}
+		version := toolVersion(opts, path)
+		versions[t.name] = version
+		if version == "" {
+			version = path
+		}
+		r.add("tools", t.name, Pass, version)
+	}
+	return versions
+}
+
+// toolVersion runs `path --version` and returns the first non-empty line of its output
+func toolVersion(opts Options, path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	arg := "--version"
+	if versionCommands[filepath.Base(path)] {
+		arg = "version"
+	}
+	out, _ := exec.CommandContext(ctx, path, arg).CombinedOutput()
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// checkServers connects to each profile and job and compares the server version with the dump tool
+func (r *Report) checkServers(opts Options, versions map[string]string) {
+	for _, t := range r.targets(opts) {
+		if t.engine == "sqlite" {
+			continue
+		}
+		diag, err := diagnose(t, opts)
+		if err != nil {
+			r.add("servers", t.name, Fail, err.Error())
+			continue
+		}
+		dumpTool, compared := dumpTools[t.engine]
+		clientOutput, installed := versions[dumpTool]
+		if !compared || !installed {
+			r.add("servers", t.name, Pass, fmt.Sprintf("%s %s", t.engine, diag.ServerVersion))
+			continue
+		}
+		status, detail := CheckClientVersion(t.engine, clientOutput, diag.ServerVersion)
+		r.add("servers", t.name, status, detail)
+	}
+}
+
+// diagnose connects to a target through its SSH tunnel, if any
+func diagnose(t target, opts Options) (*db.Diagnosis, error) {
+	params, err := secrets.ResolveParams(t.params)
+	if err != nil {
+		return nil, err
+	}
+	params, closeTunnel, err := tunnel.Rewrite(t.engine, params, tunnel.ConfigFromParams(params))
+	if err != nil {
+		return nil, err
+	}
+	defer closeTunnel()
+	params["timeout"] = opts.Timeout.String()
+	return db.DiagnoseConnection(t.engine, params)
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// clientVersion extracts the server version a dump tool was built for from its --version output,
+// e.g. "mysqldump  Ver 10.19 Distrib 10.11.6-MariaDB" gives "10.11.6-MariaDB"
+func clientVersion(output string) string {
+	for _, marker := range []string{"Distrib ", " from ", ") ", "Ver "} {
+		if i := strings.Index(output, marker); i >= 0 {
+			if fields := strings.Fields(output[i+len(marker):]); len(fields) > 0 {
+				return strings.TrimSuffix(fields[0], ",")
+			}
+		}
+	}
+	return output
+}
+
+// majorMinor returns the first "major.minor" in a version string
+func majorMinor(version string) (int, int, bool) {
+	m := versionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return 0, 0, false
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return major, minor, true
+}
+
+// CheckClientVersion compares a dump tool's --version output with the server version.
+// pg_dump refuses to dump a server with a newer major version, so that fails; an older
+// mysqldump or one from the other MySQL/MariaDB flavour usually works but is warned about.
+func CheckClientVersion(engine, toolOutput, serverVersion string) (Status, string) {
+	client := clientVersion(toolOutput)
+	detail := fmt.Sprintf("server %s, %s %s", serverVersion, dumpTools[engine], client)
+	cMajor, cMinor, okClient := majorMinor(client)
+	sMajor, sMinor, okServer := majorMinor(serverVersion)
+	if !okClient || !okServer {
+		return Warn, detail + " (couldn't compare versions)"
+	}
+
+	switch engine {
+	case "postgres":
+		if cMajor < sMajor {
+			return Fail, detail + fmt.Sprintf(" (pg_dump %d can't dump a PostgreSQL %d server; install the matching client)", cMajor, sMajor)
+		}
+	case "mysql", "mariadb":
+		clientMaria := strings.Contains(strings.ToLower(toolOutput), "mariadb")
+		serverMaria := strings.Contains(strings.ToLower(serverVersion), "mariadb")
+		if clientMaria != serverMaria {
+			return Warn, detail + " (client and server are different flavours; prefer the server's own client)"
+		}
+		if cMajor < sMajor || (cMajor == sMajor && cMinor < sMinor) {
+			return Warn, detail + " (client is older than the server)"
+		}
+	}
+	return Pass, detail
+}
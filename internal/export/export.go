@@ -0,0 +1,97 @@
+package export
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the kind of values a column holds
+type Type int
+
+const (
+	Unknown Type = iota // taken from the values, for sources without a schema (SQLite, MongoDB)
+	String
+	Int
+	Float
+	Bool
+	Time
+	Bytes
+)
+
+func (t Type) String() string {
+	switch t {
+	case String:
+		return "string"
+	case Int:
+		return "integer"
+	case Float:
+		return "float"
+	case Bool:
+		return "boolean"
+	case Time:
+		return "timestamp"
+	case Bytes:
+		return "binary"
+	}
+	return "unknown"
+}
+
+// Column is one output column
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Formats are the output formats, as given to --format
+var Formats = []string{"csv", "jsonl", "parquet"}
+
+// Writer writes rows of one table. Row values are nil, int64, float64, bool, string, []byte or
+// time.Time; anything else is written as its fmt representation.
+type Writer interface {
+	Write(row []any) error
+	// Close flushes buffered rows; it doesn't close the underlying io.Writer
+	Close() error
+}
+
+// NewWriter returns a writer for format that writes the rows of columns to w
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w, columns)
+	case "jsonl":
+		return newJSONLWriter(w, columns), nil
+	case "parquet":
+		return newParquetWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format %q (use %s)", format, strings.Join(Formats, ", "))
+}
+
+// Extension returns the file extension of a format, with its dot
+func Extension(format string) string {
+	return "." + format
+}
+
+// text formats a value for text output: times as RFC 3339 and binary data as base64
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	return fmt.Sprint(v)
+}
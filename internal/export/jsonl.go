@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+)
+
+// jsonlWriter writes one JSON object per line, with the keys in column order. Times are RFC 3339
+// strings, binary data is base64 and NaN or infinite floats are null.
+type jsonlWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func newJSONLWriter(w io.Writer, columns []Column) *jsonlWriter {
+	j := &jsonlWriter{w: bufio.NewWriter(w), keys: make([][]byte, len(columns))}
+	for i, col := range columns {
+		j.keys[i], _ = json.Marshal(col.Name)
+	}
+	return j
+}
+
+func (j *jsonlWriter) Write(row []any) error {
+	_ = j.w.WriteByte('{')
+	for i, v := range row {
+		if i > 0 {
+			_ = j.w.WriteByte(',')
+		}
+		_, _ = j.w.Write(j.keys[i])
+		_ = j.w.WriteByte(':')
+		switch value := v.(type) {
+		case float64:
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				v = nil
+			}
+		case time.Time:
+			v = value.Format(time.RFC3339Nano)
+		case int64, bool, string, []byte, nil:
+		default:
+			v = text(v)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := j.w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := j.w.WriteString("}\n")
+	return err
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,180 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/xitongsys/parquet-go/marshal"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// rowGroupBytes is about how much encoded data goes into one row group
+const rowGroupBytes = 64 << 20
+
+// parquetWriter writes a Parquet file with one optional column per output column, through
+// parquet-go. Columns of Unknown type come from sources without a schema (SQLite, MongoDB), where
+// any row may hold a value of another type, so they are written as strings.
+type parquetWriter struct {
+	pw      *writer.ParquetWriter
+	columns []Column
+	types   []Type
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	p := &parquetWriter{columns: columns, types: make([]Type, len(columns))}
+	children := int32(len(columns))
+	schema := []*parquet.SchemaElement{{Name: "schema", NumChildren: &children}}
+	// parquet-go keys columns by a Go identifier made from the name, which two names can share
+	inNames := make(map[string]string)
+	for i, col := range columns {
+		p.types[i] = col.Type
+		if col.Type == Unknown {
+			p.types[i] = String
+		}
+		key := parquetInName(col.Name)
+		if other, ok := inNames[key]; ok {
+			return nil, fmt.Errorf("columns %s and %s can't both be written to Parquet (the names are too alike)", other, col.Name)
+		}
+		inNames[key] = col.Name
+		schema = append(schema, parquetColumn(col.Name, p.types[i]))
+	}
+
+	pw, err := writer.NewParquetWriterFromWriter(w, schema, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Parquet file: %w", err)
+	}
+	// Rows are slices of values in column order, as with the CSV writer
+	pw.MarshalFunc = marshal.MarshalCSV
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	pw.RowGroupSize = rowGroupBytes
+	p.pw = pw
+	return p, nil
+}
+
+func (p *parquetWriter) Write(row []any) error {
+	values := make([]any, len(row))
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+		v, err := convert(v, p.types[i])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", p.columns[i].Name, err)
+		}
+		values[i] = v
+	}
+	return p.pw.Write(values)
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.pw.WriteStop(); err != nil {
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	return nil
+}
+
+// parquetColumn returns the schema element of an optional column of type t
+func parquetColumn(name string, t Type) *parquet.SchemaElement {
+	col := &parquet.SchemaElement{
+		Name:           name,
+		RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL),
+	}
+	switch t {
+	case Int:
+		col.Type = parquet.TypePtr(parquet.Type_INT64)
+	case Float:
+		col.Type = parquet.TypePtr(parquet.Type_DOUBLE)
+	case Bool:
+		col.Type = parquet.TypePtr(parquet.Type_BOOLEAN)
+	case Time:
+		col.Type = parquet.TypePtr(parquet.Type_INT64)
+		col.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_TIMESTAMP_MICROS)
+		col.LogicalType = &parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{
+			IsAdjustedToUTC: true,
+			Unit:            &parquet.TimeUnit{MICROS: parquet.NewMicroSeconds()},
+		}}
+	case Bytes:
+		col.Type = parquet.TypePtr(parquet.Type_BYTE_ARRAY)
+	default:
+		col.Type = parquet.TypePtr(parquet.Type_BYTE_ARRAY)
+		col.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8)
+		col.LogicalType = &parquet.LogicalType{STRING: parquet.NewStringType()}
+	}
+	return col
+}
+
+// parquetInName is the name parquet-go keys a column by: letters, digits and underscores kept,
+// any other byte replaced by its decimal code, and the first letter upper-cased
+func parquetInName(name string) string {
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' {
+			b = append(b, c)
+		} else {
+			b = strconv.AppendInt(b, int64(c), 10)
+		}
+	}
+	if len(b) > 0 && b[0] >= 'a' && b[0] <= 'z' {
+		b[0] -= 'a' - 'A'
+	}
+	return string(b)
+}
+
+// convert returns v as the Go value parquet-go stores for a column of type t: int64 for Int and
+// Time (as microseconds since the epoch), float64, bool, or string for text and binary data
+func convert(v any, t Type) (any, error) {
+	switch t {
+	case Int:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				return int64(v), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case Float:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+		}
+	case Bool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+	case Time:
+		if v, ok := v.(time.Time); ok {
+			return v.UnixMicro(), nil
+		}
+	case Bytes:
+		switch v := v.(type) {
+		case []byte:
+			return string(v), nil
+		case string:
+			return v, nil
+		}
+	default:
+		return text(v), nil
+	}
+	return nil, fmt.Errorf("%v doesn't fit the %s type", v, t)
+}
@@ -0,0 +1,570 @@
+package mask
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect selects how a SQL dump quotes strings and lays out rows
+type Dialect int
+
+const (
+	MySQL    Dialect = iota // mysqldump: INSERT statements, backslash escapes
+	Postgres                // pg_dump plain format: COPY ... FROM stdin blocks
+	SQLite                  // sqlite3 .dump: one INSERT per row
+)
+
+// Stats reports what a sanitize pass changed
+type Stats struct {
+	Rows   int   // rows of tables that have rules
+	Values int   // values replaced
+	Unused Rules // rules that matched no column, usually a typo worth checking
+}
+
+// Dump copies a SQL dump from r to w, masking the values of every column a rule matches.
+// Column names come from INSERT column lists, COPY headers and (MySQL) CREATE TABLE statements;
+// columns supplies them for dumps that have none, keyed by table name.
+// A table with rules whose columns can't be determined is an error, never passed through unmasked.
+func Dump(r io.Reader, w io.Writer, dialect Dialect, rules Rules, columns map[string][]string) (*Stats, error) {
+	s := &sanitizer{
+		dialect: dialect,
+		rules:   rules,
+		masker:  newMasker(),
+		columns: make(map[string][]string),
+		used:    make(map[int]bool),
+	}
+	for table, cols := range columns {
+		s.columns[table] = cols
+	}
+
+	in := bufio.NewReaderSize(r, 1<<20)
+	out := bufio.NewWriterSize(w, 1<<20)
+	var pending strings.Builder // an INSERT statement spanning several lines
+	for {
+		line, readErr := in.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read dump: %w", readErr)
+		}
+
+		if line != "" {
+			if pending.Len() > 0 || (s.copyRules == nil && strings.HasPrefix(line, "INSERT INTO ")) {
+				pending.WriteString(line)
+				if s.complete(pending.String()) || readErr == io.EOF {
+					line = pending.String()
+					pending.Reset()
+				} else {
+					line = ""
+				}
+				if line != "" {
+					masked, err := s.insert(line)
+					if err != nil {
+						return nil, err
+					}
+					line = masked
+				}
+			} else {
+				masked, err := s.line(line)
+				if err != nil {
+					return nil, err
+				}
+				line = masked
+			}
+			if _, err := out.WriteString(line); err != nil {
+				return nil, fmt.Errorf("failed to write sanitized dump: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write sanitized dump: %w", err)
+	}
+
+	for i, rule := range rules {
+		if !s.used[i] {
+			s.stats.Unused = append(s.stats.Unused, rule)
+		}
+	}
+	return &s.stats, nil
+}
+
+type sanitizer struct {
+	dialect Dialect
+	rules   Rules
+	masker  *masker
+	columns map[string][]string
+	used    map[int]bool
+	stats   Stats
+
+	createTable string       // MySQL table whose CREATE TABLE is being read
+	copyRules   map[int]Rule // rules by field of the COPY block being read; nil outside one
+}
+
+// line handles every line that isn't part of an INSERT statement
+func (s *sanitizer) line(line string) (string, error) {
+	if s.copyRules != nil {
+		if strings.TrimRight(line, "\r\n") == `\.` {
+			s.copyRules = nil
+			return line, nil
+		}
+		if len(s.copyRules) == 0 {
+			return line, nil
+		}
+		return s.copyRow(line), nil
+	}
+
+	switch {
+	case s.dialect == Postgres && strings.HasPrefix(line, "COPY "):
+		return line, s.copyHeader(line)
+	case s.dialect == MySQL && strings.HasPrefix(line, "CREATE TABLE "):
+		s.createTable, _ = parseName(line, len("CREATE TABLE "))
+		s.columns[s.createTable] = nil
+	case s.createTable != "":
+		// mysqldump writes one column per line, indented and backquoted; keys and options follow
+		if trimmed := strings.TrimLeft(line, " "); strings.HasPrefix(trimmed, "`") {
+			name, _ := parseIdent(trimmed, 0)
+			s.columns[s.createTable] = append(s.columns[s.createTable], name)
+		} else if strings.HasPrefix(line, ")") {
+			s.createTable = ""
+		}
+	}
+	return line, nil
+}
+
+// tableRules maps column positions to the rules that apply to them
+func (s *sanitizer) tableRules(table string, columns []string) (map[int]Rule, error) {
+	if !s.rules.forTable(table) {
+		return map[int]Rule{}, nil
+	}
+	if columns == nil {
+		columns = s.lookupColumns(table)
+	}
+	if columns == nil {
+		return nil, fmt.Errorf("cannot mask table %s: its column names are not in the dump", table)
+	}
+	matched := make(map[int]Rule)
+	for i, column := range columns {
+		if idx := s.rules.find(table, column); idx >= 0 {
+			matched[i] = s.rules[idx]
+			s.used[idx] = true
+		}
+	}
+	return matched, nil
+}
+
+func (s *sanitizer) lookupColumns(table string) []string {
+	if cols, ok := s.columns[table]; ok {
+		return cols
+	}
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return s.columns[table[i+1:]]
+	}
+	return nil
+}
+
+// copyHeader starts a COPY block: COPY schema.table (col, ...) FROM stdin;
+func (s *sanitizer) copyHeader(line string) error {
+	table, i := parseName(line, len("COPY "))
+	i = skipSpace(line, i)
+	var columns []string
+	if i < len(line) && line[i] == '(' {
+		columns, _ = parseIdentList(line, i)
+	}
+	rules, err := s.tableRules(table, columns)
+	if err != nil {
+		return err
+	}
+	s.copyRules = rules
+	return nil
+}
+
+// copyRow masks one tab-separated COPY row
+func (s *sanitizer) copyRow(line string) string {
+	body := strings.TrimSuffix(line, "\n")
+	fields := strings.Split(body, "\t")
+	s.stats.Rows++
+	for i, rule := range s.copyRules {
+		if i >= len(fields) || fields[i] == `\N` {
+			continue
+		}
+		value, null := s.masker.apply(rule, copyUnescape(fields[i]), true)
+		if null {
+			fields[i] = `\N`
+		} else {
+			fields[i] = copyEscape(value)
+		}
+		s.stats.Values++
+	}
+	return strings.Join(fields, "\t") + line[len(body):]
+}
+
+// insert masks an INSERT INTO table [(columns)] VALUES (...),(...); statement
+func (s *sanitizer) insert(stmt string) (string, error) {
+	table, i := parseName(stmt, len("INSERT INTO "))
+	if !s.rules.forTable(table) {
+		return stmt, nil
+	}
+	i = skipSpace(stmt, i)
+	var columns []string
+	if i < len(stmt) && stmt[i] == '(' {
+		columns, i = parseIdentList(stmt, i)
+	}
+	rules, err := s.tableRules(table, columns)
+	if err != nil {
+		return "", err
+	}
+	if len(rules) == 0 {
+		return stmt, nil
+	}
+
+	values := strings.Index(strings.ToUpper(stmt[i:]), "VALUES")
+	if values < 0 {
+		return stmt, nil
+	}
+	i += values + len("VALUES")
+
+	var out strings.Builder
+	out.WriteString(stmt[:i])
+	for {
+		j := skipSpace(stmt, i)
+		if j >= len(stmt) || stmt[j] != '(' {
+			out.WriteString(stmt[i:])
+			return out.String(), nil
+		}
+		out.WriteString(stmt[i : j+1])
+		i = j + 1
+
+		// one row: values separated by commas up to the closing parenthesis
+		s.stats.Rows++
+		for col := 0; ; col++ {
+			end := s.scanValue(stmt, i)
+			raw := stmt[i:end]
+			if rule, ok := rules[col]; ok {
+				raw = s.maskLiteral(rule, raw)
+			}
+			out.WriteString(raw)
+			if end >= len(stmt) {
+				return out.String(), nil
+			}
+			out.WriteByte(stmt[end])
+			i = end + 1
+			if stmt[end] == ')' {
+				break
+			}
+		}
+
+		j = skipSpace(stmt, i)
+		if j < len(stmt) && stmt[j] == ',' {
+			out.WriteString(stmt[i : j+1])
+			i = j + 1
+			continue
+		}
+		out.WriteString(stmt[i:])
+		return out.String(), nil
+	}
+}
+
+// maskLiteral masks one SQL value, keeping the whitespace around it
+func (s *sanitizer) maskLiteral(rule Rule, raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	if strings.EqualFold(trimmed, "NULL") {
+		return raw
+	}
+	value, text := trimmed, false
+	if trimmed != "" && trimmed[0] == '\'' {
+		value, text = s.unquote(trimmed), true
+	} else if s.dialect == SQLite {
+		if unwrapped, ok := unwrapSQLiteReplace(trimmed); ok {
+			value, text = unwrapped, true
+		}
+	}
+
+	masked, null := s.masker.apply(rule, value, text)
+	s.stats.Values++
+	if null {
+		return strings.Replace(raw, trimmed, "NULL", 1)
+	}
+	if masked == value && !text {
+		return raw
+	}
+	return strings.Replace(raw, trimmed, s.quote(masked), 1)
+}
+
+// complete reports whether stmt ends with a semicolon outside any quoted string
+func (s *sanitizer) complete(stmt string) bool {
+	for i := 0; i < len(stmt); i++ {
+		if c := stmt[i]; c == '\'' || c == '"' || c == '`' {
+			end := s.scanString(stmt, i)
+			if end < 0 {
+				return false
+			}
+			i = end - 1
+		}
+	}
+	return strings.HasSuffix(strings.TrimRight(stmt, " \t\r\n"), ";")
+}
+
+// scanString returns the index just past the quoted string starting at i, or -1 if it is unterminated
+func (s *sanitizer) scanString(str string, i int) int {
+	q := str[i]
+	backslash := s.dialect == MySQL && q != '`'
+	for j := i + 1; j < len(str); j++ {
+		switch c := str[j]; {
+		case backslash && c == '\\':
+			j++
+		case c == q:
+			if j+1 < len(str) && str[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// scanValue returns the index of the comma or closing parenthesis that ends the value starting at i
+func (s *sanitizer) scanValue(str string, i int) int {
+	depth := 0
+	for j := i; j < len(str); j++ {
+		switch str[j] {
+		case '\'', '"':
+			end := s.scanString(str, j)
+			if end < 0 {
+				return len(str)
+			}
+			j = end - 1
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return j
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(str)
+}
+
+func (s *sanitizer) unquote(literal string) string {
+	body := literal[1 : len(literal)-1]
+	if s.dialect != MySQL {
+		return strings.ReplaceAll(body, "''", "'")
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '\'' && i+1 < len(body) && body[i+1] == '\'' {
+			i++
+		} else if c == '\\' && i+1 < len(body) {
+			i++
+			switch body[i] {
+			case '0':
+				c = 0
+			case 'b':
+				c = '\b'
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'Z':
+				c = 0x1a
+			case '%', '_':
+				b.WriteByte('\\')
+				c = body[i]
+			default:
+				c = body[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func (s *sanitizer) quote(value string) string {
+	if s.dialect != MySQL {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return "'" + mysqlEscaper.Replace(value) + "'"
+}
+
+var mysqlEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\x00", `\0`, "\x1a", `\Z`)
+
+// sqliteReplaceSuffix matches one layer of the replace(...,'\n',char(10)) wrapping sqlite3 .dump
+// puts around text containing line breaks
+var sqliteReplaceSuffix = regexp.MustCompile(`^,'(\\[nr])',char\((10|13)\)\)`)
+
+func unwrapSQLiteReplace(raw string) (string, bool) {
+	layers, rest := 0, raw
+	for strings.HasPrefix(rest, "replace(") {
+		layers++
+		rest = rest[len("replace("):]
+	}
+	if layers == 0 || !strings.HasPrefix(rest, "'") {
+		return "", false
+	}
+	s := &sanitizer{dialect: SQLite}
+	end := s.scanString(rest, 0)
+	if end < 0 {
+		return "", false
+	}
+	value := s.unquote(rest[:end])
+	rest = rest[end:]
+	for ; layers > 0; layers-- {
+		m := sqliteReplaceSuffix.FindStringSubmatch(rest)
+		if m == nil {
+			return "", false
+		}
+		code, _ := strconv.Atoi(m[2])
+		value = strings.ReplaceAll(value, m[1], string(rune(code)))
+		rest = rest[len(m[0]):]
+	}
+	return value, rest == ""
+}
+
+// copyUnescape decodes a COPY text-format field
+func copyUnescape(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 >= len(field) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = field[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			n, j := 0, i+1
+			for ; j < len(field) && j < i+3 && isHex(field[j]); j++ {
+				v, _ := strconv.ParseUint(field[j:j+1], 16, 8)
+				n = n*16 + int(v)
+			}
+			if j == i+1 {
+				b.WriteByte('x')
+				continue
+			}
+			b.WriteByte(byte(n))
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n, j := 0, i
+			for ; j < len(field) && j < i+3 && field[j] >= '0' && field[j] <= '7'; j++ {
+				n = n*8 + int(field[j]-'0')
+			}
+			b.WriteByte(byte(n))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+var copyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func copyEscape(value string) string {
+	return copyEscaper.Replace(value)
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// parseIdent reads one identifier, bare or quoted with backquotes, double quotes or brackets
+func parseIdent(s string, i int) (string, int) {
+	if i >= len(s) {
+		return "", i
+	}
+	switch open := s[i]; open {
+	case '`', '"', '[':
+		closing := open
+		if open == '[' {
+			closing = ']'
+		}
+		var b strings.Builder
+		for j := i + 1; j < len(s); j++ {
+			if s[j] == closing {
+				if closing != ']' && j+1 < len(s) && s[j+1] == closing {
+					b.WriteByte(closing)
+					j++
+					continue
+				}
+				return b.String(), j + 1
+			}
+			b.WriteByte(s[j])
+		}
+		return b.String(), len(s)
+	}
+	j := i
+	for j < len(s) && (s[j] == '_' || s[j] == '$' || s[j] >= 0x80 ||
+		(s[j] >= 'a' && s[j] <= 'z') || (s[j] >= 'A' && s[j] <= 'Z') || (s[j] >= '0' && s[j] <= '9')) {
+		j++
+	}
+	return s[i:j], j
+}
+
+// parseName reads a possibly schema-qualified name, returned dot-joined without quotes
+func parseName(s string, i int) (string, int) {
+	var parts []string
+	for {
+		part, j := parseIdent(s, i)
+		parts = append(parts, part)
+		if j < len(s) && s[j] == '.' {
+			i = j + 1
+			continue
+		}
+		return strings.Join(parts, "."), j
+	}
+}
+
+// parseIdentList reads a parenthesized, comma-separated identifier list starting at the '(' at i
+func parseIdentList(s string, i int) ([]string, int) {
+	var names []string
+	i++
+	for i < len(s) {
+		i = skipSpace(s, i)
+		name, j := parseIdent(s, i)
+		names = append(names, name)
+		j = skipSpace(s, j)
+		if j >= len(s) || s[j] == ')' {
+			return names, j + 1
+		}
+		if s[j] != ',' || j == i {
+			return names, j
+		}
+		i = j + 1
+	}
+	return names, i
+}
@@ -0,0 +1,225 @@
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Strategy is how the values of a masked column are replaced
+type Strategy string
+
+const (
+	StrategyHash      Strategy = "hash"       // keyed SHA-256 hex digest; equal inputs stay equal
+	StrategyFakeName  Strategy = "fake_name"  // deterministic made-up "First Last"
+	StrategyFakeEmail Strategy = "fake_email" // deterministic user_<id>@example.com
+	StrategyNull      Strategy = "null"       // NULL
+	StrategyTruncate  Strategy = "truncate"   // first Length characters of text values
+)
+
+// Rule masks one column. Table and Column accept path.Match globs; a table pattern without a
+// schema also matches schema-qualified names (users matches public.users).
+type Rule struct {
+	Table    string   `yaml:"table" json:"table"`
+	Column   string   `yaml:"column" json:"column"`
+	Strategy Strategy `yaml:"strategy" json:"strategy"`
+	Length   int      `yaml:"length" json:"length,omitempty"` // truncate: characters kept; hash: digest length
+}
+
+// Rules is an ordered rule set; the first rule matching a column applies
+type Rules []Rule
+
+// rulesFile is the layout of a rules file
+type rulesFile struct {
+	Rules Rules `yaml:"rules"`
+}
+
+// Load reads masking rules from a YAML or JSON file
+func Load(file string) (Rules, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("mask rules file not found: %s", file)
+		}
+		return nil, fmt.Errorf("failed to read mask rules: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, fmt.Errorf("unsupported mask rules format %q (use .yaml, .yml or .json)", filepath.Ext(file))
+	}
+
+	// JSON is valid YAML, so one decoder reads both
+	var parsed rulesFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if len(parsed.Rules) == 0 {
+		return nil, fmt.Errorf("%s defines no mask rules", file)
+	}
+	if err := parsed.Rules.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if parsed.Rules.Unkeyed() {
+		fmt.Println("⚠️  DBX_MASK_SALT is not set: hash, fake_name and fake_email values are unkeyed digests " +
+			"that can be matched by hashing guessed inputs")
+	}
+	return parsed.Rules, nil
+}
+
+// Unkeyed reports whether rules derive masked values from digests without a key, i.e. use hash,
+// fake_name or fake_email while DBX_MASK_SALT is unset
+func (r Rules) Unkeyed() bool {
+	if os.Getenv("DBX_MASK_SALT") != "" {
+		return false
+	}
+	for _, rule := range r {
+		switch rule.Strategy {
+		case StrategyHash, StrategyFakeName, StrategyFakeEmail:
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every rule names a table, a column and a known strategy
+func (r Rules) Validate() error {
+	for i, rule := range r {
+		if rule.Table == "" || rule.Column == "" {
+			return fmt.Errorf("rule %d: table and column are required", i+1)
+		}
+		for _, pattern := range []string{rule.Table, rule.Column} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %d: invalid pattern %q: %w", i+1, pattern, err)
+			}
+		}
+		switch rule.Strategy {
+		case StrategyHash, StrategyFakeName, StrategyFakeEmail, StrategyNull:
+		case StrategyTruncate:
+			if rule.Length <= 0 {
+				return fmt.Errorf("rule %d (%s.%s): truncate needs a positive length", i+1, rule.Table, rule.Column)
+			}
+		default:
+			return fmt.Errorf("rule %d (%s.%s): invalid strategy %q (use hash, fake_name, fake_email, null or truncate)",
+				i+1, rule.Table, rule.Column, rule.Strategy)
+		}
+		if rule.Length < 0 {
+			return fmt.Errorf("rule %d (%s.%s): length cannot be negative", i+1, rule.Table, rule.Column)
+		}
+	}
+	return nil
+}
+
+// String renders a rule as table.column (strategy)
+func (r Rule) String() string {
+	if r.Length > 0 {
+		return fmt.Sprintf("%s.%s (%s %d)", r.Table, r.Column, r.Strategy, r.Length)
+	}
+	return fmt.Sprintf("%s.%s (%s)", r.Table, r.Column, r.Strategy)
+}
+
+// Describe lists the rules as strings, e.g. for a backup manifest
+func (r Rules) Describe() []string {
+	desc := make([]string, len(r))
+	for i, rule := range r {
+		desc[i] = rule.String()
+	}
+	return desc
+}
+
+// find returns the index of the first rule for table.column, or -1
+func (r Rules) find(table, column string) int {
+	for i, rule := range r {
+		if matchTable(rule.Table, table) && matchName(rule.Column, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// forTable reports whether any rule may apply to table
+func (r Rules) forTable(table string) bool {
+	for _, rule := range r {
+		if matchTable(rule.Table, table) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchName(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok || pattern == name
+}
+
+func matchTable(pattern, table string) bool {
+	if matchName(pattern, table) {
+		return true
+	}
+	if i := strings.LastIndex(table, "."); i >= 0 && !strings.Contains(pattern, ".") {
+		return matchName(pattern, table[i+1:])
+	}
+	return false
+}
+
+var firstNames = []string{
+	"Alex", "Blake", "Casey", "Dana", "Eden", "Finley", "Gray", "Harper", "Indy", "Jordan",
+	"Kai", "Logan", "Morgan", "Noel", "Oakley", "Parker", "Quinn", "Riley", "Sage", "Taylor",
+}
+
+var lastNames = []string{
+	"Adams", "Baker", "Carter", "Dalton", "Ellis", "Foster", "Garcia", "Hayes", "Irwin", "Jensen",
+	"Keller", "Lopez", "Mason", "Nolan", "Owens", "Patel", "Reyes", "Shaw", "Turner", "Walsh",
+}
+
+// masker replaces values. The digest is keyed with DBX_MASK_SALT when set, so hashes of
+// guessable values (emails, phone numbers) can't be reversed by hashing candidates; Load warns
+// when rules need the key and it is unset.
+type masker struct {
+	key []byte
+}
+
+func newMasker() *masker {
+	return &masker{key: []byte(os.Getenv("DBX_MASK_SALT"))}
+}
+
+func (m *masker) digest(value string) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// apply masks one value. text reports whether value is a text literal (truncate leaves numbers alone).
+// It returns the replacement and whether the replacement is NULL.
+func (m *masker) apply(rule Rule, value string, text bool) (string, bool) {
+	switch rule.Strategy {
+	case StrategyNull:
+		return "", true
+	case StrategyHash:
+		sum := hex.EncodeToString(m.digest(value))
+		if rule.Length > 0 && rule.Length < len(sum) {
+			sum = sum[:rule.Length]
+		}
+		return sum, false
+	case StrategyFakeName:
+		sum := m.digest(value)
+		first := firstNames[binary.BigEndian.Uint32(sum[0:4])%uint32(len(firstNames))]
+		last := lastNames[binary.BigEndian.Uint32(sum[4:8])%uint32(len(lastNames))]
+		return first + " " + last, false
+	case StrategyFakeEmail:
+		return "user_" + hex.EncodeToString(m.digest(value)[:5]) + "@example.com", false
+	case StrategyTruncate:
+		if runes := []rune(value); text && len(runes) > rule.Length {
+			return string(runes[:rule.Length]), false
+		}
+	}
+	return value, false
+}
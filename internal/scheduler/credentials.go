@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/url"
+
+	"dbx/internal/secrets"
+
+	"github.com/robfig/cron/v3"
+)
+
+// sensitiveParams lists job params that may hold credentials
+var sensitiveParams = []string{"pass", "uri"}
+
+// isSensitive reports whether a param value is a literal credential that must not be persisted
+func isSensitive(key, value string) bool {
+	if value == "" || secrets.IsReference(value) {
+		return false
+	}
+	switch key {
+	case "pass":
+		return true
+	case "uri":
+		// Only connection strings that embed a password need protecting
+		u, err := url.Parse(value)
+		if err != nil || u.User == nil {
+			return false
+		}
+		_, hasPassword := u.User.Password()
+		return hasPassword
+	}
+	return false
+}
+
+// sealParams returns a copy of params where literal credentials are moved into the
+// local secret store and replaced by store: references
+func sealParams(id cron.EntryID, params map[string]string) (map[string]string, error) {
+	sealed := make(map[string]string, len(params))
+	for key, value := range params {
+		sealed[key] = value
+	}
+	for _, key := range sensitiveParams {
+		value := sealed[key]
+		if !isSensitive(key, value) {
+			continue
+		}
+		name := fmt.Sprintf("schedule-%d-%s", id, key)
+		if err := secrets.Set(name, value); err != nil {
+			return nil, err
+		}
+		sealed[key] = secrets.PrefixStore + name
+	}
+	return sealed, nil
+}
+
+// RedactParams returns a copy of params that is safe to print.
+// References are shown as-is, literal credentials are masked.
+func RedactParams(params map[string]string) map[string]string {
+	redacted := make(map[string]string, len(params))
+	for key, value := range params {
+		if isSensitive(key, value) {
+			value = "****"
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+func equalParams(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
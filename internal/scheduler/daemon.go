@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dbx/internal/secrets"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Locations of a running daemon's heartbeat and of the trigger requests it picks up
+var (
+	DaemonFile = "./config/daemon.json"
+	TriggerDir = "./config/triggers"
+)
+
+var (
+	heartbeatInterval = 5 * time.Second
+	pollInterval      = time.Second
+)
+
+// DaemonInfo is the heartbeat a running scheduler daemon keeps on disk
+type DaemonInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TriggerResult reports the outcome of an on-demand run
+type TriggerResult struct {
+	JobID      cron.EntryID  `json:"job_id"`
+	Status     string        `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	ExecutedBy string        `json:"executed_by"`
+}
+
+type triggerRequest struct {
+	JobID       cron.EntryID `json:"job_id"`
+	RequestedAt time.Time    `json:"requested_at"`
+}
+
+// RunningDaemon returns the heartbeat of a live scheduler daemon, if there is one
+func RunningDaemon() (*DaemonInfo, bool) {
+	data, err := os.ReadFile(DaemonFile)
+	if err != nil {
+		return nil, false
+	}
+	var info DaemonInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, false
+	}
+	// A daemon that missed several heartbeats is considered dead
+	if time.Since(info.UpdatedAt) > 3*heartbeatInterval {
+		return nil, false
+	}
+	return &info, true
+}
+
+// RunDaemon runs the scheduler in the foreground until stop is closed.
+// Besides firing cron jobs it serves on-demand trigger requests from other dbx processes.
+func RunDaemon(stop <-chan struct{}) error {
+	if info, ok := RunningDaemon(); ok {
+		return fmt.Errorf("scheduler daemon already running (pid %d on %s)", info.PID, info.Host)
+	}
+
+	Init()
+	// c is replaced on reload, so stop whichever instance is current on exit
+	defer func() { c.Stop() }()
+
+	hostname, _ := os.Hostname()
+	info := DaemonInfo{PID: os.Getpid(), Host: hostname, StartedAt: time.Now()}
+	if err := writeHeartbeat(&info); err != nil {
+		return fmt.Errorf("failed to write daemon heartbeat: %w", err)
+	}
+	defer func() { _ = os.Remove(DaemonFile) }()
+	if err := os.MkdirAll(TriggerDir, 0700); err != nil {
+		return fmt.Errorf("failed to create trigger directory: %w", err)
+	}
+
+	lastModified := scheduleModTime()
+	fmt.Printf("🟢 Scheduler daemon running (pid %d). Press Ctrl+C to stop.\n", info.PID)
+	catchUpMissedRuns()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-stop:
+			fmt.Println("🛑 Scheduler daemon stopped.")
+			return nil
+		case <-heartbeat.C:
+			// Heartbeat failures are retried on the next tick
+			_ = writeHeartbeat(&info)
+		case <-poll.C:
+			// Pick up jobs added or changed by other dbx processes
+			if mod := scheduleModTime(); !mod.Equal(lastModified) {
+				fmt.Println("🔁 Schedule file changed, reloading jobs.")
+				Init()
+				lastModified = scheduleModTime()
+			}
+			processTriggers()
+		}
+	}
+}
+
+// Trigger runs a scheduled job once with its stored params and upload settings.
+// When a daemon is running the request is handed to it and this call waits for the result.
+func Trigger(id cron.EntryID) (*TriggerResult, error) {
+	if _, ok := FindJob(id); !ok {
+		return nil, fmt.Errorf("scheduled job #%d not found", id)
+	}
+
+	if _, ok := RunningDaemon(); ok {
+		return triggerViaDaemon(id)
+	}
+
+	start := time.Now()
+	err := RunNow(id)
+	return newTriggerResult(id, start, err, "local"), nil
+}
+
+func triggerViaDaemon(id cron.EntryID) (*TriggerResult, error) {
+	if err := os.MkdirAll(TriggerDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create trigger directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%d", id, time.Now().UnixNano())
+	data, _ := json.Marshal(triggerRequest{JobID: id, RequestedAt: time.Now()})
+	// Write then rename so the daemon never reads a partial request
+	tmp := filepath.Join(TriggerDir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write trigger request: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(TriggerDir, name+".request")); err != nil {
+		return nil, fmt.Errorf("failed to queue trigger request: %w", err)
+	}
+	fmt.Printf("📨 Job #%d handed to the running scheduler daemon, waiting for result...\n", id)
+
+	resultPath := filepath.Join(TriggerDir, name+".result")
+	for {
+		if data, err := os.ReadFile(resultPath); err == nil {
+			_ = os.Remove(resultPath)
+			var result TriggerResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return nil, fmt.Errorf("failed to parse trigger result: %w", err)
+			}
+			return &result, nil
+		}
+		if _, ok := RunningDaemon(); !ok {
+			return nil, fmt.Errorf("scheduler daemon stopped before reporting a result for job #%d", id)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// processTriggers runs every queued trigger request and writes its result
+func processTriggers() {
+	entries, err := os.ReadDir(TriggerDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".request") {
+			continue
+		}
+		requestPath := filepath.Join(TriggerDir, entry.Name())
+		data, err := os.ReadFile(requestPath)
+		_ = os.Remove(requestPath)
+		if err != nil {
+			continue
+		}
+
+		var req triggerRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".request")
+		// Run each request in the background so cron and further triggers keep being served
+		go func() {
+			start := time.Now()
+			runErr := RunNow(req.JobID)
+			result := newTriggerResult(req.JobID, start, runErr, "daemon")
+			out, _ := json.Marshal(result)
+			tmp := filepath.Join(TriggerDir, name+".result.tmp")
+			if err := os.WriteFile(tmp, out, 0600); err == nil {
+				_ = os.Rename(tmp, filepath.Join(TriggerDir, name+".result"))
+			}
+		}()
+	}
+}
+
+func scheduleModTime() time.Time {
+	info, err := os.Stat(ConfigFile)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func writeHeartbeat(info *DaemonInfo) error {
+	info.UpdatedAt = time.Now()
+	data, _ := json.MarshalIndent(info, "", "  ")
+	return secrets.WritePrivateFile(DaemonFile, data)
+}
+
+func newTriggerResult(id cron.EntryID, start time.Time, err error, executedBy string) *TriggerResult {
+	result := &TriggerResult{
+		JobID:      id,
+		Status:     "SUCCESS",
+		StartedAt:  start,
+		Duration:   time.Since(start).Round(time.Second),
+		ExecutedBy: executedBy,
+	}
+	if err != nil {
+		result.Status = "FAILED"
+		result.Error = err.Error()
+	}
+	return result
+}
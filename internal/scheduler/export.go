@@ -0,0 +1,495 @@
+package scheduler
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dbx/internal/db"
+	"dbx/internal/secrets"
+)
+
+// Export formats
+const (
+	ExportSystemd    = "systemd"
+	ExportCrontab    = "crontab"
+	ExportK8sCronJob = "k8s-cronjob"
+)
+
+// ExportOptions controls how schedules are rendered for external schedulers
+type ExportOptions struct {
+	// Binary is the dbx executable the generated definitions invoke
+	Binary string
+	// WorkDir is the directory dbx runs in (relative backup paths and ./config resolve against it)
+	WorkDir string
+	// Image is the container image used for Kubernetes CronJobs
+	Image string
+	// Namespace is the Kubernetes namespace (optional)
+	Namespace string
+	// SecretName is the Kubernetes Secret that env: references are read from
+	SecretName string
+}
+
+// ExportFile is one generated file
+type ExportFile struct {
+	Name    string
+	Content string
+}
+
+// flagParams maps job params to dbx backup flags, in output order
+var flagParams = []struct{ param, flag string }{
+	{"profile", "--profile"},
+	{"host", "--host"},
+	{"port", "--port"},
+	{"user", "--user"},
+	{"pass", "--password"},
+	{"uri", "--uri"},
+	{"dbname", "--database"},
+	{"name", "--name"},
+	{"path", "--path"},
+	{"include", "--include"},
+	{"exclude", "--exclude"},
+	{"content", "--content"},
+	{"mask_rules", "--mask-rules"},
+	{"jobs", "--jobs"},
+	{"dumper", "--dumper"},
+	{"format", "--format"},
+	{"method", "--method"},
+	{"repository", "--repository"},
+	{"repository_path", "--repository-path"},
+	{"indices", "--indices"},
+	{"type", "--type"},
+	{"socket", "--socket"},
+	{"tls_mode", "--tls-mode"},
+	{"tls_ca", "--tls-ca"},
+	{"tls_cert", "--tls-cert"},
+	{"tls_key", "--tls-key"},
+	{"ssh_host", "--ssh-host"},
+	{"ssh_user", "--ssh-user"},
+	{"ssh_key", "--ssh-key"},
+	{"ssh_known_hosts", "--ssh-known-hosts"},
+	{"tables", "--tables"},
+	{"exclude_tables", "--exclude-tables"},
+	{"collections", "--collections"},
+	{"exclude_collections", "--exclude-collections"},
+	{"out", "--out"},
+	{"cloud_provider", "--cloud"},
+	{"s3_bucket", "--s3-bucket"},
+	{"s3_prefix", "--s3-prefix"},
+	{"gcs_bucket", "--gcs-bucket"},
+	{"gcs_prefix", "--gcs-prefix"},
+	{"azure_account", "--azure-account"},
+	{"azure_container", "--azure-container"},
+	{"azure_blob", "--azure-blob"},
+}
+
+// backupCommands maps scheduler db types to dbx backup subcommands
+var backupCommands = map[string]string{
+	"mysql":         "mysql",
+	"mariadb":       "mariadb",
+	"postgres":      "postgres",
+	"mongodb":       "mongo",
+	"sqlite":        "sqlite",
+	"redis":         "redis",
+	"etcd":          "etcd",
+	"consul":        "consul",
+	"elasticsearch": "elasticsearch",
+	"clickhouse":    "clickhouse",
+}
+
+// Export renders jobs as native definitions for the given format.
+// Secret references are kept as references; jobs with literal credentials are rejected.
+func Export(jobs []JobConfig, format string, opts ExportOptions) ([]ExportFile, error) {
+	if opts.Binary == "" {
+		opts.Binary = "dbx"
+	}
+	if opts.SecretName == "" {
+		opts.SecretName = "dbx-secrets"
+	}
+
+	switch format {
+	case ExportSystemd:
+		var files []ExportFile
+		for _, job := range jobs {
+			units, err := exportSystemd(job, opts)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, units...)
+		}
+		return files, nil
+	case ExportCrontab:
+		content, err := exportCrontab(jobs, opts)
+		if err != nil {
+			return nil, err
+		}
+		return []ExportFile{{Name: "dbx.crontab", Content: content}}, nil
+	case ExportK8sCronJob:
+		var files []ExportFile
+		for _, job := range jobs {
+			file, err := exportK8sCronJob(job, opts)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s (use systemd, crontab, or k8s-cronjob)", format)
+	}
+}
+
+// BackupArgs returns the dbx backup arguments (without the binary) that reproduce a job
+func BackupArgs(job JobConfig) ([]string, error) {
+	sub, ok := backupCommands[job.DBType]
+	if !ok {
+		return nil, fmt.Errorf("job #%d: unsupported database type: %s", job.ID, job.DBType)
+	}
+	for key, value := range job.Params {
+		if isSensitive(key, value) {
+			return nil, fmt.Errorf("job #%d stores a literal %s; re-add it with a secret reference before exporting", job.ID, key)
+		}
+	}
+
+	args := []string{"backup", sub}
+	for _, fp := range flagParams {
+		value := job.Params[fp.param]
+		if fp.param == "method" {
+			value = db.BackupMethod(job.Params)
+		}
+		if value != "" {
+			args = append(args, fp.flag, value)
+		}
+	}
+	if job.Params["all_databases"] == "true" {
+		args = append(args, "--all-databases")
+	}
+	if job.Params["bgsave"] == "true" {
+		args = append(args, "--bgsave")
+	}
+	if job.Params["global_state"] == "true" {
+		args = append(args, "--global-state")
+	}
+	if job.Params["upload_cloud"] == "true" {
+		args = append(args, "--upload")
+	}
+	return args, nil
+}
+
+func exportSystemd(job JobConfig, opts ExportOptions) ([]ExportFile, error) {
+	args, err := BackupArgs(job)
+	if err != nil {
+		return nil, err
+	}
+	calendar, every, err := cronToOnCalendar(job.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("job #%d: %w", job.ID, err)
+	}
+
+	unit := fmt.Sprintf("dbx-backup-%d", job.ID)
+	description := fmt.Sprintf("dbx %s backup (job #%d)", job.DBType, job.ID)
+
+	var service strings.Builder
+	service.WriteString("[Unit]\n")
+	fmt.Fprintf(&service, "Description=%s\n", description)
+	service.WriteString("Wants=network-online.target\nAfter=network-online.target\n\n")
+	service.WriteString("[Service]\nType=oneshot\n")
+	if opts.WorkDir != "" {
+		fmt.Fprintf(&service, "WorkingDirectory=%s\n", opts.WorkDir)
+	}
+	fmt.Fprintf(&service, "ExecStart=%s\n", systemdCommand(append([]string{opts.Binary}, args...)))
+
+	var timer strings.Builder
+	timer.WriteString("[Unit]\n")
+	fmt.Fprintf(&timer, "Description=Timer for %s\n", description)
+	for _, b := range job.Blackouts {
+		fmt.Fprintf(&timer, "# WARNING: blackout window %q cannot be expressed in a systemd timer\n", b)
+	}
+	timer.WriteString("\n[Timer]\n")
+	if every != "" {
+		fmt.Fprintf(&timer, "OnBootSec=%s\nOnUnitActiveSec=%s\n", every, every)
+	} else {
+		if job.Timezone != "" {
+			calendar += " " + job.Timezone
+		}
+		fmt.Fprintf(&timer, "OnCalendar=%s\n", calendar)
+	}
+	if job.Jitter != "" {
+		fmt.Fprintf(&timer, "RandomizedDelaySec=%s\n", job.Jitter)
+	}
+	timer.WriteString("Persistent=true\n\n[Install]\nWantedBy=timers.target\n")
+
+	return []ExportFile{
+		{Name: unit + ".service", Content: service.String()},
+		{Name: unit + ".timer", Content: timer.String()},
+	}, nil
+}
+
+func exportCrontab(jobs []JobConfig, opts ExportOptions) (string, error) {
+	var b strings.Builder
+	b.WriteString("# Generated by dbx schedule export\n")
+	b.WriteString("SHELL=/bin/sh\n")
+
+	// CRON_TZ applies to all following lines: emit host-time jobs first, then group the rest by zone
+	ordered := make([]JobConfig, len(jobs))
+	copy(ordered, jobs)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Timezone < ordered[j].Timezone })
+
+	currentTZ := ""
+	for _, job := range ordered {
+		args, err := BackupArgs(job)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(job.Schedule, "@every") {
+			return "", fmt.Errorf("job #%d: %q cannot be expressed in crontab", job.ID, job.Schedule)
+		}
+
+		fmt.Fprintf(&b, "\n# dbx job #%d (%s)\n", job.ID, job.DBType)
+		for _, w := range job.Blackouts {
+			fmt.Fprintf(&b, "# WARNING: blackout window %q cannot be expressed in crontab\n", w)
+		}
+		if job.Timezone != currentTZ {
+			fmt.Fprintf(&b, "CRON_TZ=%s\n", job.Timezone)
+			currentTZ = job.Timezone
+		}
+
+		command := shellCommand(append([]string{opts.Binary}, args...))
+		if opts.WorkDir != "" {
+			command = "cd " + shellQuote(opts.WorkDir) + " && " + command
+		}
+		if job.Jitter != "" {
+			seconds, err := jitterSeconds(job.Jitter)
+			if err != nil {
+				return "", fmt.Errorf("job #%d: %w", job.ID, err)
+			}
+			command = fmt.Sprintf("sleep $(( $(od -An -N2 -tu2 /dev/urandom) %% %d )) && %s", seconds+1, command)
+		}
+		// % starts a new line in crontab and must be escaped
+		fmt.Fprintf(&b, "%s %s\n", job.Schedule, strings.ReplaceAll(command, "%", `\%`))
+	}
+	return b.String(), nil
+}
+
+func exportK8sCronJob(job JobConfig, opts ExportOptions) (ExportFile, error) {
+	args, err := BackupArgs(job)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	if strings.HasPrefix(job.Schedule, "@every") {
+		return ExportFile{}, fmt.Errorf("job #%d: %q cannot be expressed as a Kubernetes CronJob schedule", job.ID, job.Schedule)
+	}
+	image := opts.Image
+	if image == "" {
+		image = "dbx:latest"
+	}
+	name := fmt.Sprintf("dbx-backup-%d", job.ID)
+
+	// env: references are supplied from a Kubernetes Secret; other references are left for the pod to resolve
+	envVars := make(map[string]bool)
+	var storeRefs []string
+	for _, value := range job.Params {
+		switch {
+		case strings.HasPrefix(value, secrets.PrefixEnv):
+			envVars[strings.TrimPrefix(value, secrets.PrefixEnv)] = true
+		case strings.HasPrefix(value, secrets.PrefixStore):
+			storeRefs = append(storeRefs, value)
+		}
+	}
+	sort.Strings(storeRefs)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# dbx %s backup (job #%d)\n", job.DBType, job.ID)
+	for _, w := range job.Blackouts {
+		fmt.Fprintf(&b, "# WARNING: blackout window %q cannot be expressed in a CronJob\n", w)
+	}
+	if job.Jitter != "" {
+		fmt.Fprintf(&b, "# WARNING: jitter %q is not supported by Kubernetes CronJobs\n", job.Jitter)
+	}
+	for _, ref := range storeRefs {
+		fmt.Fprintf(&b, "# NOTE: %s needs config/secrets.enc and DBX_SECRET_KEY (or config/secret.key) in the pod's working directory\n", ref)
+	}
+	b.WriteString("apiVersion: batch/v1\nkind: CronJob\nmetadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	if opts.Namespace != "" {
+		fmt.Fprintf(&b, "  namespace: %s\n", opts.Namespace)
+	}
+	b.WriteString("spec:\n")
+	fmt.Fprintf(&b, "  schedule: %s\n", strconv.Quote(job.Schedule))
+	if job.Timezone != "" {
+		fmt.Fprintf(&b, "  timeZone: %s\n", strconv.Quote(job.Timezone))
+	}
+	b.WriteString("  concurrencyPolicy: Forbid\n")
+	b.WriteString("  jobTemplate:\n    spec:\n      template:\n        spec:\n")
+	b.WriteString("          restartPolicy: OnFailure\n")
+	b.WriteString("          containers:\n")
+	b.WriteString("            - name: dbx\n")
+	fmt.Fprintf(&b, "              image: %s\n", strconv.Quote(image))
+	b.WriteString("              workingDir: /data\n")
+	b.WriteString("              command:\n")
+	for _, arg := range append([]string{opts.Binary}, args...) {
+		fmt.Fprintf(&b, "                - %s\n", strconv.Quote(arg))
+	}
+	if len(envVars) > 0 {
+		names := make([]string, 0, len(envVars))
+		for n := range envVars {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		b.WriteString("              env:\n")
+		for _, n := range names {
+			fmt.Fprintf(&b, "                - name: %s\n", n)
+			b.WriteString("                  valueFrom:\n                    secretKeyRef:\n")
+			fmt.Fprintf(&b, "                      name: %s\n", opts.SecretName)
+			fmt.Fprintf(&b, "                      key: %s\n", n)
+		}
+	}
+	b.WriteString("              volumeMounts:\n")
+	b.WriteString("                - name: data\n                  mountPath: /data\n")
+	b.WriteString("          volumes:\n")
+	b.WriteString("            - name: data\n              persistentVolumeClaim:\n")
+	b.WriteString("                claimName: dbx-data\n")
+
+	return ExportFile{Name: name + ".yaml", Content: b.String()}, nil
+}
+
+// cronToOnCalendar converts a cron expression to a systemd OnCalendar value.
+// @every schedules have no calendar form and are returned as an interval instead.
+func cronToOnCalendar(spec string) (calendar, every string, err error) {
+	switch spec {
+	case "@yearly", "@annually":
+		return "yearly", "", nil
+	case "@monthly":
+		return "monthly", "", nil
+	case "@weekly":
+		return "weekly", "", nil
+	case "@daily", "@midnight":
+		return "daily", "", nil
+	case "@hourly":
+		return "hourly", "", nil
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		return "", strings.TrimSpace(strings.TrimPrefix(spec, "@every ")), nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return "", "", fmt.Errorf("cannot convert %q to a systemd calendar (expected 5 cron fields)", spec)
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if dom != "*" && dow != "*" {
+		return "", "", fmt.Errorf("cannot convert %q: cron ORs day-of-month and day-of-week, systemd ANDs them", spec)
+	}
+
+	var parts []string
+	if dow != "*" {
+		days, err := convertWeekdays(dow)
+		if err != nil {
+			return "", "", err
+		}
+		parts = append(parts, days)
+	}
+	parts = append(parts,
+		"*-"+calendarField(month)+"-"+calendarField(dom),
+		calendarField(hour)+":"+calendarField(minute)+":00")
+	return strings.Join(parts, " "), "", nil
+}
+
+// calendarField converts cron ranges and steps (1-5, */15) to systemd syntax (1..5, 0/15)
+func calendarField(field string) string {
+	if field == "*" {
+		return "*"
+	}
+	parts := strings.Split(field, ",")
+	for i, p := range parts {
+		p = strings.Replace(p, "*/", "0/", 1)
+		parts[i] = strings.Replace(p, "-", "..", 1)
+	}
+	return strings.Join(parts, ",")
+}
+
+func convertWeekdays(field string) (string, error) {
+	names := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	toName := func(s string) (string, error) {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 7 {
+			return names[n%7], nil
+		}
+		for _, name := range names {
+			if strings.EqualFold(s, name) {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("cannot convert day of week %q", s)
+	}
+
+	var out []string
+	for _, part := range strings.Split(field, ",") {
+		if strings.Contains(part, "/") {
+			return "", fmt.Errorf("cannot convert day of week step %q", part)
+		}
+		if from, to, isRange := strings.Cut(part, "-"); isRange {
+			a, err := toName(from)
+			if err != nil {
+				return "", err
+			}
+			b, err := toName(to)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, a+".."+b)
+			continue
+		}
+		name, err := toName(part)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, name)
+	}
+	return strings.Join(out, ","), nil
+}
+
+func jitterSeconds(jitter string) (int, error) {
+	d, err := JobOptions{Jitter: jitter}.jitter()
+	if err != nil {
+		return 0, err
+	}
+	return int(d.Seconds()), nil
+}
+
+// shellQuote quotes s for POSIX sh
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdCommand quotes args for ExecStart, escaping specifiers (%) and variable expansion ($)
+func systemdCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		a = strings.ReplaceAll(a, "%", "%%")
+		a = strings.ReplaceAll(a, "$", "$$")
+		if i == 0 {
+			// The executable must be an absolute path or a plain name
+			quoted[i] = filepath.ToSlash(a)
+			continue
+		}
+		a = strings.ReplaceAll(a, `\`, `\\`)
+		a = strings.ReplaceAll(a, `"`, `\"`)
+		quoted[i] = `"` + a + `"`
+	}
+	return strings.Join(quoted, " ")
+}
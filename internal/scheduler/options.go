@@ -0,0 +1,264 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// JobOptions holds optional timing behaviour for a scheduled job
+type JobOptions struct {
+	// Timezone is an IANA zone name (e.g. "Europe/Berlin"); empty means host local time
+	Timezone string `json:"timezone,omitempty"`
+	// Jitter is the maximum random start delay, as a Go duration (e.g. "10m")
+	Jitter string `json:"jitter,omitempty"`
+	// Blackouts are windows in which the job must not start, e.g. "Mon-Fri 09:00-18:00"
+	Blackouts []string `json:"blackouts,omitempty"`
+	// CatchUp runs a missed job once when the daemon starts after downtime
+	CatchUp bool `json:"catch_up,omitempty"`
+	// CatchUpWindow limits how old a missed run may be to still be caught up (default 24h)
+	CatchUpWindow string `json:"catch_up_window,omitempty"`
+}
+
+// BlackoutWindow is a parsed blackout window
+type BlackoutWindow struct {
+	Days  [7]bool // indexed by time.Weekday
+	Start int     // minutes since midnight
+	End   int     // minutes since midnight; End < Start means the window crosses midnight
+}
+
+var (
+	// pending holds jobs whose run is waiting out jitter or a blackout window
+	pending   = make(map[cron.EntryID]bool)
+	pendingMu sync.Mutex
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate checks the options and returns a descriptive error for the first invalid one
+func (o JobOptions) Validate() error {
+	if _, err := o.location(); err != nil {
+		return err
+	}
+	if _, err := o.jitter(); err != nil {
+		return err
+	}
+	for _, b := range o.Blackouts {
+		if _, err := ParseBlackout(b); err != nil {
+			return err
+		}
+	}
+	if _, err := o.catchUpWindow(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o JobOptions) location() (*time.Location, error) {
+	if o.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(o.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", o.Timezone, err)
+	}
+	return loc, nil
+}
+
+func (o JobOptions) jitter() (time.Duration, error) {
+	if o.Jitter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(o.Jitter)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid jitter %q (use a duration such as 30s, 10m or 1h)", o.Jitter)
+	}
+	return d, nil
+}
+
+// cronSpec returns the schedule with the job's timezone applied via CRON_TZ
+func cronSpec(job JobConfig) string {
+	if job.Timezone == "" || strings.HasPrefix(job.Schedule, "CRON_TZ=") || strings.HasPrefix(job.Schedule, "TZ=") {
+		return job.Schedule
+	}
+	return "CRON_TZ=" + job.Timezone + " " + job.Schedule
+}
+
+// ParseBlackout parses a window such as "Mon-Fri 09:00-18:00", "Sat,Sun 00:00-06:00" or "22:00-02:00".
+// Without a day list the window applies every day. Windows crossing midnight belong to their start day.
+func ParseBlackout(spec string) (BlackoutWindow, error) {
+	var w BlackoutWindow
+	fields := strings.Fields(spec)
+	var dayPart, timePart string
+	switch len(fields) {
+	case 1:
+		dayPart, timePart = "*", fields[0]
+	case 2:
+		dayPart, timePart = fields[0], fields[1]
+	default:
+		return w, fmt.Errorf("invalid blackout window %q (expected e.g. \"Mon-Fri 09:00-18:00\")", spec)
+	}
+
+	if err := parseDays(dayPart, &w.Days); err != nil {
+		return w, fmt.Errorf("invalid blackout window %q: %w", spec, err)
+	}
+
+	bounds := strings.Split(timePart, "-")
+	if len(bounds) != 2 {
+		return w, fmt.Errorf("invalid blackout window %q: time range must be HH:MM-HH:MM", spec)
+	}
+	var err error
+	if w.Start, err = parseClock(bounds[0]); err != nil {
+		return w, fmt.Errorf("invalid blackout window %q: %w", spec, err)
+	}
+	if w.End, err = parseClock(bounds[1]); err != nil {
+		return w, fmt.Errorf("invalid blackout window %q: %w", spec, err)
+	}
+	if w.Start == w.End {
+		return w, fmt.Errorf("invalid blackout window %q: start and end are equal", spec)
+	}
+	return w, nil
+}
+
+func parseDays(spec string, days *[7]bool) error {
+	if spec == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return nil
+	}
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		if from, to, isRange := strings.Cut(part, "-"); isRange {
+			start, ok1 := weekdays[from]
+			end, ok2 := weekdays[to]
+			if !ok1 || !ok2 {
+				return fmt.Errorf("unknown day range %q", part)
+			}
+			for d := start; ; d = (d + 1) % 7 {
+				days[d] = true
+				if d == end {
+					break
+				}
+			}
+			continue
+		}
+		d, ok := weekdays[part]
+		if !ok {
+			return fmt.Errorf("unknown day %q", part)
+		}
+		days[d] = true
+	}
+	return nil
+}
+
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
+
+// Contains reports whether t falls inside the window and, if so, when the window ends
+func (w BlackoutWindow) Contains(t time.Time) (bool, time.Time) {
+	minute := t.Hour()*60 + t.Minute()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	if w.Start < w.End {
+		if w.Days[t.Weekday()] && minute >= w.Start && minute < w.End {
+			return true, midnight.Add(time.Duration(w.End) * time.Minute)
+		}
+		return false, time.Time{}
+	}
+
+	// Window crosses midnight: either in today's evening part or yesterday's morning part
+	if w.Days[t.Weekday()] && minute >= w.Start {
+		return true, midnight.AddDate(0, 0, 1).Add(time.Duration(w.End) * time.Minute)
+	}
+	yesterday := (t.Weekday() + 6) % 7
+	if w.Days[yesterday] && minute < w.End {
+		return true, midnight.Add(time.Duration(w.End) * time.Minute)
+	}
+	return false, time.Time{}
+}
+
+// NextAllowedTime returns t, or the first moment after it that is outside every blackout window
+func (o JobOptions) NextAllowedTime(t time.Time) (time.Time, error) {
+	loc, err := o.location()
+	if err != nil {
+		return t, err
+	}
+	windows := make([]BlackoutWindow, 0, len(o.Blackouts))
+	for _, b := range o.Blackouts {
+		w, err := ParseBlackout(b)
+		if err != nil {
+			return t, err
+		}
+		windows = append(windows, w)
+	}
+
+	t = t.In(loc)
+	// Adjacent windows may chain; a week of hops is more than any sane configuration needs
+	for i := 0; i < 7*24; i++ {
+		moved := false
+		for _, w := range windows {
+			if in, end := w.Contains(t); in {
+				t = end
+				moved = true
+			}
+		}
+		if !moved {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("blackout windows leave no time to run")
+}
+
+// scheduledRun is the cron entry point: it applies jitter and defers runs that fall in a blackout window
+func scheduledRun(job JobConfig) {
+	pendingMu.Lock()
+	if pending[job.ID] {
+		pendingMu.Unlock()
+		fmt.Printf("⏭  Job #%d already has a deferred run pending, skipping this tick\n", job.ID)
+		return
+	}
+	pending[job.ID] = true
+	pendingMu.Unlock()
+	defer func() {
+		pendingMu.Lock()
+		delete(pending, job.ID)
+		pendingMu.Unlock()
+	}()
+
+	if jitter, err := job.jitter(); err == nil && jitter > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(jitter))))
+	}
+
+	if len(job.Blackouts) > 0 {
+		now := time.Now()
+		next, err := job.NextAllowedTime(now)
+		if err != nil {
+			fmt.Printf("⚠️  Job #%d: %v\n", job.ID, err)
+			return
+		}
+		if next.After(now) {
+			fmt.Printf("⏸  Job #%d is inside a blackout window, deferred until %s\n", job.ID, next.Format("2006-01-02 15:04 MST"))
+			time.Sleep(time.Until(next))
+		}
+	}
+
+	_ = runJob(job)
+}
@@ -6,10 +6,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"dbx/internal/cloud"
+	"dbx/internal/config"
 	"dbx/internal/db"
+	"dbx/internal/logs"
+	"dbx/internal/secrets"
+	"dbx/internal/tunnel"
 
 	"github.com/robfig/cron/v3"
 )
@@ -21,17 +26,28 @@ type JobConfig struct {
 	Schedule  string            `json:"schedule"`
 	Params    map[string]string `json:"params"`
 	CreatedAt time.Time         `json:"created_at"`
+	JobOptions
 }
 
+// ConfigFile is where scheduled jobs are saved
+var ConfigFile = "./config/schedules.json"
+
 var (
-	c          *cron.Cron
-	configFile = "./config/schedules.json"
-	jobs       []JobConfig
+	c    *cron.Cron
+	jobs []JobConfig
+
+	// running guards against the same job running twice at once (cron tick vs. trigger)
+	running   = make(map[cron.EntryID]bool)
+	runningMu sync.Mutex
 )
 
 // Init starts the scheduler and loads jobs
 func Init() {
-	_ = os.MkdirAll("./config", os.ModePerm)
+	_ = os.MkdirAll(filepath.Dir(ConfigFile), 0700)
+	// Re-initializing must not leave the previous instance firing the same jobs
+	if c != nil {
+		c.Stop()
+	}
 	c = cron.New()
 	loadJobs()
 	c.Start()
@@ -40,121 +56,375 @@ func Init() {
 
 // AddJob registers a new backup job
 func AddJob(dbType, schedule string, params map[string]string) error {
+	return AddJobWithOptions(dbType, schedule, params, JobOptions{})
+}
+
+// AddJobWithOptions registers a new backup job with timezone, jitter and blackout options.
+// Plaintext credentials are moved into the local secret store and replaced with store: references.
+func AddJobWithOptions(dbType, schedule string, params map[string]string, opts JobOptions) error {
 	if c == nil {
 		Init()
 	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 
-	id, err := c.AddFunc(schedule, func() {
-		fmt.Printf("\n🔄 Running scheduled %s backup...\n", dbType)
-		start := time.Now()
-		var backupErr error
-		var dbName string
-		
-		switch dbType {
-		case "mysql":
-			dbName = params["dbname"]
-			backupErr = db.BackupMySQL(params["host"], params["user"], params["pass"], dbName, params["out"])
-		case "postgres":
-			dbName = params["dbname"]
-			backupErr = db.BackupPostgres(params["host"], params["port"], params["user"], params["pass"], dbName, params["out"])
-		case "mongodb":
-			dbName = params["dbname"]
-			backupErr = db.BackupMongo(params["uri"], dbName, params["out"])
-		case "sqlite":
-			dbName = filepath.Base(params["path"])
-			backupErr = db.BackupSQLite(params["path"], params["out"])
-		}
-		
-		if backupErr != nil {
-			fmt.Printf("❌ %s backup failed: %v\n", dbType, backupErr)
-		} else {
-			fmt.Printf("✅ %s backup completed in %s\n", dbType, time.Since(start).Round(time.Second))
-			
-			// Handle cloud upload if configured
-			if params["upload_cloud"] == "true" || os.Getenv("DBX_AUTO_UPLOAD") == "true" {
-				if err := handleScheduledCloudUpload(dbName, params); err != nil {
-					fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
-				} else {
-					fmt.Printf("☁️  Backup uploaded to cloud storage\n")
-				}
-			}
-		}
-	})
+	job := JobConfig{ID: nextJobID(), DBType: dbType, Schedule: schedule, CreatedAt: time.Now(), JobOptions: opts}
+	sealed, err := sealParams(job.ID, params)
 	if err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+	job.Params = sealed
+
+	if _, err := c.AddFunc(cronSpec(job), func() { scheduledRun(job) }); err != nil {
 		return err
 	}
 
-	job := JobConfig{ID: id, DBType: dbType, Schedule: schedule, Params: params, CreatedAt: time.Now()}
 	jobs = append(jobs, job)
 	return saveJobs()
 }
 
+// runJob resolves the job's secret references and runs the backup and optional cloud upload
+func runJob(job JobConfig) error {
+	runningMu.Lock()
+	if running[job.ID] {
+		runningMu.Unlock()
+		fmt.Printf("⏭  Scheduled job #%d is already running, skipping\n", job.ID)
+		return fmt.Errorf("job #%d is already running", job.ID)
+	}
+	running[job.ID] = true
+	runningMu.Unlock()
+	defer func() {
+		runningMu.Lock()
+		delete(running, job.ID)
+		runningMu.Unlock()
+	}()
+
+	start := time.Now()
+	err := executeJob(job)
+	recordRun(job.ID, start, err)
+	return err
+}
+
+// executeJob performs the backup and upload for a job
+func executeJob(job JobConfig) error {
+	fmt.Printf("\n🔄 Running scheduled %s backup...\n", job.DBType)
+
+	params := job.Params
+	// Profile-based jobs read connection settings from the config file at run time
+	if name := job.Params["profile"]; name != "" {
+		merged, err := config.ProfileParams(name, job.Params)
+		if err != nil {
+			fmt.Printf("❌ %s backup failed: %v\n", job.DBType, err)
+			return err
+		}
+		params = merged
+	}
+
+	params, err := secrets.ResolveParams(params)
+	if err != nil {
+		fmt.Printf("❌ %s backup failed: %v\n", job.DBType, err)
+		return err
+	}
+	return RunBackup(job.DBType, params)
+}
+
+// RunBackup backs up what job params describe, through their SSH tunnel, and uploads the backup when
+// upload_cloud is set. Scheduled jobs and profile backups from the menu both run through it.
+func RunBackup(dbType string, params map[string]string) error {
+	start := time.Now()
+	target := params
+	params, closeTunnel, err := tunnel.Rewrite(dbType, params, tunnel.ConfigFromParams(params))
+	if err != nil {
+		fmt.Printf("❌ %s backup failed: %v\n", dbType, err)
+		return err
+	}
+	defer closeTunnel()
+
+	if params["all_databases"] == "true" || params["include"] != "" {
+		return runMultiDatabaseJob(dbType, params)
+	}
+
+	var backupErr error
+	var artifact string
+	opts := db.BackupOptionsFromParams(params)
+	opts.OnArtifact = func(path string) { artifact = path }
+
+	switch dbType {
+	case "mysql":
+		backupErr = db.BackupMySQLWithOptions(params["host"], params["user"], params["pass"], params["dbname"], params["out"], db.BackupTypeFromParams(params), opts)
+	case "mariadb":
+		backupErr = db.BackupMariaDBWithOptions(params["host"], params["user"], params["pass"], params["dbname"], params["out"], db.BackupTypeFromParams(params), opts)
+	case "postgres":
+		backupErr = db.BackupPostgresWithOptions(params["host"], params["port"], params["user"], params["pass"], params["dbname"], params["out"], db.BackupTypeFromParams(params), opts)
+	case "mongodb":
+		backupErr = db.BackupMongoWithOptions(params["uri"], params["dbname"], params["out"], opts)
+	case "sqlite":
+		backupErr = db.BackupSQLiteWithOptions(params["path"], params["out"], opts)
+	case "redis":
+		// Named from the job's host and port, not those of a tunnel
+		name := params["name"]
+		if name == "" {
+			name = db.RedisBackupName(target["host"], target["port"], db.ConnOptionsFromParams(params))
+		}
+		backupErr = db.BackupRedisWithOptions(params["host"], params["port"], params["user"], params["pass"], name, params["out"], opts)
+	case "etcd":
+		name := params["name"]
+		if name == "" {
+			name = db.EtcdBackupName(target["host"], target["port"], db.ConnOptionsFromParams(params))
+		}
+		backupErr = db.BackupEtcdWithOptions(params["host"], params["port"], params["user"], params["pass"], name, params["out"], opts)
+	case "consul":
+		name := params["name"]
+		if name == "" {
+			name = db.ConsulBackupName(target["host"], target["port"], db.ConnOptionsFromParams(params))
+		}
+		backupErr = db.BackupConsulWithOptions(params["host"], params["port"], params["pass"], name, params["out"], opts)
+	case "elasticsearch":
+		name := params["name"]
+		if name == "" {
+			name = db.ElasticsearchBackupName(target["host"], target["port"], db.ConnOptionsFromParams(params))
+		}
+		backupErr = db.BackupElasticsearch(params["host"], params["port"], params["user"], params["pass"], name, db.ElasticsearchOptionsFromParams(params))
+	case "clickhouse":
+		backupErr = db.BackupClickHouseWithOptions(params["host"], params["port"], params["user"], params["pass"], params["dbname"], params["out"], opts)
+	}
+
+	if backupErr != nil {
+		fmt.Printf("❌ %s backup failed: %v\n", dbType, backupErr)
+		return backupErr
+	}
+	fmt.Printf("✅ %s backup completed in %s\n", dbType, time.Since(start).Round(time.Second))
+
+	// Handle cloud upload if configured; Elasticsearch snapshots stay in the cluster's repository
+	if dbType != "elasticsearch" && (params["upload_cloud"] == "true" || os.Getenv("DBX_AUTO_UPLOAD") == "true") {
+		if err := uploadBackup(artifact, params); err != nil {
+			fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+		} else {
+			fmt.Printf("☁️  Backup uploaded to cloud storage\n")
+		}
+	}
+	return nil
+}
+
+// runMultiDatabaseJob backs up every discovered database matching the job's include/exclude patterns
+func runMultiDatabaseJob(dbType string, params map[string]string) error {
+	var label string
+	var discover func() ([]string, error)
+	var backup func(name string, opts db.BackupOptions) error
+
+	switch dbType {
+	case "mysql":
+		label = "MySQL"
+		discover = func() ([]string, error) { return db.ListMySQLDatabases(params["host"], params["user"], params["pass"], db.ConnOptionsFromParams(params)) }
+		backup = func(name string, opts db.BackupOptions) error {
+			return db.BackupMySQLWithOptions(params["host"], params["user"], params["pass"], name, params["out"], db.BackupTypeFromParams(params), opts)
+		}
+	case "mariadb":
+		label = "MariaDB"
+		discover = func() ([]string, error) {
+			conn := db.ConnOptionsFromParams(params)
+			conn.MariaDB = true
+			return db.ListMySQLDatabases(params["host"], params["user"], params["pass"], conn)
+		}
+		backup = func(name string, opts db.BackupOptions) error {
+			return db.BackupMariaDBWithOptions(params["host"], params["user"], params["pass"], name, params["out"], db.BackupTypeFromParams(params), opts)
+		}
+	case "postgres":
+		label = "PostgreSQL"
+		discover = func() ([]string, error) {
+			return db.ListPostgresDatabases(params["host"], params["port"], params["user"], params["pass"], db.ConnOptionsFromParams(params))
+		}
+		backup = func(name string, opts db.BackupOptions) error {
+			return db.BackupPostgresWithOptions(params["host"], params["port"], params["user"], params["pass"], name, params["out"], db.BackupTypeFromParams(params), opts)
+		}
+	case "mongodb":
+		label = "MongoDB"
+		discover = func() ([]string, error) { return db.ListMongoDatabases(params["uri"], db.ConnOptionsFromParams(params)) }
+		backup = func(name string, opts db.BackupOptions) error {
+			return db.BackupMongoWithOptions(params["uri"], name, params["out"], opts)
+		}
+	default:
+		return fmt.Errorf("%s does not support multi-database backups", dbType)
+	}
+
+	names, err := discover()
+	if err != nil {
+		fmt.Printf("❌ %s backup failed: %v\n", dbType, err)
+		return err
+	}
+	names, err = db.FilterDatabases(dbType, names, db.SplitParam(params["include"]), db.SplitParam(params["exclude"]))
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no databases matched on the server")
+	}
+
+	summary := db.BackupDatabases(label, names, db.BackupOptionsFromParams(params), func(name string, opts db.BackupOptions) error {
+		var artifact string
+		opts.OnArtifact = func(path string) { artifact = path }
+		if err := backup(name, opts); err != nil {
+			return err
+		}
+		if params["upload_cloud"] == "true" || os.Getenv("DBX_AUTO_UPLOAD") == "true" {
+			if err := uploadBackup(artifact, params); err != nil {
+				fmt.Printf("⚠️  Cloud upload failed: %v\n", err)
+			}
+		}
+		return nil
+	})
+	return summary.Err()
+}
+
 func loadJobs() {
-	data, err := os.ReadFile(configFile)
+	readJobs()
+	for _, job := range jobs {
+		// Ignore AddFunc errors - invalid schedules will be skipped
+		// Capture loop variable by value to avoid closure capturing reference
+		job := job
+		_, _ = c.AddFunc(cronSpec(job), func() { scheduledRun(job) })
+	}
+}
+
+// readJobs loads the schedule file without registering anything with cron
+func readJobs() {
+	jobs = nil
+	data, err := os.ReadFile(ConfigFile)
 	if err != nil {
 		// No existing schedule file - start with empty job list
 		return
 	}
 	// Ignore unmarshal errors - corrupted file will result in empty job list
 	_ = json.Unmarshal(data, &jobs)
-	for _, job := range jobs {
-		// Ignore AddFunc errors - invalid schedules will be skipped
-		// Capture loop variable by value to avoid closure capturing reference
-		job := job
-		_, _ = c.AddFunc(job.Schedule, func() {
-			var backupErr error
-			var dbName string
-			
-			switch job.DBType {
-			case "mysql":
-				dbName = job.Params["dbname"]
-				backupErr = db.BackupMySQL(job.Params["host"], job.Params["user"], job.Params["pass"], dbName, job.Params["out"])
-			case "postgres":
-				dbName = job.Params["dbname"]
-				backupErr = db.BackupPostgres(job.Params["host"], job.Params["port"], job.Params["user"], job.Params["pass"], dbName, job.Params["out"])
-			case "mongodb":
-				dbName = job.Params["dbname"]
-				backupErr = db.BackupMongo(job.Params["uri"], dbName, job.Params["out"])
-			case "sqlite":
-				dbName = filepath.Base(job.Params["path"])
-				backupErr = db.BackupSQLite(job.Params["path"], job.Params["out"])
-			}
-			
-			if backupErr == nil {
-				// Handle cloud upload if configured
-				if job.Params["upload_cloud"] == "true" || os.Getenv("DBX_AUTO_UPLOAD") == "true" {
-					_ = handleScheduledCloudUpload(dbName, job.Params)
-				}
-			}
-		})
+
+	migrated := false
+	seen := make(map[cron.EntryID]bool)
+	for i := range jobs {
+		// Older files may lack IDs or contain duplicates - give every job a stable, unique ID
+		if jobs[i].ID == 0 || seen[jobs[i].ID] {
+			jobs[i].ID = nextJobID()
+			migrated = true
+		}
+		seen[jobs[i].ID] = true
+
+		// Move plaintext credentials written by older versions into the secret store
+		if sealed, err := sealParams(jobs[i].ID, jobs[i].Params); err == nil && !equalParams(sealed, jobs[i].Params) {
+			jobs[i].Params = sealed
+			migrated = true
+		}
+	}
+	if migrated {
+		_ = saveJobs()
 	}
 }
 
 func saveJobs() error {
 	// MarshalIndent should never fail with valid job data, but handle via WriteFile error if it does
 	data, _ := json.MarshalIndent(jobs, "", "  ")
-	return os.WriteFile(configFile, data, 0644)
+	// Schedules reference credentials, keep the file private to the owner
+	return secrets.WritePrivateFile(ConfigFile, data)
+}
+
+// nextJobID returns an ID not used by any known job
+func nextJobID() cron.EntryID {
+	var max cron.EntryID
+	for _, job := range jobs {
+		if job.ID > max {
+			max = job.ID
+		}
+	}
+	return max + 1
 }
 
+// ListJobs returns all scheduled jobs, reading the schedule file if the scheduler is not running
 func ListJobs() []JobConfig {
+	if c == nil {
+		readJobs()
+	}
 	return jobs
 }
 
-// handleScheduledCloudUpload handles cloud upload for scheduled backups
-func handleScheduledCloudUpload(dbName string, params map[string]string) error {
-	outDir := params["out"]
-	if outDir == "" {
-		outDir = "./backups"
+// FindJob returns the scheduled job with the given ID
+func FindJob(id cron.EntryID) (JobConfig, bool) {
+	for _, job := range ListJobs() {
+		if job.ID == id {
+			return job, true
+		}
 	}
-	
-	// Find the most recent backup file
-	pattern := filepath.Join(outDir, dbName+"*")
-	matches, _ := filepath.Glob(pattern)
-	if len(matches) == 0 {
-		return fmt.Errorf("no backup file found in %s", outDir)
+	return JobConfig{}, false
+}
+
+// wholeServer are the engines whose backups cover a whole server or file, so jobs have no database name
+var wholeServer = map[string]bool{"sqlite": true, "redis": true, "etcd": true, "consul": true, "elasticsearch": true}
+
+// WholeServer reports whether an engine's backups cover a whole server or file rather than named databases
+func WholeServer(engine string) bool {
+	return wholeServer[engine]
+}
+
+// ValidateJob checks a stored job without running it: its engine, schedule, timing options,
+// profile and required connection params
+func ValidateJob(job JobConfig) error {
+	switch job.DBType {
+	case "mysql", "mariadb", "postgres", "mongodb", "sqlite", "redis", "etcd", "consul", "elasticsearch", "clickhouse":
+	default:
+		return fmt.Errorf("unsupported database type %q", job.DBType)
+	}
+	if _, err := cron.ParseStandard(cronSpec(job)); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", job.Schedule, err)
+	}
+	if err := job.JobOptions.Validate(); err != nil {
+		return err
+	}
+
+	params := job.Params
+	if name := job.Params["profile"]; name != "" {
+		merged, err := config.ProfileParams(name, job.Params)
+		if err != nil {
+			return err
+		}
+		params = merged
+	}
+	multi := params["all_databases"] == "true" || params["include"] != ""
+	physical := db.BackupMethod(params) == "physical"
+	switch db.BackupTypeFromParams(params) {
+	case db.BackupTypeFull, db.BackupTypeIncremental, db.BackupTypeDifferential:
+	default:
+		return fmt.Errorf("invalid backup type %q (use full, incremental or differential)", params["type"])
+	}
+	switch {
+	case job.DBType == "sqlite" && params["path"] == "":
+		return fmt.Errorf("missing SQLite path")
+	case job.DBType == "mongodb" && params["uri"] == "":
+		return fmt.Errorf("missing MongoDB URI")
+	case !wholeServer[job.DBType] && !physical && params["dbname"] == "" && !multi:
+		return fmt.Errorf("missing database name")
+	}
+	return nil
+}
+
+// RunNow runs a scheduled job once in this process and logs the outcome
+func RunNow(id cron.EntryID) error {
+	job, ok := FindJob(id)
+	if !ok {
+		return fmt.Errorf("scheduled job #%d not found", id)
+	}
+	start := time.Now()
+	err := runJob(job)
+	status := "SUCCESS"
+	if err != nil {
+		status = "FAILED"
+	}
+	logs.LogEntry("Scheduler", fmt.Sprintf("Trigger(#%d %s)", job.ID, job.DBType), status, start, err)
+	return err
+}
+
+// uploadBackup uploads a backup file to the job's cloud storage
+func uploadBackup(backupFile string, params map[string]string) error {
+	if backupFile == "" {
+		return fmt.Errorf("the backup did not report a file to upload")
 	}
-	
-	backupFile := matches[len(matches)-1]
 	cloudProvider := params["cloud_provider"]
 	if cloudProvider == "" {
 		cloudProvider = os.Getenv("DBX_CLOUD_PROVIDER")
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"dbx/internal/secrets"
+
+	"github.com/robfig/cron/v3"
+)
+
+// JobState records the outcome of a job's most recent runs
+type JobState struct {
+	LastRun     time.Time `json:"last_run"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastStatus  string    `json:"last_status"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// StateFile records each job's last run, for catch-up decisions
+var StateFile = "./config/job_state.json"
+
+var stateMu sync.Mutex
+
+const defaultCatchUpWindow = 24 * time.Hour
+
+// JobStates returns the recorded run state of every job that has run at least once
+func JobStates() map[cron.EntryID]JobState {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return readState()
+}
+
+func readState() map[cron.EntryID]JobState {
+	states := make(map[cron.EntryID]JobState)
+	data, err := os.ReadFile(StateFile)
+	if err != nil {
+		return states
+	}
+	// JSON object keys are strings, so the file is keyed by the job ID's decimal form
+	var raw map[string]JobState
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return states
+	}
+	for key, state := range raw {
+		if id, err := strconv.Atoi(key); err == nil {
+			states[cron.EntryID(id)] = state
+		}
+	}
+	return states
+}
+
+// recordRun stores the outcome of a run so catch-up knows the last success
+func recordRun(id cron.EntryID, start time.Time, runErr error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	states := readState()
+	state := states[id]
+	state.LastRun = start
+	if runErr != nil {
+		state.LastStatus = "FAILED"
+		state.LastError = runErr.Error()
+	} else {
+		state.LastStatus = "SUCCESS"
+		state.LastError = ""
+		state.LastSuccess = start
+	}
+	states[id] = state
+
+	raw := make(map[string]JobState, len(states))
+	for key, s := range states {
+		raw[strconv.Itoa(int(key))] = s
+	}
+	data, _ := json.MarshalIndent(raw, "", "  ")
+	if err := secrets.WritePrivateFile(StateFile, data); err != nil {
+		fmt.Printf("⚠️  Failed to record job state: %v\n", err)
+	}
+}
+
+func (o JobOptions) catchUpWindow() (time.Duration, error) {
+	if o.CatchUpWindow == "" {
+		return defaultCatchUpWindow, nil
+	}
+	d, err := time.ParseDuration(o.CatchUpWindow)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid catch-up window %q (use a duration such as 12h or 72h)", o.CatchUpWindow)
+	}
+	return d, nil
+}
+
+// MissedRun returns the most recent scheduled time between the job's last success and now,
+// provided it lies within the job's catch-up window. Jobs that never succeeded count from creation.
+func MissedRun(job JobConfig, state JobState, now time.Time) (time.Time, bool) {
+	if !job.CatchUp {
+		return time.Time{}, false
+	}
+	window, err := job.catchUpWindow()
+	if err != nil {
+		return time.Time{}, false
+	}
+	schedule, err := cron.ParseStandard(cronSpec(job))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	from := state.LastSuccess
+	if from.IsZero() {
+		from = job.CreatedAt
+	}
+	// Runs older than the window are never caught up, so don't walk the schedule before it
+	if earliest := now.Add(-window); from.Before(earliest) {
+		from = earliest
+	}
+
+	var missed time.Time
+	for next := schedule.Next(from); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		missed = next
+	}
+	return missed, !missed.IsZero()
+}
+
+// catchUpMissedRuns runs each catch-up enabled job once if it missed a run while the daemon was down
+func catchUpMissedRuns() {
+	states := JobStates()
+	now := time.Now()
+	for _, job := range jobs {
+		missed, ok := MissedRun(job, states[job.ID], now)
+		if !ok {
+			continue
+		}
+		fmt.Printf("⏪ Job #%d missed its run at %s, catching up now\n", job.ID, missed.Format("2006-01-02 15:04 MST"))
+		go scheduledRun(job)
+	}
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Reference prefixes understood by Resolve
+const (
+	PrefixEnv   = "env:"
+	PrefixFile  = "file:"
+	PrefixStore = "store:"
+)
+
+// IsReference reports whether value points at a secret instead of holding it
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, PrefixEnv) ||
+		strings.HasPrefix(value, PrefixFile) ||
+		strings.HasPrefix(value, PrefixStore)
+}
+
+// Resolve returns the secret a reference points at.
+// Supported forms are env:VAR, file:/path/to/secret and store:name.
+// Values without a known prefix are treated as literals and returned unchanged.
+func Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, PrefixEnv):
+		name := strings.TrimPrefix(value, PrefixEnv)
+		if name == "" {
+			return "", fmt.Errorf("secret reference %q has no variable name", value)
+		}
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, PrefixFile):
+		path := strings.TrimPrefix(value, PrefixFile)
+		if path == "" {
+			return "", fmt.Errorf("secret reference %q has no file path", value)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		// Secret files usually end with a newline (docker/k8s secrets, echo > file)
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, PrefixStore):
+		name := strings.TrimPrefix(value, PrefixStore)
+		if name == "" {
+			return "", fmt.Errorf("secret reference %q has no store entry name", value)
+		}
+		return Get(name)
+	default:
+		return value, nil
+	}
+}
+
+// ResolveParams returns a copy of params with every secret reference resolved
+func ResolveParams(params map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(params))
+	for key, value := range params {
+		if !IsReference(value) {
+			resolved[key] = value
+			continue
+		}
+		secret, err := Resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", key, err)
+		}
+		resolved[key] = secret
+	}
+	return resolved, nil
+}
@@ -0,0 +1,222 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Locations of the local encrypted secret store.
+// The key file is only used when DBX_SECRET_KEY is not set.
+var (
+	StoreFile = "./config/secrets.enc"
+	KeyFile   = "./config/secret.key"
+)
+
+const pbkdf2Iterations = 600000
+
+// storeFile is the on-disk layout of the encrypted store
+type storeFile struct {
+	Version int    `json:"version"`
+	Salt    string `json:"salt"`
+	Nonce   string `json:"nonce"`
+	Data    string `json:"data"`
+}
+
+// Get returns a secret from the local encrypted store
+func Get(name string) (string, error) {
+	entries, err := load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := entries[name]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in store", name)
+	}
+	return value, nil
+}
+
+// Set adds or replaces a secret in the local encrypted store
+func Set(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret name cannot be empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n") {
+		return fmt.Errorf("secret name cannot contain whitespace")
+	}
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	entries[name] = value
+	return save(entries)
+}
+
+// Delete removes a secret from the local encrypted store
+func Delete(name string) error {
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[name]; !ok {
+		return fmt.Errorf("secret %q not found in store", name)
+	}
+	delete(entries, name)
+	return save(entries)
+}
+
+// List returns the names of all secrets in the store, sorted
+func List() ([]string, error) {
+	entries, err := load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func load() (map[string]string, error) {
+	entries := make(map[string]string)
+	data, err := os.ReadFile(StoreFile)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret store: %w", err)
+	}
+
+	var sf storeFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store: %w", err)
+	}
+	salt, err := hex.DecodeString(sf.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret store salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(sf.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret store nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(sf.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret store data: %w", err)
+	}
+
+	gcm, err := newCipher(salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret store (wrong key?): %w", err)
+	}
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store contents: %w", err)
+	}
+	return entries, nil
+}
+
+func save(entries map[string]string) error {
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newCipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sf := storeFile{
+		Version: 1,
+		Salt:    hex.EncodeToString(salt),
+		Nonce:   hex.EncodeToString(nonce),
+		Data:    hex.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}
+	data, err := json.MarshalIndent(sf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret store: %w", err)
+	}
+	return WritePrivateFile(StoreFile, data)
+}
+
+// newCipher derives the store key and returns an AES-256-GCM cipher.
+// DBX_SECRET_KEY is stretched with PBKDF2; otherwise a random key file is used (created on first use).
+func newCipher(salt []byte) (cipher.AEAD, error) {
+	var key []byte
+	if passphrase := os.Getenv("DBX_SECRET_KEY"); passphrase != "" {
+		derived, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive secret key: %w", err)
+		}
+		key = derived
+	} else {
+		fileKey, err := loadOrCreateKeyFile()
+		if err != nil {
+			return nil, err
+		}
+		key = fileKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func loadOrCreateKeyFile() ([]byte, error) {
+	data, err := os.ReadFile(KeyFile)
+	if err == nil {
+		key, decErr := hex.DecodeString(strings.TrimSpace(string(data)))
+		if decErr != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid secret key file %s", KeyFile)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read secret key file: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	if err := WritePrivateFile(KeyFile, []byte(hex.EncodeToString(key)+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write secret key file: %w", err)
+	}
+	return key, nil
+}
+
+// WritePrivateFile writes data readable by the owner only (0600, directory 0700).
+// Permissions are enforced even if the file already existed with looser ones.
+func WritePrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,267 @@
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultPorts are the database ports forwarded when none is given
+var defaultPorts = map[string]string{
+	"mysql":         "3306",
+	"mariadb":       "3306",
+	"postgres":      "5432",
+	"mongodb":       "27017",
+	"redis":         "6379",
+	"etcd":          "2379",
+	"consul":        "8500",
+	"elasticsearch": "9200",
+	"clickhouse":    "9000",
+}
+
+// Config describes the jump host a tunnel goes through
+type Config struct {
+	Host       string // jump host, as host or host:port (default port 22)
+	User       string // SSH user (default: the local user)
+	KeyFile    string // private key; without one, keys from ssh-agent (SSH_AUTH_SOCK) are used
+	KnownHosts string // known_hosts file checked for the jump host's key (default ~/.ssh/known_hosts)
+}
+
+// ConfigFromParams reads the SSH settings from scheduler job params
+func ConfigFromParams(params map[string]string) Config {
+	return Config{
+		Host:       params["ssh_host"],
+		User:       params["ssh_user"],
+		KeyFile:    params["ssh_key"],
+		KnownHosts: params["ssh_known_hosts"],
+	}
+}
+
+// Enabled reports whether a jump host is configured
+func (c Config) Enabled() bool {
+	return c.Host != ""
+}
+
+// Tunnel is an SSH local port forward: connections to Addr are carried over SSH to the remote address
+type Tunnel struct {
+	client   *ssh.Client
+	listener net.Listener
+	remote   string
+	wg       sync.WaitGroup
+}
+
+// Open connects to the jump host and forwards a local port on 127.0.0.1 to remoteAddr,
+// as seen from the jump host. Close tears it down.
+func Open(cfg Config, remoteAddr string) (*Tunnel, error) {
+	clientConfig, err := cfg.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	sshAddr := cfg.Host
+	if _, _, err := net.SplitHostPort(sshAddr); err != nil {
+		sshAddr = net.JoinHostPort(sshAddr, "22")
+	}
+	client, err := ssh.Dial("tcp", sshAddr, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH host %s: %w", cfg.Host, err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to open local tunnel port: %w", err)
+	}
+
+	t := &Tunnel{client: client, listener: listener, remote: remoteAddr}
+	t.wg.Add(1)
+	go t.serve()
+	return t, nil
+}
+
+// Addr returns the local address the tunnel listens on
+func (t *Tunnel) Addr() string {
+	return t.listener.Addr().String()
+}
+
+// Port returns the local port the tunnel listens on
+func (t *Tunnel) Port() string {
+	_, port, _ := net.SplitHostPort(t.Addr())
+	return port
+}
+
+// Close stops accepting connections and closes the SSH connection, ending any forwarded ones
+func (t *Tunnel) Close() error {
+	err := t.listener.Close()
+	if cerr := t.client.Close(); err == nil {
+		err = cerr
+	}
+	t.wg.Wait()
+	return err
+}
+
+func (t *Tunnel) serve() {
+	defer t.wg.Done()
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go t.forward(local)
+	}
+}
+
+// forward copies one local connection to and from the remote address
+func (t *Tunnel) forward(local net.Conn) {
+	defer func() { _ = local.Close() }()
+	remote, err := t.client.Dial("tcp", t.remote)
+	if err != nil {
+		fmt.Printf("⚠️  SSH tunnel failed to reach %s: %v\n", t.remote, err)
+		return
+	}
+	defer func() { _ = remote.Close() }()
+
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(remote, local); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(local, remote); done <- struct{}{} }()
+	<-done
+}
+
+// clientConfig builds the SSH client configuration: key file or agent auth, and known_hosts checking
+func (c Config) clientConfig() (*ssh.ClientConfig, error) {
+	user := c.User
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	if user == "" {
+		return nil, fmt.Errorf("SSH user required (use --ssh-user)")
+	}
+
+	var auth []ssh.AuthMethod
+	if c.KeyFile != "" {
+		signer, err := loadKey(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	} else if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach ssh-agent: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	} else {
+		return nil, fmt.Errorf("SSH key required (use --ssh-key or run ssh-agent)")
+	}
+
+	knownHostsFile := c.KnownHosts
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known_hosts: %w", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts (connect once with ssh to add the jump host): %w", err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         15 * time.Second,
+	}, nil
+}
+
+// loadKey reads a private key, decrypting it with DBX_SSH_PASSPHRASE when it is protected
+func loadKey(keyFile string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if _, protected := err.(*ssh.PassphraseMissingError); protected {
+		passphrase := os.Getenv("DBX_SSH_PASSPHRASE")
+		if passphrase == "" {
+			return nil, fmt.Errorf("SSH key %s is passphrase-protected; set DBX_SSH_PASSPHRASE or use ssh-agent", keyFile)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+	}
+	return signer, nil
+}
+
+// Rewrite opens a tunnel to the database described by params (host and port, or uri for MongoDB)
+// and returns a copy of params pointing at the tunnel instead, plus a function that closes it.
+// tls_server_name keeps the database's own host, so certificates are still checked against it.
+// Without a jump host, params are returned unchanged.
+func Rewrite(engine string, params map[string]string, cfg Config) (map[string]string, func(), error) {
+	if !cfg.Enabled() {
+		return params, func() {}, nil
+	}
+	if params["socket"] != "" {
+		return nil, nil, fmt.Errorf("--socket can't be combined with --ssh-host")
+	}
+
+	var dbHost, dbPort string
+	var mongoURI *url.URL
+	switch engine {
+	case "mysql", "mariadb", "postgres", "redis", "etcd", "consul", "elasticsearch", "clickhouse":
+		dbHost, dbPort = params["host"], params["port"]
+	case "mongodb":
+		u, err := url.Parse(params["uri"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid MongoDB URI: %w", err)
+		}
+		if u.Scheme != "mongodb" || strings.Contains(u.Host, ",") {
+			return nil, nil, fmt.Errorf("SSH tunnels need a single-host mongodb:// URI")
+		}
+		mongoURI = u
+		dbHost, dbPort = u.Hostname(), u.Port()
+		if params["port"] != "" {
+			dbPort = params["port"]
+		}
+	default:
+		return nil, nil, fmt.Errorf("SSH tunnels are not supported for %s", engine)
+	}
+	if dbHost == "" {
+		dbHost = "localhost"
+	}
+	if dbPort == "" {
+		dbPort = defaultPorts[engine]
+	}
+
+	remoteAddr := net.JoinHostPort(dbHost, dbPort)
+	t, err := Open(cfg, remoteAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Printf("🔐 SSH tunnel to %s via %s on %s\n", remoteAddr, cfg.Host, t.Addr())
+
+	rewritten := make(map[string]string, len(params))
+	for k, v := range params {
+		rewritten[k] = v
+	}
+	rewritten["tls_server_name"] = dbHost
+	if mongoURI != nil {
+		mongoURI.Host = t.Addr()
+		rewritten["uri"] = mongoURI.String()
+		delete(rewritten, "port")
+	} else {
+		rewritten["host"] = "127.0.0.1"
+		rewritten["port"] = t.Port()
+	}
+	return rewritten, func() { _ = t.Close() }, nil
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // CompressFolder zips the contents of srcDir into destZip
@@ -129,3 +130,90 @@ func CompressGzip(srcFile, destGz string) error {
 
 	return nil
 }
+
+// ExtractFile extracts the single file held by a zip archive made with CompressFile into destDir
+// and returns its path
+func ExtractFile(srcZip, destDir string) (string, error) {
+	archive, err := zip.OpenReader(srcZip)
+	if err != nil {
+		return "", fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer func() { _ = archive.Close() }()
+
+	if len(archive.File) != 1 || archive.File[0].FileInfo().IsDir() {
+		return "", fmt.Errorf("expected a zip holding one file, %s has %d entries", filepath.Base(srcZip), len(archive.File))
+	}
+	entry := archive.File[0]
+	// Only the base name is used, so entries like ../../x can't escape destDir
+	destFile := filepath.Join(destDir, filepath.Base(entry.Name))
+
+	src, err := entry.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read zip entry: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.Create(destFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to create extracted file: %w", err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", fmt.Errorf("failed to extract file: %w", err)
+	}
+	return destFile, nil
+}
+
+// ExtractFolder extracts a zip archive made with CompressFolder into destDir
+func ExtractFolder(srcZip, destDir string) error {
+	archive, err := zip.OpenReader(srcZip)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer func() { _ = archive.Close() }()
+
+	root, err := filepath.Abs(destDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range archive.File {
+		target := filepath.Join(root, entry.Name)
+		// Reject entries like ../../x that would land outside destDir
+		if rel, err := filepath.Rel(root, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("zip entry %q escapes the destination directory", entry.Name)
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractEntry(entry, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractEntry(entry *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	src, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read zip entry: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create extracted file: %w", err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", entry.Name, err)
+	}
+	return nil
+}
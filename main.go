@@ -2,9 +2,12 @@ package main
 
 import (
 	"bufio"
+	"dbx/cmd"
 	"dbx/internal/cloud"
+	"dbx/internal/config"
 	"dbx/internal/db"
 	"dbx/internal/scheduler"
+	"dbx/internal/secrets"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,6 +15,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/robfig/cron/v3"
 	"golang.org/x/term"
 )
 
@@ -20,6 +24,12 @@ type App struct {
 }
 
 func main() {
+	// Any arguments select the command-line interface, none launch the interactive menu
+	if len(os.Args) > 1 {
+		cmd.Execute()
+		return
+	}
+
 	app := &App{
 		reader: bufio.NewReader(os.Stdin),
 	}
@@ -198,6 +208,13 @@ func (a *App) BackupMenu() {
 	fmt.Println("[1] Run MySQL Backup")
 	fmt.Println("[2] Run MongoDB Backup")
 	fmt.Println("[3] Run PostgreSQL Backup")
+	fmt.Println("[4] Run Backup From Profile")
+	fmt.Println("[5] Run Redis Backup")
+	fmt.Println("[6] Run MariaDB Backup")
+	fmt.Println("[7] Run etcd Backup")
+	fmt.Println("[8] Run Consul Backup")
+	fmt.Println("[9] Run Elasticsearch/OpenSearch Snapshot")
+	fmt.Println("[10] Run ClickHouse Backup")
 	fmt.Println("[0] Back to Main Menu")
 	fmt.Print("Enter your choice: ")
 
@@ -209,6 +226,20 @@ func (a *App) BackupMenu() {
 		a.RunMongoBackup()
 	case 3:
 		a.RunPostgresBackup()
+	case 4:
+		a.RunProfileBackup()
+	case 5:
+		a.RunRedisBackup()
+	case 6:
+		a.RunMariaDBBackup()
+	case 7:
+		a.RunEtcdBackup()
+	case 8:
+		a.RunConsulBackup()
+	case 9:
+		a.RunElasticsearchBackup()
+	case 10:
+		a.RunClickHouseBackup()
 	case 0:
 		a.MainMenu()
 	default:
@@ -238,6 +269,12 @@ func (a *App) RestoreMenu() {
 	fmt.Println("[2] Restore PostgreSQL Backup")
 	fmt.Println("[3] Restore MongoDB Backup")
 	fmt.Println("[4] Restore SQLite Backup")
+	fmt.Println("[5] Restore Redis Backup")
+	fmt.Println("[6] Restore MariaDB Backup")
+	fmt.Println("[7] Restore etcd Backup")
+	fmt.Println("[8] Restore Consul Backup")
+	fmt.Println("[9] Restore Elasticsearch/OpenSearch Snapshot")
+	fmt.Println("[10] Restore ClickHouse Backup")
 	fmt.Println("[0] Back to Main Menu")
 	fmt.Print("Enter your choice: ")
 
@@ -250,6 +287,18 @@ func (a *App) RestoreMenu() {
 		a.RunMongoRestore()
 	case 4:
 		a.RunSQLiteRestore()
+	case 5:
+		a.RunRedisRestore()
+	case 6:
+		a.RunMariaDBRestore()
+	case 7:
+		a.RunEtcdRestore()
+	case 8:
+		a.RunConsulRestore()
+	case 9:
+		a.RunElasticsearchRestore()
+	case 10:
+		a.RunClickHouseRestore()
 	case 0:
 		a.MainMenu()
 	default:
@@ -434,6 +483,138 @@ func (a *App) RunPostgresBackup() {
 	a.BackupMenu()
 }
 
+func (a *App) RunRedisBackup() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("Redis Host", "localhost", false)
+	port := a.promptInput("Redis Port", "6379", false)
+	user := a.promptInput("Redis ACL User (optional)", "", false)
+	pass := a.promptInput("Redis Password", "", true)
+	out := a.promptInput("Backup Directory", "./backups", false)
+
+	if err := db.BackupRedis(host, port, user, pass, "", out); err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Println("\n✅ Backup successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
+func (a *App) RunEtcdBackup() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("etcd Host", "localhost", false)
+	port := a.promptInput("etcd Port", "2379", false)
+	user := a.promptInput("etcd User (optional)", "", false)
+	pass := a.promptInput("etcd Password", "", true)
+	out := a.promptInput("Backup Directory", "./backups", false)
+
+	if err := db.BackupEtcd(host, port, user, pass, "", out); err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Println("\n✅ Backup successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
+func (a *App) RunConsulBackup() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("Consul Host", "localhost", false)
+	port := a.promptInput("Consul Port", "8500", false)
+	token := a.promptInput("Consul ACL Token (optional)", "", true)
+	out := a.promptInput("Backup Directory", "./backups", false)
+
+	if err := db.BackupConsul(host, port, token, "", out); err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Println("\n✅ Backup successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
+func (a *App) RunElasticsearchBackup() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("Elasticsearch Host", "localhost", false)
+	port := a.promptInput("Elasticsearch Port", "9200", false)
+	user := a.promptInput("Elasticsearch User (optional)", "", false)
+	pass := a.promptInput("Elasticsearch Password or API Key", "", true)
+	opts := db.ElasticsearchOptions{
+		Repository:     a.promptInput("Snapshot Repository", "dbx", false),
+		RepositoryPath: a.promptInput("Register repository at path (optional, must be in path.repo)", "", false),
+	}
+	if indices := a.promptInput("Indices (comma-separated, empty for all)", "", false); indices != "" {
+		opts.Indices = strings.Split(indices, ",")
+	}
+
+	if err := db.BackupElasticsearch(host, port, user, pass, "", opts); err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Println("\n✅ Backup successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
+func (a *App) RunClickHouseBackup() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("ClickHouse Host", "localhost", false)
+	port := a.promptInput("ClickHouse Port", "9000", false)
+	user := a.promptInput("ClickHouse User", "default", false)
+	pass := a.promptInput("ClickHouse Password", "", true)
+	dbname := a.promptInput("Database Name", "", false)
+	out := a.promptInput("Backup Directory", "./backups", false)
+
+	if err := db.BackupClickHouse(host, port, user, pass, dbname, out); err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Println("\n✅ Backup successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
+func (a *App) RunMariaDBBackup() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("MariaDB Host", "localhost", false)
+	user := a.promptInput("MariaDB User", "root", false)
+	pass := a.promptInput("MariaDB Password", "", true)
+	dbname := a.promptInput("Database Name", "", false)
+	out := a.promptInput("Backup Directory", "./backups", false)
+
+	if err := db.BackupMariaDB(host, user, pass, dbname, out); err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Println("\n✅ Backup successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
 func (a *App) RunMySQLRestore() {
 	a.clearScreen()
 	a.showBanner()
@@ -455,6 +636,27 @@ func (a *App) RunMySQLRestore() {
 	a.RestoreMenu()
 }
 
+func (a *App) RunMariaDBRestore() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("MariaDB Host", "localhost", false)
+	user := a.promptInput("MariaDB User", "root", false)
+	pass := a.promptInput("MariaDB Password", "", true)
+	dbname := a.promptInput("Database Name", "", false)
+	file := a.promptInput("Path to .sql backup file", "./backups/backup.sql", false)
+
+	if err := db.RestoreMariaDB(host, user, pass, dbname, file); err != nil {
+		fmt.Println("\n❌ Restore failed:", err)
+	} else {
+		fmt.Println("\n✅ Restore successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Restore Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.RestoreMenu()
+}
+
 func (a *App) RunPostgresRestore() {
 	a.clearScreen()
 	a.showBanner()
@@ -496,6 +698,115 @@ func (a *App) RunMongoRestore() {
 	a.RestoreMenu()
 }
 
+func (a *App) RunRedisRestore() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("Redis Host", "localhost", false)
+	port := a.promptInput("Redis Port", "6379", false)
+	user := a.promptInput("Redis ACL User (optional)", "", false)
+	pass := a.promptInput("Redis Password", "", true)
+	file := a.promptInput("Path to backup file", "./backups/redis.rdb.zip", false)
+
+	fmt.Println("⚠️  This replaces all data on the Redis server.")
+	if err := db.RestoreRedis(host, port, user, pass, file); err != nil {
+		fmt.Println("\n❌ Restore failed:", err)
+	} else {
+		fmt.Println("\n✅ Restore successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Restore Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.RestoreMenu()
+}
+
+func (a *App) RunEtcdRestore() {
+	a.clearScreen()
+	a.showBanner()
+	file := a.promptInput("Path to backup file", "./backups/etcd.db.zip", false)
+	dataDir := a.promptInput("New data directory", "./default.etcd", false)
+
+	if err := db.RestoreEtcd(file, db.RestoreOptions{DataDir: dataDir}); err != nil {
+		fmt.Println("\n❌ Restore failed:", err)
+	} else {
+		fmt.Println("\n✅ Restore successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Restore Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.RestoreMenu()
+}
+
+func (a *App) RunConsulRestore() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("Consul Host", "localhost", false)
+	port := a.promptInput("Consul Port", "8500", false)
+	token := a.promptInput("Consul ACL Token (optional)", "", true)
+	file := a.promptInput("Path to backup file", "./backups/consul.snap", false)
+
+	fmt.Println("⚠️  This replaces the whole state of the Consul cluster.")
+	if err := db.RestoreConsul(host, port, token, file); err != nil {
+		fmt.Println("\n❌ Restore failed:", err)
+	} else {
+		fmt.Println("\n✅ Restore successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Restore Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.RestoreMenu()
+}
+
+func (a *App) RunElasticsearchRestore() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("Elasticsearch Host", "localhost", false)
+	port := a.promptInput("Elasticsearch Port", "9200", false)
+	user := a.promptInput("Elasticsearch User (optional)", "", false)
+	pass := a.promptInput("Elasticsearch Password or API Key", "", true)
+	opts := db.ElasticsearchOptions{Repository: a.promptInput("Snapshot Repository", "dbx", false)}
+	snapshot := a.promptInput("Snapshot (empty to list them)", "", false)
+	if indices := a.promptInput("Indices (comma-separated, empty for all)", "", false); indices != "" {
+		opts.Indices = strings.Split(indices, ",")
+	}
+	opts.RenameReplacement = a.promptInput("Restore as (e.g. restored_$1, empty to keep names)", "", false)
+
+	if err := db.RestoreElasticsearch(host, port, user, pass, snapshot, opts); err != nil {
+		fmt.Println("\n❌ Restore failed:", err)
+	} else {
+		fmt.Println("\n✅ Restore successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Restore Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.RestoreMenu()
+}
+
+func (a *App) RunClickHouseRestore() {
+	a.clearScreen()
+	a.showBanner()
+	host := a.promptInput("ClickHouse Host", "localhost", false)
+	port := a.promptInput("ClickHouse Port", "9000", false)
+	user := a.promptInput("ClickHouse User", "default", false)
+	pass := a.promptInput("ClickHouse Password", "", true)
+	dbname := a.promptInput("Database Name (empty for the one backed up)", "", false)
+	file := a.promptInput("Path to backup file", "./backups/backup.zip", false)
+
+	if err := db.RestoreClickHouse(host, port, user, pass, dbname, file); err != nil {
+		fmt.Println("\n❌ Restore failed:", err)
+	} else {
+		fmt.Println("\n✅ Restore successful!")
+	}
+
+	fmt.Print("\nPress ENTER to return to Restore Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.RestoreMenu()
+}
+
 func (a *App) RunSQLiteRestore() {
 	a.clearScreen()
 	a.showBanner()
@@ -601,6 +912,7 @@ func (a *App) ScheduleMenu() {
 	fmt.Println("--- Backup Scheduler ---")
 	fmt.Println("[1] Add New Scheduled Backup")
 	fmt.Println("[2] View Scheduled Jobs")
+	fmt.Println("[3] Run a Scheduled Job Now")
 	fmt.Println("[0] Back to Main Menu")
 	fmt.Print("Enter choice: ")
 
@@ -609,6 +921,8 @@ func (a *App) ScheduleMenu() {
 		a.AddScheduledBackup()
 	case 2:
 		a.ViewScheduledJobs()
+	case 3:
+		a.TriggerScheduledJob()
 	case 0:
 		a.MainMenu()
 	default:
@@ -625,6 +939,13 @@ func (a *App) AddScheduledBackup() {
 	fmt.Println("[2] PostgreSQL")
 	fmt.Println("[3] MongoDB")
 	fmt.Println("[4] SQLite")
+	fmt.Println("[5] From Profile (config file)")
+	fmt.Println("[6] Redis")
+	fmt.Println("[7] MariaDB")
+	fmt.Println("[8] etcd")
+	fmt.Println("[9] Consul")
+	fmt.Println("[10] Elasticsearch/OpenSearch")
+	fmt.Println("[11] ClickHouse")
 	fmt.Print("Select: ")
 
 	dbChoice := a.readInt()
@@ -632,11 +953,20 @@ func (a *App) AddScheduledBackup() {
 	params := make(map[string]string)
 
 	switch dbChoice {
+	case 5:
+		profile, ok := a.selectProfile()
+		if !ok {
+			a.ScheduleMenu()
+			return
+		}
+		// The profile is read at run time, so later edits to the config file apply to the job
+		dbType = profile.Engine
+		params["profile"] = profile.Name
 	case 1:
 		dbType = "mysql"
 		params["host"] = a.promptInput("Host", "localhost", false)
 		params["user"] = a.promptInput("User", "root", false)
-		params["pass"] = a.promptInput("Password", "", true)
+		params["pass"] = a.promptInput("Password (or env:VAR, file:PATH, store:NAME)", "", true)
 		params["dbname"] = a.promptInput("Database Name", "", false)
 		params["out"] = a.promptInput("Backup Dir", "./backups", false)
 	case 2:
@@ -644,7 +974,7 @@ func (a *App) AddScheduledBackup() {
 		params["host"] = a.promptInput("Host", "localhost", false)
 		params["port"] = a.promptInput("Port", "5432", false)
 		params["user"] = a.promptInput("User", "postgres", false)
-		params["pass"] = a.promptInput("Password", "", true)
+		params["pass"] = a.promptInput("Password (or env:VAR, file:PATH, store:NAME)", "", true)
 		params["dbname"] = a.promptInput("Database Name", "", false)
 		params["out"] = a.promptInput("Backup Dir", "./backups", false)
 	case 3:
@@ -656,6 +986,49 @@ func (a *App) AddScheduledBackup() {
 		dbType = "sqlite"
 		params["path"] = a.promptInput("SQLite file path", "./database.db", false)
 		params["out"] = a.promptInput("Backup Dir", "./backups", false)
+	case 6:
+		dbType = "redis"
+		params["host"] = a.promptInput("Host", "localhost", false)
+		params["port"] = a.promptInput("Port", "6379", false)
+		params["user"] = a.promptInput("ACL User (optional)", "", false)
+		params["pass"] = a.promptInput("Password (or env:VAR, file:PATH, store:NAME)", "", true)
+		params["out"] = a.promptInput("Backup Dir", "./backups", false)
+	case 7:
+		dbType = "mariadb"
+		params["host"] = a.promptInput("Host", "localhost", false)
+		params["user"] = a.promptInput("User", "root", false)
+		params["pass"] = a.promptInput("Password (or env:VAR, file:PATH, store:NAME)", "", true)
+		params["dbname"] = a.promptInput("Database Name", "", false)
+		params["out"] = a.promptInput("Backup Dir", "./backups", false)
+	case 8:
+		dbType = "etcd"
+		params["host"] = a.promptInput("Host", "localhost", false)
+		params["port"] = a.promptInput("Port", "2379", false)
+		params["user"] = a.promptInput("User (optional)", "", false)
+		params["pass"] = a.promptInput("Password (or env:VAR, file:PATH, store:NAME)", "", true)
+		params["out"] = a.promptInput("Backup Dir", "./backups", false)
+	case 9:
+		dbType = "consul"
+		params["host"] = a.promptInput("Host", "localhost", false)
+		params["port"] = a.promptInput("Port", "8500", false)
+		params["pass"] = a.promptInput("ACL Token (or env:VAR, file:PATH, store:NAME)", "", true)
+		params["out"] = a.promptInput("Backup Dir", "./backups", false)
+	case 10:
+		dbType = "elasticsearch"
+		params["host"] = a.promptInput("Host", "localhost", false)
+		params["port"] = a.promptInput("Port", "9200", false)
+		params["user"] = a.promptInput("User (optional)", "", false)
+		params["pass"] = a.promptInput("Password or API Key (or env:VAR, file:PATH, store:NAME)", "", true)
+		params["repository"] = a.promptInput("Snapshot Repository", "dbx", false)
+		params["indices"] = a.promptInput("Indices (comma-separated, empty for all)", "", false)
+	case 11:
+		dbType = "clickhouse"
+		params["host"] = a.promptInput("Host", "localhost", false)
+		params["port"] = a.promptInput("Port", "9000", false)
+		params["user"] = a.promptInput("User", "default", false)
+		params["pass"] = a.promptInput("Password (or env:VAR, file:PATH, store:NAME)", "", true)
+		params["dbname"] = a.promptInput("Database Name", "", false)
+		params["out"] = a.promptInput("Backup Dir", "./backups", false)
 	default:
 		fmt.Println("Invalid DB type.")
 		a.ScheduleMenu()
@@ -663,8 +1036,19 @@ func (a *App) AddScheduledBackup() {
 	}
 
 	schedule := a.promptInput("Cron schedule (e.g. @daily, @hourly, */30 * * * *)", "@daily", false)
+	opts := scheduler.JobOptions{
+		Timezone: a.promptInput("Timezone (e.g. Europe/Berlin, empty for local time)", "", false),
+		Jitter:   a.promptInput("Random start delay (e.g. 10m, empty for none)", "", false),
+	}
+	if blackout := a.promptInput("Blackout window (e.g. Mon-Fri 09:00-18:00, empty for none)", "", false); blackout != "" {
+		opts.Blackouts = []string{blackout}
+	}
+	if strings.ToLower(a.promptInput("Catch up missed runs after downtime? (y/N)", "N", false)) == "y" {
+		opts.CatchUp = true
+		opts.CatchUpWindow = a.promptInput("Catch-up window", "24h", false)
+	}
 
-	if err := scheduler.AddJob(dbType, schedule, params); err != nil {
+	if err := scheduler.AddJobWithOptions(dbType, schedule, params, opts); err != nil {
 		fmt.Println("❌ Failed to schedule job:", err)
 	} else {
 		fmt.Println("✅ Backup job scheduled successfully!")
@@ -685,7 +1069,7 @@ func (a *App) ViewScheduledJobs() {
 	} else {
 		fmt.Println("--- Scheduled Jobs ---")
 		for _, j := range jobs {
-			fmt.Printf("[%s] %s @ %s → %v\n", j.CreatedAt.Format("2006-01-02 15:04"), j.DBType, j.Schedule, j.Params)
+			fmt.Printf("#%d [%s] %s @ %s → %v\n", j.ID, j.CreatedAt.Format("2006-01-02 15:04"), j.DBType, j.Schedule, scheduler.RedactParams(j.Params))
 		}
 	}
 	fmt.Print("\nPress ENTER to return...")
@@ -693,3 +1077,104 @@ func (a *App) ViewScheduledJobs() {
 	a.reader.ReadString('\n')
 	a.ScheduleMenu()
 }
+
+func (a *App) TriggerScheduledJob() {
+	a.clearScreen()
+	a.showBanner()
+	jobs := scheduler.ListJobs()
+	if len(jobs) == 0 {
+		fmt.Println("⚠️ No scheduled jobs found.")
+	} else {
+		fmt.Println("--- Run a Scheduled Job Now ---")
+		for _, j := range jobs {
+			fmt.Printf("#%d %s @ %s\n", j.ID, j.DBType, j.Schedule)
+		}
+		fmt.Print("Job ID: ")
+		id := a.readInt()
+
+		result, err := scheduler.Trigger(cron.EntryID(id))
+		if err != nil {
+			fmt.Println("❌ Failed to run job:", err)
+		} else if result.Status == "SUCCESS" {
+			fmt.Printf("✅ Job #%d completed in %s\n", result.JobID, result.Duration)
+		} else {
+			fmt.Printf("❌ Job #%d failed after %s: %s\n", result.JobID, result.Duration, result.Error)
+		}
+	}
+	fmt.Print("\nPress ENTER to return...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.ScheduleMenu()
+}
+
+// selectProfile lists the profiles from the config file and lets the user pick one
+func (a *App) selectProfile() (config.Profile, bool) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		fmt.Println("❌", err)
+		fmt.Println("   Define profiles in", config.DefaultPath(), "(see Readme: Configuration File)")
+		a.reader.ReadString('\n')
+		return config.Profile{}, false
+	}
+	names := cfg.ProfileNames()
+	if len(names) == 0 {
+		fmt.Println("⚠️ No profiles defined in", config.DefaultPath())
+		a.reader.ReadString('\n')
+		return config.Profile{}, false
+	}
+
+	fmt.Println("--- Profiles ---")
+	for i, name := range names {
+		fmt.Printf("[%d] %s (%s)\n", i+1, name, cfg.Profiles[name].Engine)
+	}
+	fmt.Print("Select profile: ")
+	choice := a.readInt()
+	if choice < 1 || choice > len(names) {
+		fmt.Println("Invalid profile.")
+		return config.Profile{}, false
+	}
+	profile, err := cfg.Profile(names[choice-1])
+	if err != nil {
+		fmt.Println("❌", err)
+		return config.Profile{}, false
+	}
+	return profile, true
+}
+
+func (a *App) RunProfileBackup() {
+	a.clearScreen()
+	a.showBanner()
+	profile, ok := a.selectProfile()
+	if !ok {
+		a.BackupMenu()
+		return
+	}
+
+	params, err := secrets.ResolveParams(profile.Params())
+	if err == nil {
+		err = a.backupProfile(profile, params)
+	}
+
+	if err != nil {
+		fmt.Println("\n❌ Backup failed:", err)
+	} else {
+		fmt.Printf("\n✅ Backup of profile %s successful!\n", profile.Name)
+	}
+
+	fmt.Print("\nPress ENTER to return to Backup Menu...")
+	// Ignore ReadString error - always return to menu
+	a.reader.ReadString('\n')
+	a.BackupMenu()
+}
+
+// backupProfile backs up the database a profile describes the way a scheduled job of the profile
+// would, including its options, databases, SSH tunnel and cloud upload
+func (a *App) backupProfile(profile config.Profile, params map[string]string) error {
+	if params["out"] == "" {
+		params["out"] = "./backups"
+	}
+	if profile.Engine == "postgres" && params["port"] == "" {
+		params["port"] = "5432"
+	}
+	return scheduler.RunBackup(profile.Engine, params)
+}
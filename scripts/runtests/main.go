@@ -70,6 +70,19 @@ func main() {
 				}{moduleName, "./" + dir})
 			}
 		}
+		// Packages that also test unexported helpers next to the code
+		unitTests, _ := filepath.Glob("internal/*/*_test.go")
+		seen := make(map[string]bool)
+		for _, file := range unitTests {
+			dir := filepath.Dir(file)
+			if !seen[dir] {
+				seen[dir] = true
+				modules = append(modules, struct {
+					name string
+					path string
+				}{filepath.Base(dir) + " (unit)", "./" + dir})
+			}
+		}
 	} else {
 		fmt.Println(ColorYellow + "⚠️  Warning: tests/ directory not found" + ColorReset)
 		fmt.Println()
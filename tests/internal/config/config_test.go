@@ -0,0 +1,200 @@
+package config_test
+
+import (
+	"dbx/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const yamlConfig = `
+defaults:
+  out: /var/backups/dbx
+  destination: archive
+destinations:
+  archive:
+    provider: s3
+    bucket: company-backups
+    prefix: dbx/
+profiles:
+  prod-orders:
+    engine: postgresql
+    host: db.internal
+    port: 5433
+    user: backup
+    password: store:prod-orders
+    database: orders
+  local:
+    engine: sqlite
+    path: ./app.db
+    out: ./backups
+    destination: none
+`
+
+const tomlConfig = `
+[defaults]
+out = "/var/backups/dbx"
+
+[profiles.shop]
+engine = "mysql"
+host = "mysql.internal"
+user = "root"
+password = "env:MYSQL_PWD"
+database = "shop"
+`
+
+// writeConfig writes a config file with the given name into a temp dir
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestLoad_YAML tests loading profiles with defaults and destinations from YAML
+func TestLoad_YAML(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, "config.yaml", yamlConfig))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := strings.Join(cfg.ProfileNames(), ","); got != "local,prod-orders" {
+		t.Errorf("ProfileNames() = %s, want local,prod-orders", got)
+	}
+
+	p, err := cfg.Profile("prod-orders")
+	if err != nil {
+		t.Fatalf("Profile() error = %v", err)
+	}
+	want := map[string]string{
+		"host":           "db.internal",
+		"port":           "5433",
+		"user":           "backup",
+		"pass":           "store:prod-orders",
+		"dbname":         "orders",
+		"out":            "/var/backups/dbx",
+		"upload_cloud":   "true",
+		"cloud_provider": "s3",
+		"s3_bucket":      "company-backups",
+		"s3_prefix":      "dbx/",
+	}
+	params := p.Params()
+	if p.Engine != "postgres" {
+		t.Errorf("Engine = %s, want postgres", p.Engine)
+	}
+	for key, value := range want {
+		if params[key] != value {
+			t.Errorf("Params()[%s] = %q, want %q", key, params[key], value)
+		}
+	}
+}
+
+// TestLoad_ProfileOverridesDefaults tests that profile values win over defaults
+func TestLoad_ProfileOverridesDefaults(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, "config.yaml", yamlConfig))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	p, err := cfg.Profile("local")
+	if err != nil {
+		t.Fatalf("Profile() error = %v", err)
+	}
+	params := p.Params()
+	if params["out"] != "./backups" {
+		t.Errorf("Params()[out] = %q, want ./backups", params["out"])
+	}
+	if _, ok := params["upload_cloud"]; ok {
+		t.Error("destination: none should disable the default destination")
+	}
+}
+
+// TestLoad_TOML tests loading profiles from TOML
+func TestLoad_TOML(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, "config.toml", tomlConfig))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	p, err := cfg.Profile("shop")
+	if err != nil {
+		t.Fatalf("Profile() error = %v", err)
+	}
+	params := p.Params()
+	if p.Engine != "mysql" || params["dbname"] != "shop" || params["out"] != "/var/backups/dbx" {
+		t.Errorf("Profile() = %+v, params %v", p, params)
+	}
+}
+
+// TestProfile_PhysicalAlias tests that the deprecated physical key reads as method: physical
+func TestProfile_PhysicalAlias(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, "config.yaml", `
+profiles:
+  old:
+    engine: mariadb
+    physical: true
+  new:
+    engine: mariadb
+    method: physical
+`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, name := range []string{"old", "new"} {
+		p, err := cfg.Profile(name)
+		if err != nil {
+			t.Fatalf("Profile(%s) error = %v", name, err)
+		}
+		params := p.Params()
+		if params["method"] != "physical" || params["physical"] != "" {
+			t.Errorf("%s: Params() = %v, want method physical", name, params)
+		}
+	}
+}
+
+// TestLoad_Errors tests error handling for missing files, bad formats and invalid profiles
+func TestLoad_Errors(t *testing.T) {
+	if _, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() should return error for missing file")
+	}
+	if _, err := config.Load(writeConfig(t, "config.ini", "")); err == nil {
+		t.Error("Load() should return error for unsupported extension")
+	}
+	if _, err := config.Load(writeConfig(t, "config.yaml", "profiles: [")); err == nil {
+		t.Error("Load() should return error for malformed YAML")
+	}
+
+	cfg, err := config.Load(writeConfig(t, "config.yaml", `
+profiles:
+  bad-engine:
+    engine: oracle
+  bad-destination:
+    engine: mysql
+    destination: nowhere
+`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, name := range []string{"missing", "bad-engine", "bad-destination"} {
+		if _, err := cfg.Profile(name); err == nil {
+			t.Errorf("Profile(%q) should return error", name)
+		}
+	}
+}
+
+// TestProfileParams tests loading a profile via DBX_CONFIG with overrides applied
+func TestProfileParams(t *testing.T) {
+	t.Setenv("DBX_CONFIG", writeConfig(t, "config.yaml", yamlConfig))
+
+	params, err := config.ProfileParams("prod-orders", map[string]string{"dbname": "orders_eu", "profile": "prod-orders"})
+	if err != nil {
+		t.Fatalf("ProfileParams() error = %v", err)
+	}
+	if params["dbname"] != "orders_eu" {
+		t.Errorf("ProfileParams()[dbname] = %q, want override orders_eu", params["dbname"])
+	}
+	if params["host"] != "db.internal" {
+		t.Errorf("ProfileParams()[host] = %q, want db.internal", params["host"])
+	}
+}
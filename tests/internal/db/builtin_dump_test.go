@@ -0,0 +1,385 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"dbx/internal/db"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+)
+
+// TestBackupOptions_Dumper tests the accepted --dumper values
+func TestBackupOptions_Dumper(t *testing.T) {
+	for _, dumper := range []string{"", db.DumperAuto, db.DumperTool, db.DumperBuiltin} {
+		if err := (db.BackupOptions{Dumper: dumper}).Validate(); err != nil {
+			t.Errorf("Validate(Dumper: %q) error = %v", dumper, err)
+		}
+	}
+	if err := (db.BackupOptions{Dumper: "go"}).Validate(); err == nil {
+		t.Error("Validate() should reject an unknown dumper")
+	}
+}
+
+// TestBuiltinDump_Fallback tests that backups without the client tools use the built-in dumper,
+// which fails on connecting to a closed port rather than on the missing tool, and writes nothing
+func TestBuiltinDump_Fallback(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	outDir := t.TempDir()
+	conn := db.ConnOptions{Port: "1"}
+
+	backups := map[string]func() error{
+		"mysql": func() error {
+			return db.BackupMySQLWithOptions("127.0.0.1", "root", "", "shop", outDir, db.BackupTypeFull, db.BackupOptions{Conn: conn})
+		},
+		"postgres": func() error {
+			return db.BackupPostgresWithOptions("127.0.0.1", "1", "postgres", "", "shop", outDir, db.BackupTypeFull, db.BackupOptions{})
+		},
+		"mongodb": func() error {
+			return db.BackupMongoWithOptions("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=200", "shop", outDir, db.BackupOptions{})
+		},
+	}
+	for engine, backup := range backups {
+		err := backup()
+		if err == nil || !strings.Contains(err.Error(), "built-in dump failed") {
+			t.Errorf("%s: expected the built-in dumper to fail connecting, got %v", engine, err)
+		}
+	}
+	if entries, _ := os.ReadDir(outDir); len(entries) > 0 {
+		t.Errorf("failed built-in dumps left %d files behind", len(entries))
+	}
+}
+
+// TestBuiltinDump_ToolRequired tests that --dumper tool still needs the client tool
+func TestBuiltinDump_ToolRequired(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	opts := db.BackupOptions{Dumper: db.DumperTool}
+	if err := db.BackupMySQLWithOptions("127.0.0.1", "root", "", "shop", t.TempDir(), db.BackupTypeFull, opts); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("MySQL: expected a missing mysqldump error, got %v", err)
+	}
+	if err := db.BackupPostgresWithOptions("127.0.0.1", "1", "postgres", "", "shop", t.TempDir(), db.BackupTypeFull, opts); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("PostgreSQL: expected a missing pg_dump error, got %v", err)
+	}
+}
+
+// TestBuiltinDump_Unsupported tests the options only the client tools support
+func TestBuiltinDump_Unsupported(t *testing.T) {
+	builtin := db.BackupOptions{Dumper: db.DumperBuiltin}
+	if err := db.BackupMySQLWithOptions("127.0.0.1", "root", "", "shop", t.TempDir(), db.BackupTypeIncremental, builtin); err == nil || !strings.Contains(err.Error(), "full backups only") {
+		t.Errorf("expected incremental backups to be rejected, got %v", err)
+	}
+	builtin.Jobs = 4
+	if err := db.BackupPostgresWithOptions("127.0.0.1", "1", "postgres", "", "shop", t.TempDir(), db.BackupTypeFull, builtin); err == nil || !strings.Contains(err.Error(), "--jobs") {
+		t.Errorf("expected --jobs to be rejected, got %v", err)
+	}
+}
+
+// integrationParams returns connection params for a test server of engine, taken from
+// DBX_TEST_<ENGINE>_HOST/_PORT/_USER/_PASSWORD, and skips the test when the restore client isn't
+// installed or nothing answers there
+func integrationParams(t *testing.T, engine, client, port, user string) map[string]string {
+	t.Helper()
+	if _, err := exec.LookPath(client); err != nil {
+		t.Skipf("Skipping test: %s not found in PATH", client)
+	}
+	prefix := "DBX_TEST_" + strings.ToUpper(engine) + "_"
+	env := func(name, fallback string) string {
+		if v := os.Getenv(prefix + name); v != "" {
+			return v
+		}
+		return fallback
+	}
+	params := map[string]string{
+		"host":    env("HOST", "127.0.0.1"),
+		"port":    env("PORT", port),
+		"user":    env("USER", user),
+		"pass":    env("PASSWORD", "password"),
+		"timeout": "2s",
+	}
+	if err := db.TestConnection(engine, params); err != nil {
+		t.Skipf("Skipping test: no %s server at %s:%s (%v)", engine, params["host"], params["port"], err)
+	}
+	return params
+}
+
+// dumpFile returns the single .sql file a backup wrote to dir
+func dumpFile(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .sql dump in %s, found %v", dir, files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read dump: %v", err)
+	}
+	return files[0], string(data)
+}
+
+// roundTripStrings are values that need escaping in INSERT statements or COPY blocks
+var roundTripStrings = []string{
+	"plain",
+	"O'Brien",
+	`say "hi"`,
+	`C:\temp\new`,
+	"line one\nline two\r\n",
+	"tab\tseparated",
+	"\\.",
+	"\\.\nafter the COPY terminator",
+	"naïve – ✓",
+	"",
+}
+
+// TestBuiltinDump_MySQLRoundTrip dumps a database with the built-in dumper, restores it with the
+// mysql client into another database and compares the rows, views and triggers
+func TestBuiltinDump_MySQLRoundTrip(t *testing.T) {
+	params := integrationParams(t, "mysql", "mysql", "3306", "root")
+	cfg := mysql.NewConfig()
+	cfg.User, cfg.Passwd = params["user"], params["pass"]
+	cfg.Net, cfg.Addr = "tcp", net.JoinHostPort(params["host"], params["port"])
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := sql.OpenDB(connector)
+
+	const src, dst = "dbx_builtin_src", "dbx_builtin_dst"
+	for _, stmt := range []string{
+		"DROP DATABASE IF EXISTS " + src, "DROP DATABASE IF EXISTS " + dst,
+		"CREATE DATABASE " + src + " CHARACTER SET utf8mb4", "CREATE DATABASE " + dst + " CHARACTER SET utf8mb4",
+		"CREATE TABLE " + src + ".items (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(200), note TEXT, data BLOB," +
+			" code VARBINARY(8), flag BIT(3), price DECIMAL(10,2), created DATETIME, doc JSON," +
+			" total DECIMAL(12,2) AS (price * 2) STORED)",
+		"CREATE DEFINER=CURRENT_USER VIEW " + src + ".item_names AS SELECT id, name FROM " + src + ".items",
+		"CREATE DEFINER=CURRENT_USER TRIGGER " + src + ".items_note BEFORE INSERT ON " + src + ".items" +
+			" FOR EACH ROW SET NEW.note = COALESCE(NEW.note, 'from trigger')",
+	} {
+		if _, err := server.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	t.Cleanup(func() {
+		server.Exec("DROP DATABASE IF EXISTS " + src)
+		server.Exec("DROP DATABASE IF EXISTS " + dst)
+		server.Close()
+	})
+
+	insert := "INSERT INTO " + src + ".items (name, note, data, code, flag, price, created, doc) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	for i, s := range append(roundTripStrings, "NUL\x00inside", "Ctrl-Z\x1a") {
+		if _, err := server.Exec(insert, s, s+"\\", binary[i:], []byte{0, 0x1a, '\'', '\\'}, i%8, "19.99", "2024-02-29 23:59:59", `{"s": "a\"b\\c"}`); err != nil {
+			t.Fatalf("insert %q: %v", s, err)
+		}
+	}
+	if _, err := server.Exec("INSERT INTO " + src + ".items (id) VALUES (1000)"); err != nil {
+		t.Fatalf("insert NULLs: %v", err)
+	}
+
+	outDir := t.TempDir()
+	conn := db.ConnOptions{Port: params["port"]}
+	if err := db.BackupMySQLWithOptions(params["host"], params["user"], params["pass"], src, outDir, db.BackupTypeFull, db.BackupOptions{Dumper: db.DumperBuiltin, Conn: conn}); err != nil {
+		t.Fatalf("BackupMySQLWithOptions() error = %v", err)
+	}
+	file, dump := dumpFile(t, outDir)
+	if strings.Contains(dump, "DEFINER=") {
+		t.Error("dump still holds DEFINER clauses")
+	}
+	for _, want := range []string{"CREATE TABLE `items`", "VIEW `item_names`", "DELIMITER ;;", "CREATE TRIGGER `items_note`"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q", want)
+		}
+	}
+
+	if err := db.RestoreMySQLWithOptions(params["host"], params["user"], params["pass"], dst, file, db.RestoreOptions{Conn: conn}); err != nil {
+		t.Fatalf("RestoreMySQLWithOptions() error = %v", err)
+	}
+	query := "SELECT id, name, note, HEX(data), HEX(code), flag + 0, price, created, doc, total FROM %s.items ORDER BY id"
+	want, got := queryRows(t, server, fmt.Sprintf(query, src)), queryRows(t, server, fmt.Sprintf(query, dst))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restored rows differ:\n got %q\nwant %q", got, want)
+	}
+	var views, triggers int
+	server.QueryRow("SELECT COUNT(*) FROM " + dst + ".item_names").Scan(&views)
+	server.QueryRow("SELECT COUNT(*) FROM information_schema.triggers WHERE trigger_schema = ?", dst).Scan(&triggers)
+	if views != len(want) || triggers != 1 {
+		t.Errorf("restored view has %d rows and %d triggers, want %d rows and 1 trigger", views, triggers, len(want))
+	}
+}
+
+// queryRows returns the rows of a query as strings, "NULL" for NULL
+func queryRows(t *testing.T, conn *sql.DB, query string) [][]string {
+	t.Helper()
+	rows, err := conn.Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	var result [][]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = "NULL"
+			if v.Valid {
+				row[i] = v.String
+			}
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// TestBuiltinDump_PostgresRoundTrip dumps a database with the built-in dumper, checks its COPY blocks,
+// restores it with psql into another database and compares the rows and sequence positions
+func TestBuiltinDump_PostgresRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("pg_restore"); err != nil {
+		t.Skip("Skipping test: pg_restore not found in PATH")
+	}
+	params := integrationParams(t, "postgres", "psql", "5432", "postgres")
+	ctx := context.Background()
+	connect := func(database string) *pgx.Conn {
+		t.Helper()
+		conn, err := pgx.Connect(ctx, fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s",
+			params["host"], params["port"], params["user"], params["pass"], database))
+		if err != nil {
+			t.Fatalf("connect to %s: %v", database, err)
+		}
+		return conn
+	}
+	server := connect("postgres")
+
+	const src, dst = "dbx_builtin_src", "dbx_builtin_dst"
+	for _, stmt := range []string{
+		"DROP DATABASE IF EXISTS " + src, "DROP DATABASE IF EXISTS " + dst,
+		"CREATE DATABASE " + src + " ENCODING 'UTF8' TEMPLATE template0", "CREATE DATABASE " + dst + " ENCODING 'UTF8' TEMPLATE template0",
+	} {
+		if _, err := server.Exec(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	t.Cleanup(func() {
+		server.Exec(ctx, "DROP DATABASE IF EXISTS "+src)
+		server.Exec(ctx, "DROP DATABASE IF EXISTS "+dst)
+		server.Close(ctx)
+	})
+
+	source := connect(src)
+	defer source.Close(ctx)
+	for _, stmt := range []string{
+		`CREATE TABLE items (id serial PRIMARY KEY, name text, data bytea, tags text[], doc jsonb, price numeric(10,2),
+			created timestamptz, total numeric GENERATED ALWAYS AS (price * 2) STORED)`,
+		`CREATE TABLE "Mixed Case" (id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY, "Value" text)`,
+		"CREATE VIEW item_names AS SELECT id, name FROM items",
+	} {
+		if _, err := source.Exec(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	for i, s := range roundTripStrings {
+		if _, err := source.Exec(ctx, "INSERT INTO items (name, data, tags, doc, price, created) VALUES ($1, $2, $3, $4, 19.99, '2024-02-29 23:59:59+02')",
+			s, binary[i:], []string{s, `back\slash`, "comma,quote\""}, `{"s": "a\"b\\c\td"}`); err != nil {
+			t.Fatalf("insert %q: %v", s, err)
+		}
+		if _, err := source.Exec(ctx, `INSERT INTO "Mixed Case" ("Value") VALUES ($1)`, s); err != nil {
+			t.Fatalf("insert %q: %v", s, err)
+		}
+	}
+	if _, err := source.Exec(ctx, "INSERT INTO items (id) VALUES (1000)"); err != nil {
+		t.Fatalf("insert NULLs: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if err := db.BackupPostgresWithOptions(params["host"], params["port"], params["user"], params["pass"], src, outDir, db.BackupTypeFull, db.BackupOptions{Dumper: db.DumperBuiltin}); err != nil {
+		t.Fatalf("BackupPostgresWithOptions() error = %v", err)
+	}
+	file, dump := dumpFile(t, outDir)
+	for _, want := range []string{
+		"COPY public.items (id, name, data, tags, doc, price, created) FROM stdin;\n",
+		`COPY public."Mixed Case" (id, "Value") FROM stdin;` + "\n",
+		"\\.\n",
+		"SELECT pg_catalog.setval(",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q", want)
+		}
+	}
+
+	if err := db.RestorePostgres(params["host"], params["port"], params["user"], params["pass"], dst, file); err != nil {
+		t.Fatalf("RestorePostgres() error = %v", err)
+	}
+	restored := connect(dst)
+	defer restored.Close(ctx)
+	for _, query := range []string{
+		"SELECT id, name, encode(data, 'hex'), tags::text, doc::text, price::text, created::text, total::text FROM items ORDER BY id",
+		`SELECT id, "Value" FROM "Mixed Case" ORDER BY id`,
+		"SELECT id, name FROM item_names ORDER BY id",
+	} {
+		want, got := pgRows(t, source, query), pgRows(t, restored, query)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: restored rows differ:\n got %q\nwant %q", query, got, want)
+		}
+	}
+	// The sequences continue where they were, for serial and identity columns alike
+	for _, stmt := range []string{"INSERT INTO items (name) VALUES ('next') RETURNING id", `INSERT INTO "Mixed Case" ("Value") VALUES ('next') RETURNING id`} {
+		var want, got int64
+		source.QueryRow(ctx, stmt).Scan(&want)
+		if err := restored.QueryRow(ctx, stmt).Scan(&got); err != nil || got != want {
+			t.Errorf("%s = %d (%v), want %d", stmt, got, err, want)
+		}
+	}
+}
+
+// pgRows returns the rows of a query as strings, "NULL" for NULL
+func pgRows(t *testing.T, conn *pgx.Conn, query string) [][]string {
+	t.Helper()
+	rows, err := conn.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	var result [][]string
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = "NULL"
+			if v != nil {
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
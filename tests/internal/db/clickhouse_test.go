@@ -0,0 +1,182 @@
+package db_test
+
+import (
+	"archive/zip"
+	"dbx/internal/db"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClickHouseClient logs each command line to LOG and the password from its config file, and
+// answers the queries dbx sends: an analytics database with two tables and a materialized view.
+// INSERT data is appended to LOG.data.
+const fakeClickHouseClient = `#!/bin/sh
+echo "$*" >> LOG
+query=""
+config=""
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --query) query="$2"; shift ;;
+    --config-file) config="$2"; shift ;;
+  esac
+  shift
+done
+[ -n "$config" ] && grep -o '<password>[^<]*</password>' "$config" >> LOG
+case "$query" in
+  *system.tables*) printf 'events\tMergeTree\nevents_mv\tMaterializedView\nusers\tReplacingMergeTree\n' ;;
+  "SHOW CREATE TABLE"*events_mv*) echo 'CREATE MATERIALIZED VIEW analytics.events_mv TO analytics.users AS SELECT id FROM analytics.events' ;;
+  "SHOW CREATE TABLE"*) echo 'CREATE TABLE analytics.events (id UInt64) ENGINE = MergeTree ORDER BY id' ;;
+  "SELECT *"*) printf 'NATIVE' ;;
+  INSERT*) cat >> LOG.data ;;
+esac
+`
+
+// backupFakeClickHouse backs up the fake analytics database and returns the bundle path
+func backupFakeClickHouse(t *testing.T, opts db.BackupOptions) string {
+	t.Helper()
+	out := t.TempDir()
+	if err := db.BackupClickHouseWithOptions("ch.internal", "", "default", "secret", "analytics", out, opts); err != nil {
+		t.Fatalf("BackupClickHouseWithOptions() error = %v", err)
+	}
+	bundles, _ := filepath.Glob(filepath.Join(out, "analytics-full_*.zip"))
+	if len(bundles) != 1 {
+		t.Fatalf("expected one bundle, got %v", bundles)
+	}
+	return bundles[0]
+}
+
+// readClickHouseBundle returns the index of a ClickHouse bundle and the names of its files
+func readClickHouseBundle(t *testing.T, bundle string) (db.ClickHouseIndex, []string) {
+	t.Helper()
+	r, err := zip.OpenReader(bundle)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer r.Close()
+	var index db.ClickHouseIndex
+	var names []string
+	for _, f := range r.File {
+		names = append(names, filepath.Base(f.Name))
+		if filepath.Base(f.Name) != "dbx-clickhouse.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to read bundle index: %v", err)
+		}
+		err = json.NewDecoder(rc).Decode(&index)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to parse bundle index: %v", err)
+		}
+	}
+	return index, names
+}
+
+// TestBackupClickHouse tests exporting the selected tables, with views last and the password kept
+// off the command line
+func TestBackupClickHouse(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "clickhouse-client", fakeClickHouseClient)
+
+	bundle := backupFakeClickHouse(t, db.BackupOptions{Jobs: 2, ExcludeTables: []string{"users"}})
+	index, names := readClickHouseBundle(t, bundle)
+	if index.Database != "analytics" || len(index.Tables) != 2 {
+		t.Fatalf("index = %+v", index)
+	}
+	if index.Tables[0].Name != "events" || index.Tables[0].Data == "" {
+		t.Errorf("first entry = %+v, want the events table with data", index.Tables[0])
+	}
+	if view := index.Tables[1]; view.Name != "events_mv" || view.Engine != "MaterializedView" || view.Data != "" {
+		t.Errorf("last entry = %+v, want the schema-only view", view)
+	}
+	if len(names) != 4 {
+		t.Errorf("bundle files = %v, want the index, two schemas and one data file", names)
+	}
+
+	log, _ := os.ReadFile(logFile)
+	if strings.Contains(string(log), "users") {
+		t.Error("excluded table was exported")
+	}
+	lines := readLines(t, logFile)
+	if !contains(lines, "<password>secret</password>") {
+		t.Error("password not passed in the client config file")
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "--password") || strings.Contains(line, "--host") && strings.Contains(line, "secret") {
+			t.Errorf("password on the command line: %s", line)
+		}
+	}
+	if _, err := os.Stat(db.ManifestPath(bundle)); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+}
+
+// TestBackupClickHouse_RejectsMasking tests that masking rules are refused before connecting
+func TestBackupClickHouse_RejectsMasking(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "clickhouse-client", fakeClickHouseClient)
+
+	err := db.BackupClickHouseWithOptions("localhost", "", "default", "", "analytics", t.TempDir(), db.BackupOptions{MaskRules: "rules.yaml"})
+	if err == nil {
+		t.Fatal("BackupClickHouseWithOptions() with mask rules should fail")
+	}
+	if _, err := os.Stat(logFile); err == nil {
+		t.Error("clickhouse-client was run")
+	}
+}
+
+// TestRestoreClickHouse tests restoring into another database: statements are pointed at it and
+// the view is created after the tables are filled
+func TestRestoreClickHouse(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "clickhouse-client", fakeClickHouseClient)
+	bundle := backupFakeClickHouse(t, db.BackupOptions{})
+	_ = os.Remove(logFile)
+
+	if err := db.RestoreClickHouseWithOptions("localhost", "9000", "default", "", "staging", bundle, db.RestoreOptions{Jobs: 2}); err != nil {
+		t.Fatalf("RestoreClickHouseWithOptions() error = %v", err)
+	}
+	log, _ := os.ReadFile(logFile)
+	text := string(log)
+	if !strings.Contains(text, "CREATE DATABASE IF NOT EXISTS `staging`") {
+		t.Error("target database not created")
+	}
+	view := strings.Index(text, "CREATE MATERIALIZED VIEW `staging`.events_mv TO `staging`.users AS SELECT id FROM `staging`.events")
+	if view < 0 {
+		t.Fatalf("view not retargeted:\n%s", text)
+	}
+	if insert := strings.LastIndex(text, "INSERT INTO"); insert < 0 || insert > view {
+		t.Error("view created before the tables were filled")
+	}
+	if strings.Count(text, "INSERT INTO") != 2 {
+		t.Errorf("expected two tables filled:\n%s", text)
+	}
+	if data, _ := os.ReadFile(logFile + ".data"); string(data) != "NATIVENATIVE" {
+		t.Errorf("inserted data = %q", data)
+	}
+}
+
+// TestRestoreClickHouseTable tests restoring one table into the database it was backed up from
+func TestRestoreClickHouseTable(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "clickhouse-client", fakeClickHouseClient)
+	bundle := backupFakeClickHouse(t, db.BackupOptions{})
+	_ = os.Remove(logFile)
+
+	if err := db.RestoreClickHouseTableWithOptions("localhost", "", "default", "", "", bundle, "users", db.RestoreOptions{}); err != nil {
+		t.Fatalf("RestoreClickHouseTableWithOptions() error = %v", err)
+	}
+	log, _ := os.ReadFile(logFile)
+	text := string(log)
+	if !strings.Contains(text, "--database analytics --query INSERT INTO `users` FORMAT Native") {
+		t.Errorf("users not restored into analytics:\n%s", text)
+	}
+	if strings.Contains(text, "INSERT INTO `events`") || strings.Contains(text, "events_mv") {
+		t.Errorf("other tables restored:\n%s", text)
+	}
+
+	err := db.RestoreClickHouseTableWithOptions("localhost", "", "default", "", "", bundle, "missing", db.RestoreOptions{})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RestoreClickHouseTableWithOptions() error = %v, want table not found", err)
+	}
+}
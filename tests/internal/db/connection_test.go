@@ -3,6 +3,7 @@ package db_test
 import (
 	"dbx/internal/db"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -96,3 +97,82 @@ func TestTestConnection_CaseInsensitive(t *testing.T) {
 	}
 }
 
+
+// TestDiagnoseConnection_SQLite tests the SQLite file check
+func TestDiagnoseConnection_SQLite(t *testing.T) {
+	testDB := t.TempDir() + "/test.db"
+	os.WriteFile(testDB, []byte("SQLite format 3\x00"), 0644)
+
+	diag, err := db.DiagnoseConnection("sqlite", map[string]string{"path": testDB})
+	if err != nil {
+		t.Fatalf("DiagnoseConnection() error = %v", err)
+	}
+	if diag.Engine != "sqlite" {
+		t.Errorf("Engine = %q, want sqlite", diag.Engine)
+	}
+
+	notSQLite := t.TempDir() + "/notes.txt"
+	os.WriteFile(notSQLite, []byte("hello"), 0644)
+	if _, err := db.DiagnoseConnection("sqlite", map[string]string{"path": notSQLite}); err == nil {
+		t.Error("DiagnoseConnection() should reject a file that isn't a SQLite database")
+	}
+}
+
+// TestDiagnoseConnection_InvalidTimeout tests that a bad timeout is refused before connecting
+func TestDiagnoseConnection_InvalidTimeout(t *testing.T) {
+	if _, err := db.DiagnoseConnection("mysql", map[string]string{"timeout": "soon"}); err == nil {
+		t.Error("DiagnoseConnection() should reject an invalid timeout")
+	}
+}
+
+// TestDiagnoseConnection_Refused tests that an unreachable server fails promptly with the driver's error
+func TestDiagnoseConnection_Refused(t *testing.T) {
+	params := map[string]string{"host": "127.0.0.1", "port": "1", "user": "root", "timeout": "2s"}
+	for _, engine := range []string{"mysql", "postgres"} {
+		if _, err := db.DiagnoseConnection(engine, params); err == nil || !strings.Contains(err.Error(), "connection failed") {
+			t.Errorf("DiagnoseConnection(%s) error = %v, want connection failure", engine, err)
+		}
+	}
+}
+
+// TestMissingMySQLPrivileges tests reading dump privileges from SHOW GRANTS output
+func TestMissingMySQLPrivileges(t *testing.T) {
+	tests := []struct {
+		name   string
+		grants []string
+		want   []string
+	}{
+		{"all privileges", []string{"GRANT ALL PRIVILEGES ON *.* TO `root`@`localhost` WITH GRANT OPTION"}, nil},
+		{
+			"backup account",
+			[]string{
+				"GRANT PROCESS, REPLICATION CLIENT ON *.* TO `backup`@`%`",
+				"GRANT SELECT, SHOW VIEW, TRIGGER, LOCK TABLES ON `shop`.* TO `backup`@`%`",
+			},
+			nil,
+		},
+		{"other database", []string{"GRANT SELECT ON `crm`.* TO `app`@`%`"}, []string{"SELECT", "SHOW VIEW", "TRIGGER", "LOCK TABLES", "PROCESS", "REPLICATION CLIENT"}},
+		{"database-level all", []string{"GRANT ALL PRIVILEGES ON `shop`.* TO `app`@`%`"}, []string{"PROCESS", "REPLICATION CLIENT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := db.MissingMySQLPrivileges(tt.grants, "shop")
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("MissingMySQLPrivileges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMissingMongoRoles tests which roles allow a mongodump of a database
+func TestMissingMongoRoles(t *testing.T) {
+	if missing := db.MissingMongoRoles([]string{"backup@admin"}, "shop"); missing != nil {
+		t.Errorf("backup role reported missing %v", missing)
+	}
+	if missing := db.MissingMongoRoles([]string{"read@shop"}, "shop"); missing != nil {
+		t.Errorf("read on the database reported missing %v", missing)
+	}
+	if missing := db.MissingMongoRoles([]string{"readWrite@crm"}, "shop"); len(missing) != 1 {
+		t.Errorf("role on another database: missing = %v, want one entry", missing)
+	}
+}
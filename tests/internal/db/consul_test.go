@@ -0,0 +1,195 @@
+package db_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"dbx/internal/db"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// consulSnapshot builds a snapshot archive as Consul writes it: meta.json, state.bin and their
+// SHA256SUMS in a gzipped tar; corrupt changes state.bin after it was summed
+func consulSnapshot(t *testing.T, corrupt bool) []byte {
+	t.Helper()
+	files := []struct{ name, data string }{
+		{"meta.json", `{"Version":1,"ID":"2-1234-1700000000000","Index":1234,"Term":2}`},
+		{"state.bin", "raft state"},
+	}
+	var sums strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256([]byte(f.data)), f.name)
+	}
+	if corrupt {
+		files[1].data = "tampered state"
+	}
+	files = append(files, struct{ name, data string }{"SHA256SUMS", sums.String()})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := archive.WriteHeader(&tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// fakeConsul serves the snapshot API and records the token and restored snapshot it receives
+type fakeConsul struct {
+	snapshot []byte
+	token    string
+	restored []byte
+}
+
+// start serves the fake agent and returns its host and port
+func (c *fakeConsul) start(t *testing.T) (string, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.token = r.Header.Get("X-Consul-Token")
+		switch {
+		case r.URL.Path == "/v1/snapshot" && r.Method == http.MethodGet:
+			w.Header().Set("X-Consul-Index", "1234")
+			_, _ = w.Write(c.snapshot)
+		case r.URL.Path == "/v1/snapshot" && r.Method == http.MethodPut:
+			c.restored, _ = io.ReadAll(r.Body)
+		case r.URL.Path == "/v1/status/leader":
+			_, _ = w.Write([]byte(`"10.0.0.1:8300"`))
+		case r.URL.Path == "/v1/agent/self":
+			_, _ = w.Write([]byte(`{"Config":{"Version":"1.17.2","NodeName":"consul-0"}}`))
+		default:
+			http.Error(w, "Permission denied", http.StatusForbidden)
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	return u.Hostname(), u.Port()
+}
+
+// TestBackupConsul tests a snapshot backup: the ACL token, verification and manifest
+func TestBackupConsul(t *testing.T) {
+	agent := &fakeConsul{snapshot: consulSnapshot(t, false)}
+	host, port := agent.start(t)
+	out := t.TempDir()
+
+	if err := db.BackupConsul(host, port, "mgmt-token", "", out); err != nil {
+		t.Fatalf("BackupConsul() error = %v", err)
+	}
+	if agent.token != "mgmt-token" {
+		t.Errorf("X-Consul-Token = %q, want mgmt-token", agent.token)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(out, "consul_"+host+"_"+port+"_*.snap"))
+	if len(matches) != 1 {
+		entries, _ := os.ReadDir(out)
+		t.Fatalf("expected one snapshot, got %v", entries)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil || !bytes.Equal(data, agent.snapshot) {
+		t.Errorf("snapshot not saved as served: %v", err)
+	}
+	manifest, err := db.LoadManifest(matches[0])
+	if err != nil || manifest == nil {
+		t.Fatalf("LoadManifest() = %v, %v", manifest, err)
+	}
+	if manifest.DBType != "consul" || manifest.Revision != 1234 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+// TestBackupConsul_TokenFromEnv tests that CONSUL_HTTP_TOKEN is used without --password
+func TestBackupConsul_TokenFromEnv(t *testing.T) {
+	agent := &fakeConsul{snapshot: consulSnapshot(t, false)}
+	host, port := agent.start(t)
+	t.Setenv("CONSUL_HTTP_TOKEN", "env-token")
+
+	if err := db.BackupConsul(host, port, "", "consul", t.TempDir()); err != nil {
+		t.Fatalf("BackupConsul() error = %v", err)
+	}
+	if agent.token != "env-token" {
+		t.Errorf("X-Consul-Token = %q, want env-token", agent.token)
+	}
+}
+
+// TestBackupConsul_CorruptSnapshot tests that a snapshot failing its checksums is removed
+func TestBackupConsul_CorruptSnapshot(t *testing.T) {
+	agent := &fakeConsul{snapshot: consulSnapshot(t, true)}
+	host, port := agent.start(t)
+	out := t.TempDir()
+
+	err := db.BackupConsul(host, port, "", "", out)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch for state.bin") {
+		t.Fatalf("BackupConsul() error = %v, want a checksum mismatch", err)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("failed backup left files behind: %v", entries)
+	}
+}
+
+// TestRestoreConsul tests that a verified snapshot is uploaded unchanged
+func TestRestoreConsul(t *testing.T) {
+	agent := &fakeConsul{}
+	host, port := agent.start(t)
+	snapshot := filepath.Join(t.TempDir(), "consul.snap")
+	if err := os.WriteFile(snapshot, consulSnapshot(t, false), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RestoreConsul(host, port, "mgmt-token", snapshot); err != nil {
+		t.Fatalf("RestoreConsul() error = %v", err)
+	}
+	want, _ := os.ReadFile(snapshot)
+	if !bytes.Equal(agent.restored, want) || agent.token != "mgmt-token" {
+		t.Errorf("restored %d bytes with token %q, want the snapshot's %d", len(agent.restored), agent.token, len(want))
+	}
+}
+
+// TestRestoreConsul_Corrupt tests that a corrupt snapshot is never uploaded
+func TestRestoreConsul_Corrupt(t *testing.T) {
+	agent := &fakeConsul{}
+	host, port := agent.start(t)
+	snapshot := filepath.Join(t.TempDir(), "consul.snap")
+	if err := os.WriteFile(snapshot, consulSnapshot(t, true), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RestoreConsul(host, port, "", snapshot); err == nil {
+		t.Error("RestoreConsul() should fail for a corrupt snapshot")
+	}
+	if agent.restored != nil {
+		t.Error("corrupt snapshot was uploaded")
+	}
+}
+
+// TestDiagnoseConnection_Consul tests reading the agent version with the ACL token
+func TestDiagnoseConnection_Consul(t *testing.T) {
+	agent := &fakeConsul{}
+	host, port := agent.start(t)
+
+	diag, err := db.DiagnoseConnection("consul", map[string]string{"host": host, "port": port, "pass": "token"})
+	if err != nil {
+		t.Fatalf("DiagnoseConnection() error = %v", err)
+	}
+	if diag.Engine != "consul" || diag.ServerVersion != "1.17.2" || agent.token != "token" {
+		t.Errorf("diagnosis = %+v (token %q)", diag, agent.token)
+	}
+}
@@ -0,0 +1,183 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeCluster serves the snapshot API of a single-node cluster. Snapshots report IN_PROGRESS on
+// their first status check and finalState after that; requests are recorded as "METHOD path".
+type fakeCluster struct {
+	finalState string
+	repository string // registered fs location
+	requests   []string
+	bodies     map[string]any // decoded request bodies by "METHOD path"
+	auth       string
+	polls      int
+}
+
+// start serves the fake cluster and returns its host and port
+func (c *fakeCluster) start(t *testing.T) (string, string) {
+	t.Helper()
+	c.bodies = make(map[string]any)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.Path
+		c.requests = append(c.requests, key)
+		c.auth = r.Header.Get("Authorization")
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			var body any
+			_ = json.Unmarshal(data, &body)
+			c.bodies[key] = body
+		}
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.URL.Path == "/":
+			_, _ = w.Write([]byte(`{"name":"node-1","version":{"number":"2.13.0","distribution":"opensearch"}}`))
+		case len(parts) == 2 && r.Method == http.MethodPut:
+			if body, ok := c.bodies[key].(map[string]any); ok {
+				c.repository, _ = body["settings"].(map[string]any)["location"].(string)
+			}
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case len(parts) == 2 && r.Method == http.MethodGet:
+			if c.repository == "" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"type":"repository_missing_exception","reason":"[dbx] missing"},"status":404}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"dbx":{"type":"fs"}}`))
+		case len(parts) == 3 && parts[2] == "_all":
+			_, _ = w.Write([]byte(`{"snapshots":[{"snapshot":"nightly_1","state":"SUCCESS"},{"snapshot":"nightly_2","state":"SUCCESS"}]}`))
+		case len(parts) == 3 && r.Method == http.MethodPut:
+			_, _ = w.Write([]byte(`{"accepted":true}`))
+		case len(parts) == 3 && r.Method == http.MethodGet:
+			c.polls++
+			state, failed := "IN_PROGRESS", 0
+			if c.polls > 1 {
+				state = c.finalState
+			}
+			if state != "SUCCESS" && state != "IN_PROGRESS" {
+				failed = 1
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"snapshots": []any{map[string]any{
+				"snapshot": parts[2], "state": state, "indices": []string{"logs-1", "logs-2"},
+				"shards":   map[string]int{"total": 2, "failed": failed, "successful": 2 - failed},
+				"failures": []any{},
+			}}})
+		case len(parts) == 4 && parts[3] == "_restore":
+			_, _ = w.Write([]byte(`{"snapshot":{"snapshot":"nightly_1","indices":["restored_logs-1"],"shards":{"total":1,"failed":0,"successful":1}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	return u.Hostname(), u.Port()
+}
+
+// TestBackupElasticsearch tests registering a repository, starting a snapshot and polling it to completion
+func TestBackupElasticsearch(t *testing.T) {
+	cluster := &fakeCluster{finalState: "SUCCESS"}
+	host, port := cluster.start(t)
+
+	opts := db.ElasticsearchOptions{Repository: "dbx", RepositoryPath: "/mnt/snapshots", Indices: []string{"logs-*"}}
+	if err := db.BackupElasticsearch(host, port, "elastic", "changeme", "Nightly", opts); err != nil {
+		t.Fatalf("BackupElasticsearch() error = %v", err)
+	}
+	if cluster.repository != "/mnt/snapshots" {
+		t.Errorf("repository registered at %q, want /mnt/snapshots", cluster.repository)
+	}
+	if cluster.polls != 2 {
+		t.Errorf("snapshot status polled %d times, want 2", cluster.polls)
+	}
+	if !strings.HasPrefix(cluster.auth, "Basic ") {
+		t.Errorf("Authorization = %q, want basic auth", cluster.auth)
+	}
+
+	var create map[string]any
+	for key, body := range cluster.bodies {
+		if strings.HasPrefix(key, "PUT /_snapshot/dbx/nightly_") {
+			create, _ = body.(map[string]any)
+		}
+	}
+	if create == nil {
+		t.Fatalf("no lowercase snapshot created: %v", cluster.requests)
+	}
+	if create["indices"] != "logs-*" || create["include_global_state"] != false {
+		t.Errorf("snapshot request = %v", create)
+	}
+}
+
+// TestBackupElasticsearch_MissingRepository tests that an unregistered repository is reported
+func TestBackupElasticsearch_MissingRepository(t *testing.T) {
+	cluster := &fakeCluster{finalState: "SUCCESS"}
+	host, port := cluster.start(t)
+
+	err := db.BackupElasticsearch(host, port, "", "", "", db.ElasticsearchOptions{})
+	if err == nil || !strings.Contains(err.Error(), "repository dbx not found") {
+		t.Fatalf("BackupElasticsearch() error = %v, want a missing repository", err)
+	}
+	if cluster.polls != 0 {
+		t.Error("snapshot started without a repository")
+	}
+}
+
+// TestBackupElasticsearch_Partial tests that a snapshot that didn't save every shard fails
+func TestBackupElasticsearch_Partial(t *testing.T) {
+	cluster := &fakeCluster{finalState: "PARTIAL"}
+	host, port := cluster.start(t)
+
+	opts := db.ElasticsearchOptions{RepositoryPath: "/mnt/snapshots"}
+	err := db.BackupElasticsearch(host, port, "", "api-key", "", opts)
+	if err == nil || !strings.Contains(err.Error(), "PARTIAL") {
+		t.Fatalf("BackupElasticsearch() error = %v, want a PARTIAL snapshot", err)
+	}
+	if cluster.auth != "ApiKey api-key" {
+		t.Errorf("Authorization = %q, want the API key", cluster.auth)
+	}
+}
+
+// TestRestoreElasticsearch tests restoring selected indices under new names
+func TestRestoreElasticsearch(t *testing.T) {
+	cluster := &fakeCluster{}
+	host, port := cluster.start(t)
+
+	opts := db.ElasticsearchOptions{Indices: []string{"logs-1"}, RenameReplacement: "restored_$1"}
+	if err := db.RestoreElasticsearch(host, port, "", "", "nightly_1", opts); err != nil {
+		t.Fatalf("RestoreElasticsearch() error = %v", err)
+	}
+	body, _ := cluster.bodies["POST /_snapshot/dbx/nightly_1/_restore"].(map[string]any)
+	if body["indices"] != "logs-1" || body["rename_pattern"] != "(.+)" || body["rename_replacement"] != "restored_$1" {
+		t.Errorf("restore request = %v", body)
+	}
+}
+
+// TestRestoreElasticsearch_ListsSnapshots tests that a restore without a snapshot lists the available ones
+func TestRestoreElasticsearch_ListsSnapshots(t *testing.T) {
+	cluster := &fakeCluster{}
+	host, port := cluster.start(t)
+
+	err := db.RestoreElasticsearch(host, port, "", "", "", db.ElasticsearchOptions{})
+	if err == nil || !strings.Contains(err.Error(), "nightly_1, nightly_2") {
+		t.Fatalf("RestoreElasticsearch() error = %v, want the snapshot list", err)
+	}
+}
+
+// TestDiagnoseConnection_Elasticsearch tests reading the version and distribution from /
+func TestDiagnoseConnection_Elasticsearch(t *testing.T) {
+	cluster := &fakeCluster{}
+	host, port := cluster.start(t)
+
+	diag, err := db.DiagnoseConnection("elasticsearch", map[string]string{"host": host, "port": port})
+	if err != nil {
+		t.Fatalf("DiagnoseConnection() error = %v", err)
+	}
+	if diag.Engine != "opensearch" || diag.ServerVersion != "2.13.0" || diag.TLS != "off" {
+		t.Errorf("diagnosis = %+v", diag)
+	}
+}
@@ -0,0 +1,191 @@
+package db_test
+
+import (
+	"crypto/sha256"
+	"dbx/internal/db"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEtcdctl is a stand-in for etcdctl and etcdutl: snapshot save copies SNAPSHOT to the target,
+// snapshot status reports a fixed revision and key count, and snapshot restore creates --data-dir
+const fakeEtcdctl = `#!/bin/sh
+for a in "$@"; do echo "$a" >> LOG; done
+echo "user=$ETCDCTL_USER" >> LOG
+case " $* " in
+  *" snapshot save "*) for a in "$@"; do file="$a"; done; cp SNAPSHOT "$file" ;;
+  *" snapshot status "*) echo '{"hash":3735928559,"revision":42,"totalKey":7,"totalSize":4096}' ;;
+  *" snapshot restore "*)
+    for a in "$@"; do case "$a" in --data-dir=*) mkdir -p "${a#--data-dir=}/member" ;; esac; done ;;
+esac
+`
+
+// writeEtcdSnapshot writes a database of whole pages followed by its SHA-256, as etcd sends
+// snapshots; corrupt flips a byte after the checksum was taken
+func writeEtcdSnapshot(t *testing.T, path string, corrupt bool) {
+	t.Helper()
+	data := []byte(strings.Repeat("etcd-page", 512))[:4096]
+	sum := sha256.Sum256(data)
+	if corrupt {
+		data[100] ^= 0xff
+	}
+	if err := os.WriteFile(path, append(data, sum[:]...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// installFakeEtcdctl installs fakeEtcdctl under name, saving snapshot on snapshot save
+func installFakeEtcdctl(t *testing.T, name, snapshot string) string {
+	t.Helper()
+	return installFakePhysicalTool(t, name, strings.ReplaceAll(fakeEtcdctl, "SNAPSHOT", snapshot))
+}
+
+// TestBackupEtcd tests a snapshot backup: etcdctl arguments, credentials, verification and manifest
+func TestBackupEtcd(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	writeEtcdSnapshot(t, snapshot, false)
+	logFile := installFakeEtcdctl(t, "etcdctl", snapshot)
+	out := t.TempDir()
+
+	if err := db.BackupEtcd("etcd-0.internal", "", "root", "s3cret", "", out); err != nil {
+		t.Fatalf("BackupEtcd() error = %v", err)
+	}
+	args := readLines(t, logFile)
+	for _, want := range []string{"--endpoints=http://etcd-0.internal:2379", "snapshot", "save", "status", "user=root:s3cret"} {
+		if !contains(args, want) {
+			t.Errorf("etcdctl args %v missing %q", args, want)
+		}
+	}
+	if contains(args, "s3cret") {
+		t.Errorf("password passed as an argument: %v", args)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(out, "etcd_etcd-0.internal_2379_*.db.zip"))
+	if len(matches) != 1 {
+		entries, _ := os.ReadDir(out)
+		t.Fatalf("expected one compressed snapshot, got %v", entries)
+	}
+	manifest, err := db.LoadManifest(matches[0])
+	if err != nil || manifest == nil {
+		t.Fatalf("LoadManifest() = %v, %v", manifest, err)
+	}
+	if manifest.DBType != "etcd" || manifest.Revision != 42 || manifest.Keys != 7 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+// TestBackupEtcd_TLS tests the endpoint scheme and TLS flags passed to etcdctl
+func TestBackupEtcd_TLS(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	writeEtcdSnapshot(t, snapshot, false)
+	logFile := installFakeEtcdctl(t, "etcdctl", snapshot)
+
+	opts := db.BackupOptions{Conn: db.ConnOptions{TLSMode: "require"}}
+	if err := db.BackupEtcdWithOptions("10.0.0.5", "2379", "", "", "cluster", t.TempDir(), opts); err != nil {
+		t.Fatalf("BackupEtcdWithOptions() error = %v", err)
+	}
+	args := readLines(t, logFile)
+	if !contains(args, "--endpoints=https://10.0.0.5:2379") || !contains(args, "--insecure-skip-tls-verify") {
+		t.Errorf("etcdctl args %v, want an https endpoint without verification", args)
+	}
+	if contains(args, "user=:") {
+		t.Errorf("ETCDCTL_USER set without a user: %v", args)
+	}
+}
+
+// TestBackupEtcd_CorruptSnapshot tests that a snapshot failing its checksum is removed
+func TestBackupEtcd_CorruptSnapshot(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	writeEtcdSnapshot(t, snapshot, true)
+	installFakeEtcdctl(t, "etcdctl", snapshot)
+	out := t.TempDir()
+
+	err := db.BackupEtcd("localhost", "", "", "", "", out)
+	if err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("BackupEtcd() error = %v, want a checksum mismatch", err)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("failed backup left files behind: %v", entries)
+	}
+}
+
+// TestRestoreEtcd tests that a zipped snapshot is verified and restored with etcdutl, which is
+// preferred over etcdctl, as the given member
+func TestRestoreEtcd(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	writeEtcdSnapshot(t, snapshot, false)
+	installFakeEtcdctl(t, "etcdctl", snapshot)
+	out := t.TempDir()
+	if err := db.BackupEtcd("localhost", "", "", "", "etcd", out); err != nil {
+		t.Fatalf("BackupEtcd() error = %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(out, "etcd_*.db.zip"))
+	if len(matches) != 1 {
+		t.Fatalf("expected one backup, got %v", matches)
+	}
+	logFile := installFakeEtcdctl(t, "etcdutl", snapshot)
+
+	dataDir := filepath.Join(t.TempDir(), "infra0.etcd")
+	opts := db.RestoreOptions{
+		DataDir:        dataDir,
+		MemberName:     "infra0",
+		InitialCluster: "infra0=https://10.0.0.1:2380,infra1=https://10.0.0.2:2380",
+		PeerURLs:       "https://10.0.0.1:2380",
+	}
+	if err := db.RestoreEtcd(matches[0], opts); err != nil {
+		t.Fatalf("RestoreEtcd() error = %v", err)
+	}
+	args := readLines(t, logFile)
+	for _, want := range []string{"restore", "--data-dir=" + dataDir, "--name=infra0", "--initial-cluster=" + opts.InitialCluster, "--initial-advertise-peer-urls=https://10.0.0.1:2380"} {
+		if !contains(args, want) {
+			t.Errorf("etcdutl args %v missing %q", args, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "member")); err != nil {
+		t.Errorf("data directory not restored: %v", err)
+	}
+}
+
+// TestRestoreEtcd_DataDir tests that a restore needs a new or empty data directory
+func TestRestoreEtcd_DataDir(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+	writeEtcdSnapshot(t, snapshot, false)
+	installFakeEtcdctl(t, "etcdutl", snapshot)
+
+	if err := db.RestoreEtcd(snapshot, db.RestoreOptions{}); err == nil {
+		t.Error("expected an error without a data directory")
+	}
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "member"), []byte("live"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RestoreEtcd(snapshot, db.RestoreOptions{DataDir: dataDir}); err == nil {
+		t.Error("expected an error for a non-empty data directory")
+	}
+}
+
+// TestDiagnoseConnection_Etcd tests reading the server version from /version
+func TestDiagnoseConnection_Etcd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"etcdserver":"3.5.12","etcdcluster":"3.5.0"}`))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	diag, err := db.DiagnoseConnection("etcd", map[string]string{"host": u.Hostname(), "port": u.Port()})
+	if err != nil {
+		t.Fatalf("DiagnoseConnection() error = %v", err)
+	}
+	if diag.Engine != "etcd" || diag.ServerVersion != "3.5.12" || diag.TLS != "off" {
+		t.Errorf("diagnosis = %+v", diag)
+	}
+}
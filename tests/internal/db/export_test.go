@@ -0,0 +1,162 @@
+package db_test
+
+import (
+	"archive/zip"
+	"dbx/internal/db"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClickHouseExport answers the table list and one SELECT in JSONCompactEachRowWithNamesAndTypes,
+// logging each command line to LOG
+const fakeClickHouseExport = `#!/bin/sh
+echo "$*" >> LOG
+query=""
+while [ $# -gt 0 ]; do
+  [ "$1" = "--query" ] && query="$2" && shift
+  shift
+done
+case "$query" in
+  *system.tables*) printf 'events\tMergeTree\nusers\tMergeTree\n' ;;
+  *FORMAT\ JSONCompactEachRowWithNamesAndTypes)
+    echo '["id","name","at","score"]'
+    echo '["Int64","Nullable(String)","DateTime","Float64"]'
+    echo '["9007199254740993","click","2024-05-01 12:30:00",0.5]'
+    echo '["2",null,"2024-05-01 12:31:00",1]' ;;
+esac
+`
+
+// createExportSQLite creates a SQLite database with the sqlite3 CLI, skipping the test without it
+func createExportSQLite(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	path := filepath.Join(t.TempDir(), "shop.db")
+	script := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, avatar BLOB);
+INSERT INTO users VALUES (1, 'Ann, "A"', x'0102'), (2, NULL, NULL), (3, 'Bob', NULL);
+CREATE TABLE "order items" (sku TEXT);`
+	if out, err := exec.Command("sqlite3", path, script).CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 failed: %v: %s", err, out)
+	}
+	return path
+}
+
+// TestExportSQLite_CSV tests one file per table, safe file names and binary columns
+func TestExportSQLite_CSV(t *testing.T) {
+	path := createExportSQLite(t)
+	out := t.TempDir()
+	dir, err := db.ExportSQLite(path, out, db.ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportSQLite() error = %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(dir), "shop-export_") {
+		t.Errorf("export directory = %s", dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "users.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,avatar\n1,\"Ann, \"\"A\"\"\",AQI=\n2,,\n3,Bob,\n"
+	if string(data) != want {
+		t.Errorf("users.csv =\n%s\nwant\n%s", data, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "order_items.csv")); err != nil {
+		t.Errorf("order items table not exported: %v", err)
+	}
+}
+
+// TestExportSQLite_Selection tests --tables, --columns and --where with JSON Lines output
+func TestExportSQLite_Selection(t *testing.T) {
+	path := createExportSQLite(t)
+	dir, err := db.ExportSQLite(path, t.TempDir(), db.ExportOptions{
+		Format:  "jsonl",
+		Tables:  []string{"users"},
+		Columns: []string{"name", "id"},
+		Where:   "id > 1",
+	})
+	if err != nil {
+		t.Fatalf("ExportSQLite() error = %v", err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected only users.jsonl, got %d files", len(files))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "users.jsonl"))
+	want := "{\"name\":null,\"id\":2}\n{\"name\":\"Bob\",\"id\":3}\n"
+	if string(data) != want {
+		t.Errorf("users.jsonl =\n%s\nwant\n%s", data, want)
+	}
+}
+
+// TestExportSQLite_Compress tests that a compressed export leaves only the zip
+func TestExportSQLite_Compress(t *testing.T) {
+	path := createExportSQLite(t)
+	out := t.TempDir()
+	artifact, err := db.ExportSQLite(path, out, db.ExportOptions{Format: "parquet", Compress: true})
+	if err != nil {
+		t.Fatalf("ExportSQLite() error = %v", err)
+	}
+	if !strings.HasSuffix(artifact, ".zip") {
+		t.Fatalf("artifact = %s, want a zip", artifact)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(artifact, ".zip")); !os.IsNotExist(err) {
+		t.Error("export directory should be removed after compressing")
+	}
+	r, err := zip.OpenReader(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		names = append(names, filepath.Base(f.Name))
+	}
+	if !contains(names, "users.parquet") || !contains(names, "order_items.parquet") {
+		t.Errorf("zip holds %v", names)
+	}
+}
+
+// TestExportSQLite_Errors tests invalid formats, unknown tables and failing filters
+func TestExportSQLite_Errors(t *testing.T) {
+	path := createExportSQLite(t)
+	out := t.TempDir()
+	if _, err := db.ExportSQLite(path, out, db.ExportOptions{Format: "xml"}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	if _, err := db.ExportSQLite(path, out, db.ExportOptions{Tables: []string{"missing"}}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected an error naming the missing table, got %v", err)
+	}
+	if _, err := db.ExportSQLite(path, out, db.ExportOptions{Where: "no_such_column = 1"}); err == nil {
+		t.Error("expected an error for an invalid filter")
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("failed exports should leave nothing behind, found %d entries", len(entries))
+	}
+}
+
+// TestExportClickHouse tests reading typed rows through clickhouse-client
+func TestExportClickHouse(t *testing.T) {
+	log := installFakePhysicalTool(t, "clickhouse-client", fakeClickHouseExport)
+	dir, err := db.ExportClickHouse("ch.internal", "", "default", "", "analytics", t.TempDir(), db.ExportOptions{
+		Format: "jsonl",
+		Tables: []string{"events"},
+		Where:  "at > '2024-01-01'",
+	})
+	if err != nil {
+		t.Fatalf("ExportClickHouse() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "events.jsonl"))
+	want := `{"id":9007199254740993,"name":"click","at":"2024-05-01T12:30:00Z","score":0.5}` + "\n" +
+		`{"id":2,"name":null,"at":"2024-05-01T12:31:00Z","score":1}` + "\n"
+	if string(data) != want {
+		t.Errorf("events.jsonl =\n%s\nwant\n%s", data, want)
+	}
+	query := "--query SELECT * FROM `events` WHERE at > '2024-01-01' FORMAT JSONCompactEachRowWithNamesAndTypes"
+	if lines := readLines(t, log); !strings.Contains(strings.Join(lines, "\n"), query) {
+		t.Errorf("query not found in %v", lines)
+	}
+}
@@ -0,0 +1,89 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBackupOptions_Validate tests rejection of unsupported filter combinations
+func TestBackupOptions_Validate(t *testing.T) {
+	valid := db.BackupOptions{Tables: []string{"orders"}, ExcludeTables: []string{"audit_log"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	invalid := db.BackupOptions{Collections: []string{"users"}, ExcludeCollections: []string{"events"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Validate() should reject --collections combined with --exclude-collections")
+	}
+}
+
+// TestBackupOptions_Partial tests detection of partial backups
+func TestBackupOptions_Partial(t *testing.T) {
+	if (db.BackupOptions{}).Partial() {
+		t.Error("Partial() = true for empty options")
+	}
+	if !(db.BackupOptions{ExcludeCollections: []string{"events"}}).Partial() {
+		t.Error("Partial() = false with excluded collections")
+	}
+}
+
+// TestSaveAndLoadManifest tests the manifest round trip and partial description
+func TestSaveAndLoadManifest(t *testing.T) {
+	artifact := filepath.Join(t.TempDir(), "shop-full_2024-01-01_02-00.sql")
+	os.WriteFile(artifact, []byte("-- dump"), 0644)
+
+	manifest := &db.BackupManifest{
+		DBType:     "mysql",
+		Database:   "shop",
+		BackupType: db.BackupTypeFull,
+		BackupOptions: db.BackupOptions{
+			ExcludeTables: []string{"audit_log", "sessions"},
+		},
+	}
+	if err := db.SaveManifest(artifact, manifest); err != nil {
+		t.Fatalf("SaveManifest() error = %v", err)
+	}
+	if _, err := os.Stat(db.ManifestPath(artifact)); err != nil {
+		t.Fatalf("manifest not written next to artifact: %v", err)
+	}
+
+	loaded, err := db.LoadManifest(artifact)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if loaded.Database != "shop" || len(loaded.ExcludeTables) != 2 {
+		t.Errorf("LoadManifest() = %+v", loaded)
+	}
+	if desc := loaded.Describe(); !strings.Contains(desc, "excluding tables audit_log, sessions") {
+		t.Errorf("Describe() = %q", desc)
+	}
+}
+
+// TestLoadManifest_Missing tests that artifacts without a manifest load as nil
+func TestLoadManifest_Missing(t *testing.T) {
+	manifest, err := db.LoadManifest(filepath.Join(t.TempDir(), "old-backup.sql"))
+	if err != nil || manifest != nil {
+		t.Errorf("LoadManifest() = %v, %v; want nil, nil", manifest, err)
+	}
+}
+
+// TestBackupOptions_InvalidContent tests rejection of unknown content modes
+func TestBackupOptions_InvalidContent(t *testing.T) {
+	if err := (db.BackupOptions{Content: "indexes"}).Validate(); err == nil {
+		t.Error("Validate() should reject unknown content")
+	}
+	if err := (db.BackupOptions{Content: db.ContentSchema}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+// TestBackupOptions_NegativeJobs tests validation of the parallel job count
+func TestBackupOptions_NegativeJobs(t *testing.T) {
+	if err := (db.BackupOptions{Jobs: -1}).Validate(); err == nil {
+		t.Error("Validate() should reject negative jobs")
+	}
+}
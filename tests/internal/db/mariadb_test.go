@@ -0,0 +1,212 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeMariabackup is a stand-in for mariadb-backup: --backup writes a data file and checkpoints into
+// --target-dir, --prepare marks them prepared and --copy-back copies them into --datadir
+const fakeMariabackup = `#!/bin/sh
+for a in "$@"; do echo "$a" >> LOG; done
+for a in "$@"; do
+  case "$a" in
+    --target-dir=*) target="${a#--target-dir=}" ;;
+    --datadir=*) datadir="${a#--datadir=}" ;;
+  esac
+done
+case " $* " in
+  *" --backup "*) mkdir -p "$target" && echo data > "$target/ibdata1" && echo "backup_type = full-backuped" > "$target/xtrabackup_checkpoints" ;;
+  *" --prepare "*) echo "backup_type = full-prepared" > "$target/xtrabackup_checkpoints" ;;
+  *" --copy-back "*) mkdir -p "$datadir" && cp "$target/ibdata1" "$datadir/" ;;
+esac
+`
+
+// installFakePhysicalTool puts a physical backup tool script first on PATH under name and returns
+// its argument log; LOG in the script stands for the log's path
+func installFakePhysicalTool(t *testing.T, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: fake client tools are shell scripts")
+	}
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, name+".log")
+	script = strings.ReplaceAll(script, "LOG", logFile)
+	if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake %s: %v", name, err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+// installFakeMariabackup installs fakeMariabackup as mariadb-backup
+func installFakeMariabackup(t *testing.T) string {
+	t.Helper()
+	return installFakePhysicalTool(t, "mariadb-backup", fakeMariabackup)
+}
+
+// TestBackupMariaDB_Logical tests that mariadb-dump is used with MariaDB's TLS flags and the
+// manifest records the mariadb engine
+func TestBackupMariaDB_Logical(t *testing.T) {
+	logFile := installArgLogger(t, "mariadb-dump", "CREATE TABLE t (id INT);\\n")
+	out := t.TempDir()
+	opts := db.BackupOptions{Conn: db.ConnOptions{Port: "3307", TLSMode: "verify-full", TLSCA: "ca.pem"}}
+
+	if err := db.BackupMariaDBWithOptions("db.internal", "root", "", "shop", out, db.BackupTypeFull, opts); err != nil {
+		t.Fatalf("BackupMariaDBWithOptions() error = %v", err)
+	}
+
+	args := readLines(t, logFile)
+	for _, want := range []string{"--port=3307", "--ssl", "--ssl-verify-server-cert", "--ssl-ca=ca.pem", "shop"} {
+		if !contains(args, want) {
+			t.Errorf("mariadb-dump args %v missing %q", args, want)
+		}
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--ssl-mode") {
+			t.Errorf("mariadb-dump was passed MySQL's %s", arg)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(out, "shop-*.sql"))
+	if len(matches) != 1 {
+		entries, _ := os.ReadDir(out)
+		t.Fatalf("expected one dump, got %v", entries)
+	}
+	manifest, err := db.LoadManifest(matches[0])
+	if err != nil || manifest == nil {
+		t.Fatalf("LoadManifest() = %v, %v", manifest, err)
+	}
+	if manifest.DBType != "mariadb" {
+		t.Errorf("manifest DBType = %q, want mariadb", manifest.DBType)
+	}
+}
+
+// TestBackupMySQL_FallsBackToMariaDBDump tests that a MySQL backup uses mariadb-dump when mysqldump
+// isn't installed, as on hosts with only the MariaDB client packages
+func TestBackupMySQL_FallsBackToMariaDBDump(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: fake client tools are shell scripts")
+	}
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "mariadb-dump.log")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\" >> " + logFile + "; done\necho 'CREATE TABLE t (id INT);'\n"
+	if err := os.WriteFile(filepath.Join(binDir, "mariadb-dump"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake mariadb-dump: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+	out := t.TempDir()
+
+	opts := db.BackupOptions{Conn: db.ConnOptions{TLSMode: "require"}}
+	if err := db.BackupMySQLWithOptions("localhost", "root", "", "shop", out, db.BackupTypeFull, opts); err != nil {
+		t.Fatalf("BackupMySQLWithOptions() error = %v", err)
+	}
+	args := readLines(t, logFile)
+	if !contains(args, "--ssl") || contains(args, "--ssl-mode=REQUIRED") {
+		t.Errorf("mariadb-dump args %v, want MariaDB TLS flags", args)
+	}
+}
+
+// TestBackupMariaDB_Physical tests a physical backup: mariabackup arguments, password handling,
+// preparation and the zipped result
+func TestBackupMariaDB_Physical(t *testing.T) {
+	logFile := installFakeMariabackup(t)
+	out := t.TempDir()
+	opts := db.BackupOptions{Physical: true, Jobs: 4, Conn: db.ConnOptions{Socket: "/run/mysqld/mysqld.sock"}}
+
+	if err := db.BackupMariaDBWithOptions("localhost", "backup", "s3cret", "", out, db.BackupTypeFull, opts); err != nil {
+		t.Fatalf("BackupMariaDBWithOptions() error = %v", err)
+	}
+
+	args := readLines(t, logFile)
+	if !strings.HasPrefix(args[0], "--defaults-extra-file=") {
+		t.Errorf("first argument = %q, want --defaults-extra-file", args[0])
+	}
+	for _, want := range []string{"--backup", "--user=backup", "--socket=/run/mysqld/mysqld.sock", "--parallel=4", "--prepare"} {
+		if !contains(args, want) {
+			t.Errorf("mariadb-backup args %v missing %q", args, want)
+		}
+	}
+	for _, arg := range args {
+		if strings.Contains(arg, "s3cret") {
+			t.Errorf("password passed as an argument: %v", args)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(out, "mariadb_mysqld.sock-physical_*.zip"))
+	if len(matches) != 1 {
+		entries, _ := os.ReadDir(out)
+		t.Fatalf("expected one zipped backup, got %v", entries)
+	}
+	manifest, err := db.LoadManifest(matches[0])
+	if err != nil || manifest == nil {
+		t.Fatalf("LoadManifest() = %v, %v", manifest, err)
+	}
+	if manifest.DBType != "mariadb" || !manifest.Physical {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+// TestBackupMariaDB_PhysicalRejectsFilters tests that physical backups refuse partial and non-full backups
+func TestBackupMariaDB_PhysicalRejectsFilters(t *testing.T) {
+	installFakeMariabackup(t)
+	out := t.TempDir()
+
+	err := db.BackupMariaDBWithOptions("localhost", "root", "", "", out, db.BackupTypeFull,
+		db.BackupOptions{Physical: true, Tables: []string{"orders"}})
+	if err == nil {
+		t.Error("expected an error for a table filter")
+	}
+	err = db.BackupMariaDBWithOptions("localhost", "root", "", "", out, db.BackupTypeIncremental, db.BackupOptions{Physical: true})
+	if err == nil {
+		t.Error("expected an error for an incremental backup")
+	}
+}
+
+// TestRestoreMariaDBPhysical tests copying a prepared physical backup into an empty data directory
+func TestRestoreMariaDBPhysical(t *testing.T) {
+	logFile := installFakeMariabackup(t)
+	backup := t.TempDir()
+	if err := os.WriteFile(filepath.Join(backup, "xtrabackup_checkpoints"), []byte("backup_type = full-prepared\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(backup, "ibdata1"), []byte("data\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataDir := filepath.Join(t.TempDir(), "mysql")
+
+	if err := db.RestoreMariaDBWithOptions("", "", "", "", backup, db.RestoreOptions{DataDir: dataDir}); err != nil {
+		t.Fatalf("RestoreMariaDBWithOptions() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "ibdata1")); err != nil {
+		t.Errorf("data file not copied back: %v", err)
+	}
+	args := readLines(t, logFile)
+	if contains(args, "--prepare") || !contains(args, "--datadir="+dataDir) {
+		t.Errorf("mariadb-backup args %v, want --copy-back only", args)
+	}
+}
+
+// TestRestoreMariaDBPhysical_DataDirNotEmpty tests that an existing data directory isn't overwritten
+func TestRestoreMariaDBPhysical_DataDirNotEmpty(t *testing.T) {
+	installFakeMariabackup(t)
+	backup := t.TempDir()
+	if err := os.WriteFile(filepath.Join(backup, "xtrabackup_checkpoints"), []byte("backup_type = full-prepared\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "ibdata1"), []byte("live\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RestoreMariaDBPhysical(backup, dataDir); err == nil {
+		t.Error("expected an error for a non-empty data directory")
+	}
+	if err := db.RestoreMariaDBPhysical(backup, ""); err == nil {
+		t.Error("expected an error without a data directory")
+	}
+}
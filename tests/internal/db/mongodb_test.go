@@ -15,7 +15,7 @@ func TestBackupMongo_MissingMongodump(t *testing.T) {
 
 	os.Setenv("PATH", "")
 
-	err := db.BackupMongo("mongodb://localhost:27017", "testdb", "./backups")
+	err := db.BackupMongoWithOptions("mongodb://localhost:27017", "testdb", "./backups", db.BackupOptions{Dumper: db.DumperTool})
 	if err == nil {
 		t.Error("BackupMongo() should return error when mongodump is not found")
 	}
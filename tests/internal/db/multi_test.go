@@ -0,0 +1,82 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFilterDatabases tests include/exclude glob selection of discovered databases
+func TestFilterDatabases(t *testing.T) {
+	mysqlNames := []string{"information_schema", "mysql", "performance_schema", "shop", "shop_eu", "shop_test", "sys", "wiki"}
+
+	tests := []struct {
+		name    string
+		engine  string
+		names   []string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"all skips system databases", "mysql", mysqlNames, nil, nil, []string{"shop", "shop_eu", "shop_test", "wiki"}},
+		{"include glob", "mysql", mysqlNames, []string{"shop*"}, nil, []string{"shop", "shop_eu", "shop_test"}},
+		{"include and exclude", "mysql", mysqlNames, []string{"shop*"}, []string{"*_test"}, []string{"shop", "shop_eu"}},
+		{"exclude only", "mysql", mysqlNames, nil, []string{"wiki"}, []string{"shop", "shop_eu", "shop_test"}},
+		{"explicit system database", "mysql", mysqlNames, []string{"mysql"}, nil, []string{"mysql"}},
+		{"mongo system databases", "mongodb", []string{"admin", "app", "config", "local"}, nil, nil, []string{"app"}},
+		{"nothing matches", "postgres", []string{"orders"}, []string{"shop*"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.FilterDatabases(tt.engine, tt.names, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("FilterDatabases() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterDatabases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFilterDatabases_InvalidPattern tests rejection of malformed glob patterns
+func TestFilterDatabases_InvalidPattern(t *testing.T) {
+	if _, err := db.FilterDatabases("mysql", []string{"shop"}, []string{"shop["}, nil); err == nil {
+		t.Error("FilterDatabases() should return error for invalid pattern")
+	}
+}
+
+// TestBackupDatabases tests that every database is attempted and failures are summarized
+func TestBackupDatabases(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK", "")
+	t.Setenv("DBX_LOG_DIR", t.TempDir())
+
+	var attempted []string
+	summary := db.BackupDatabases("MySQL", []string{"a", "b", "c"}, db.BackupOptions{}, func(name string, opts db.BackupOptions) error {
+		attempted = append(attempted, name)
+		if !opts.SkipNotify {
+			t.Errorf("backup of %s should skip its own notification", name)
+		}
+		if name == "b" {
+			return errors.New("access denied")
+		}
+		return nil
+	})
+
+	if !reflect.DeepEqual(attempted, []string{"a", "b", "c"}) {
+		t.Errorf("attempted %v, want all databases despite the failure", attempted)
+	}
+	if summary.Failed() != 1 {
+		t.Errorf("Failed() = %d, want 1", summary.Failed())
+	}
+	err := summary.Err()
+	if err == nil || !strings.Contains(err.Error(), "b") {
+		t.Errorf("Err() = %v, want error naming database b", err)
+	}
+	if !strings.Contains(summary.String(), "2 succeeded, 1 failed") {
+		t.Errorf("String() = %q", summary.String())
+	}
+}
@@ -0,0 +1,183 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeXtrabackup is a stand-in for xtrabackup: each --backup copies an LSN 100 past the last one as
+// its only "page" and records it as to_lsn, preparing with --incremental-dir appends the increment's
+// pages to the full backup, and --copy-back copies the pages into --datadir
+const fakeXtrabackup = `#!/bin/sh
+for a in "$@"; do echo "$a" >> LOG; done
+type=full-backuped
+for a in "$@"; do
+  case "$a" in
+    --target-dir=*) target="${a#--target-dir=}" ;;
+    --incremental-dir=*) inc="${a#--incremental-dir=}" ;;
+    --datadir=*) datadir="${a#--datadir=}" ;;
+    --incremental-lsn=*) type=incremental ;;
+  esac
+done
+case " $* " in
+  *" --backup "*)
+    lsn=$(cat LOG.lsn 2>/dev/null || echo 1000); lsn=$((lsn + 100)); echo $lsn > LOG.lsn
+    mkdir -p "$target" && echo "$lsn" > "$target/ibdata1"
+    printf 'backup_type = %s\nto_lsn = %s\n' "$type" "$lsn" > "$target/xtrabackup_checkpoints" ;;
+  *" --prepare "*)
+    if [ -n "$inc" ]; then cat "$inc/ibdata1" >> "$target/ibdata1"; fi ;;
+  *" --copy-back "*) mkdir -p "$datadir" && cp "$target/ibdata1" "$datadir/" ;;
+esac
+`
+
+// physicalBackup takes a physical MySQL backup of localhost into out and returns the new zip and
+// the arguments xtrabackup was run with
+func physicalBackup(t *testing.T, logFile, out string, backupType db.BackupType) (string, []string) {
+	t.Helper()
+	_ = os.Remove(logFile)
+	before, _ := filepath.Glob(filepath.Join(out, "*.zip"))
+	err := db.BackupMySQLWithOptions("localhost", "backup", "s3cret", "", out, backupType, db.BackupOptions{Physical: true})
+	if err != nil {
+		t.Fatalf("BackupMySQLWithOptions(%s) error = %v", backupType, err)
+	}
+	after, _ := filepath.Glob(filepath.Join(out, "mysql_localhost_3306-physical_"+string(backupType)+"_*.zip"))
+	for _, zip := range after {
+		if !contains(before, zip) {
+			return zip, readLines(t, logFile)
+		}
+	}
+	t.Fatalf("no new %s backup in %v", backupType, after)
+	return "", nil
+}
+
+// TestBackupMySQL_PhysicalChain tests that incremental backups continue from the last backup's LSN,
+// differential ones from the last full backup's, and that the chain is recorded
+func TestBackupMySQL_PhysicalChain(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "xtrabackup", fakeXtrabackup)
+	out := t.TempDir()
+
+	full, args := physicalBackup(t, logFile, out, db.BackupTypeFull)
+	if !strings.HasPrefix(args[0], "--defaults-extra-file=") || contains(args, "--prepare") {
+		t.Errorf("full backup args = %v, want an option file first and no prepare", args)
+	}
+	incremental, args := physicalBackup(t, logFile, out, db.BackupTypeIncremental)
+	if !contains(args, "--incremental-lsn=1100") {
+		t.Errorf("incremental backup args %v missing --incremental-lsn=1100", args)
+	}
+	differential, args := physicalBackup(t, logFile, out, db.BackupTypeDifferential)
+	if !contains(args, "--incremental-lsn=1100") {
+		t.Errorf("differential backup args %v should start from the full backup's LSN", args)
+	}
+
+	for _, tt := range []struct {
+		artifact string
+		toLSN    string
+		base     string
+	}{
+		{full, "1100", ""},
+		{incremental, "1200", filepath.Base(full)},
+		{differential, "1300", filepath.Base(full)},
+	} {
+		manifest, err := db.LoadManifest(tt.artifact)
+		if err != nil || manifest == nil {
+			t.Fatalf("LoadManifest(%s) = %v, %v", tt.artifact, manifest, err)
+		}
+		if manifest.DBType != "mysql" || manifest.ToLSN != tt.toLSN || manifest.Base != tt.base || !manifest.Physical {
+			t.Errorf("manifest of %s = %+v", filepath.Base(tt.artifact), manifest)
+		}
+	}
+
+	metadata, err := db.LoadMetadata(db.GetMetadataPath(out, "mysql", "mysql_localhost_3306"))
+	if err != nil {
+		t.Fatalf("LoadMetadata() error = %v", err)
+	}
+	if filepath.Base(metadata.FullBackupPath) != filepath.Base(full) || filepath.Base(metadata.BackupPath) != filepath.Base(differential) {
+		t.Errorf("metadata = %+v", metadata)
+	}
+	if metadata.LastFullBackup.IsZero() || metadata.LastIncrementalBackup.IsZero() {
+		t.Errorf("metadata times not recorded: %+v", metadata)
+	}
+}
+
+// TestBackupMySQL_PhysicalIncrementalNeedsFull tests that an incremental backup without a full one fails
+func TestBackupMySQL_PhysicalIncrementalNeedsFull(t *testing.T) {
+	installFakePhysicalTool(t, "xtrabackup", fakeXtrabackup)
+	err := db.BackupMySQLWithOptions("localhost", "root", "", "", t.TempDir(), db.BackupTypeIncremental, db.BackupOptions{Physical: true})
+	if err == nil || !strings.Contains(err.Error(), "full backup first") {
+		t.Errorf("BackupMySQLWithOptions() error = %v, want a request for a full backup", err)
+	}
+}
+
+// TestRestoreMySQLPhysical_Chain tests that restoring an incremental backup prepares the full backup
+// and applies each increment in order before copying back
+func TestRestoreMySQLPhysical_Chain(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "xtrabackup", fakeXtrabackup)
+	dir := t.TempDir()
+	chain := []struct{ name, backupType, lsn, base string }{
+		{"full", "full-backuped", "1100", ""},
+		{"inc1", "incremental", "1200", "full"},
+		{"inc2", "incremental", "1300", "inc1"},
+	}
+	for _, b := range chain {
+		backup := filepath.Join(dir, b.name)
+		if err := os.MkdirAll(backup, 0755); err != nil {
+			t.Fatal(err)
+		}
+		checkpoints := "backup_type = " + b.backupType + "\nto_lsn = " + b.lsn + "\n"
+		if err := os.WriteFile(filepath.Join(backup, "xtrabackup_checkpoints"), []byte(checkpoints), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(backup, "ibdata1"), []byte(b.lsn+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifest := &db.BackupManifest{DBType: "mysql", BackupType: db.BackupTypeIncremental, Base: b.base, ToLSN: b.lsn}
+		if err := db.SaveManifest(backup, manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataDir := filepath.Join(t.TempDir(), "mysql")
+	if err := db.RestoreMySQLWithOptions("", "", "", "", filepath.Join(dir, "inc2"), db.RestoreOptions{DataDir: dataDir}); err != nil {
+		t.Fatalf("RestoreMySQLWithOptions() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, "ibdata1"))
+	if err != nil {
+		t.Fatalf("data file not copied back: %v", err)
+	}
+	if got := strings.Fields(string(data)); strings.Join(got, ",") != "1100,1200,1300" {
+		t.Errorf("restored pages = %v, want the full backup's and both increments'", got)
+	}
+	args := readLines(t, logFile)
+	applyLogOnly := 0
+	for _, arg := range args {
+		if arg == "--apply-log-only" {
+			applyLogOnly++
+		}
+	}
+	if applyLogOnly != 2 || !contains(args, "--incremental-dir="+filepath.Join(dir, "inc2")) || !contains(args, "--copy-back") {
+		t.Errorf("xtrabackup args = %v, want every prepare but the last with --apply-log-only", args)
+	}
+}
+
+// TestRestoreMySQLPhysical_MissingBase tests that a broken chain is reported before anything is copied
+func TestRestoreMySQLPhysical_MissingBase(t *testing.T) {
+	logFile := installFakePhysicalTool(t, "xtrabackup", fakeXtrabackup)
+	out := t.TempDir()
+	full, _ := physicalBackup(t, logFile, out, db.BackupTypeFull)
+	incremental, _ := physicalBackup(t, logFile, out, db.BackupTypeIncremental)
+	if err := os.Remove(full); err != nil {
+		t.Fatal(err)
+	}
+
+	dataDir := filepath.Join(t.TempDir(), "mysql")
+	err := db.RestoreMySQLPhysical(incremental, dataDir)
+	if err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Errorf("RestoreMySQLPhysical() error = %v, want an incomplete chain", err)
+	}
+	if _, err := os.Stat(dataDir); !os.IsNotExist(err) {
+		t.Errorf("data directory was created for a broken chain")
+	}
+}
@@ -0,0 +1,70 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// installFakeMySQL puts shell stand-ins for mysql and mysqldump first on PATH. mysql lists two tables
+// and a view, and appends whatever it is fed to restore.log; mysqldump writes the object names it was given.
+func installFakeMySQL(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: fake client tools are shell scripts")
+	}
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "restore.log")
+	scripts := map[string]string{
+		"mysql": "#!/bin/sh\ncase \"$*\" in\n*\"SHOW FULL TABLES\"*) printf 'users\\tBASE TABLE\\norders\\tBASE TABLE\\nactive_users\\tVIEW\\n' ;;\n" +
+			"*) cat >> " + logFile + " ;;\nesac\n",
+		"mysqldump": "#!/bin/sh\nseen=0\nfor a in \"$@\"; do\n  if [ \"$seen\" = 1 ]; then echo \"-- object $a\"; fi\n" +
+			"  if [ \"$a\" = shop ]; then seen=1; fi\ndone\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatalf("failed to write fake %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+// TestBackupMySQLWithOptions_Parallel tests that a parallel MySQL backup becomes one bundle that restores
+// every table and then the views
+func TestBackupMySQLWithOptions_Parallel(t *testing.T) {
+	restoreLog := installFakeMySQL(t)
+	outDir := t.TempDir()
+
+	opts := db.BackupOptions{Jobs: 2, ExcludeTables: []string{"orders"}}
+	if err := db.BackupMySQLWithOptions("localhost", "root", "", "shop", outDir, db.BackupTypeFull, opts); err != nil {
+		t.Fatalf("BackupMySQLWithOptions() error = %v", err)
+	}
+	bundles, _ := filepath.Glob(filepath.Join(outDir, "shop-full_*.zip"))
+	if len(bundles) != 1 {
+		t.Fatalf("expected one bundle, got %v", bundles)
+	}
+	if manifest, _ := db.LoadManifest(bundles[0]); manifest == nil || manifest.Jobs != 2 {
+		t.Errorf("manifest = %+v, want jobs recorded", manifest)
+	}
+
+	if err := db.RestoreMySQL("localhost", "root", "", "shop", bundles[0]); err != nil {
+		t.Fatalf("RestoreMySQL() error = %v", err)
+	}
+	restored, _ := os.ReadFile(restoreLog)
+	if string(restored) != "-- object users\n-- object active_users\n" {
+		t.Errorf("restored %q, want users then the view, without the excluded table", restored)
+	}
+}
+
+// TestBackupMySQLWithOptions_ParallelNeedsFullBackup tests that --jobs is refused for incremental MySQL backups
+func TestBackupMySQLWithOptions_ParallelNeedsFullBackup(t *testing.T) {
+	opts := db.BackupOptions{Jobs: 4}
+	err := db.BackupMySQLWithOptions("localhost", "root", "", "shop", t.TempDir(), db.BackupTypeIncremental, opts)
+	if err == nil || !strings.Contains(err.Error(), "full backups only") {
+		t.Errorf("BackupMySQLWithOptions() error = %v, want full-backup-only error", err)
+	}
+}
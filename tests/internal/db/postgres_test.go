@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -56,11 +57,27 @@ func TestRestorePostgres_MissingPgRestore(t *testing.T) {
 
 	tmpDir := t.TempDir()
 	backupFile := filepath.Join(tmpDir, "backup.dump")
-	os.WriteFile(backupFile, []byte("dummy backup"), 0644)
+	os.WriteFile(backupFile, []byte("PGDMP dummy backup"), 0644)
 
 	err := db.RestorePostgres("localhost", "5432", "postgres", "password", "testdb", backupFile)
-	if err == nil {
-		t.Error("RestorePostgres() should return error when pg_restore is not found")
+	if err == nil || !strings.Contains(err.Error(), "pg_restore") {
+		t.Errorf("RestorePostgres() error = %v, want pg_restore not found", err)
+	}
+}
+
+// TestRestorePostgres_PlainDumpWithoutPgRestore tests that plain SQL dumps only need psql
+func TestRestorePostgres_PlainDumpWithoutPgRestore(t *testing.T) {
+	logFile := installArgLogger(t, "psql", "")
+	t.Setenv("PATH", filepath.Dir(logFile))
+
+	backupFile := filepath.Join(t.TempDir(), "shop_full_masked.sql")
+	os.WriteFile(backupFile, []byte("SET statement_timeout = 0;\n"), 0644)
+
+	if err := db.RestorePostgres("localhost", "5432", "postgres", "", "shop", backupFile); err != nil {
+		t.Fatalf("RestorePostgres() error = %v", err)
+	}
+	if args := readLines(t, logFile); !contains(args, backupFile) {
+		t.Errorf("psql args %v should read %s", args, backupFile)
 	}
 }
 
@@ -162,3 +179,39 @@ func TestBackupPostgres_EmptyDatabase(t *testing.T) {
 	}
 }
 
+
+// TestBackupPostgresWithOptions_MaskStreamsDump tests that masked backups read pg_dump's output
+// from stdout and mask it on the way to the file, leaving no unmasked copy on disk
+func TestBackupPostgresWithOptions_MaskStreamsDump(t *testing.T) {
+	logFile := installArgLogger(t, "pg_dump", "SET statement_timeout = 0;\\n\\n"+
+		"COPY public.users (id, email) FROM stdin;\\n1\\tjane@corp.com\\n\\\\.\\n")
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "backups")
+
+	opts := db.BackupOptions{MaskRules: writeMaskRules(t, dir)}
+	if err := db.BackupPostgresWithOptions("localhost", "5432", "postgres", "", "shop", outDir, db.BackupTypeFull, opts); err != nil {
+		t.Fatalf("BackupPostgresWithOptions() error = %v", err)
+	}
+
+	args := readLines(t, logFile)
+	if contains(args, "-f") {
+		t.Errorf("pg_dump args %v should not write a file of its own", args)
+	}
+	entries, _ := os.ReadDir(outDir)
+	var dump string
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "unmasked") {
+			t.Errorf("unmasked dump left on disk: %s", entry.Name())
+		}
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			dump = filepath.Join(outDir, entry.Name())
+		}
+	}
+	data, err := os.ReadFile(dump)
+	if err != nil {
+		t.Fatalf("no masked dump written: %v", err)
+	}
+	if strings.Contains(string(data), "jane@corp.com") || !strings.Contains(string(data), "COPY public.users") {
+		t.Errorf("masked dump =\n%s", data)
+	}
+}
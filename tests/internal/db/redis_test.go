@@ -0,0 +1,341 @@
+package db_test
+
+import (
+	"bufio"
+	"dbx/internal/db"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRDB is the start of an RDB file: the magic, a version and an EOF opcode
+const fakeRDB = "REDIS0011\xff"
+
+// installFakeRedisCli puts a redis-cli stand-in on PATH that logs its arguments and REDISCLI_AUTH,
+// and writes a snapshot to the --rdb path
+func installFakeRedisCli(t *testing.T, snapshot string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: fake client tools are shell scripts")
+	}
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "redis-cli.log")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\" >> " + logFile + "; done\n" +
+		"echo \"auth=$REDISCLI_AUTH\" >> " + logFile + "\n" +
+		"while [ $# -gt 0 ]; do if [ \"$1\" = --rdb ]; then printf '" + snapshot + "' > \"$2\"; fi; shift; done\n"
+	if err := os.WriteFile(filepath.Join(binDir, "redis-cli"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake redis-cli: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+// TestBackupRedis_Stream tests a streamed backup: redis-cli arguments, compression and manifest
+func TestBackupRedis_Stream(t *testing.T) {
+	logFile := installFakeRedisCli(t, `REDIS0011\377`)
+	out := t.TempDir()
+
+	err := db.BackupRedisWithOptions("cache.internal", "6380", "backup", "s3cret", "", out, db.BackupOptions{Conn: db.ConnOptions{TLSMode: "require"}})
+	if err != nil {
+		t.Fatalf("BackupRedisWithOptions() error = %v", err)
+	}
+
+	lines := readLines(t, logFile)
+	for _, want := range []string{"-h", "cache.internal", "-p", "6380", "--user", "backup", "--tls", "--insecure", "--rdb", "auth=s3cret"} {
+		if !contains(lines, want) {
+			t.Errorf("redis-cli arguments %v missing %q", lines, want)
+		}
+	}
+	if contains(lines, "s3cret") || contains(lines, "-a") {
+		t.Errorf("password passed as an argument: %v", lines)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(out, "redis_cache.internal_6380_*.rdb.zip"))
+	if len(matches) != 1 {
+		entries, _ := os.ReadDir(out)
+		t.Fatalf("expected one compressed snapshot, got %v", entries)
+	}
+	manifest, err := db.LoadManifest(matches[0])
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if manifest.DBType != "redis" || manifest.Database != "redis_cache.internal_6380" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+// TestBackupRedis_NotAnRDB tests that output without the RDB magic fails and is removed
+func TestBackupRedis_NotAnRDB(t *testing.T) {
+	installFakeRedisCli(t, "ERR not allowed")
+	out := t.TempDir()
+
+	if err := db.BackupRedis("localhost", "", "", "", "cache", out); err == nil {
+		t.Fatal("BackupRedis() should fail for output that isn't an RDB snapshot")
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("failed backup left files behind: %v", entries)
+	}
+}
+
+// TestBackupRedis_RejectsFilters tests that options a whole-instance snapshot can't honour are rejected
+func TestBackupRedis_RejectsFilters(t *testing.T) {
+	for name, opts := range map[string]db.BackupOptions{
+		"tables": {Tables: []string{"users"}},
+		"jobs":   {Jobs: 4},
+		"mask":   {MaskRules: "rules.yaml"},
+	} {
+		if err := db.BackupRedisWithOptions("localhost", "6379", "", "", "", t.TempDir(), opts); err == nil {
+			t.Errorf("BackupRedisWithOptions() with %s should fail", name)
+		}
+	}
+}
+
+// TestRedisBackupName tests default backup names
+func TestRedisBackupName(t *testing.T) {
+	if got := db.RedisBackupName("10.0.0.5", "", db.ConnOptions{}); got != "redis_10.0.0.5_6379" {
+		t.Errorf("RedisBackupName() = %q", got)
+	}
+	if got := db.RedisBackupName("::1", "6380", db.ConnOptions{}); got != "redis__1_6380" {
+		t.Errorf("RedisBackupName(IPv6) = %q", got)
+	}
+	if got := db.RedisBackupName("localhost", "6379", db.ConnOptions{Socket: "/run/redis/redis-server.sock"}); got != "redis_redis-server.sock" {
+		t.Errorf("RedisBackupName(socket) = %q", got)
+	}
+}
+
+// fakeRedis is a Redis server stand-in that answers INFO and REPLICAOF, and on REPLICAOF connects to
+// the given master and runs a replica's side of a full resync
+type fakeRedis struct {
+	listener net.Listener
+	role     string
+
+	mu       sync.Mutex
+	linkUp   bool
+	received []byte
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, role string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeRedis{listener: listener, role: role}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		s.mu.Unlock()
+
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			_, _ = io.WriteString(conn, "+PONG\r\n")
+		case "INFO":
+			s.mu.Lock()
+			info := fmt.Sprintf("# Replication\r\nrole:%s\r\nmaster_sync_in_progress:0\r\n", s.role)
+			if s.linkUp {
+				info += "master_link_status:up\r\n"
+			}
+			info += "loading:0\r\n"
+			s.mu.Unlock()
+			_, _ = fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+		case "REPLICAOF":
+			if strings.ToUpper(args[1]) != "NO" {
+				go s.resync(net.JoinHostPort(args[1], args[2]))
+			}
+			_, _ = io.WriteString(conn, "+OK\r\n")
+		default:
+			_, _ = fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+// resync runs the replica side of the handshake and reads the snapshot
+func (s *fakeRedis) resync(master string) {
+	conn, err := net.Dial("tcp", master)
+	if err != nil {
+		return
+	}
+	r := bufio.NewReader(conn)
+	for _, cmd := range []string{"PING", "REPLCONF listening-port " + s.port(), "REPLCONF capa eof capa psync2", "PSYNC ? -1"} {
+		_, _ = io.WriteString(conn, cmd+"\r\n")
+		if _, err := r.ReadString('\n'); err != nil {
+			return
+		}
+	}
+	header, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(header, "$") {
+		return
+	}
+	size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.received, s.linkUp = payload, true
+	s.mu.Unlock()
+	// Hold the link like a replica until the master goes away
+	_, _ = io.Copy(io.Discard, conn)
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// TestRestoreRedis tests that a restore hands the snapshot over a full resync and promotes the server back
+func TestRestoreRedis(t *testing.T) {
+	server := startFakeRedis(t, "master")
+	snapshot := filepath.Join(t.TempDir(), "cache.rdb")
+	if err := os.WriteFile(snapshot, []byte(fakeRDB), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RestoreRedis("127.0.0.1", server.port(), "", "", snapshot); err != nil {
+		t.Fatalf("RestoreRedis() error = %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if string(server.received) != fakeRDB {
+		t.Errorf("server received %q, want %q", server.received, fakeRDB)
+	}
+	if last := server.commands[len(server.commands)-1]; last != "REPLICAOF NO ONE" {
+		t.Errorf("last command = %q, want REPLICAOF NO ONE", last)
+	}
+}
+
+// TestRestoreRedis_Replica tests that restoring into a replica is refused
+func TestRestoreRedis_Replica(t *testing.T) {
+	server := startFakeRedis(t, "slave")
+	snapshot := filepath.Join(t.TempDir(), "cache.rdb")
+	if err := os.WriteFile(snapshot, []byte(fakeRDB), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.RestoreRedis("127.0.0.1", server.port(), "", "", snapshot)
+	if err == nil || !strings.Contains(err.Error(), "replica") {
+		t.Errorf("RestoreRedis() error = %v, want a replica error", err)
+	}
+}
+
+// TestRestoreRedis_NotAnRDB tests that files without the RDB magic are rejected before connecting
+func TestRestoreRedis_NotAnRDB(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(file, []byte("CREATE TABLE t (id int);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RestoreRedis("127.0.0.1", "1", "", "", file); err == nil || !strings.Contains(err.Error(), "not a Redis RDB snapshot") {
+		t.Errorf("RestoreRedis() error = %v", err)
+	}
+}
+
+// TestRedis_RoundTrip backs up and restores a real redis-server when one is installed
+func TestRedis_RoundTrip(t *testing.T) {
+	for _, tool := range []string{"redis-server", "redis-cli"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("Skipping test: %s not installed", tool)
+		}
+	}
+	dir := t.TempDir()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	_ = listener.Close()
+
+	server := exec.Command("redis-server", "--port", port, "--dir", dir, "--save", "")
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start redis-server: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Process.Kill(); _ = server.Wait() })
+	cli := func(args ...string) string {
+		out, _ := exec.Command("redis-cli", append([]string{"-p", port}, args...)...).CombinedOutput()
+		return strings.TrimSpace(string(out))
+	}
+	for i := 0; cli("PING") != "PONG"; i++ {
+		if i > 50 {
+			t.Fatal("redis-server did not start")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cli("SET", "greeting", "hello")
+	out := filepath.Join(dir, "backups")
+	if err := db.BackupRedis("127.0.0.1", port, "", "", "roundtrip", out); err != nil {
+		t.Fatalf("BackupRedis() error = %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(out, "roundtrip_*.rdb.zip"))
+	if len(matches) != 1 {
+		t.Fatalf("expected one backup, got %v", matches)
+	}
+
+	cli("FLUSHALL")
+	cli("SET", "other", "x")
+	if err := db.RestoreRedis("127.0.0.1", port, "", "", matches[0]); err != nil {
+		t.Fatalf("RestoreRedis() error = %v", err)
+	}
+	if got := cli("GET", "greeting"); got != "hello" {
+		t.Errorf("GET greeting = %q after restore, want hello", got)
+	}
+	if got := cli("EXISTS", "other"); got != "0" {
+		t.Errorf("key written after the backup survived the restore")
+	}
+	if role := cli("ROLE"); !strings.HasPrefix(role, "master") {
+		t.Errorf("server left as %q, want master", role)
+	}
+}
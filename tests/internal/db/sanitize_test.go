@@ -0,0 +1,171 @@
+package db_test
+
+import (
+	"dbx/internal/db"
+	"dbx/internal/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMaskRules(t *testing.T, dir string) string {
+	t.Helper()
+	rules := filepath.Join(dir, "mask.yaml")
+	content := "rules:\n  - table: users\n    column: email\n    strategy: fake_email\n"
+	if err := os.WriteFile(rules, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	return rules
+}
+
+// TestSanitizeBackup_MySQLDump tests sanitizing a MySQL dump, detecting the engine from its header
+func TestSanitizeBackup_MySQLDump(t *testing.T) {
+	dir := t.TempDir()
+	backup := filepath.Join(dir, "shop-full_2024-01-01_02-00.sql")
+	dump := "-- MySQL dump 10.13\n" +
+		"INSERT INTO `users` (`id`, `email`) VALUES (1,'jane@corp.com');\n"
+	os.WriteFile(backup, []byte(dump), 0644)
+
+	out, err := db.SanitizeBackup(backup, writeMaskRules(t, dir), "", "")
+	if err != nil {
+		t.Fatalf("SanitizeBackup() error = %v", err)
+	}
+	if filepath.Base(out) != "shop-full_2024-01-01_02-00_masked.sql" {
+		t.Errorf("SanitizeBackup() wrote %s", out)
+	}
+	data, _ := os.ReadFile(out)
+	if strings.Contains(string(data), "jane@corp.com") {
+		t.Errorf("sanitized dump still contains the original email:\n%s", data)
+	}
+
+	manifest, err := db.LoadManifest(out)
+	if err != nil || manifest == nil {
+		t.Fatalf("LoadManifest() = %v, %v", manifest, err)
+	}
+	if manifest.DBType != "mysql" || len(manifest.Masked) != 1 {
+		t.Errorf("manifest = %+v, want mysql with one masked column", manifest)
+	}
+	if original, _ := os.ReadFile(backup); string(original) != dump {
+		t.Error("SanitizeBackup() must not modify the original backup")
+	}
+}
+
+// TestSanitizeBackup_UnknownEngine tests that an unrecognised file needs an explicit engine
+func TestSanitizeBackup_UnknownEngine(t *testing.T) {
+	dir := t.TempDir()
+	backup := filepath.Join(dir, "mystery.bin")
+	os.WriteFile(backup, []byte("not a dump"), 0644)
+
+	if _, err := db.SanitizeBackup(backup, writeMaskRules(t, dir), "", ""); err == nil {
+		t.Error("SanitizeBackup() should fail when the engine can't be detected")
+	}
+}
+
+// TestSanitizeBackup_SQLite tests sanitizing a zipped SQLite backup into a masked database
+func TestSanitizeBackup_SQLite(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("Skipping test: sqlite3 not found in PATH")
+	}
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.db")
+	setup := exec.Command("sqlite3", source, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); INSERT INTO users VALUES (1, 'jane@corp.com');")
+	if out, err := setup.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create test database: %v\n%s", err, out)
+	}
+	backupDir := filepath.Join(dir, "backups")
+	if err := db.BackupSQLite(source, backupDir); err != nil {
+		t.Fatalf("BackupSQLite() error = %v", err)
+	}
+	backups, _ := filepath.Glob(filepath.Join(backupDir, "app_*.db.zip"))
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup, got %v", backups)
+	}
+
+	out, err := db.SanitizeBackup(backups[0], writeMaskRules(t, dir), "", filepath.Join(dir, "sanitized"))
+	if err != nil {
+		t.Fatalf("SanitizeBackup() error = %v", err)
+	}
+	email, err := exec.Command("sqlite3", out, "SELECT email FROM users WHERE id = 1").Output()
+	if err != nil {
+		t.Fatalf("failed to query sanitized database: %v", err)
+	}
+	if got := strings.TrimSpace(string(email)); got == "jane@corp.com" || !strings.HasSuffix(got, "@example.com") {
+		t.Errorf("sanitized email = %q, want a fake address", got)
+	}
+}
+
+// TestSanitizeBackup_MariaDB tests that MariaDB backups are masked with the MySQL dialect
+func TestSanitizeBackup_MariaDB(t *testing.T) {
+	dir := t.TempDir()
+	backup := filepath.Join(dir, "shop-full_2024-01-01_02-00.sql")
+	os.WriteFile(backup, []byte("INSERT INTO `users` (`id`, `email`) VALUES (1,'jane@corp.com');\n"), 0644)
+
+	out, err := db.SanitizeBackup(backup, writeMaskRules(t, dir), "mariadb", "")
+	if err != nil {
+		t.Fatalf("SanitizeBackup() error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); strings.Contains(string(data), "jane@corp.com") {
+		t.Errorf("sanitized dump still contains the original email:\n%s", data)
+	}
+}
+
+// TestSanitizeBackup_MySQLBundle tests that a zipped parallel bundle is masked table file by table
+// file and comes out as a bundle with the same restore plan
+func TestSanitizeBackup_MySQLBundle(t *testing.T) {
+	dir := t.TempDir()
+	bundleDir := filepath.Join(dir, "shop-full_2024-01-01_02-00")
+	os.MkdirAll(bundleDir, 0755)
+	index := `{"database":"shop","tables":[{"table":"users","file":"table-0001.sql"},{"table":"orders","file":"table-0002.sql"}]}`
+	os.WriteFile(filepath.Join(bundleDir, "dbx-bundle.json"), []byte(index), 0644)
+	os.WriteFile(filepath.Join(bundleDir, "table-0001.sql"),
+		[]byte("-- MySQL dump 10.13\nINSERT INTO `users` (`id`, `email`) VALUES (1,'jane@corp.com');\n"), 0644)
+	os.WriteFile(filepath.Join(bundleDir, "table-0002.sql"),
+		[]byte("-- MySQL dump 10.13\nINSERT INTO `orders` (`id`, `total`) VALUES (1,9.5);\n"), 0644)
+	backup := bundleDir + ".zip"
+	if err := utils.CompressFolder(bundleDir, backup); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := db.SanitizeBackup(backup, writeMaskRules(t, dir), "", "")
+	if err != nil {
+		t.Fatalf("SanitizeBackup() error = %v", err)
+	}
+	if filepath.Base(out) != "shop-full_2024-01-01_02-00_masked.zip" {
+		t.Errorf("SanitizeBackup() wrote %s", out)
+	}
+	extracted := filepath.Join(dir, "extracted")
+	if err := utils.ExtractFolder(out, extracted); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(extracted, "dbx-bundle.json")); string(data) != index {
+		t.Errorf("bundle index = %s", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(extracted, "table-0001.sql")); strings.Contains(string(data), "jane@corp.com") {
+		t.Errorf("masked bundle still contains the original email:\n%s", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(extracted, "table-0002.sql")); !strings.Contains(string(data), "(1,9.5)") {
+		t.Errorf("orders should pass through unchanged:\n%s", data)
+	}
+}
+
+// TestSanitizeBackup_MultiFileZip tests that a zip that is neither one dump, a bundle nor a
+// directory-format dump is rejected
+func TestSanitizeBackup_MultiFileZip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "files")
+	os.MkdirAll(src, 0755)
+	os.WriteFile(filepath.Join(src, "a.sql"), []byte("-- MySQL dump\n"), 0644)
+	os.WriteFile(filepath.Join(src, "b.sql"), []byte("-- MySQL dump\n"), 0644)
+	backup := filepath.Join(dir, "files.zip")
+	if err := utils.CompressFolder(src, backup); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := db.SanitizeBackup(backup, writeMaskRules(t, dir), "mysql", "")
+	if err == nil || !strings.Contains(err.Error(), "neither") {
+		t.Errorf("SanitizeBackup() error = %v, want a zip layout error", err)
+	}
+}
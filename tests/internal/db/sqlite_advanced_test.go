@@ -3,6 +3,7 @@ package db_test
 import (
 	"dbx/internal/db"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 )
@@ -54,3 +55,45 @@ func TestBackupSQLite_CompressionSuccess(t *testing.T) {
 	// Compression is tested indirectly through BackupSQLite
 }
 
+
+// TestBackupSQLiteWithOptions_SchemaOnly tests schema-only backups read from sqlite_master
+func TestBackupSQLiteWithOptions_SchemaOnly(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("Skipping test: sqlite3 not found in PATH")
+	}
+
+	tmpDir := t.TempDir()
+	testDB := filepath.Join(tmpDir, "app.db")
+	setup := exec.Command("sqlite3", testDB, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); INSERT INTO users VALUES (1, 'a@example.com');")
+	if out, err := setup.CombinedOutput(); err != nil {
+		t.Fatalf("failed to create test database: %v\n%s", err, out)
+	}
+
+	backupDir := filepath.Join(tmpDir, "backups")
+	if err := db.BackupSQLiteWithOptions(testDB, backupDir, db.BackupOptions{Content: db.ContentSchema}); err != nil {
+		t.Fatalf("BackupSQLiteWithOptions() error = %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(backupDir, "app_schema_*.sql.zip"))
+	if len(matches) != 1 {
+		t.Fatalf("expected one labelled schema artifact, got %v", matches)
+	}
+	manifest, err := db.LoadManifest(matches[0])
+	if err != nil || manifest == nil {
+		t.Fatalf("LoadManifest() = %v, %v", manifest, err)
+	}
+	if manifest.Content != db.ContentSchema {
+		t.Errorf("manifest content = %q, want schema", manifest.Content)
+	}
+}
+
+// TestBackupSQLiteWithOptions_DataOnlyUnsupported tests that data-only SQLite backups are rejected
+func TestBackupSQLiteWithOptions_DataOnlyUnsupported(t *testing.T) {
+	tmpDir := t.TempDir()
+	testDB := filepath.Join(tmpDir, "app.db")
+	os.WriteFile(testDB, []byte("SQLite format 3"), 0644)
+
+	if err := db.BackupSQLiteWithOptions(testDB, tmpDir, db.BackupOptions{Content: db.ContentData}); err == nil {
+		t.Error("BackupSQLiteWithOptions() should reject data-only backups")
+	}
+}
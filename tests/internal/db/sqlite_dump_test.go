@@ -0,0 +1,230 @@
+package db_test
+
+import (
+	"bufio"
+	"dbx/internal/db"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sqliteDumpSchema covers what the SQL format has to lay out: a rowid alias with AUTOINCREMENT,
+// a WITHOUT ROWID table keyed in descending order, generated columns, a column added after rows
+// were written, quoted names, and the indexes, views and triggers created after the data
+const sqliteDumpSchema = `PRAGMA user_version = 7;
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL UNIQUE, score REAL, avatar BLOB, note TEXT);
+INSERT INTO users (email, score, avatar, note) VALUES ('ann@example.com', 1.5, x'00ff', 'it''s'), ('bob@example.com', 3.0, NULL, 'multi
+line'), ('cy@example.com', -1e300, zeroblob(5000), 'ünïcødé');
+DELETE FROM users WHERE email = 'bob@example.com';
+CREATE TABLE kv (k TEXT, v ANY, PRIMARY KEY (k DESC)) WITHOUT ROWID;
+INSERT INTO kv VALUES ('a', 1), ('b', 'two'), ('c', 2.5), ('d', NULL), ('e', -9223372036854775808);
+CREATE TABLE totals ("a b" INT, b INT, c INT GENERATED ALWAYS AS ("a b" + b) VIRTUAL, d INT AS ("a b" * 2) STORED);
+INSERT INTO totals ("a b", b) VALUES (1, 2), (3, 4);
+CREATE TABLE "odd ""name""" ([col 1] INTEGER, x TEXT, PRIMARY KEY ([col 1]));
+INSERT INTO "odd ""name""" VALUES (5, 'q'), (9, replace(hex(randomblob(3000)), 'A', 'a'));
+CREATE TABLE log (msg TEXT);
+INSERT INTO log VALUES ('old row');
+ALTER TABLE log ADD COLUMN level TEXT DEFAULT 'info';
+INSERT INTO log VALUES ('new row', 'warn');
+CREATE INDEX users_score ON users (score);
+CREATE VIEW high AS SELECT * FROM users WHERE score > 1;
+CREATE TRIGGER log_insert AFTER INSERT ON users BEGIN INSERT INTO log (msg) VALUES (new.email); END;`
+
+// sqliteContents returns every row of every table, and the schema, as the sqlite3 CLI shows them
+func sqliteContents(t *testing.T, path string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(sqliteQuery(t, path, "SELECT type, name, sql FROM sqlite_master ORDER BY name"))
+	b.WriteString(sqliteQuery(t, path, "PRAGMA user_version"))
+	for _, table := range strings.Split(sqliteQuery(t, path, "SELECT name FROM sqlite_master WHERE type = 'table'"), "\n") {
+		out, err := exec.Command("sqlite3", "-quote", path, `SELECT * FROM "`+strings.ReplaceAll(table, `"`, `""`)+`"`).CombinedOutput()
+		if err != nil {
+			t.Fatalf("sqlite3 failed: %v: %s", err, out)
+		}
+		b.WriteString(table + ":\n" + string(out))
+	}
+	return b.String()
+}
+
+// withoutSQLiteCLI runs fn with the sqlite3 CLI out of PATH
+func withoutSQLiteCLI(t *testing.T, fn func() error) error {
+	t.Helper()
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", t.TempDir())
+	defer os.Setenv("PATH", path)
+	return fn()
+}
+
+// sqliteSQLBackup takes a --format sql backup without the sqlite3 CLI and returns its path
+func sqliteSQLBackup(t *testing.T, source string, opts db.BackupOptions) string {
+	t.Helper()
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	opts.Format = db.SQLiteFormatSQL
+	if err := withoutSQLiteCLI(t, func() error { return db.BackupSQLiteWithOptions(source, backupDir, opts) }); err != nil {
+		t.Fatalf("BackupSQLiteWithOptions() error = %v", err)
+	}
+	backups, _ := filepath.Glob(filepath.Join(backupDir, "*.sql.zip"))
+	if len(backups) != 1 {
+		t.Fatalf("expected one SQL backup, got %v", backups)
+	}
+	return backups[0]
+}
+
+// sqliteRestore restores a backup without the sqlite3 CLI
+func sqliteRestore(t *testing.T, backup, target string) {
+	t.Helper()
+	if err := withoutSQLiteCLI(t, func() error { return db.RestoreSQLite(backup, target) }); err != nil {
+		t.Fatalf("RestoreSQLite() error = %v", err)
+	}
+}
+
+// TestBackupSQLite_FormatSQL tests that a SQL dump, written and restored without sqlite3, restores
+// the same database
+func TestBackupSQLite_FormatSQL(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "app.db")
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	sqliteQuery(t, source, sqliteDumpSchema)
+
+	backup := sqliteSQLBackup(t, source, db.BackupOptions{})
+	manifest, err := db.LoadManifest(backup)
+	if err != nil || manifest == nil || manifest.Format != db.SQLiteFormatSQL {
+		t.Errorf("manifest = %+v, %v; want format sql", manifest, err)
+	}
+
+	target := filepath.Join(t.TempDir(), "restored.db")
+	sqliteRestore(t, backup, target)
+	if got, want := sqliteContents(t, target), sqliteContents(t, source); got != want {
+		t.Errorf("restored database differs:\n%s\nwant\n%s", got, want)
+	}
+	// AUTOINCREMENT keeps counting from the original's last id
+	if got := sqliteQuery(t, target, "INSERT INTO users (email) VALUES ('new@example.com'); SELECT max(id) FROM users"); got != "4" {
+		t.Errorf("next id = %s, want 4", got)
+	}
+}
+
+// TestBackupSQLite_FormatSQLWAL tests that rows committed to the write-ahead log are dumped, and
+// rows of a transaction still open in another process aren't
+func TestBackupSQLite_FormatSQLWAL(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	source := filepath.Join(t.TempDir(), "app.db")
+
+	// Keep a connection open, so the log isn't checkpointed into the database file on close
+	cli := exec.Command("sqlite3", source)
+	stdin, _ := cli.StdinPipe()
+	stdout, _ := cli.StdoutPipe()
+	if err := cli.Start(); err != nil {
+		t.Fatalf("failed to start sqlite3: %v", err)
+	}
+	defer func() {
+		_ = stdin.Close()
+		_ = cli.Wait()
+	}()
+	io.WriteString(stdin, `PRAGMA journal_mode = WAL; PRAGMA wal_autocheckpoint = 0;
+CREATE TABLE events (id INTEGER PRIMARY KEY, payload TEXT);
+WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 2000) INSERT INTO events (payload) SELECT printf('%0*d', i % 500, i) FROM n;
+DELETE FROM events WHERE id > 1500;
+BEGIN; INSERT INTO events (payload) VALUES ('uncommitted');
+.print ready
+`)
+	lines := bufio.NewScanner(stdout)
+	for lines.Scan() && lines.Text() != "ready" {
+	}
+	if info, err := os.Stat(source + "-wal"); err != nil || info.Size() == 0 {
+		t.Fatalf("expected a write-ahead log, got %v", err)
+	}
+
+	backup := sqliteSQLBackup(t, source, db.BackupOptions{})
+	target := filepath.Join(t.TempDir(), "restored.db")
+	sqliteRestore(t, backup, target)
+	if got := sqliteQuery(t, target, "SELECT count(*), max(id), sum(length(payload)) FROM events"); got != "1500|1500|374270" {
+		t.Errorf("restored events = %s, want 1500|1500|374270", got)
+	}
+}
+
+// TestBackupSQLite_FormatSQLMasked tests masking while writing a SQL dump
+func TestBackupSQLite_FormatSQLMasked(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "app.db")
+	sqliteQuery(t, source, sqliteDumpSchema)
+
+	backup := sqliteSQLBackup(t, source, db.BackupOptions{MaskRules: writeMaskRules(t, dir)})
+	if !strings.Contains(filepath.Base(backup), "_masked_") {
+		t.Errorf("masked backup name = %s", filepath.Base(backup))
+	}
+	target := filepath.Join(dir, "restored.db")
+	sqliteRestore(t, backup, target)
+	emails := sqliteQuery(t, target, "SELECT email FROM users ORDER BY id")
+	if strings.Contains(emails, "ann@example.com") || strings.Count(emails, "@example.com") != 2 {
+		t.Errorf("masked emails = %q, want two fake addresses", emails)
+	}
+}
+
+// TestBackupSQLite_FormatErrors tests invalid formats and files that aren't SQLite databases
+func TestBackupSQLite_FormatErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := db.BackupSQLiteWithOptions(filepath.Join(dir, "app.db"), dir, db.BackupOptions{Format: "csv"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	notSQLite := filepath.Join(dir, "notes.db")
+	os.WriteFile(notSQLite, []byte("just some text"), 0644)
+	err := db.BackupSQLiteWithOptions(notSQLite, filepath.Join(dir, "backups"), db.BackupOptions{Format: db.SQLiteFormatSQL})
+	if err == nil || !strings.Contains(err.Error(), "not a SQLite database") {
+		t.Errorf("expected an error for a file that isn't a database, got %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "backups", "*.sql*")); len(matches) > 0 {
+		t.Errorf("a failed dump left %v behind", matches)
+	}
+}
+
+// TestRestoreSQLite_SQLDump tests loading a hand-written dump: semicolons in strings and comments,
+// values over several lines, and a trigger body with statements and CASE ... END of its own
+func TestRestoreSQLite_SQLDump(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dir := t.TempDir()
+	dump := filepath.Join(dir, "app.sql")
+	os.WriteFile(dump, []byte(`PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT); -- one; two
+INSERT INTO notes VALUES(1,'a;b'); INSERT INTO notes VALUES(2,'line one;
+line two');
+/* a comment; with a semicolon */
+CREATE TABLE audit (note INTEGER, kind TEXT);
+CREATE TRIGGER notes_audit AFTER INSERT ON notes BEGIN
+  INSERT INTO audit VALUES (new.id, CASE WHEN new.body LIKE '%;%' THEN 'semi' ELSE 'plain' END);
+  INSERT INTO audit VALUES (new.id, 'end;');
+END;
+INSERT INTO notes VALUES(3,'x;y');
+COMMIT;
+`), 0644)
+
+	target := filepath.Join(dir, "restored.db")
+	sqliteRestore(t, dump, target)
+	if got := sqliteQuery(t, target, "SELECT group_concat(body, '|') FROM notes"); got != "a;b|line one;\nline two|x;y" {
+		t.Errorf("notes = %q", got)
+	}
+	if got := sqliteQuery(t, target, "SELECT group_concat(kind, '|') FROM audit"); got != "semi|end;" {
+		t.Errorf("audit = %q", got)
+	}
+
+	// A failing statement reports its line and leaves the target alone
+	os.WriteFile(dump, []byte("CREATE TABLE a (x);\n\nINSERT INTO missing VALUES(1);\n"), 0644)
+	err := withoutSQLiteCLI(t, func() error { return db.RestoreSQLite(dump, target) })
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error on line 3, got %v", err)
+	}
+	if got := sqliteQuery(t, target, "SELECT count(*) FROM notes"); got != "3" {
+		t.Errorf("failed restore changed the target: %s notes", got)
+	}
+}
@@ -0,0 +1,147 @@
+package doctor_test
+
+import (
+	"dbx/internal/config"
+	"dbx/internal/doctor"
+	"dbx/internal/scheduler"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckClientVersion tests dump tool versions against server versions
+func TestCheckClientVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine string
+		tool   string
+		server string
+		want   doctor.Status
+	}{
+		{"pg same major", "postgres", "pg_dump (PostgreSQL) 16.2 (Ubuntu 16.2-1.pgdg22.04+1)", "16.4 (Debian 16.4-1.pgdg120+1)", doctor.Pass},
+		{"pg newer client", "postgres", "pg_dump (PostgreSQL) 17.0", "15.8", doctor.Pass},
+		{"pg older client", "postgres", "pg_dump (PostgreSQL) 14.11", "16.2", doctor.Fail},
+		{"mysql 8", "mysql", "mysqldump  Ver 8.0.36 for Linux on x86_64 (MySQL Community Server - GPL)", "8.0.36", doctor.Pass},
+		{"mysql older client", "mysql", "mysqldump  Ver 10.13 Distrib 5.7.44, for Linux (x86_64)", "8.0.36", doctor.Warn},
+		{"mariadb client on mysql", "mysql", "mysqldump  Ver 10.19 Distrib 10.11.6-MariaDB, for debian-linux-gnu (x86_64)", "8.0.36", doctor.Warn},
+		{"mariadb", "mysql", "mariadb-dump from 11.4.2-MariaDB, client 10.19 for Linux (x86_64)", "11.4.2-MariaDB-ubu2404", doctor.Pass},
+		{"unparseable", "postgres", "pg_dump", "16.2", doctor.Warn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, detail := doctor.CheckClientVersion(tt.engine, tt.tool, tt.server)
+			if got != tt.want {
+				t.Errorf("CheckClientVersion() = %s (%s), want %s", got, detail, tt.want)
+			}
+		})
+	}
+}
+
+// TestCheckDir tests writability and free space checks
+func TestCheckDir(t *testing.T) {
+	dir := t.TempDir()
+	if status, detail := doctor.CheckDir(dir, 1); status != doctor.Pass {
+		t.Errorf("CheckDir(existing) = %s (%s), want pass", status, detail)
+	}
+	status, detail := doctor.CheckDir(filepath.Join(dir, "new", "backups"), 1)
+	if status != doctor.Pass || !strings.Contains(detail, "will be created") {
+		t.Errorf("CheckDir(missing) = %s (%s), want pass noting it will be created", status, detail)
+	}
+	if status, _ := doctor.CheckDir(dir, 1<<62); status != doctor.Fail {
+		t.Errorf("CheckDir() with an impossible minimum = %s, want fail", status)
+	}
+
+	file := filepath.Join(dir, "file")
+	_ = os.WriteFile(file, nil, 0644)
+	if status, _ := doctor.CheckDir(file, 1); status != doctor.Fail {
+		t.Errorf("CheckDir(file) = %s, want fail", status)
+	}
+}
+
+// TestCloudCredentials tests credential discovery from the environment and credential files
+func TestCloudCredentials(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_SHARED_CREDENTIALS_FILE", "GOOGLE_APPLICATION_CREDENTIALS"} {
+		t.Setenv(name, "")
+	}
+
+	if status, _ := doctor.CloudCredentials("s3"); status != doctor.Warn {
+		t.Errorf("CloudCredentials(s3) without credentials = %s, want warn", status)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	if status, _ := doctor.CloudCredentials("s3"); status != doctor.Fail {
+		t.Errorf("CloudCredentials(s3) with a key id but no secret = %s, want fail", status)
+	}
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	if status, _ := doctor.CloudCredentials("s3"); status != doctor.Pass {
+		t.Errorf("CloudCredentials(s3) with keys = %s, want pass", status)
+	}
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(home, "missing.json"))
+	if status, _ := doctor.CloudCredentials("gcs"); status != doctor.Fail {
+		t.Errorf("CloudCredentials(gcs) with a missing key file = %s, want fail", status)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	adc := filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+	_ = os.MkdirAll(filepath.Dir(adc), 0755)
+	_ = os.WriteFile(adc, []byte("{}"), 0600)
+	if status, detail := doctor.CloudCredentials("gcs"); status != doctor.Pass || !strings.Contains(detail, adc) {
+		t.Errorf("CloudCredentials(gcs) = %s (%s), want pass from %s", status, detail, adc)
+	}
+}
+
+// TestRun tests a run over a config and jobs, without connecting to servers
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir) // no client tools
+	cfg := &config.Config{
+		Profiles: map[string]config.Profile{
+			"app": {Engine: "postgres", Host: "db", Database: "app", Out: filepath.Join(dir, "backups")},
+		},
+	}
+	jobs := []scheduler.JobConfig{
+		{ID: 1, DBType: "sqlite", Schedule: "0 2 * * *", Params: map[string]string{"path": "app.db", "out": dir}},
+		{ID: 2, DBType: "mysql", Schedule: "every tuesday", Params: map[string]string{"dbname": "shop"}},
+	}
+
+	report := doctor.Run(doctor.Options{Config: cfg, Jobs: jobs, LogDir: filepath.Join(dir, "logs"), MinFree: 1})
+	statuses := make(map[string]doctor.Status)
+	for _, c := range report.Checks {
+		statuses[c.Category+"/"+c.Name] = c.Status
+	}
+
+	want := map[string]doctor.Status{
+		"tools/pg_dump":    doctor.Fail, // used by a profile
+		"tools/sqlite3":    doctor.Fail, // used by a job
+		"tools/mongodump":  doctor.Warn, // not used
+		"tools/mongosh":    doctor.Warn,
+		"schedules/job #1": doctor.Pass,
+		"schedules/job #2": doctor.Fail,
+		"storage/backup directory " + filepath.Join(dir, "backups"): doctor.Pass,
+		"storage/log directory " + filepath.Join(dir, "logs"):       doctor.Pass,
+	}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("%s = %q, want %q", name, statuses[name], status)
+		}
+	}
+	if report.OK() || report.Failures == 0 {
+		t.Error("report with failed checks should not be OK")
+	}
+}
+
+// TestParseSize tests byte size parsing
+func TestParseSize(t *testing.T) {
+	tests := map[string]uint64{"1GB": 1 << 30, "500mb": 500 << 20, "1.5 KB": 1536, "42": 42}
+	for in, want := range tests {
+		if got, err := doctor.ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := doctor.ParseSize("lots"); err == nil {
+		t.Error("ParseSize(lots) should fail")
+	}
+}
@@ -0,0 +1,192 @@
+package export_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"dbx/internal/export"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+var columns = []export.Column{
+	{Name: "id", Type: export.Int},
+	{Name: "name", Type: export.String},
+	{Name: "score", Type: export.Float},
+	{Name: "active", Type: export.Bool},
+	{Name: "created", Type: export.Time},
+	{Name: "data", Type: export.Bytes},
+}
+
+var created = time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+var rows = [][]any{
+	{int64(1), "a,\"b\"", 1.5, true, created, []byte{1, 2}},
+	{int64(2), nil, math.NaN(), false, nil, nil},
+}
+
+func write(t *testing.T, format string, columns []export.Column, rows [][]any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := export.NewWriter(format, &buf, columns)
+	if err != nil {
+		t.Fatalf("NewWriter(%s) error = %v", format, err)
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestNewWriter_UnsupportedFormat tests that unknown formats are rejected
+func TestNewWriter_UnsupportedFormat(t *testing.T) {
+	if _, err := export.NewWriter("xml", io.Discard, columns); err == nil {
+		t.Error("NewWriter(xml) should fail")
+	}
+}
+
+// TestCSV tests the header, quoting, NULLs, times and binary data
+func TestCSV(t *testing.T) {
+	got := string(write(t, "csv", columns, rows))
+	want := "id,name,score,active,created,data\n" +
+		"1,\"a,\"\"b\"\"\",1.5,true,2024-05-01T12:30:00Z,AQI=\n" +
+		"2,,NaN,false,,\n"
+	if got != want {
+		t.Errorf("csv =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestJSONL tests key order, NULLs and the mapping of NaN to null
+func TestJSONL(t *testing.T) {
+	got := string(write(t, "jsonl", columns, rows))
+	want := `{"id":1,"name":"a,\"b\"","score":1.5,"active":true,"created":"2024-05-01T12:30:00Z","data":"AQI="}` + "\n" +
+		`{"id":2,"name":null,"score":null,"active":false,"created":null,"data":null}` + "\n"
+	if got != want {
+		t.Errorf("jsonl =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestParquet tests that a Parquet file reads back with its schema, row count and values
+func TestParquet(t *testing.T) {
+	data := write(t, "parquet", columns, rows)
+	file := readParquet(t, data)
+
+	if file.rows != 2 {
+		t.Errorf("num_rows = %d, want 2", file.rows)
+	}
+	wantTypes := []int64{2, 6, 5, 0, 2, 6} // INT64, BYTE_ARRAY, DOUBLE, BOOLEAN, INT64, BYTE_ARRAY
+	for i, col := range columns {
+		if file.names[i] != col.Name || file.types[i] != wantTypes[i] {
+			t.Errorf("column %d = %s type %d, want %s type %d", i, file.names[i], file.types[i], col.Name, wantTypes[i])
+		}
+	}
+
+	if got := file.values[0]; got[0] != int64(1) || got[1] != int64(2) {
+		t.Errorf("id = %v", got)
+	}
+	if got := file.values[1]; got[0] != "a,\"b\"" || got[1] != nil {
+		t.Errorf("name = %v", got)
+	}
+	if got := file.values[2]; got[0] != 1.5 || !math.IsNaN(got[1].(float64)) {
+		t.Errorf("score = %v", got)
+	}
+	if got := file.values[3]; got[0] != true || got[1] != false {
+		t.Errorf("active = %v", got)
+	}
+	if got := file.values[4]; got[0] != created.UnixMicro() || got[1] != nil {
+		t.Errorf("created = %v", got)
+	}
+	if got := file.values[5]; got[0] != "\x01\x02" || got[1] != nil {
+		t.Errorf("data = %v", got)
+	}
+}
+
+// TestParquet_UnknownTypes tests that columns of unknown type are strings, so a value of another
+// type many rows in doesn't fail the file
+func TestParquet_UnknownTypes(t *testing.T) {
+	cols := []export.Column{{Name: "n"}, {Name: "empty"}}
+	var input [][]any
+	for i := 0; i < 70000; i++ {
+		input = append(input, []any{int64(i), nil})
+	}
+	input = append(input, []any{"not a number", nil}, []any{2.5, nil})
+	file := readParquet(t, write(t, "parquet", cols, input))
+	if file.types[0] != 6 || file.types[1] != 6 {
+		t.Errorf("types = %v, want BYTE_ARRAY", file.types)
+	}
+	if got := file.values[0]; len(got) != 70002 || got[1] != "1" || got[70000] != "not a number" || got[70001] != "2.5" {
+		t.Errorf("n has %d values", len(got))
+	}
+}
+
+// TestParquet_SimilarNames tests that names parquet-go can't tell apart are rejected
+func TestParquet_SimilarNames(t *testing.T) {
+	cols := []export.Column{{Name: "id", Type: export.Int}, {Name: "Id", Type: export.Int}}
+	if _, err := export.NewWriter("parquet", io.Discard, cols); err == nil {
+		t.Error("expected an error for the columns id and Id")
+	}
+}
+
+// TestParquet_Empty tests a file without rows
+func TestParquet_Empty(t *testing.T) {
+	file := readParquet(t, write(t, "parquet", columns, nil))
+	if file.rows != 0 || len(file.names) != len(columns) {
+		t.Errorf("rows = %d, columns = %v", file.rows, file.names)
+	}
+}
+
+// TestParquet_RowGroups tests that a large table is split into several row groups
+func TestParquet_RowGroups(t *testing.T) {
+	cols := []export.Column{{Name: "b", Type: export.Bytes}}
+	// Random data, so compression doesn't shrink the row groups
+	big := make([]byte, 16<<10)
+	rand.Read(big)
+	var input [][]any
+	for i := 0; i < 6000; i++ {
+		input = append(input, []any{big})
+	}
+	file := readParquet(t, write(t, "parquet", cols, input))
+	if file.groups < 2 || file.rows != 6000 || len(file.values[0]) != 6000 || file.values[0][5999] != string(big) {
+		t.Errorf("groups = %d, rows = %d, values = %d", file.groups, file.rows, len(file.values[0]))
+	}
+}
+
+// parquetFile is what readParquet reads: the schema and each column's values from all row groups
+type parquetFile struct {
+	groups int
+	rows   int64
+	names  []string
+	types  []int64
+	values [][]any
+}
+
+// readParquet reads a file back with parquet-go's reader
+func readParquet(t *testing.T, data []byte) parquetFile {
+	t.Helper()
+	pr, err := reader.NewParquetColumnReader(buffer.NewBufferFileFromBytes(data), 1)
+	if err != nil {
+		t.Fatalf("NewParquetColumnReader() error = %v", err)
+	}
+	defer pr.ReadStop()
+
+	file := parquetFile{groups: len(pr.Footer.RowGroups), rows: pr.GetNumRows()}
+	for i, elem := range pr.Footer.Schema[1:] {
+		file.names = append(file.names, pr.SchemaHandler.Infos[i+1].ExName)
+		file.types = append(file.types, int64(elem.GetType()))
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), file.rows)
+		if err != nil {
+			t.Fatalf("ReadColumnByIndex(%d) error = %v", i, err)
+		}
+		file.values = append(file.values, values)
+	}
+	return file
+}
@@ -0,0 +1,209 @@
+package mask_test
+
+import (
+	"bytes"
+	"dbx/internal/mask"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func dump(t *testing.T, input string, dialect mask.Dialect, rules mask.Rules, columns map[string][]string) (string, *mask.Stats) {
+	t.Helper()
+	var out bytes.Buffer
+	stats, err := mask.Dump(strings.NewReader(input), &out, dialect, rules, columns)
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	return out.String(), stats
+}
+
+// TestLoad tests reading rules from YAML and JSON files
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "rules.yaml")
+	os.WriteFile(yamlFile, []byte("rules:\n  - table: users\n    column: email\n    strategy: fake_email\n  - table: users\n    column: phone\n    strategy: truncate\n    length: 4\n"), 0644)
+	jsonFile := filepath.Join(dir, "rules.json")
+	os.WriteFile(jsonFile, []byte(`{"rules": [{"table": "users", "column": "ssn", "strategy": "null"}]}`), 0644)
+
+	rules, err := mask.Load(yamlFile)
+	if err != nil {
+		t.Fatalf("Load(yaml) error = %v", err)
+	}
+	if len(rules) != 2 || rules[1].Strategy != mask.StrategyTruncate || rules[1].Length != 4 {
+		t.Errorf("Load(yaml) = %+v", rules)
+	}
+	rules, err = mask.Load(jsonFile)
+	if err != nil {
+		t.Fatalf("Load(json) error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Strategy != mask.StrategyNull {
+		t.Errorf("Load(json) = %+v", rules)
+	}
+}
+
+// TestRules_Validate tests rejection of incomplete or unknown rules
+func TestRules_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule mask.Rule
+	}{
+		{"missing column", mask.Rule{Table: "users", Strategy: mask.StrategyHash}},
+		{"unknown strategy", mask.Rule{Table: "users", Column: "email", Strategy: "scramble"}},
+		{"truncate without length", mask.Rule{Table: "users", Column: "email", Strategy: mask.StrategyTruncate}},
+		{"bad pattern", mask.Rule{Table: "users[", Column: "email", Strategy: mask.StrategyHash}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (mask.Rules{tt.rule}).Validate(); err == nil {
+				t.Error("Validate() should return error")
+			}
+		})
+	}
+}
+
+// TestRules_Unkeyed tests detecting digest-based rules without DBX_MASK_SALT
+func TestRules_Unkeyed(t *testing.T) {
+	hashed := mask.Rules{{Table: "users", Column: "email", Strategy: mask.StrategyHash}}
+	faked := mask.Rules{{Table: "users", Column: "name", Strategy: mask.StrategyFakeName}}
+	plain := mask.Rules{
+		{Table: "users", Column: "ssn", Strategy: mask.StrategyNull},
+		{Table: "users", Column: "phone", Strategy: mask.StrategyTruncate, Length: 4},
+	}
+
+	t.Setenv("DBX_MASK_SALT", "")
+	if !hashed.Unkeyed() || !faked.Unkeyed() {
+		t.Error("Unkeyed() should report hash and fake rules without a salt")
+	}
+	if plain.Unkeyed() {
+		t.Error("Unkeyed() should ignore rules that don't hash values")
+	}
+
+	t.Setenv("DBX_MASK_SALT", "s3cret")
+	if hashed.Unkeyed() {
+		t.Error("Unkeyed() should be false once DBX_MASK_SALT is set")
+	}
+}
+
+// TestDump_MySQL tests masking mysqldump INSERT statements using columns from CREATE TABLE
+func TestDump_MySQL(t *testing.T) {
+	input := "-- MySQL dump 10.13\n" +
+		"CREATE TABLE `users` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `name` varchar(100) DEFAULT NULL,\n" +
+		"  `email` varchar(255) DEFAULT NULL,\n" +
+		"  `ssn` char(11) DEFAULT NULL,\n" +
+		"  `note` text,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB;\n" +
+		"INSERT INTO `users` VALUES (1,'Jane O\\'Hara','jane@corp.com','123-45-6789','likes, commas'),(2,'John Roe','jane@corp.com',NULL,NULL);\n" +
+		"INSERT INTO `orders` VALUES (1,'jane@corp.com');\n"
+	rules := mask.Rules{
+		{Table: "users", Column: "name", Strategy: mask.StrategyFakeName},
+		{Table: "users", Column: "email", Strategy: mask.StrategyFakeEmail},
+		{Table: "users", Column: "ssn", Strategy: mask.StrategyNull},
+		{Table: "users", Column: "note", Strategy: mask.StrategyTruncate, Length: 5},
+	}
+
+	out, stats := dump(t, input, mask.MySQL, rules, nil)
+	lines := strings.Split(out, "\n")
+	users := lines[9]
+	for _, secret := range []string{"Jane", "John", "jane@corp.com", "123-45-6789", "commas"} {
+		if strings.Contains(users, secret) {
+			t.Errorf("masked INSERT still contains %q: %s", secret, users)
+		}
+	}
+	if !strings.Contains(users, ",'likes'),(2,") {
+		t.Errorf("note not truncated or row layout broken: %s", users)
+	}
+	if strings.Count(users, "NULL") != 3 {
+		t.Errorf("expected ssn masked to NULL and NULLs kept: %s", users)
+	}
+	// Equal inputs mask to equal outputs, so joins on masked columns still line up
+	emails := strings.Split(users, "@example.com")
+	if len(emails) != 3 || emails[0][len(emails[0])-15:] != emails[1][len(emails[1])-15:] {
+		t.Errorf("fake_email should be deterministic: %s", users)
+	}
+	if lines[10] != "INSERT INTO `orders` VALUES (1,'jane@corp.com');" {
+		t.Errorf("tables without rules must pass through unchanged, got %s", lines[10])
+	}
+	if stats.Rows != 2 || stats.Values != 6 {
+		t.Errorf("stats = %+v, want 2 rows and 6 values", stats)
+	}
+}
+
+// TestDump_PostgresCopy tests masking pg_dump COPY blocks
+func TestDump_PostgresCopy(t *testing.T) {
+	input := "COPY public.users (id, email, bio) FROM stdin;\n" +
+		"1\tjane@corp.com\tline one\\nline two\n" +
+		"2\t\\N\tshort\n" +
+		"\\.\n" +
+		"COPY public.audit (id, email) FROM stdin;\n" +
+		"1\tjane@corp.com\n" +
+		"\\.\n"
+	rules := mask.Rules{
+		{Table: "users", Column: "email", Strategy: mask.StrategyHash, Length: 12},
+		{Table: "public.users", Column: "bio", Strategy: mask.StrategyTruncate, Length: 6},
+	}
+
+	out, _ := dump(t, input, mask.Postgres, rules, nil)
+	lines := strings.Split(out, "\n")
+	fields := strings.Split(lines[1], "\t")
+	if len(fields) != 3 || len(fields[1]) != 12 || fields[1] == "jane@corp.com" {
+		t.Errorf("email not hashed to 12 characters: %q", lines[1])
+	}
+	if fields[2] != "line o" {
+		t.Errorf("bio = %q, want truncated decoded text", fields[2])
+	}
+	if lines[2] != "2\t\\N\tshort" {
+		t.Errorf("NULL and short values should be kept, got %q", lines[2])
+	}
+	if lines[5] != "1\tjane@corp.com" {
+		t.Errorf("tables without rules must pass through unchanged, got %q", lines[5])
+	}
+}
+
+// TestDump_SQLite tests masking sqlite3 .dump output with columns supplied by the caller
+func TestDump_SQLite(t *testing.T) {
+	input := "CREATE TABLE users(id INTEGER PRIMARY KEY, email TEXT, bio TEXT);\n" +
+		"INSERT INTO users VALUES(1,'jane@corp.com',replace('first\\nsecond','\\n',char(10)));\n"
+	rules := mask.Rules{
+		{Table: "users", Column: "email", Strategy: mask.StrategyFakeEmail},
+		{Table: "users", Column: "bio", Strategy: mask.StrategyTruncate, Length: 3},
+	}
+	columns := map[string][]string{"users": {"id", "email", "bio"}}
+
+	out, _ := dump(t, input, mask.SQLite, rules, columns)
+	insert := strings.Split(out, "\n")[1]
+	if strings.Contains(insert, "jane@corp.com") || !strings.Contains(insert, "@example.com") {
+		t.Errorf("email not masked: %s", insert)
+	}
+	if !strings.HasSuffix(insert, ",'fir');") {
+		t.Errorf("bio not truncated: %s", insert)
+	}
+}
+
+// TestDump_UnknownColumns tests that a table with rules but no known columns is an error, not a leak
+func TestDump_UnknownColumns(t *testing.T) {
+	input := "INSERT INTO users VALUES(1,'jane@corp.com');\n"
+	rules := mask.Rules{{Table: "users", Column: "email", Strategy: mask.StrategyNull}}
+
+	if _, err := mask.Dump(strings.NewReader(input), &bytes.Buffer{}, mask.SQLite, rules, nil); err == nil {
+		t.Error("Dump() should fail when the columns of a masked table are unknown")
+	}
+}
+
+// TestDump_UnusedRules tests reporting of rules that matched nothing
+func TestDump_UnusedRules(t *testing.T) {
+	input := "COPY public.users (id, email) FROM stdin;\n1\ta@b.c\n\\.\n"
+	rules := mask.Rules{
+		{Table: "users", Column: "email", Strategy: mask.StrategyNull},
+		{Table: "users", Column: "emial", Strategy: mask.StrategyNull},
+	}
+
+	_, stats := dump(t, input, mask.Postgres, rules, nil)
+	if len(stats.Unused) != 1 || stats.Unused[0].Column != "emial" {
+		t.Errorf("Unused = %+v, want the misspelled rule", stats.Unused)
+	}
+}
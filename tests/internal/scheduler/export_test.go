@@ -0,0 +1,247 @@
+package scheduler_test
+
+import (
+	"dbx/internal/scheduler"
+	"strings"
+	"testing"
+)
+
+func exportJob() scheduler.JobConfig {
+	return scheduler.JobConfig{
+		ID:       7,
+		DBType:   "mysql",
+		Schedule: "30 2 * * 1-5",
+		Params: map[string]string{
+			"host":   "db.internal",
+			"user":   "backup",
+			"pass":   "env:DB_PASS",
+			"dbname": "orders",
+			"out":    "./backups",
+		},
+		JobOptions: scheduler.JobOptions{Timezone: "Europe/Berlin", Jitter: "10m"},
+	}
+}
+
+// TestBackupArgs tests translation of a job into dbx backup arguments
+func TestBackupArgs(t *testing.T) {
+	args, err := scheduler.BackupArgs(exportJob())
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "backup mysql --host db.internal --user backup --password env:DB_PASS --database orders --out ./backups"
+	if got != want {
+		t.Errorf("BackupArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestBackupArgs_RejectsLiteralPassword tests that plaintext credentials are never exported
+func TestBackupArgs_RejectsLiteralPassword(t *testing.T) {
+	job := exportJob()
+	job.Params["pass"] = "hunter2"
+	if _, err := scheduler.BackupArgs(job); err == nil {
+		t.Error("BackupArgs() should reject literal passwords")
+	}
+}
+
+// TestBackupArgs_MariaDBPhysical tests that physical MariaDB jobs export with --method physical and no
+// database, including jobs saved with the deprecated physical param
+func TestBackupArgs_MariaDBPhysical(t *testing.T) {
+	for _, param := range []string{"method", "physical"} {
+		job := exportJob()
+		job.DBType = "mariadb"
+		job.Params["dbname"] = ""
+		if param == "method" {
+			job.Params["method"] = "physical"
+		} else {
+			job.Params["physical"] = "true"
+		}
+		args, err := scheduler.BackupArgs(job)
+		if err != nil {
+			t.Fatalf("BackupArgs() error = %v", err)
+		}
+		got := strings.Join(args, " ")
+		want := "backup mariadb --host db.internal --user backup --password env:DB_PASS --method physical --out ./backups"
+		if got != want {
+			t.Errorf("%s: BackupArgs() = %q, want %q", param, got, want)
+		}
+	}
+}
+
+// TestBackupArgs_MySQLPhysicalIncremental tests that the backup method and type are exported
+func TestBackupArgs_MySQLPhysicalIncremental(t *testing.T) {
+	job := exportJob()
+	job.Params["dbname"] = ""
+	job.Params["method"] = "physical"
+	job.Params["type"] = "incremental"
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "backup mysql --host db.internal --user backup --password env:DB_PASS --method physical --type incremental --out ./backups"
+	if got != want {
+		t.Errorf("BackupArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestExport_Systemd tests systemd unit generation
+func TestExport_Systemd(t *testing.T) {
+	files, err := scheduler.Export([]scheduler.JobConfig{exportJob()}, scheduler.ExportSystemd, scheduler.ExportOptions{Binary: "/usr/local/bin/dbx", WorkDir: "/srv/dbx"})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Export() returned %d files, want 2", len(files))
+	}
+
+	service, timer := files[0].Content, files[1].Content
+	for _, want := range []string{"WorkingDirectory=/srv/dbx", `ExecStart=/usr/local/bin/dbx "backup" "mysql"`, `"env:DB_PASS"`} {
+		if !strings.Contains(service, want) {
+			t.Errorf("service unit missing %q:\n%s", want, service)
+		}
+	}
+	for _, want := range []string{"OnCalendar=Mon..Fri *-*-* 2:30:00 Europe/Berlin", "RandomizedDelaySec=10m"} {
+		if !strings.Contains(timer, want) {
+			t.Errorf("timer unit missing %q:\n%s", want, timer)
+		}
+	}
+}
+
+// TestExport_Crontab tests crontab generation
+func TestExport_Crontab(t *testing.T) {
+	files, err := scheduler.Export([]scheduler.JobConfig{exportJob()}, scheduler.ExportCrontab, scheduler.ExportOptions{Binary: "dbx"})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	content := files[0].Content
+	for _, want := range []string{"CRON_TZ=Europe/Berlin", "30 2 * * 1-5 sleep", `\% 601`, "dbx backup mysql", "env:DB_PASS"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("crontab missing %q:\n%s", want, content)
+		}
+	}
+}
+
+// TestExport_K8sCronJob tests Kubernetes CronJob generation
+func TestExport_K8sCronJob(t *testing.T) {
+	files, err := scheduler.Export([]scheduler.JobConfig{exportJob()}, scheduler.ExportK8sCronJob, scheduler.ExportOptions{Image: "registry/dbx:1.0"})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	content := files[0].Content
+	for _, want := range []string{"kind: CronJob", `schedule: "30 2 * * 1-5"`, `timeZone: "Europe/Berlin"`, `image: "registry/dbx:1.0"`, "- name: DB_PASS", "name: dbx-secrets", `- "env:DB_PASS"`} {
+		if !strings.Contains(content, want) {
+			t.Errorf("CronJob missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "hunter2") {
+		t.Error("CronJob contains a plaintext secret")
+	}
+}
+
+// TestExport_UnsupportedFormat tests error handling for unknown formats
+func TestExport_UnsupportedFormat(t *testing.T) {
+	if _, err := scheduler.Export([]scheduler.JobConfig{exportJob()}, "launchd", scheduler.ExportOptions{}); err == nil {
+		t.Error("Export() should return error for unsupported format")
+	}
+}
+
+// TestBackupArgs_Profile tests that profile-based jobs export as dbx backup --profile
+func TestBackupArgs_Profile(t *testing.T) {
+	job := scheduler.JobConfig{ID: 7, DBType: "postgres", Schedule: "@daily", Params: map[string]string{"profile": "prod-orders"}}
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	if got := strings.Join(args, " "); got != "backup postgres --profile prod-orders" {
+		t.Errorf("BackupArgs() = %q", got)
+	}
+}
+
+// TestBackupArgs_Redis tests that Redis jobs export their name label and --bgsave
+func TestBackupArgs_Redis(t *testing.T) {
+	job := scheduler.JobConfig{ID: 8, DBType: "redis", Schedule: "@hourly", Params: map[string]string{
+		"host": "cache.internal", "port": "6380", "pass": "env:REDIS_PASS", "name": "sessions", "out": "./backups", "bgsave": "true",
+	}}
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "backup redis --host cache.internal --port 6380 --password env:REDIS_PASS --name sessions --out ./backups --bgsave"
+	if got != want {
+		t.Errorf("BackupArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestBackupArgs_Consul tests that Consul jobs export as dbx backup consul with the token as --password
+func TestBackupArgs_Consul(t *testing.T) {
+	job := scheduler.JobConfig{ID: 9, DBType: "consul", Schedule: "@daily", Params: map[string]string{
+		"host": "consul.internal", "pass": "env:CONSUL_HTTP_TOKEN", "out": "./backups", "tls_mode": "verify-full",
+	}}
+	if err := scheduler.ValidateJob(job); err != nil {
+		t.Fatalf("ValidateJob() error = %v", err)
+	}
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "backup consul --host consul.internal --password env:CONSUL_HTTP_TOKEN --tls-mode verify-full --out ./backups"
+	if got != want {
+		t.Errorf("BackupArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestBackupArgs_Elasticsearch tests that snapshot jobs export their repository and indices
+func TestBackupArgs_Elasticsearch(t *testing.T) {
+	job := scheduler.JobConfig{ID: 10, DBType: "elasticsearch", Schedule: "@daily", Params: map[string]string{
+		"host": "es.internal", "user": "elastic", "pass": "env:ES_PASSWORD", "repository": "nightly",
+		"indices": "logs-*,metrics-*", "global_state": "true",
+	}}
+	if err := scheduler.ValidateJob(job); err != nil {
+		t.Fatalf("ValidateJob() error = %v", err)
+	}
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "backup elasticsearch --host es.internal --user elastic --password env:ES_PASSWORD --repository nightly --indices logs-*,metrics-* --global-state"
+	if got != want {
+		t.Errorf("BackupArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestBackupArgs_ClickHouse tests that ClickHouse jobs export their database and table filters
+func TestBackupArgs_ClickHouse(t *testing.T) {
+	job := scheduler.JobConfig{ID: 11, DBType: "clickhouse", Schedule: "@daily", Params: map[string]string{
+		"host": "ch.internal", "user": "default", "pass": "env:CH_PASSWORD", "dbname": "analytics",
+		"exclude_tables": "tmp_events", "out": "./backups",
+	}}
+	if err := scheduler.ValidateJob(job); err != nil {
+		t.Fatalf("ValidateJob() error = %v", err)
+	}
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "backup clickhouse --host ch.internal --user default --password env:CH_PASSWORD --database analytics --exclude-tables tmp_events --out ./backups"
+	if got != want {
+		t.Errorf("BackupArgs() = %q, want %q", got, want)
+	}
+}
+
+// TestBackupArgs_Dumper tests that jobs pinned to a dumper export --dumper
+func TestBackupArgs_Dumper(t *testing.T) {
+	job := exportJob()
+	job.Params["dumper"] = "builtin"
+	args, err := scheduler.BackupArgs(job)
+	if err != nil {
+		t.Fatalf("BackupArgs() error = %v", err)
+	}
+	if got := strings.Join(args, " "); !strings.Contains(got, " --dumper builtin") {
+		t.Errorf("BackupArgs() = %q, want --dumper builtin", got)
+	}
+}
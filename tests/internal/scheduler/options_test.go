@@ -0,0 +1,134 @@
+package scheduler_test
+
+import (
+	"dbx/internal/scheduler"
+	"testing"
+	"time"
+)
+
+// TestParseBlackout_Valid tests parsing of valid blackout windows
+func TestParseBlackout_Valid(t *testing.T) {
+	tests := []string{
+		"Mon-Fri 09:00-18:00",
+		"Sat,Sun 00:00-06:00",
+		"22:00-02:00",
+		"Fri-Mon 20:00-24:00",
+	}
+	for _, spec := range tests {
+		if _, err := scheduler.ParseBlackout(spec); err != nil {
+			t.Errorf("ParseBlackout(%q) error = %v", spec, err)
+		}
+	}
+}
+
+// TestParseBlackout_Invalid tests rejection of malformed blackout windows
+func TestParseBlackout_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"Mon-Fri",
+		"Funday 09:00-18:00",
+		"Mon 9-18",
+		"Mon 25:00-26:00",
+		"Mon 09:00-09:00",
+	}
+	for _, spec := range tests {
+		if _, err := scheduler.ParseBlackout(spec); err == nil {
+			t.Errorf("ParseBlackout(%q) should return error", spec)
+		}
+	}
+}
+
+// TestBlackoutWindow_Contains tests window membership, including windows crossing midnight
+func TestBlackoutWindow_Contains(t *testing.T) {
+	weekday, _ := scheduler.ParseBlackout("Mon-Fri 09:00-18:00")
+	overnight, _ := scheduler.ParseBlackout("Fri 22:00-02:00")
+
+	// 2024-01-01 is a Monday
+	tests := []struct {
+		name    string
+		window  scheduler.BlackoutWindow
+		at      time.Time
+		want    bool
+		wantEnd time.Time
+	}{
+		{"weekday inside", weekday, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), true, time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)},
+		{"weekday after", weekday, time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"weekend", weekday, time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"overnight evening", overnight, time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC), true, time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC)},
+		{"overnight morning", overnight, time.Date(2024, 1, 6, 1, 0, 0, 0, time.UTC), true, time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC)},
+		{"overnight wrong day", overnight, time.Date(2024, 1, 5, 1, 0, 0, 0, time.UTC), false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, end := tt.window.Contains(tt.at)
+			if got != tt.want || !end.Equal(tt.wantEnd) {
+				t.Errorf("Contains() = %v, %v, want %v, %v", got, end, tt.want, tt.wantEnd)
+			}
+		})
+	}
+}
+
+// TestNextAllowedTime tests that runs inside a window are deferred to its end
+func TestNextAllowedTime(t *testing.T) {
+	opts := scheduler.JobOptions{
+		Timezone:  "UTC",
+		Blackouts: []string{"Mon-Fri 09:00-18:00", "Mon-Fri 18:00-19:00"},
+	}
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	next, err := opts.NextAllowedTime(at)
+	if err != nil {
+		t.Fatalf("NextAllowedTime() error = %v", err)
+	}
+	if want := time.Date(2024, 1, 1, 19, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("NextAllowedTime() = %v, want %v (chained windows)", next, want)
+	}
+
+	outside := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	if next, _ := opts.NextAllowedTime(outside); !next.Equal(outside) {
+		t.Errorf("NextAllowedTime() = %v, want unchanged %v", next, outside)
+	}
+}
+
+// TestJobOptions_Validate tests option validation
+func TestJobOptions_Validate(t *testing.T) {
+	valid := scheduler.JobOptions{Timezone: "Europe/Berlin", Jitter: "10m", Blackouts: []string{"Mon-Fri 09:00-18:00"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	invalid := []scheduler.JobOptions{
+		{Timezone: "Mars/Olympus_Mons"},
+		{Jitter: "soon"},
+		{Jitter: "-5m"},
+		{Blackouts: []string{"never"}},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should return error", opts)
+		}
+	}
+}
+
+// TestAddJobWithOptions tests that options are validated and persisted
+func TestAddJobWithOptions(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+
+	params := map[string]string{"path": "./test.db", "out": "./backups"}
+	opts := scheduler.JobOptions{Timezone: "America/New_York", Jitter: "5m", Blackouts: []string{"Mon-Fri 09:00-18:00"}}
+	if err := scheduler.AddJobWithOptions("sqlite", "0 2 * * *", params, opts); err != nil {
+		t.Fatalf("AddJobWithOptions() error = %v", err)
+	}
+
+	jobs := scheduler.ListJobs()
+	job := jobs[len(jobs)-1]
+	if job.Timezone != "America/New_York" || job.Jitter != "5m" || len(job.Blackouts) != 1 {
+		t.Errorf("AddJobWithOptions() stored options = %+v", job.JobOptions)
+	}
+
+	if err := scheduler.AddJobWithOptions("sqlite", "@daily", params, scheduler.JobOptions{Timezone: "Nowhere/City"}); err == nil {
+		t.Error("AddJobWithOptions() should reject invalid timezone")
+	}
+}
@@ -0,0 +1,77 @@
+package scheduler_test
+
+import (
+	"dbx/internal/scheduler"
+	"testing"
+	"time"
+)
+
+// TestMissedRun tests detection of runs missed while the daemon was down
+func TestMissedRun(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	daily := scheduler.JobConfig{
+		Schedule:   "0 2 * * *",
+		CreatedAt:  now.AddDate(0, 0, -30),
+		JobOptions: scheduler.JobOptions{Timezone: "UTC", CatchUp: true},
+	}
+	disabled := daily
+	disabled.CatchUp = false
+	narrow := daily
+	narrow.CatchUpWindow = "6h"
+
+	tests := []struct {
+		name       string
+		job        scheduler.JobConfig
+		state      scheduler.JobState
+		wantMissed bool
+		want       time.Time
+	}{
+		{"catch-up disabled", disabled, scheduler.JobState{LastSuccess: now.AddDate(0, 0, -2)}, false, time.Time{}},
+		{"ran today", daily, scheduler.JobState{LastSuccess: time.Date(2024, 1, 10, 2, 0, 5, 0, time.UTC)}, false, time.Time{}},
+		{"missed today", daily, scheduler.JobState{LastSuccess: time.Date(2024, 1, 9, 2, 0, 5, 0, time.UTC)}, true, time.Date(2024, 1, 10, 2, 0, 0, 0, time.UTC)},
+		{"missed several, latest reported", daily, scheduler.JobState{LastSuccess: now.AddDate(0, 0, -5)}, true, time.Date(2024, 1, 10, 2, 0, 0, 0, time.UTC)},
+		{"outside window", narrow, scheduler.JobState{LastSuccess: now.AddDate(0, 0, -2)}, false, time.Time{}},
+		{"never ran counts from creation", daily, scheduler.JobState{}, true, time.Date(2024, 1, 10, 2, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, missed := scheduler.MissedRun(tt.job, tt.state, now)
+			if missed != tt.wantMissed {
+				t.Fatalf("MissedRun() missed = %v, want %v", missed, tt.wantMissed)
+			}
+			if missed && !got.Equal(tt.want) {
+				t.Errorf("MissedRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestJobOptions_InvalidCatchUpWindow tests validation of the catch-up window
+func TestJobOptions_InvalidCatchUpWindow(t *testing.T) {
+	for _, window := range []string{"soon", "-1h", "0s"} {
+		opts := scheduler.JobOptions{CatchUp: true, CatchUpWindow: window}
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate() with catch-up window %q should return error", window)
+		}
+	}
+}
+
+// TestRunNow_RecordsJobState tests that runs are recorded for later catch-up decisions
+func TestRunNow_RecordsJobState(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+	job := addSQLiteJob(t)
+
+	if err := scheduler.RunNow(job.ID); err != nil {
+		t.Fatalf("RunNow() error = %v", err)
+	}
+
+	state, ok := scheduler.JobStates()[job.ID]
+	if !ok {
+		t.Fatal("JobStates() has no entry for the job that just ran")
+	}
+	if state.LastStatus != "SUCCESS" || state.LastSuccess.IsZero() {
+		t.Errorf("JobStates() = %+v, want a recorded success", state)
+	}
+}
@@ -0,0 +1,126 @@
+package scheduler_test
+
+import (
+	"dbx/internal/scheduler"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// addSQLiteJob schedules a backup of a throwaway SQLite file and returns the job
+func addSQLiteJob(t *testing.T) scheduler.JobConfig {
+	t.Helper()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "trigger.db")
+	os.WriteFile(dbPath, []byte("SQLite format 3\x00"), 0644)
+
+	params := map[string]string{"path": dbPath, "out": filepath.Join(tmpDir, "backups")}
+	if err := scheduler.AddJob("sqlite", "@yearly", params); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	jobs := scheduler.ListJobs()
+	return jobs[len(jobs)-1]
+}
+
+// TestTrigger_UnknownJob tests error handling for unknown job IDs
+func TestTrigger_UnknownJob(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+
+	if _, err := scheduler.Trigger(999999); err == nil {
+		t.Error("Trigger() should return error for unknown job id")
+	}
+}
+
+// TestTrigger_RunsLocallyWithoutDaemon tests an on-demand run in the current process
+func TestTrigger_RunsLocallyWithoutDaemon(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+	job := addSQLiteJob(t)
+
+	if _, ok := scheduler.RunningDaemon(); ok {
+		t.Skip("a scheduler daemon is running in this directory")
+	}
+
+	result, err := scheduler.Trigger(job.ID)
+	if err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+	if result.Status != "SUCCESS" {
+		t.Errorf("Trigger() status = %s (%s), want SUCCESS", result.Status, result.Error)
+	}
+	if result.ExecutedBy != "local" {
+		t.Errorf("Trigger() executed by %s, want local", result.ExecutedBy)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(job.Params["out"], "trigger_*.zip"))
+	if len(matches) == 0 {
+		t.Error("Trigger() did not produce a backup")
+	}
+}
+
+// TestTrigger_HandsOffToDaemon tests that a running daemon executes the trigger
+func TestTrigger_HandsOffToDaemon(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+	job := addSQLiteJob(t)
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- scheduler.RunDaemon(stop) }()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := scheduler.RunningDaemon(); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	result, err := scheduler.Trigger(job.ID)
+	if err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+	if result.ExecutedBy != "daemon" {
+		t.Errorf("Trigger() executed by %s, want daemon", result.ExecutedBy)
+	}
+	if result.Status != "SUCCESS" {
+		t.Errorf("Trigger() status = %s (%s), want SUCCESS", result.Status, result.Error)
+	}
+}
+
+// TestRunNow_ProfileJob tests that profile-based jobs read their settings from the config file at run time
+func TestRunNow_ProfileJob(t *testing.T) {
+	useTempConfig(t)
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "profile.db")
+	os.WriteFile(dbPath, []byte("SQLite format 3\x00"), 0644)
+	outDir := filepath.Join(tmpDir, "backups")
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	os.WriteFile(configPath, []byte("profiles:\n  local:\n    engine: sqlite\n    path: "+dbPath+"\n    out: "+outDir+"\n"), 0600)
+	t.Setenv("DBX_CONFIG", configPath)
+
+	scheduler.Init()
+	if err := scheduler.AddJob("sqlite", "@yearly", map[string]string{"profile": "local"}); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	jobs := scheduler.ListJobs()
+	job := jobs[len(jobs)-1]
+
+	if err := scheduler.RunNow(job.ID); err != nil {
+		t.Fatalf("RunNow() error = %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(outDir, "profile_*.zip"))
+	if len(matches) == 0 {
+		t.Error("RunNow() did not back up the profile's database")
+	}
+}
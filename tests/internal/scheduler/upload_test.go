@@ -0,0 +1,130 @@
+package scheduler_test
+
+import (
+	"dbx/internal/scheduler"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// installFakeAWS puts an aws script on PATH that logs the files it is asked to copy
+func installFakeAWS(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: the fake aws CLI is a shell script")
+	}
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "aws.log")
+	script := "#!/bin/sh\necho \"$3\" >> " + logFile + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake aws: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+// uploadedFiles returns the local paths the fake aws CLI uploaded
+func uploadedFiles(t *testing.T, logFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("nothing was uploaded: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// TestRunNow_UploadsBackupNotManifest tests that the backup itself is uploaded, not its .meta.json
+func TestRunNow_UploadsBackupNotManifest(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+	logFile := installFakeAWS(t)
+
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "shop.db")
+	os.WriteFile(dbPath, []byte("SQLite format 3\x00"), 0644)
+	out := filepath.Join(tmpDir, "backups")
+	params := map[string]string{
+		"path": dbPath, "out": out,
+		"upload_cloud": "true", "cloud_provider": "s3", "s3_bucket": "backups",
+	}
+	if err := scheduler.AddJob("sqlite", "@yearly", params); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	jobs := scheduler.ListJobs()
+	job := jobs[len(jobs)-1]
+
+	if err := scheduler.RunNow(job.ID); err != nil {
+		t.Fatalf("RunNow() error = %v", err)
+	}
+
+	uploaded := uploadedFiles(t, logFile)
+	if len(uploaded) != 1 {
+		t.Fatalf("uploaded %v, want exactly one file", uploaded)
+	}
+	if strings.HasSuffix(uploaded[0], ".meta.json") {
+		t.Errorf("uploaded the manifest %s instead of the backup", uploaded[0])
+	}
+	if filepath.Dir(uploaded[0]) != out || !strings.HasPrefix(filepath.Base(uploaded[0]), "shop_") {
+		t.Errorf("uploaded %s, want the shop backup in %s", uploaded[0], out)
+	}
+	if _, err := os.Stat(uploaded[0] + ".meta.json"); err != nil {
+		t.Errorf("uploaded %s, which has no manifest next to it: %v", uploaded[0], err)
+	}
+}
+
+// TestRunNow_UploadsOnlyItsOwnBackup tests that a job doesn't upload another database's backup
+// whose name starts with the same prefix (shop_archive sorts after shop in the backup directory)
+func TestRunNow_UploadsOnlyItsOwnBackup(t *testing.T) {
+	useTempConfig(t)
+	scheduler.Init()
+	logFile := installFakeAWS(t)
+
+	tmpDir := t.TempDir()
+	out := filepath.Join(tmpDir, "backups")
+	var jobs []scheduler.JobConfig
+	for _, name := range []string{"shop_archive", "shop"} {
+		dbPath := filepath.Join(tmpDir, name+".db")
+		os.WriteFile(dbPath, []byte("SQLite format 3\x00"), 0644)
+		params := map[string]string{"path": dbPath, "out": out}
+		if name == "shop" {
+			params["upload_cloud"], params["s3_bucket"] = "true", "backups"
+		}
+		if err := scheduler.AddJob("sqlite", "@yearly", params); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+		all := scheduler.ListJobs()
+		jobs = append(jobs, all[len(all)-1])
+	}
+
+	for _, job := range jobs {
+		if err := scheduler.RunNow(job.ID); err != nil {
+			t.Fatalf("RunNow(%s) error = %v", job.Params["path"], err)
+		}
+	}
+
+	uploaded := uploadedFiles(t, logFile)
+	if len(uploaded) != 1 || strings.HasPrefix(filepath.Base(uploaded[0]), "shop_archive") {
+		t.Errorf("uploaded %v, want only the shop backup", uploaded)
+	}
+}
+
+// TestRunBackup_ProfileParams tests a one-off backup from profile params, as the menu runs them
+func TestRunBackup_ProfileParams(t *testing.T) {
+	logFile := installFakeAWS(t)
+
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "crm.db")
+	os.WriteFile(dbPath, []byte("SQLite format 3\x00"), 0644)
+	out := filepath.Join(tmpDir, "backups")
+	params := map[string]string{"path": dbPath, "out": out, "upload_cloud": "true", "s3_bucket": "backups"}
+
+	if err := scheduler.RunBackup("sqlite", params); err != nil {
+		t.Fatalf("RunBackup() error = %v", err)
+	}
+	uploaded := uploadedFiles(t, logFile)
+	if len(uploaded) != 1 || filepath.Dir(uploaded[0]) != out || !strings.HasPrefix(filepath.Base(uploaded[0]), "crm_") {
+		t.Errorf("uploaded %v, want the crm backup in %s", uploaded, out)
+	}
+}
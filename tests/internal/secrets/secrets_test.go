@@ -0,0 +1,173 @@
+package secrets_test
+
+import (
+	"dbx/internal/secrets"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTempStore points the secret store at a temporary directory
+func useTempStore(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	oldStore, oldKey := secrets.StoreFile, secrets.KeyFile
+	secrets.StoreFile = filepath.Join(dir, "secrets.enc")
+	secrets.KeyFile = filepath.Join(dir, "secret.key")
+	t.Cleanup(func() {
+		secrets.StoreFile, secrets.KeyFile = oldStore, oldKey
+	})
+	return dir
+}
+
+// TestIsReference tests reference detection
+func TestIsReference(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"env:DB_PASS", true},
+		{"file:/run/secrets/db", true},
+		{"store:prod-db", true},
+		{"plain-password", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := secrets.IsReference(tt.value); got != tt.want {
+			t.Errorf("IsReference(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestResolve_Env tests env: references
+func TestResolve_Env(t *testing.T) {
+	t.Setenv("DBX_TEST_PASS", "s3cret")
+
+	got, err := secrets.Resolve("env:DBX_TEST_PASS")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("Resolve() = %q, want %q", got, "s3cret")
+	}
+
+	if _, err := secrets.Resolve("env:DBX_TEST_UNSET_VARIABLE"); err == nil {
+		t.Error("Resolve() should return error for unset environment variable")
+	}
+}
+
+// TestResolve_File tests file: references
+func TestResolve_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	os.WriteFile(path, []byte("from-file\n"), 0600)
+
+	got, err := secrets.Resolve("file:" + path)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "from-file" {
+		t.Errorf("Resolve() = %q, want %q", got, "from-file")
+	}
+
+	if _, err := secrets.Resolve("file:/nonexistent/secret"); err == nil {
+		t.Error("Resolve() should return error for missing secret file")
+	}
+}
+
+// TestResolve_Literal tests that literal values pass through
+func TestResolve_Literal(t *testing.T) {
+	got, err := secrets.Resolve("literal")
+	if err != nil || got != "literal" {
+		t.Errorf("Resolve() = %q, %v, want %q, nil", got, err, "literal")
+	}
+}
+
+// TestStore_SetGetDelete tests the encrypted store round trip
+func TestStore_SetGetDelete(t *testing.T) {
+	useTempStore(t)
+
+	if err := secrets.Set("prod-db", "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	got, err := secrets.Resolve("store:prod-db")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "hunter2" {
+		t.Errorf("Resolve() = %q, want %q", got, "hunter2")
+	}
+
+	names, _ := secrets.List()
+	if len(names) != 1 || names[0] != "prod-db" {
+		t.Errorf("List() = %v, want [prod-db]", names)
+	}
+
+	data, _ := os.ReadFile(secrets.StoreFile)
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("Secret store contains plaintext secret")
+	}
+
+	if err := secrets.Delete("prod-db"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := secrets.Get("prod-db"); err == nil {
+		t.Error("Get() should return error after Delete()")
+	}
+}
+
+// TestStore_Permissions tests that store and key files are private
+func TestStore_Permissions(t *testing.T) {
+	useTempStore(t)
+
+	if err := secrets.Set("name", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	for _, path := range []string{secrets.StoreFile, secrets.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat(%s) error = %v", path, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 && os.Getenv("OS") != "Windows_NT" {
+			t.Errorf("%s permissions = %o, want 600", path, perm)
+		}
+	}
+}
+
+// TestStore_Passphrase tests that DBX_SECRET_KEY is used when set
+func TestStore_Passphrase(t *testing.T) {
+	useTempStore(t)
+	t.Setenv("DBX_SECRET_KEY", "correct horse")
+
+	if err := secrets.Set("name", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := os.Stat(secrets.KeyFile); !os.IsNotExist(err) {
+		t.Error("Key file should not be created when DBX_SECRET_KEY is set")
+	}
+
+	t.Setenv("DBX_SECRET_KEY", "wrong")
+	if _, err := secrets.Get("name"); err == nil {
+		t.Error("Get() should fail with the wrong passphrase")
+	}
+}
+
+// TestResolveParams tests resolving a params map
+func TestResolveParams(t *testing.T) {
+	t.Setenv("DBX_TEST_PASS", "s3cret")
+	params := map[string]string{"host": "localhost", "pass": "env:DBX_TEST_PASS"}
+
+	resolved, err := secrets.ResolveParams(params)
+	if err != nil {
+		t.Fatalf("ResolveParams() error = %v", err)
+	}
+	if resolved["pass"] != "s3cret" || resolved["host"] != "localhost" {
+		t.Errorf("ResolveParams() = %v", resolved)
+	}
+	if params["pass"] != "env:DBX_TEST_PASS" {
+		t.Error("ResolveParams() must not modify the input map")
+	}
+}
@@ -0,0 +1,252 @@
+package tunnel_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"dbx/internal/tunnel"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSSHServer runs a minimal SSH server that accepts clientKey and serves direct-tcpip
+// (local port forward) channels, and returns its address and a matching known_hosts file
+func startSSHServer(t *testing.T, clientKey ssh.PublicKey) (string, string) {
+	t.Helper()
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatalf("failed to create host key: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "deploy" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config)
+		}
+	}()
+
+	addr := listener.Addr().String()
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write known_hosts: %v", err)
+	}
+	return addr, knownHosts
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only port forwarding")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, "bad request")
+			continue
+		}
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.FormatUint(uint64(target.Port), 10)))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			_ = remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			defer channel.Close()
+			defer remote.Close()
+			go func() { _, _ = io.Copy(remote, channel) }()
+			_, _ = io.Copy(channel, remote)
+		}()
+	}
+}
+
+// startEchoServer stands in for a database: it echoes whatever it receives
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// writeClientKey writes a new OpenSSH private key and returns its path and public key
+func writeClientKey(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	sshPub, _ := ssh.NewPublicKey(pub)
+	return keyFile, sshPub
+}
+
+// TestOpen tests that a tunnel carries traffic to the remote address and stops after Close
+func TestOpen(t *testing.T) {
+	keyFile, pub := writeClientKey(t)
+	sshAddr, knownHosts := startSSHServer(t, pub)
+	dbAddr := startEchoServer(t)
+
+	tun, err := tunnel.Open(tunnel.Config{Host: sshAddr, User: "deploy", KeyFile: keyFile, KnownHosts: knownHosts}, dbAddr)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	conn, err := net.Dial("tcp", tun.Addr())
+	if err != nil {
+		t.Fatalf("failed to connect to tunnel: %v", err)
+	}
+	if _, err := conn.Write([]byte("SELECT 1")); err != nil {
+		t.Fatalf("write through tunnel failed: %v", err)
+	}
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "SELECT 1" {
+		t.Errorf("read through tunnel = %q, %v; want echo", reply, err)
+	}
+	_ = conn.Close()
+
+	if err := tun.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if conn, err := net.Dial("tcp", tun.Addr()); err == nil {
+		_ = conn.Close()
+		t.Error("tunnel still accepts connections after Close")
+	}
+}
+
+// TestOpen_UnknownHostKey tests that a jump host missing from known_hosts is refused
+func TestOpen_UnknownHostKey(t *testing.T) {
+	keyFile, pub := writeClientKey(t)
+	sshAddr, _ := startSSHServer(t, pub)
+	empty := filepath.Join(t.TempDir(), "known_hosts")
+	_ = os.WriteFile(empty, nil, 0600)
+
+	_, err := tunnel.Open(tunnel.Config{Host: sshAddr, User: "deploy", KeyFile: keyFile, KnownHosts: empty}, "127.0.0.1:3306")
+	if err == nil {
+		t.Fatal("Open() should refuse an unknown host key")
+	}
+}
+
+// TestRewrite tests that params are pointed at the tunnel for each engine
+func TestRewrite(t *testing.T) {
+	keyFile, pub := writeClientKey(t)
+	sshAddr, knownHosts := startSSHServer(t, pub)
+	cfg := tunnel.Config{Host: sshAddr, User: "deploy", KeyFile: keyFile, KnownHosts: knownHosts}
+
+	params, closeTunnel, err := tunnel.Rewrite("mysql", map[string]string{"host": "db.internal", "user": "root"}, cfg)
+	if err != nil {
+		t.Fatalf("Rewrite(mysql) error = %v", err)
+	}
+	defer closeTunnel()
+	if params["host"] != "127.0.0.1" || params["port"] == "" || params["user"] != "root" {
+		t.Errorf("Rewrite(mysql) = %v, want host/port of the tunnel", params)
+	}
+	if params["tls_server_name"] != "db.internal" {
+		t.Errorf("Rewrite(mysql) tls_server_name = %q, want the database host", params["tls_server_name"])
+	}
+
+	params, closeMongo, err := tunnel.Rewrite("mongodb", map[string]string{"uri": "mongodb://admin@db.internal/?authSource=admin"}, cfg)
+	if err != nil {
+		t.Fatalf("Rewrite(mongodb) error = %v", err)
+	}
+	defer closeMongo()
+	if !strings.HasPrefix(params["uri"], "mongodb://admin@127.0.0.1:") || !strings.HasSuffix(params["uri"], "/?authSource=admin") {
+		t.Errorf("Rewrite(mongodb) uri = %q, want the tunnel address", params["uri"])
+	}
+	if params["tls_server_name"] != "db.internal" {
+		t.Errorf("Rewrite(mongodb) tls_server_name = %q, want the database host", params["tls_server_name"])
+	}
+}
+
+// TestRewrite_Disabled tests that params are unchanged without a jump host
+func TestRewrite_Disabled(t *testing.T) {
+	in := map[string]string{"host": "db.internal", "port": "5432"}
+	out, closeTunnel, err := tunnel.Rewrite("postgres", in, tunnel.Config{})
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	closeTunnel()
+	if out["host"] != "db.internal" || out["port"] != "5432" {
+		t.Errorf("Rewrite() = %v, want params unchanged", out)
+	}
+}
+
+// TestRewrite_Unsupported tests the combinations a tunnel can't serve
+func TestRewrite_Unsupported(t *testing.T) {
+	cfg := tunnel.Config{Host: "jump.example.com"}
+	tests := []struct {
+		name   string
+		engine string
+		params map[string]string
+	}{
+		{"socket", "mysql", map[string]string{"socket": "/run/mysqld.sock"}},
+		{"srv uri", "mongodb", map[string]string{"uri": "mongodb+srv://cluster.example.com/"}},
+		{"sqlite", "sqlite", map[string]string{"path": "app.db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tunnel.Rewrite(tt.engine, tt.params, cfg); err == nil {
+				t.Error("Rewrite() should fail")
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"archive/zip"
 	"dbx/internal/utils"
 	"fmt"
 	"os"
@@ -246,3 +247,47 @@ func TestCompressFile_PathTraversalSecurity(t *testing.T) {
 	}
 }
 
+
+// TestExtractFolder tests that a compressed folder extracts back to the same files
+func TestExtractFolder(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "bundle")
+	os.MkdirAll(filepath.Join(srcDir, "nested"), 0755)
+	os.WriteFile(filepath.Join(srcDir, "a.sql"), []byte("table a"), 0644)
+	os.WriteFile(filepath.Join(srcDir, "nested", "b.sql"), []byte("table b"), 0644)
+
+	zipPath := filepath.Join(tmpDir, "bundle.zip")
+	if err := utils.CompressFolder(srcDir, zipPath); err != nil {
+		t.Fatalf("CompressFolder() error = %v", err)
+	}
+	destDir := filepath.Join(tmpDir, "out")
+	if err := utils.ExtractFolder(zipPath, destDir); err != nil {
+		t.Fatalf("ExtractFolder() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"a.sql": "table a", filepath.Join("nested", "b.sql"): "table b"} {
+		got, err := os.ReadFile(filepath.Join(destDir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("extracted %s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+// TestExtractFolder_PathTraversal tests that entries escaping the destination are rejected
+func TestExtractFolder_PathTraversal(t *testing.T) {
+	tmpDir := t.TempDir()
+	zipPath := filepath.Join(tmpDir, "evil.zip")
+	f, _ := os.Create(zipPath)
+	archive := zip.NewWriter(f)
+	w, _ := archive.Create("../escaped.txt")
+	w.Write([]byte("owned"))
+	archive.Close()
+	f.Close()
+
+	if err := utils.ExtractFolder(zipPath, filepath.Join(tmpDir, "out")); err == nil {
+		t.Error("ExtractFolder() should reject entries outside the destination")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "escaped.txt")); err == nil {
+		t.Error("ExtractFolder() wrote a file outside the destination")
+	}
+}